@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/luanzeba/gh-csd/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Inspect repository aliases",
+}
+
+var aliasResolveCmd = &cobra.Command{
+	Use:   "resolve <input>",
+	Short: "Show how a repo argument resolves, without creating anything",
+	Long: `Show each step 'gh csd create' takes to turn its repo argument into
+owner/repo: the alias lookup in the config, the github/ prefix assumed for
+bare names, and the final repository.
+
+Examples:
+  gh csd alias resolve gh
+  gh csd alias resolve my-org/my-repo`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAliasResolve,
+}
+
+func init() {
+	aliasCmd.AddCommand(aliasResolveCmd)
+	rootCmd.AddCommand(aliasCmd)
+}
+
+func runAliasResolve(cmd *cobra.Command, args []string) error {
+	cfg := loadValidatedConfig()
+	writeRepoResolution(os.Stdout, cfg, explainRepoInput(cfg, args[0]))
+	return nil
+}
+
+func writeRepoResolution(w io.Writer, cfg *config.Config, res repoResolution) {
+	fmt.Fprintf(w, "Input:  %s\n", res.Input)
+
+	resolved := res.Input
+	if res.Alias {
+		resolved = cfg.ResolveAlias(res.Input)
+		fmt.Fprintf(w, "Alias:  %s -> %s\n", res.Input, resolved)
+	} else {
+		fmt.Fprintf(w, "Alias:  no alias named %q\n", res.Input)
+	}
+
+	if res.Prefixed {
+		fmt.Fprintf(w, "Owner:  none given, assuming github/ (%s -> %s)\n", resolved, res.Repo)
+	} else {
+		fmt.Fprintf(w, "Owner:  already owner/repo\n")
+	}
+
+	if cfg.GetRepoConfig(res.Repo) != nil {
+		fmt.Fprintf(w, "Repo:   %s (configured in repos)\n", res.Repo)
+	} else {
+		fmt.Fprintf(w, "Repo:   %s (not in config; defaults apply)\n", res.Repo)
+	}
+}
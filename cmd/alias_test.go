@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/luanzeba/gh-csd/internal/config"
+)
+
+func TestExplainRepoInput(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Repos = map[string]config.Repo{
+		"github/github": {Alias: "gh"},
+		"my-org/web":    {Alias: "web"},
+		// An alias pointing at a bare name still gets the github/ prefix
+		"bare": {Alias: "b"},
+	}
+
+	for _, tt := range []struct {
+		input    string
+		alias    bool
+		prefixed bool
+		repo     string
+	}{
+		{"gh", true, false, "github/github"},
+		{"web", true, false, "my-org/web"},
+		{"b", true, true, "github/bare"},
+		{"docs", false, true, "github/docs"},
+		{"owner/repo", false, false, "owner/repo"},
+	} {
+		res := explainRepoInput(cfg, tt.input)
+		if res.Alias != tt.alias || res.Prefixed != tt.prefixed || res.Repo != tt.repo {
+			t.Errorf("explainRepoInput(%q) = %+v, want alias=%v prefixed=%v repo=%s", tt.input, res, tt.alias, tt.prefixed, tt.repo)
+		}
+		if got := resolveRepoInput(cfg, tt.input); got != tt.repo {
+			t.Errorf("resolveRepoInput(%q) = %q, want %q", tt.input, got, tt.repo)
+		}
+	}
+}
+
+func TestWriteRepoResolution(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Repos = map[string]config.Repo{"github/github": {Alias: "gh"}}
+
+	var buf strings.Builder
+	writeRepoResolution(&buf, cfg, explainRepoInput(cfg, "gh"))
+	want := `Input:  gh
+Alias:  gh -> github/github
+Owner:  already owner/repo
+Repo:   github/github (configured in repos)
+`
+	if buf.String() != want {
+		t.Errorf("alias output =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	writeRepoResolution(&buf, cfg, explainRepoInput(cfg, "docs"))
+	want = `Input:  docs
+Alias:  no alias named "docs"
+Owner:  none given, assuming github/ (docs -> github/docs)
+Repo:   github/docs (not in config; defaults apply)
+`
+	if buf.String() != want {
+		t.Errorf("prefix output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/luanzeba/gh-csd/internal/config"
+	"github.com/luanzeba/gh-csd/internal/ghapp"
+	"github.com/luanzeba/gh-csd/internal/protocol"
+	"github.com/spf13/cobra"
+)
+
+var (
+	appTokenRepo        string
+	appTokenPermissions []string
+)
+
+var appTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Get a short-lived GitHub token from the local server",
+	Long: `Ask the local gh-csd server for a short-lived GitHub token scoped to one
+repository, instead of running gh commands on your local machine.
+
+The server mints a GitHub App installation token (valid for an hour) and
+only for the repositories and permissions allowed in
+'server.token_minting'. Your own 'gh auth token' is never sent, since it
+can't be narrowed to a repository or permission.
+
+The token is printed to stdout and its expiry to stderr.
+
+Examples:
+  # Token for the codespace's repository with everything the server allows
+  gh csd token
+
+  # Read-only token for another repository
+  GH_TOKEN=$(gh csd token -R github/docs -p contents=read) gh api repos/github/docs`,
+	Args: cobra.NoArgs,
+	RunE: runAppToken,
+}
+
+func init() {
+	appTokenCmd.Flags().StringVarP(&appTokenRepo, "repo", "R", "", "Repository (owner/repo) the token is for (default: the current repository)")
+	appTokenCmd.Flags().StringArrayVarP(&appTokenPermissions, "permission", "p", nil, "Permission to request as name=level, e.g. pull_requests=write (repeatable)")
+	rootCmd.AddCommand(appTokenCmd)
+}
+
+func runAppToken(cmd *cobra.Command, args []string) error {
+	repo := appTokenRepo
+	if repo == "" {
+		var err error
+		repo, err = detectCodespaceRepo()
+		if err != nil {
+			return fmt.Errorf("could not detect the repository, pass --repo: %w", err)
+		}
+	}
+
+	permissions, err := parsePermissions(appTokenPermissions)
+	if err != nil {
+		return err
+	}
+
+	req := protocol.ExecRequest{
+		Type:        "token",
+		Token:       clientToken(),
+		Client:      sessionClientID(),
+		Repository:  repo,
+		Permissions: permissions,
+	}
+
+	client := newSocketClient(getRemoteSocketPath(), 60*time.Second)
+	resp, err := postLocalRequest(context.Background(), client, &req)
+	if err != nil {
+		return fmt.Errorf("%w (is 'gh csd server' running and the socket forwarded?)", err)
+	}
+	defer resp.Body.Close()
+
+	var tokenResp protocol.TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if tokenResp.Error != "" {
+		return errors.New(tokenResp.Error)
+	}
+	if tokenResp.Token == "" {
+		return errors.New("server returned no token (it may be too old to mint tokens)")
+	}
+
+	fmt.Println(tokenResp.Token)
+	if !tokenResp.ExpiresAt.IsZero() {
+		fmt.Fprintf(os.Stderr, "Token for %s expires at %s\n", repo, tokenResp.ExpiresAt.Local().Format("15:04:05"))
+	}
+	return nil
+}
+
+// parsePermissions parses name=level flags into a permissions map.
+func parsePermissions(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	permissions := make(map[string]string, len(values))
+	for _, value := range values {
+		name, level, ok := strings.Cut(value, "=")
+		name, level = strings.TrimSpace(name), strings.TrimSpace(level)
+		if !ok || name == "" || permissionRank(level) == 0 {
+			return nil, fmt.Errorf("invalid permission %q (expected name=read|write|admin)", value)
+		}
+		permissions[name] = level
+	}
+	return permissions, nil
+}
+
+// permissionRank orders permission levels; unknown levels rank 0.
+func permissionRank(level string) int {
+	switch level {
+	case "read":
+		return 1
+	case "write":
+		return 2
+	case "admin":
+		return 3
+	}
+	return 0
+}
+
+// tokenMinter mints GitHub App tokens within the limits configured in
+// server.token_minting.
+type tokenMinter struct {
+	app         *ghapp.App
+	repos       []string
+	permissions map[string]string
+}
+
+func newTokenMinter(cfg *config.TokenMinting) (*tokenMinter, error) {
+	if cfg.AppID == 0 || cfg.PrivateKeyPath == "" {
+		return nil, errors.New("server.token_minting needs app_id and private_key_path")
+	}
+	key, err := ghapp.LoadPrivateKey(expandLocalHome(cfg.PrivateKeyPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load GitHub App private key: %w", err)
+	}
+	return &tokenMinter{
+		app: &ghapp.App{
+			AppID:          cfg.AppID,
+			InstallationID: cfg.InstallationID,
+			PrivateKey:     key,
+		},
+		repos:       cfg.Repos,
+		permissions: cfg.Permissions,
+	}, nil
+}
+
+// grant checks a request against the configured limits and returns the
+// permissions to mint. With none requested, everything allowed is granted.
+func (m *tokenMinter) grant(repo string, requested map[string]string) (map[string]string, error) {
+	if !repoAllowed(repo, m.repos) {
+		return nil, fmt.Errorf("tokens for %s are not allowed (see server.token_minting.repos)", repo)
+	}
+	if len(m.permissions) == 0 {
+		return nil, errors.New("no permissions are allowed (see server.token_minting.permissions)")
+	}
+	if len(requested) == 0 {
+		return m.permissions, nil
+	}
+
+	var denied []string
+	for name, level := range requested {
+		allowed, ok := m.permissions[name]
+		if !ok || permissionRank(level) == 0 || permissionRank(level) > permissionRank(allowed) {
+			denied = append(denied, name+"="+level)
+		}
+	}
+	if len(denied) > 0 {
+		sort.Strings(denied)
+		return nil, fmt.Errorf("permissions not allowed: %s", strings.Join(denied, ", "))
+	}
+	return requested, nil
+}
+
+// repoAllowed reports whether repo matches an owner/repo or owner/*
+// pattern, case-insensitively like GitHub.
+func repoAllowed(repo string, patterns []string) bool {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		patternOwner, patternName, _ := strings.Cut(pattern, "/")
+		if !strings.EqualFold(owner, patternOwner) {
+			continue
+		}
+		if patternName == "*" || strings.EqualFold(name, patternName) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleToken answers a "token" request with a scoped installation token.
+func (s *Server) handleToken(ctx context.Context, w http.ResponseWriter, req *protocol.ExecRequest) {
+	entry := auditEntry{Time: time.Now(), Command: tokenAuditCommand(req), Client: s.Clients.name(req.Client)}
+	writeError := func(msg string) {
+		s.logger.Printf("token request denied: %s", msg)
+		s.Clients.block(req.Client)
+		entry.ExitCode, entry.Blocked, entry.Error = 1, true, msg
+		s.recordAudit(entry)
+		json.NewEncoder(w).Encode(protocol.TokenResponse{Error: msg})
+	}
+
+	if s.Minter == nil {
+		writeError("this server doesn't mint tokens (configure server.token_minting)")
+		return
+	}
+	permissions, err := s.Minter.grant(req.Repository, req.Permissions)
+	if err != nil {
+		writeError(err.Error())
+		return
+	}
+
+	token, err := s.Minter.app.InstallationToken(ctx, req.Repository, permissions)
+	if err != nil {
+		s.logger.Printf("failed to mint token: %v", err)
+		entry.ExitCode, entry.Error = 1, err.Error()
+		entry.DurationMs = time.Since(entry.Time).Milliseconds()
+		s.recordAudit(entry)
+		json.NewEncoder(w).Encode(protocol.TokenResponse{Error: err.Error()})
+		return
+	}
+
+	s.logger.Printf("minted token for %s %v, expires %s", req.Repository, permissions, token.ExpiresAt.Format(time.RFC3339))
+	entry.DurationMs = time.Since(entry.Time).Milliseconds()
+	s.recordAudit(entry)
+	json.NewEncoder(w).Encode(protocol.TokenResponse{Token: token.Token, ExpiresAt: token.ExpiresAt})
+}
+
+// tokenAuditCommand describes a token request for the audit log, e.g.
+// "token owner/repo contents=read".
+func tokenAuditCommand(req *protocol.ExecRequest) []string {
+	command := []string{"token", req.Repository}
+	for name, level := range req.Permissions {
+		command = append(command, name+"="+level)
+	}
+	sort.Strings(command[2:])
+	return command
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRepoAllowed(t *testing.T) {
+	patterns := []string{"luanzeba/gh-csd", "github/*"}
+	tests := []struct {
+		repo string
+		want bool
+	}{
+		{"luanzeba/gh-csd", true},
+		{"LuanZeba/GH-CSD", true},
+		{"luanzeba/dotfiles", false},
+		{"github/docs", true},
+		{"githubber/docs", false},
+		{"github", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := repoAllowed(tt.repo, patterns); got != tt.want {
+			t.Errorf("repoAllowed(%q) = %v, want %v", tt.repo, got, tt.want)
+		}
+	}
+}
+
+func TestTokenMinterGrant(t *testing.T) {
+	m := &tokenMinter{
+		repos:       []string{"luanzeba/gh-csd"},
+		permissions: map[string]string{"contents": "read", "pull_requests": "write"},
+	}
+
+	tests := []struct {
+		name      string
+		repo      string
+		requested map[string]string
+		want      map[string]string
+		wantErr   string
+	}{
+		{
+			name: "defaults to everything allowed",
+			repo: "luanzeba/gh-csd",
+			want: map[string]string{"contents": "read", "pull_requests": "write"},
+		},
+		{
+			name:      "narrower level",
+			repo:      "luanzeba/gh-csd",
+			requested: map[string]string{"pull_requests": "read"},
+			want:      map[string]string{"pull_requests": "read"},
+		},
+		{
+			name:      "level above the limit",
+			repo:      "luanzeba/gh-csd",
+			requested: map[string]string{"contents": "write"},
+			wantErr:   "contents=write",
+		},
+		{
+			name:      "permission not configured",
+			repo:      "luanzeba/gh-csd",
+			requested: map[string]string{"administration": "read"},
+			wantErr:   "administration=read",
+		},
+		{
+			name:    "repository not allowed",
+			repo:    "luanzeba/other",
+			wantErr: "not allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.grant(tt.repo, tt.requested)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("grant() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("grant() failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("grant() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePermissions(t *testing.T) {
+	got, err := parsePermissions([]string{"contents=read", "pull_requests = write"})
+	if err != nil {
+		t.Fatalf("parsePermissions failed: %v", err)
+	}
+	want := map[string]string{"contents": "read", "pull_requests": "write"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePermissions() = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"contents", "contents=all", "=read"} {
+		if _, err := parsePermissions([]string{bad}); err == nil {
+			t.Errorf("parsePermissions(%q) succeeded, want error", bad)
+		}
+	}
+}
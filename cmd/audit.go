@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditSince string
+	auditUntil string
+)
+
+var serverAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the audit log of executed commands",
+	Long: `Show every command the server has executed or blocked, from
+~/.csd/audit.log.
+
+Each entry records the hash of the entry before it, so edited or removed
+entries break the chain and are reported.
+
+--since and --until accept a duration (e.g. 24h), a date (2006-01-02) or
+an RFC 3339 timestamp.`,
+	Args: cobra.NoArgs,
+	RunE: runServerAudit,
+}
+
+func init() {
+	serverAuditCmd.Flags().StringVar(&auditSince, "since", "", "Only show entries at or after this time")
+	serverAuditCmd.Flags().StringVar(&auditUntil, "until", "", "Only show entries before this time")
+	serverCmd.AddCommand(serverAuditCmd)
+}
+
+// auditEntry is one line of the audit log.
+type auditEntry struct {
+	Time       time.Time `json:"time"`
+	Command    []string  `json:"command"`
+	Workdir    string    `json:"workdir,omitempty"`
+	Client     string    `json:"client,omitempty"` // codespace the request came from
+	ExitCode   int       `json:"exit_code"`
+	DurationMs int64     `json:"duration_ms"`
+	Blocked    bool      `json:"blocked,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	// Prev is the hash of the previous line, chaining entries together.
+	Prev string `json:"prev"`
+}
+
+// auditLog appends hash-chained JSON entries to the audit log. A nil
+// *auditLog discards entries.
+type auditLog struct {
+	mu   sync.Mutex
+	file *reopenableFile
+	last string
+}
+
+func openAuditLog(path string) (*auditLog, error) {
+	last, err := lastAuditHash(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := openReopenableFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLog{file: file, last: last}, nil
+}
+
+// Record appends entry to the log, filling in Prev.
+func (a *auditLog) Record(entry auditEntry) error {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry.Prev = a.last
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := a.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	a.last = auditHash(line)
+	return nil
+}
+
+// Reopen reopens the log file after rotation. The chain continues from the
+// last entry written.
+func (a *auditLog) Reopen() error {
+	if a == nil {
+		return nil
+	}
+	return a.file.Reopen()
+}
+
+func (a *auditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	return a.file.Close()
+}
+
+func auditHash(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+// lastAuditHash returns the hash of the last line in the audit log at
+// path, or "" if it is empty or doesn't exist.
+func lastAuditHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer f.Close()
+
+	var last []byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			last = append(last[:0], scanner.Bytes()...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if last == nil {
+		return "", nil
+	}
+	return auditHash(last), nil
+}
+
+// readAuditLog parses the audit log from r. brokenAt is the 1-based line
+// of the first entry whose Prev doesn't match the line before it, or 0 if
+// the chain is intact.
+func readAuditLog(r io.Reader) (entries []auditEntry, brokenAt int, err error) {
+	var prev string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry auditEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, 0, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		// The first entry of a rotated file chains to a line we can't see
+		if prev != "" && entry.Prev != prev && brokenAt == 0 {
+			brokenAt = lineNum
+		}
+		prev = auditHash(line)
+		entries = append(entries, entry)
+	}
+	return entries, brokenAt, scanner.Err()
+}
+
+// parseAuditTime parses a --since/--until value relative to now.
+func parseAuditTime(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use a duration like 24h, a date like 2006-01-02, or RFC 3339)", value)
+}
+
+func runServerAudit(cmd *cobra.Command, args []string) error {
+	now := time.Now()
+	var since, until time.Time
+	var err error
+	if auditSince != "" {
+		if since, err = parseAuditTime(auditSince, now); err != nil {
+			return err
+		}
+	}
+	if auditUntil != "" {
+		if until, err = parseAuditTime(auditUntil, now); err != nil {
+			return err
+		}
+	}
+
+	path := getAuditLogPath()
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			fmt.Println("No commands audited yet.")
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	entries, brokenAt, err := readAuditLog(f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tEXIT\tDURATION\tCOMMAND")
+	for _, e := range entries {
+		if (!since.IsZero() && e.Time.Before(since)) || (!until.IsZero() && !e.Time.Before(until)) {
+			continue
+		}
+		exit := fmt.Sprintf("%d", e.ExitCode)
+		if e.Blocked {
+			exit = "BLOCKED"
+		}
+		duration := (time.Duration(e.DurationMs) * time.Millisecond).String()
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04:05"), exit, duration, strings.Join(e.Command, " "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if brokenAt > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: audit log chain is broken at line %d; entries may have been edited or removed\n", brokenAt)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLogChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	audit, err := openAuditLog(path)
+	if err != nil {
+		t.Fatalf("openAuditLog failed: %v", err)
+	}
+	audit.Record(auditEntry{Time: time.Now(), Command: []string{"gh", "pr", "view"}})
+	audit.Record(auditEntry{Time: time.Now(), Command: []string{"rm", "-rf", "/"}, ExitCode: 1, Blocked: true})
+	audit.Close()
+
+	// Reopening continues the chain from the last entry
+	audit, err = openAuditLog(path)
+	if err != nil {
+		t.Fatalf("openAuditLog (reopen) failed: %v", err)
+	}
+	audit.Record(auditEntry{Time: time.Now(), Command: []string{"gh", "pr", "list"}})
+	audit.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, brokenAt, err := readAuditLog(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("readAuditLog failed: %v", err)
+	}
+	if len(entries) != 3 || brokenAt != 0 {
+		t.Fatalf("got %d entries, brokenAt=%d; want 3 entries and an intact chain", len(entries), brokenAt)
+	}
+	if !entries[1].Blocked {
+		t.Error("blocked entry lost its blocked marker")
+	}
+
+	// Removing an entry breaks the chain at the entry after it
+	lines := strings.SplitAfter(string(data), "\n")
+	tampered := lines[0] + lines[2]
+	if _, brokenAt, _ := readAuditLog(strings.NewReader(tampered)); brokenAt != 2 {
+		t.Errorf("brokenAt after removing an entry = %d, want 2", brokenAt)
+	}
+}
+
+func TestParseAuditTime(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{value: "24h", want: now.Add(-24 * time.Hour)},
+		{value: "2026-10-01", want: time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)},
+		{value: "2026-10-15T08:00:00Z", want: time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseAuditTime(tt.value, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseAuditTime(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+		}
+	}
+
+	if _, err := parseAuditTime("last week", now); err == nil {
+		t.Error("parseAuditTime(\"last week\") should fail")
+	}
+}
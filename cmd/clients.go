@@ -0,0 +1,290 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+	"github.com/luanzeba/gh-csd/internal/protocol"
+)
+
+// clientEnvVar carries a session's client ID into the codespace, where
+// 'gh csd local' and 'gh csd token' send it with each request. ssh only
+// sets it if the codespace's sshd accepts it (AcceptEnv), which it doesn't
+// by default, so the ID is also written to getClientIDPath there.
+const clientEnvVar = "CSD_CLIENT"
+
+// clientIDBytes is the length of a client ID before hex encoding.
+const clientIDBytes = 16
+
+var (
+	errClientRequired = errors.New("this server only accepts requests from 'gh csd ssh' sessions it knows; reconnect with 'gh csd ssh'")
+	errRateLimited    = errors.New("rate limit exceeded")
+)
+
+// getClientsDir returns the directory where 'gh csd ssh' registers the
+// client IDs of its sessions. Only the local machine can write it, so a
+// codespace can't claim to be another one.
+func getClientsDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".csd", "clients")
+}
+
+// getClientIDPath returns ~/.csd/client, where 'gh csd ssh' writes its
+// client ID in the codespace.
+func getClientIDPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".csd", "client")
+}
+
+// sessionClientID returns the client ID to send with requests from the
+// codespace: $CSD_CLIENT if ssh set it, otherwise the ID written to
+// ~/.csd/client by the latest 'gh csd ssh' session.
+func sessionClientID() string {
+	if id := os.Getenv(clientEnvVar); id != "" {
+		return id
+	}
+	data, err := os.ReadFile(getClientIDPath())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// syncClientIDToCodespace writes id to ~/.csd/client in codespace name,
+// so requests carry it even where sshd drops $CSD_CLIENT.
+func syncClientIDToCodespace(name, id string) error {
+	cmd := exec.Command("gh", "cs", "ssh", "-c", name, "--",
+		"umask 077 && mkdir -p ~/.csd && cat > ~/.csd/client")
+	cmd.Stdin = strings.NewReader(id + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := gh.RunTraced(cmd); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// clientRegistration is the record 'gh csd ssh' writes for a session.
+type clientRegistration struct {
+	Codespace  string    `json:"codespace"`
+	Repository string    `json:"repository"`
+	Created    time.Time `json:"created"`
+}
+
+// registerClient generates a client ID for an SSH session to name and
+// records it for the server. The returned func removes the record.
+func registerClient(name, repo string) (string, func(), error) {
+	buf := make([]byte, clientIDBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("failed to generate client ID: %w", err)
+	}
+	id := hex.EncodeToString(buf)
+
+	dir := getClientsDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", nil, err
+	}
+	data, err := json.Marshal(clientRegistration{Codespace: name, Repository: repo, Created: time.Now()})
+	if err != nil {
+		return "", nil, err
+	}
+	path := filepath.Join(dir, id)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", nil, err
+	}
+	return id, func() { os.Remove(path) }, nil
+}
+
+// isClientID reports whether id looks like an ID from registerClient,
+// which also keeps it from naming a path outside the clients directory.
+func isClientID(id string) bool {
+	if len(id) != 2*clientIDBytes {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// readClientRegistration returns the registration for id in dir, or false
+// if id isn't a registered, still running session.
+func readClientRegistration(dir, id string) (clientRegistration, bool) {
+	var reg clientRegistration
+	if !isClientID(id) {
+		return reg, false
+	}
+	data, err := os.ReadFile(filepath.Join(dir, id))
+	if err != nil {
+		return reg, false
+	}
+	if err := json.Unmarshal(data, &reg); err != nil {
+		return reg, false
+	}
+	return reg, true
+}
+
+// clientActivity is what the server has seen from one client.
+type clientActivity struct {
+	registration clientRegistration
+	requests     int
+	blocked      int
+	lastSeen     time.Time
+	lastCommand  []string
+	// recent holds the times of requests in the last minute, for the
+	// rate limit
+	recent []time.Time
+}
+
+// clientTracker attributes requests to the 'gh csd ssh' session they came
+// through and applies per-client policy. Requests without a registered
+// client ID are tracked together under the empty ID.
+type clientTracker struct {
+	mu  sync.Mutex
+	dir string
+	// RateLimit is the most requests a client may make per minute. 0
+	// means no limit.
+	RateLimit int
+	// RequireClient rejects requests that don't come from a registered
+	// session.
+	RequireClient bool
+
+	clients map[string]*clientActivity
+}
+
+func newClientTracker(dir string) *clientTracker {
+	return &clientTracker{dir: dir, clients: make(map[string]*clientActivity)}
+}
+
+// admit records a request from client id and checks it against the
+// per-client policy. Rejected requests are counted as blocked by the
+// caller's audit record, like those blocked by the command policy.
+func (t *clientTracker) admit(id string, command []string, now time.Time) error {
+	reg, registered := readClientRegistration(t.dir, id)
+	if !registered {
+		id = ""
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	activity := t.activity(id)
+	if registered {
+		activity.registration = reg
+	}
+	activity.requests++
+	activity.lastSeen = now
+	activity.lastCommand = command
+
+	if !registered && t.RequireClient {
+		return errClientRequired
+	}
+
+	if t.RateLimit > 0 {
+		cutoff := now.Add(-time.Minute)
+		recent := activity.recent[:0]
+		for _, at := range activity.recent {
+			if at.After(cutoff) {
+				recent = append(recent, at)
+			}
+		}
+		activity.recent = recent
+		if len(recent) >= t.RateLimit {
+			return fmt.Errorf("%w: %s may make %d requests per minute (server.client_rate_limit)", errRateLimited, t.nameLocked(id), t.RateLimit)
+		}
+		activity.recent = append(activity.recent, now)
+	}
+	return nil
+}
+
+// block counts a request from id that was rejected by the server.
+func (t *clientTracker) block(id string) {
+	if _, ok := readClientRegistration(t.dir, id); !ok {
+		id = ""
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.activity(id).blocked++
+}
+
+// name returns the codespace a request from id came from, for logs and
+// the audit log, or "" if it didn't identify one.
+func (t *clientTracker) name(id string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if activity, ok := t.clients[id]; ok {
+		return activity.registration.Codespace
+	}
+	return ""
+}
+
+func (t *clientTracker) nameLocked(id string) string {
+	if name := t.clients[id].registration.Codespace; name != "" {
+		return name
+	}
+	return "unidentified clients"
+}
+
+func (t *clientTracker) activity(id string) *clientActivity {
+	activity, ok := t.clients[id]
+	if !ok {
+		activity = &clientActivity{}
+		t.clients[id] = activity
+	}
+	return activity
+}
+
+// status returns the activity of every client seen, most recent first.
+func (t *clientTracker) status() []protocol.ClientStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	clients := make([]protocol.ClientStatus, 0, len(t.clients))
+	for id, activity := range t.clients {
+		_, connected := readClientRegistration(t.dir, id)
+		clients = append(clients, protocol.ClientStatus{
+			Codespace:   activity.registration.Codespace,
+			Repository:  activity.registration.Repository,
+			Connected:   connected,
+			Requests:    activity.requests,
+			Blocked:     activity.blocked,
+			LastSeen:    activity.lastSeen,
+			LastCommand: activity.lastCommand,
+		})
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].LastSeen.After(clients[j].LastSeen)
+	})
+	return clients
+}
+
+// writeClientStatus prints the clients reported by 'gh csd server status'.
+func writeClientStatus(w io.Writer, clients []protocol.ClientStatus, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CODESPACE\tREPOSITORY\tSESSION\tREQUESTS\tBLOCKED\tLAST SEEN\tLAST COMMAND")
+	for _, c := range clients {
+		codespace, repo, session := c.Codespace, c.Repository, "ended"
+		switch {
+		case codespace == "":
+			codespace, repo, session = "(unidentified)", "-", "-"
+		case c.Connected:
+			session = "connected"
+		}
+		ago := now.Sub(c.LastSeen).Round(time.Second)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%s ago\t%s\n", codespace, repo, session, c.Requests, c.Blocked, ago, strings.Join(c.LastCommand, " "))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luanzeba/gh-csd/internal/protocol"
+)
+
+// writeTestClient registers id in dir the way registerClient does.
+func writeTestClient(t *testing.T, dir, id, codespace string) {
+	t.Helper()
+	data, err := json.Marshal(clientRegistration{Codespace: codespace, Repository: "github/github"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, id), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRegisterClient(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	id, unregister, err := registerClient("my-cs", "github/github")
+	if err != nil {
+		t.Fatalf("registerClient() error = %v", err)
+	}
+	if !isClientID(id) {
+		t.Fatalf("registerClient() ID %q is not a client ID", id)
+	}
+	reg, ok := readClientRegistration(getClientsDir(), id)
+	if !ok || reg.Codespace != "my-cs" || reg.Repository != "github/github" {
+		t.Fatalf("readClientRegistration() = %+v, %v", reg, ok)
+	}
+
+	unregister()
+	if _, ok := readClientRegistration(getClientsDir(), id); ok {
+		t.Error("registration should be gone after unregister")
+	}
+}
+
+func TestIsClientID(t *testing.T) {
+	valid := strings.Repeat("ab", clientIDBytes)
+	if !isClientID(valid) {
+		t.Errorf("isClientID(%q) = false", valid)
+	}
+	for _, id := range []string{"", "abc", "../../.ssh/id_ed25519", strings.Repeat("zz", clientIDBytes)} {
+		if isClientID(id) {
+			t.Errorf("isClientID(%q) = true", id)
+		}
+	}
+}
+
+func TestClientTrackerAdmit(t *testing.T) {
+	dir := t.TempDir()
+	id := strings.Repeat("0a", clientIDBytes)
+	writeTestClient(t, dir, id, "my-cs")
+
+	tracker := newClientTracker(dir)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	if err := tracker.admit(id, []string{"gh", "pr", "view"}, now); err != nil {
+		t.Fatalf("admit() registered client error = %v", err)
+	}
+	if got := tracker.name(id); got != "my-cs" {
+		t.Errorf("name() = %q, want my-cs", got)
+	}
+	if err := tracker.admit("forged", []string{"gh", "pr", "list"}, now); err != nil {
+		t.Fatalf("admit() unidentified client error = %v", err)
+	}
+	if got := tracker.name("forged"); got != "" {
+		t.Errorf("name() for an unknown ID = %q, want empty", got)
+	}
+
+	tracker.RequireClient = true
+	if err := tracker.admit("", []string{"gh"}, now); !errors.Is(err, errClientRequired) {
+		t.Errorf("admit() with RequireClient = %v, want errClientRequired", err)
+	}
+	if err := tracker.admit(id, []string{"gh"}, now); err != nil {
+		t.Errorf("admit() registered client with RequireClient = %v", err)
+	}
+}
+
+func TestClientTrackerRateLimit(t *testing.T) {
+	dir := t.TempDir()
+	id := strings.Repeat("0b", clientIDBytes)
+	writeTestClient(t, dir, id, "my-cs")
+
+	tracker := newClientTracker(dir)
+	tracker.RateLimit = 2
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		if err := tracker.admit(id, nil, now); err != nil {
+			t.Fatalf("request %d: admit() error = %v", i, err)
+		}
+	}
+	err := tracker.admit(id, nil, now.Add(30*time.Second))
+	if !errors.Is(err, errRateLimited) || !strings.Contains(err.Error(), "my-cs") {
+		t.Fatalf("admit() over the limit = %v, want a rate limit error naming the codespace", err)
+	}
+	// Other clients have their own budget
+	if err := tracker.admit("", nil, now); err != nil {
+		t.Errorf("admit() for another client = %v", err)
+	}
+	if err := tracker.admit(id, nil, now.Add(61*time.Second)); err != nil {
+		t.Errorf("admit() after the window = %v", err)
+	}
+}
+
+func TestClientTrackerStatus(t *testing.T) {
+	dir := t.TempDir()
+	active := strings.Repeat("0c", clientIDBytes)
+	ended := strings.Repeat("0d", clientIDBytes)
+	writeTestClient(t, dir, active, "active-cs")
+	writeTestClient(t, dir, ended, "ended-cs")
+
+	tracker := newClientTracker(dir)
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	tracker.admit(ended, []string{"gh", "pr", "list"}, now)
+	tracker.admit(active, []string{"gh", "pr", "view"}, now.Add(time.Minute))
+	tracker.block(active)
+	os.Remove(filepath.Join(dir, ended))
+
+	clients := tracker.status()
+	if len(clients) != 2 {
+		t.Fatalf("status() returned %d clients, want 2", len(clients))
+	}
+	if c := clients[0]; c.Codespace != "active-cs" || !c.Connected || c.Requests != 1 || c.Blocked != 1 {
+		t.Errorf("status()[0] = %+v", c)
+	}
+	if c := clients[1]; c.Codespace != "ended-cs" || c.Connected {
+		t.Errorf("status()[1] = %+v", c)
+	}
+}
+
+func TestWriteClientStatus(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	clients := []protocol.ClientStatus{
+		{Codespace: "my-cs", Repository: "github/github", Connected: true, Requests: 3, LastSeen: now.Add(-5 * time.Second), LastCommand: []string{"gh", "pr", "view"}},
+		{Requests: 1, Blocked: 1, LastSeen: now.Add(-time.Minute)},
+	}
+
+	var buf bytes.Buffer
+	if err := writeClientStatus(&buf, clients, now); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"my-cs", "connected", "5s ago", "gh pr view", "(unidentified)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone [repo]",
+	Short: "Connect to a codespace for a repo, creating one only if needed",
+	Long: `Get into a codespace for a repository, whether or not one exists yet.
+
+If you already have a codespace for the repo (on --branch, if given), the
+most recently used one is started if needed, selected as current and
+connected to, like 'gh csd start --ssh'. Otherwise a new one is created
+exactly as 'gh csd create' would, with the same alias resolution, config
+settings, hooks and flags.
+
+Running it again is safe: it keeps reusing the same codespace instead of
+creating duplicates.
+
+Examples:
+  gh csd clone gh
+  gh csd clone github/github -b my-feature
+  gh csd clone gh --no-ssh`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runClone,
+}
+
+func init() {
+	addCreateFlags(cloneCmd)
+	rootCmd.AddCommand(cloneCmd)
+}
+
+func runClone(cmd *cobra.Command, args []string) error {
+	createReuse = true
+	return runCreate(cmd, args)
+}
@@ -1,18 +1,37 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"os/exec"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/luanzeba/gh-csd/internal/config"
+	"github.com/luanzeba/gh-csd/internal/gh"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 	"gopkg.in/yaml.v3"
 )
 
 var (
-	configEdit bool
-	configInit bool
+	configEdit     bool
+	configInit     bool
+	configNoBackup bool
+
+	configValidateStrict        bool
+	configValidateCheckRepos    bool
+	configValidateCheckMachines bool
+
+	testHooksName        string
+	testHooksRepo        string
+	testHooksBranch      string
+	testHooksMachine     string
+	testHooksState       string
+	testHooksDisplayName string
 )
 
 var configCmd = &cobra.Command{
@@ -21,16 +40,135 @@ var configCmd = &cobra.Command{
 	Long: `View or edit the gh-csd configuration file.
 
 Without flags, prints the current configuration.
-Use --edit to open in $EDITOR.
+Use --edit (or 'gh csd config edit') to open in $EDITOR.
 Use --init to create a default config file.
+Use 'get' and 'set' to read or change a single value.
 
 Config location: ~/.config/gh-csd/config.yaml`,
 	RunE: runConfig,
 }
 
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Open the config in $EDITOR and check it when the editor exits",
+	Long: `Open the config file in $EDITOR (vim if unset), like 'gh csd config --edit'.
+
+Before the editor opens, the file is copied to config.yaml.bak. When the
+editor exits, the file is validated: problems such as unknown keys are
+printed as warnings, but a file that can't be parsed would break every
+command, so you're asked to edit it again. If you decline (or there's no
+terminal to ask on), the backup is restored and your edit is kept in
+config.yaml.rejected.
+
+Use --no-backup to skip the backup; a broken edit is then left in place.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.Path()
+		if err != nil {
+			return err
+		}
+		return editConfig(path, !configNoBackup)
+	},
+}
+
+var configTestHooksCmd = &cobra.Command{
+	Use:   "test-hooks",
+	Short: "Print hook commands with placeholders expanded, without running them",
+	Long: `Print each configured hook with placeholders expanded, without running it.
+
+Uses the same substitution as 'gh csd create', so the output matches what
+would actually run. Unknown placeholders (e.g. a typo like {repoo}) are
+reported as warnings.
+
+Example:
+  gh csd config test-hooks --name my-cs --repo gh --branch main`,
+	Args: cobra.NoArgs,
+	RunE: runConfigTestHooks,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print one configuration value",
+	Long: `Print the value at a dotted key. Lists and sections are printed as YAML.
+
+Repository keys may contain dots and slashes; they are matched against the
+configured repos.
+
+Examples:
+  gh csd config get defaults.machine
+  gh csd config get repos.github/github.ports`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set one configuration value",
+	Long: `Set the value at a dotted key and save the config, creating the file if
+it doesn't exist. Only that value changes; the rest of the file keeps its
+comments and order.
+
+The value is checked against the field's type and refused if it makes the
+config invalid. Lists take comma-separated items or YAML flow syntax; an
+empty value clears them. Setting a field of a repo that isn't configured
+yet adds it.
+
+Examples:
+  gh csd config set defaults.machine largePremiumLinux
+  gh csd config set defaults.ssh_retry true
+  gh csd config set repos.github/github.ports 80,3000
+  gh csd config set hooks.post_create '["make setup"]'`,
+	Args: cobra.ExactArgs(2),
+	RunE: runConfigSet,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Check a config file for problems",
+	Long: `Check a config file for unknown keys, values of the wrong type, invalid
+settings and unknown hook placeholders, and print a report.
+
+Checks your config by default; pass a path to check another file, such as a
+config shared in a repository. The online checks are opt-in: --check-repos
+looks each configured repo up on GitHub, and --check-machines checks that
+the repo's effective machine type is offered for it, listing the ones that
+are, so an unavailable machine is caught before 'gh cs create' fails.
+
+Problems are reported but only fail the command with --strict, which exits
+nonzero if any are found, for use in CI or a pre-commit hook.
+
+Examples:
+  gh csd config validate
+  gh csd config validate --check-machines
+  gh csd config validate --strict --check-repos team/gh-csd.yaml`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE:         runConfigValidate,
+}
+
+// placeholderPattern matches anything that looks like a hook placeholder,
+// ignoring shell parameter expansions like ${HOME}.
+var placeholderPattern = regexp.MustCompile(`(?:^|[^$])(\{[a-z_]+\})`)
+
 func init() {
 	configCmd.Flags().BoolVarP(&configEdit, "edit", "e", false, "Open config in $EDITOR")
 	configCmd.Flags().BoolVar(&configInit, "init", false, "Create default config file")
+	configCmd.Flags().BoolVar(&configNoBackup, "no-backup", false, "With --edit, don't back up the config to config.yaml.bak first")
+	configEditCmd.Flags().BoolVar(&configNoBackup, "no-backup", false, "Don't back up the config to config.yaml.bak first")
+	configTestHooksCmd.Flags().StringVar(&testHooksName, "name", "example-codespace", "Codespace name for {name}")
+	configTestHooksCmd.Flags().StringVar(&testHooksRepo, "repo", "owner/repo", "Repository (or alias) for {repo} and {short_repo}")
+	configTestHooksCmd.Flags().StringVar(&testHooksBranch, "branch", "main", "Branch for {branch}")
+	configTestHooksCmd.Flags().StringVar(&testHooksMachine, "machine", "basicLinux32gb", "Machine type for {machine}")
+	configTestHooksCmd.Flags().StringVar(&testHooksState, "state", "Available", "Codespace state for {state}")
+	configTestHooksCmd.Flags().StringVar(&testHooksDisplayName, "display-name", "example display name", "Display name for {display_name}")
+	configValidateCmd.Flags().BoolVar(&configValidateStrict, "strict", false, "Exit nonzero if any problem is found")
+	configValidateCmd.Flags().BoolVar(&configValidateCheckRepos, "check-repos", false, "Check that each configured repo exists on GitHub")
+	configValidateCmd.Flags().BoolVar(&configValidateCheckMachines, "check-machines", false, "Check that each configured repo's machine type is available for it")
+	configCmd.AddCommand(configEditCmd)
+	configCmd.AddCommand(configTestHooksCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
 	rootCmd.AddCommand(configCmd)
 }
 
@@ -56,38 +194,330 @@ func runConfig(cmd *cobra.Command, args []string) error {
 	}
 
 	if configEdit {
-		editor := os.Getenv("EDITOR")
-		if editor == "" {
-			editor = "vim"
+		return editConfig(path, !configNoBackup)
+	}
+
+	// Print current config
+	cfg, warnings, err := config.LoadAndValidate()
+	if err != nil {
+		return err
+	}
+	printConfigWarnings(warnings)
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("# Config file: %s\n\n", path)
+	fmt.Println(string(data))
+	return nil
+}
+
+// editConfig opens the config at path in $EDITOR and validates it when the
+// editor exits. An edit that can't be parsed is edited again, or with
+// backup, rolled back to the copy taken before editing.
+func editConfig(path string, backup bool) error {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vim"
+	}
+
+	// Create default config if it doesn't exist
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		cfg := config.DefaultConfig()
+		if err := config.Save(cfg); err != nil {
+			return fmt.Errorf("failed to create config: %w", err)
 		}
+	}
 
-		// Create default config if it doesn't exist
-		if _, err := os.Stat(path); os.IsNotExist(err) {
-			cfg := config.DefaultConfig()
-			if err := config.Save(cfg); err != nil {
-				return fmt.Errorf("failed to create config: %w", err)
-			}
+	backupPath := path + ".bak"
+	if backup {
+		if err := copyConfigFile(path, backupPath); err != nil {
+			return fmt.Errorf("failed to back up config: %w", err)
 		}
+	}
 
+	for {
 		editCmd := exec.Command(editor, path)
 		editCmd.Stdin = os.Stdin
 		editCmd.Stdout = os.Stdout
 		editCmd.Stderr = os.Stderr
-		return editCmd.Run()
+		if err := editCmd.Run(); err != nil {
+			return err
+		}
+
+		problems, err := validateConfigFile(path, onlineChecks{})
+		if err == nil {
+			printConfigWarnings(problems)
+			return nil
+		}
+
+		fmt.Fprintf(os.Stderr, "Error: the edited config can't be parsed: %v\n", err)
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			fmt.Print("Edit it again? [Y/n] ")
+			response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			response = strings.TrimSpace(strings.ToLower(response))
+			if response == "" || response == "y" || response == "yes" {
+				continue
+			}
+		}
+
+		if !backup {
+			return fmt.Errorf("left %s as edited; fix it with 'gh csd config edit'", path)
+		}
+		return restoreConfigBackup(path, backupPath)
 	}
+}
 
-	// Print current config
+// restoreConfigBackup puts the pre-edit backup back at path, keeping the
+// rejected edit next to it so nothing typed is lost.
+func restoreConfigBackup(path, backupPath string) error {
+	rejectedPath := path + ".rejected"
+	if err := os.Rename(path, rejectedPath); err != nil {
+		return fmt.Errorf("failed to set aside the broken config: %w", err)
+	}
+	if err := copyConfigFile(backupPath, path); err != nil {
+		return fmt.Errorf("failed to restore %s from %s: %w", path, backupPath, err)
+	}
+	return fmt.Errorf("restored the config from before the edit; your changes are in %s", rejectedPath)
+}
+
+// copyConfigFile copies the config at src to dst, keeping its permissions.
+func copyConfigFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, info.Mode().Perm())
+}
+
+// loadValidatedConfig loads the config for commands that should carry on
+// with defaults when it has problems, printing them as warnings.
+func loadValidatedConfig() *config.Config {
+	cfg, warnings, err := config.LoadAndValidate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load config: %v\n", err)
+		return config.DefaultConfig()
+	}
+	printConfigWarnings(warnings)
+	return cfg
+}
+
+func printConfigWarnings(warnings []error) {
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: config: %v\n", warning)
+	}
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return err
 	}
 
-	data, err := yaml.Marshal(cfg)
+	value, err := cfg.GetValue(args[0])
 	if err != nil {
 		return err
 	}
+	fmt.Println(value)
+	return nil
+}
 
-	fmt.Printf("# Config file: %s\n\n", path)
-	fmt.Println(string(data))
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+	return config.SetFileValue(path, args[0], args[1])
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	var path string
+	if len(args) == 1 {
+		path = args[0]
+	} else {
+		var err error
+		if path, err = config.Path(); err != nil {
+			return err
+		}
+	}
+
+	var checks onlineChecks
+	if configValidateCheckRepos {
+		checks.repoExists = gh.RepoExists
+	}
+	if configValidateCheckMachines {
+		checks.machineTypes = gh.ListMachineTypes
+	}
+	problems, err := validateConfigFile(path, checks)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) == 0 {
+		fmt.Printf("%s: no problems found\n", path)
+		return nil
+	}
+	for _, problem := range problems {
+		fmt.Printf("%v\n", problem)
+	}
+	if configValidateStrict {
+		return fmt.Errorf("%s: %d problem(s) found", path, len(problems))
+	}
+	fmt.Printf("%d problem(s) found\n", len(problems))
 	return nil
 }
+
+// onlineChecks are the opt-in checks of 'config validate' that query
+// GitHub. Nil funcs are skipped.
+type onlineChecks struct {
+	repoExists   func(repo string) (bool, error)
+	machineTypes func(repo string) ([]string, error)
+}
+
+// validateConfigFile returns the problems in the config file at path: what
+// config.LoadFileAndValidate reports, unknown hook placeholders and the
+// problems found by checks for the repos configured in the file. The error
+// is only set when the file can't be read or parsed.
+func validateConfigFile(path string, checks onlineChecks) ([]error, error) {
+	cfg, problems, err := config.LoadFileAndValidate(path)
+	if err != nil {
+		return nil, err
+	}
+
+	phases := []struct {
+		name  string
+		hooks []string
+	}{
+		{"pre_create", cfg.Hooks.PreCreate},
+		{"post_create", cfg.Hooks.PostCreate},
+		{"post_connect", cfg.Hooks.PostConnect},
+	}
+	for _, phase := range phases {
+		for _, hook := range phase.hooks {
+			for _, unknown := range unknownPlaceholders(hook) {
+				problems = append(problems, fmt.Errorf("hooks.%s: unknown placeholder %s in %q", phase.name, unknown, hook))
+			}
+		}
+	}
+
+	if checks.repoExists == nil && checks.machineTypes == nil {
+		return problems, nil
+	}
+	repos, err := configFileRepos(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, repo := range repos {
+		if checks.repoExists != nil {
+			exists, err := checks.repoExists(repo)
+			if err != nil {
+				problems = append(problems, fmt.Errorf("repos.%s: couldn't check the repository: %w", repo, err))
+				continue
+			}
+			if !exists {
+				problems = append(problems, fmt.Errorf("repos.%s: repository not found on GitHub", repo))
+				continue
+			}
+		}
+
+		if checks.machineTypes != nil {
+			available, err := checks.machineTypes(repo)
+			if err != nil {
+				problems = append(problems, fmt.Errorf("repos.%s: couldn't list machine types: %w", repo, err))
+				continue
+			}
+			if machine := cfg.GetEffectiveMachine(repo); machine != config.AutoMachine && !slices.Contains(available, machine) {
+				problems = append(problems, fmt.Errorf("repos.%s: machine %s isn't available for this repository (available: %s)", repo, machine, strings.Join(available, ", ")))
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+// configFileRepos returns the repos configured in the file at path, leaving
+// out those that only come from the defaults.
+func configFileRepos(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Repos map[string]yaml.Node `yaml:"repos"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	repos := make([]string, 0, len(file.Repos))
+	for repo := range file.Repos {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+	return repos, nil
+}
+
+func runConfigTestHooks(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	repo := cfg.ResolveAlias(testHooksRepo)
+	created := hookVars{
+		Name:   testHooksName,
+		Repo:   repo,
+		Branch: testHooksBranch,
+		Codespace: &gh.Codespace{
+			Name:        testHooksName,
+			DisplayName: testHooksDisplayName,
+			State:       testHooksState,
+			MachineName: testHooksMachine,
+		},
+	}
+
+	phases := []struct {
+		name  string
+		hooks []string
+		vars  hookVars
+	}{
+		// pre-create hooks run before the codespace exists, so {name},
+		// {machine}, {state} and {display_name} are empty
+		{name: "pre_create", hooks: cfg.Hooks.PreCreate, vars: hookVars{Repo: repo, Branch: testHooksBranch}},
+		{name: "post_create", hooks: cfg.Hooks.PostCreate, vars: created},
+		{name: "post_connect", hooks: cfg.Hooks.PostConnect, vars: created},
+	}
+
+	for _, phase := range phases {
+		fmt.Printf("%s:\n", phase.name)
+		if len(phase.hooks) == 0 {
+			fmt.Println("  (none)")
+			continue
+		}
+		for _, hook := range phase.hooks {
+			fmt.Printf("  %s\n", expandHook(hook, phase.vars))
+			for _, unknown := range unknownPlaceholders(hook) {
+				fmt.Fprintf(os.Stderr, "  Warning: unknown placeholder %s\n", unknown)
+			}
+		}
+	}
+
+	return nil
+}
+
+// unknownPlaceholders returns placeholders in hook that expandHook doesn't
+// substitute.
+func unknownPlaceholders(hook string) []string {
+	remaining := expandHook(hook, hookVars{})
+	var unknown []string
+	for _, match := range placeholderPattern.FindAllStringSubmatch(remaining, -1) {
+		unknown = append(unknown, match[1])
+	}
+	return unknown
+}
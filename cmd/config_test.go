@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+)
+
+func TestUnknownPlaceholders(t *testing.T) {
+	tests := []struct {
+		name string
+		hook string
+		want []string
+	}{
+		{name: "all known", hook: "echo {name} {repo} {short_repo} {branch}", want: nil},
+		{name: "typo", hook: "echo {repoo} on {branch}", want: []string{"{repoo}"}},
+		{name: "shell expansion ignored", hook: "echo ${home} {name}", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := unknownPlaceholders(tt.hook)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("unexpected placeholders: want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExpandHook(t *testing.T) {
+	vars := hookVars{Name: "cs-1", Repo: "github/github", Branch: "main"}
+	got := expandHook("gh cs ssh -c {name} -- echo {short_repo}@{branch} ({repo})", vars)
+	want := "gh cs ssh -c cs-1 -- echo github@main (github/github)"
+	if got != want {
+		t.Fatalf("unexpected expansion\nwant: %s\n got: %s", want, got)
+	}
+
+	vars.Codespace = &gh.Codespace{MachineName: "largePremiumLinux", State: "Available", DisplayName: "fuzzy robot"}
+	got = expandHook("{name} {machine} {state} '{display_name}'", vars)
+	want = "cs-1 largePremiumLinux Available 'fuzzy robot'"
+	if got != want {
+		t.Fatalf("unexpected expansion\nwant: %s\n got: %s", want, got)
+	}
+
+	// Without a codespace, its placeholders expand to nothing
+	if got := expandHook("[{machine}{state}{display_name}]", hookVars{}); got != "[]" {
+		t.Fatalf("expandHook() without a codespace = %q, want []", got)
+	}
+}
+
+func TestRunHookOldStyle(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	vars := hookVars{Name: "cs-1", Repo: "github/github", Branch: "it's main"}
+
+	// Hooks that quote their placeholders keep working
+	if err := runHook(`echo "Codespace {name} created for {repo}" > `+out, vars); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "Codespace cs-1 created for github/github\n" {
+		t.Errorf("old-style hook wrote %q", got)
+	}
+
+	// The environment carries the same values, safe to quote
+	if err := runHook(`printf '%s|%s|%s' "$CSD_NAME" "$CSD_SHORT_REPO" "$CSD_BRANCH" > `+out, vars); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "cs-1|github|it's main" {
+		t.Errorf("hook with environment variables wrote %q", got)
+	}
+}
+
+func TestWithHookCodespace(t *testing.T) {
+	cs := &gh.Codespace{Name: "cs-1", MachineName: "basicLinux32gb"}
+	lookups := 0
+	lookup := func(name string) (*gh.Codespace, error) {
+		lookups++
+		return cs, nil
+	}
+
+	vars := withHookCodespace([]string{"echo {name} {repo}"}, hookVars{Name: "cs-1"}, lookup)
+	if lookups != 0 || vars.Codespace != nil {
+		t.Errorf("looked up the codespace for hooks that don't need it")
+	}
+
+	vars = withHookCodespace([]string{"echo {name}", "echo {machine}"}, hookVars{Name: "cs-1"}, lookup)
+	if lookups != 1 || vars.Codespace != cs {
+		t.Errorf("withHookCodespace() made %d lookups, Codespace = %v; want 1 lookup", lookups, vars.Codespace)
+	}
+
+	vars = withHookCodespace([]string{`echo "$CSD_STATE"`}, hookVars{Name: "cs-1"}, lookup)
+	if lookups != 2 || vars.Codespace != cs {
+		t.Errorf("withHookCodespace() didn't look up the codespace for $CSD_STATE")
+	}
+
+	// Pre-create hooks have no codespace to look up
+	withHookCodespace([]string{"echo {machine}"}, hookVars{}, lookup)
+	if lookups != 2 {
+		t.Errorf("looked up a codespace without a name")
+	}
+}
+
+func TestValidateConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.yaml")
+	content := `defaults:
+  idle_timeout: -5
+hooks:
+  post_create: ["echo {repoo}"]
+repos:
+  owner/missing: {}
+  owner/repo:
+    alias: r
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := onlineChecks{
+		repoExists: func(repo string) (bool, error) {
+			return repo == "owner/repo", nil
+		},
+	}
+	problems, err := validateConfigFile(path, checks)
+	if err != nil {
+		t.Fatalf("validateConfigFile() failed: %v", err)
+	}
+
+	var messages []string
+	for _, p := range problems {
+		messages = append(messages, p.Error())
+	}
+	joined := strings.Join(messages, "\n")
+	for _, want := range []string{"defaults.idle_timeout must be positive", "unknown placeholder {repoo}", "repos.owner/missing: repository not found"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("problems %q don't mention %q", messages, want)
+		}
+	}
+	if len(problems) != 3 {
+		t.Errorf("got %d problems, want 3: %q", len(problems), messages)
+	}
+
+	// Repos are only checked when asked
+	problems, err = validateConfigFile(path, onlineChecks{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 2 {
+		t.Errorf("got %d problems without repo checks, want 2: %v", len(problems), problems)
+	}
+
+	if _, err := validateConfigFile(filepath.Join(t.TempDir(), "missing.yaml"), onlineChecks{}); err == nil {
+		t.Error("validateConfigFile() succeeded on a missing file")
+	}
+}
+
+func TestValidateConfigFileMachines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.yaml")
+	content := `defaults:
+  machine: xLargePremiumLinux
+repos:
+  owner/small: {}
+  owner/large: {}
+  owner/override:
+    machine: basicLinux32gb
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := onlineChecks{
+		machineTypes: func(repo string) ([]string, error) {
+			if repo == "owner/large" {
+				return []string{"basicLinux32gb", "xLargePremiumLinux"}, nil
+			}
+			return []string{"basicLinux32gb", "standardLinux32gb"}, nil
+		},
+	}
+	problems, err := validateConfigFile(path, checks)
+	if err != nil {
+		t.Fatalf("validateConfigFile() failed: %v", err)
+	}
+	if len(problems) != 1 {
+		t.Fatalf("got %d problems, want 1: %v", len(problems), problems)
+	}
+	want := "repos.owner/small: machine xLargePremiumLinux isn't available for this repository (available: basicLinux32gb, standardLinux32gb)"
+	if problems[0].Error() != want {
+		t.Errorf("problem = %q, want %q", problems[0], want)
+	}
+}
+
+func TestEditConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	original := "defaults:\n  machine: largePremiumLinux\n"
+
+	// The "editor" overwrites the file with the content of $EDIT_CONTENT
+	editor := filepath.Join(dir, "editor")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\nprintf '%s' \"$EDIT_CONTENT\" > \"$1\"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EDITOR", editor)
+
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	t.Run("valid edit", func(t *testing.T) {
+		os.WriteFile(path, []byte(original), 0600)
+		edited := "defaults:\n  machine: xLargePremiumLinux\n"
+		t.Setenv("EDIT_CONTENT", edited)
+
+		if err := editConfig(path, true); err != nil {
+			t.Fatal(err)
+		}
+		if got := read(path); got != edited {
+			t.Errorf("config = %q, want the edit", got)
+		}
+		if got := read(path + ".bak"); got != original {
+			t.Errorf("backup = %q, want the original", got)
+		}
+	})
+
+	t.Run("broken edit is rolled back", func(t *testing.T) {
+		os.WriteFile(path, []byte(original), 0600)
+		t.Setenv("EDIT_CONTENT", "defaults: [\n")
+
+		err := editConfig(path, true)
+		if err == nil || !strings.Contains(err.Error(), "restored") {
+			t.Fatalf("editConfig() = %v, want the restore reported", err)
+		}
+		if got := read(path); got != original {
+			t.Errorf("config = %q, want the original restored", got)
+		}
+		if got := read(path + ".rejected"); got != "defaults: [\n" {
+			t.Errorf("rejected edit = %q, want it kept", got)
+		}
+	})
+
+	t.Run("broken edit without backup", func(t *testing.T) {
+		os.WriteFile(path, []byte(original), 0600)
+		os.Remove(path + ".bak")
+		t.Setenv("EDIT_CONTENT", "defaults: [\n")
+
+		if err := editConfig(path, false); err == nil {
+			t.Fatal("editConfig() = nil, want an error")
+		}
+		if got := read(path); got != "defaults: [\n" {
+			t.Errorf("config = %q, want the edit left in place", got)
+		}
+		if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+			t.Error("a backup was written with backup disabled")
+		}
+	})
+}
@@ -4,17 +4,24 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"runtime"
+	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/luanzeba/gh-csd/internal/config"
 	"github.com/luanzeba/gh-csd/internal/gh"
+	"github.com/luanzeba/gh-csd/internal/notify"
 	"github.com/luanzeba/gh-csd/internal/state"
+	"github.com/luanzeba/gh-csd/internal/stats"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var (
@@ -25,8 +32,41 @@ var (
 	createNoTerminfo         bool
 	createNoNotify           bool
 	createDefaultPermissions bool
+	createStatusFollow       bool
+	createFromTemplate       string
+	createRepoName           string
+	createVisibility         string
+	createIdleTimeout        int
+	createWait               bool
+	createOnReady            string
+	createSecretsFrom        string
+	createDryRun             bool
+	createWaitPostCreate     bool
+	createPostCreateTimeout  time.Duration
+	createDotfiles           string
+	createDisplayName        string
+
+	// createReuse connects to an existing codespace for the repo, if there
+	// is one, instead of creating another; createNew creates one without
+	// checking. Without either, create asks.
+	createReuse bool
+	createNew   bool
 )
 
+const (
+	createWaitTimeout  = 10 * time.Minute
+	createWaitInterval = 5 * time.Second
+)
+
+// postCreateDoneMarker is the last line Codespaces writes to the creation
+// log once the devcontainer setup, including postCreateCommand, is done.
+const postCreateDoneMarker = "Finished configuring codespace"
+
+// postCreateFailedPattern matches the line the devcontainer CLI writes to
+// the creation log when a setup command fails. The rest of the setup is
+// skipped then, so postCreateDoneMarker may never come.
+var postCreateFailedPattern = regexp.MustCompile(`(?m)^.*\b(onCreateCommand|updateContentCommand|postCreateCommand)\b.* failed with exit code \d+.*$`)
+
 var createCmd = &cobra.Command{
 	Use:   "create [repo]",
 	Short: "Create a codespace and optionally SSH into it",
@@ -38,88 +78,247 @@ manual owner/repo entry option.
 Workflow:
 1. Runs pre-create hooks if defined
 2. Creates the codespace
-3. Copies Ghostty terminfo for terminal support (configurable)
-4. Runs post-create hooks if defined
-5. Sends a desktop notification when ready
-6. SSHes into the codespace with rdm forwarding
+3. Copies your terminal's terminfo so it works there too (configurable)
+4. Applies your dotfiles repo, if --dotfiles or defaults.dotfiles is set
+5. Runs post-create hooks if defined
+6. Sends a desktop notification when ready
+7. SSHes into the codespace with rdm forwarding
 
 Settings like machine type, permissions, and SSH retry can be configured
 per-repo in ~/.config/gh-csd/config.yaml.
 
-Use --no-ssh to just create without connecting.`,
+If you already have a codespace for the repo (on --branch, if given), create
+asks whether to reuse it instead, so you don't end up with duplicates. Use
+--reuse to connect to it without asking (like 'gh csd clone'), or --new to
+always create another. When there's no terminal to ask on, create keeps
+creating.
+
+Use --no-ssh to just create without connecting.
+Use --status-follow to see gh's provisioning status live while creating.
+
+Use --from-template to first create a new repository from a template repo
+(alias or owner/repo) and then create the codespace on it. --name sets the
+new repository's name (owner/name or just name for your account) and
+--visibility sets its visibility.
+
+Use --wait to block until the codespace is Available, and --on-ready to run
+a local command once it is (after the notification). The command supports
+the same {name}, {repo}, and {branch} placeholders as hooks and implies
+--wait.
+
+A codespace is Available before its devcontainer's postCreateCommand has
+finished. Use --wait-for-postcreate (or defaults.wait_for_postcreate) to
+also wait, by watching the creation log, until setup is done before
+notifying and connecting. It stops with a warning as soon as the log
+reports a failed setup command, or after --postcreate-timeout (default
+from config, 30m); pass
+--wait-for-postcreate=false to skip it when config enables it.
+
+Use --secrets-from to upload KEY=VALUE lines from a local env file as
+Codespaces user secrets for the repository before the codespace is
+created, so they're available in it. A user secret that already exists
+gets the new value and stays available to the repositories it already was,
+with this one added.
+
+Use --dry-run to check your config without side effects: it prints the
+resolved repository, machine, devcontainer, idle timeout and permissions,
+the exact 'gh cs create' command, any secrets that would be uploaded, the
+hooks that would run, and whether it would wait, copy terminfo, notify
+and connect, then exits without creating anything.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runCreate,
 }
 
 func init() {
-	createCmd.Flags().StringVarP(&createMachine, "machine", "m", "", "Machine type (default from config)")
-	createCmd.Flags().StringVarP(&createDevcontainer, "devcontainer", "d", "", "Devcontainer path (default from config)")
-	createCmd.Flags().StringVarP(&createBranch, "branch", "b", "", "Branch to create codespace from")
-	createCmd.Flags().BoolVar(&createNoSSH, "no-ssh", false, "Don't SSH after creation")
-	createCmd.Flags().BoolVar(&createNoTerminfo, "no-terminfo", false, "Don't copy Ghostty terminfo")
-	createCmd.Flags().BoolVar(&createNoNotify, "no-notify", false, "Don't send desktop notification")
-	createCmd.Flags().BoolVarP(&createDefaultPermissions, "default-permissions", "y", false, "Accept default permissions (skip prompt)")
+	addCreateFlags(createCmd)
+	createCmd.Flags().StringVar(&createFromTemplate, "from-template", "", "Create a new repo from this template repo, then a codespace on it")
+	createCmd.Flags().StringVar(&createRepoName, "name", "", "Name of the repo created with --from-template")
+	createCmd.Flags().StringVar(&createVisibility, "visibility", "private", "Visibility of the repo created with --from-template (public, private, internal)")
+	createCmd.Flags().BoolVar(&createReuse, "reuse", false, "Connect to an existing codespace for the repo instead of creating one, if there is one")
+	createCmd.Flags().BoolVar(&createNew, "new", false, "Create a new codespace even if one exists for the repo")
 	rootCmd.AddCommand(createCmd)
 }
 
+// addCreateFlags adds the flags shared by create and clone, which create
+// the codespace the same way.
+func addCreateFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&createMachine, "machine", "m", "", "Machine type, or \"auto\" for the largest available (default from config)")
+	cmd.Flags().StringVarP(&createDevcontainer, "devcontainer", "d", "", "Devcontainer path (default from config)")
+	cmd.Flags().StringVarP(&createBranch, "branch", "b", "", "Branch to create codespace from")
+	cmd.Flags().StringVar(&createDisplayName, "display-name", "", "Display name for the codespace (rename it later with 'gh csd rename')")
+	cmd.Flags().BoolVar(&createNoSSH, "no-ssh", false, "Don't SSH after creation")
+	cmd.Flags().BoolVar(&createNoTerminfo, "no-terminfo", false, "Don't copy the terminal's terminfo")
+	cmd.Flags().StringVar(&createDotfiles, "dotfiles", "", "Dotfiles repo (owner/repo) to clone and install in the codespace (default from config; \"\" for none)")
+	cmd.Flags().BoolVar(&createNoNotify, "no-notify", false, "Don't send desktop notification")
+	cmd.Flags().BoolVar(&createWait, "wait", false, "Wait until the codespace is Available")
+	cmd.Flags().BoolVar(&createWaitPostCreate, "wait-for-postcreate", false, "Wait for the devcontainer setup (postCreateCommand) to finish (implies --wait)")
+	cmd.Flags().DurationVar(&createPostCreateTimeout, "postcreate-timeout", 0, "How long --wait-for-postcreate waits (default from config)")
+	cmd.Flags().StringVar(&createOnReady, "on-ready", "", "Local command to run once the codespace is Available (implies --wait)")
+	cmd.Flags().IntVar(&createIdleTimeout, "idle-timeout", 0, "Minutes of inactivity before the codespace stops (default from config)")
+	cmd.Flags().BoolVarP(&createDefaultPermissions, "default-permissions", "y", false, "Accept default permissions (skip prompt)")
+	cmd.Flags().BoolVar(&createStatusFollow, "status-follow", false, "Stream provisioning status while the codespace is created")
+	cmd.Flags().StringVar(&createSecretsFrom, "secrets-from", "", "Env file of KEY=VALUE lines to set as Codespaces user secrets for the repo")
+	cmd.Flags().BoolVar(&createDryRun, "dry-run", false, "Print the resolved settings and gh command without creating anything")
+}
+
 func runCreate(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to load config: %v\n", err)
-		cfg = config.DefaultConfig()
+	if createReuse && createNew {
+		return fmt.Errorf("--reuse and --new cannot be combined")
 	}
 
-	repoInput := ""
-	if len(args) > 0 {
-		repoInput = args[0]
-	} else {
-		selectedRepo, err := selectCreateRepoInteractive(cfg)
+	cfg := loadValidatedConfig()
+	var err error
+
+	// Parse secrets up front so a bad file fails before anything is created
+	var secrets []envFileVar
+	if createSecretsFrom != "" {
+		secrets, err = readSecretsFile(createSecretsFrom)
 		if err != nil {
 			return err
 		}
-		repoInput = selectedRepo
 	}
 
-	// Resolve alias to full repo name
-	repo := cfg.ResolveAlias(repoInput)
-	if !strings.Contains(repo, "/") {
-		// Assume it's a GitHub org repo
-		repo = "github/" + repo
+	var repo string
+	if createFromTemplate != "" {
+		if len(args) > 0 {
+			return fmt.Errorf("--from-template cannot be combined with a repo argument (use --name for the new repo)")
+		}
+		template := resolveRepoInput(cfg, createFromTemplate)
+		if createDryRun {
+			if createRepoName == "" {
+				return fmt.Errorf("--from-template requires --name for the new repository")
+			}
+			fmt.Printf("Would create repository %s from template %s\n", createRepoName, template)
+			repo = createRepoName
+		} else {
+			repo, err = createRepoFromTemplate(template, createRepoName, createVisibility)
+			if err != nil {
+				return err
+			}
+		}
+	} else {
+		repoInput := ""
+		if len(args) > 0 {
+			repoInput = args[0]
+		} else {
+			selectedRepo, err := selectCreateRepoInteractive(cfg)
+			if err != nil {
+				return err
+			}
+			repoInput = selectedRepo
+		}
+		repo = resolveRepoInput(cfg, repoInput)
 	}
 
-	fmt.Printf("Creating codespace for %s...\n", repo)
+	// A repo created from a template can't have codespaces yet
+	if !createNew && createFromTemplate == "" {
+		existing, err := findExistingCodespace(repo, createBranch)
+		switch {
+		case err != nil && createReuse:
+			return err
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Warning: couldn't check for existing codespaces: %v\n", err)
+		case existing != nil && createDryRun && !createReuse:
+			fmt.Printf("Note: codespace %s already exists for %s; create would ask whether to reuse it (--new to skip)\n", existing.Name, repo)
+		case existing != nil && (createReuse || confirmReuseCodespace(existing)):
+			if createDryRun {
+				fmt.Printf("Would reuse codespace %s (%s @ %s) instead of creating one\n", existing.Name, existing.Repository, existing.DisplayBranch())
+				return nil
+			}
+			return connectExistingCodespace(cfg, existing)
+		case existing == nil && createReuse:
+			fmt.Printf("No codespace for %s yet; creating one.\n", repo)
+		}
+	}
 
 	// Get effective settings: flags override per-repo config, which overrides defaults
 	machine := cfg.GetEffectiveMachine(repo)
 	if cmd.Flags().Changed("machine") {
 		machine = createMachine
 	}
+	if machine == config.AutoMachine {
+		machine = autoSelectMachine(cfg, repo)
+	}
 
 	devcontainer := cfg.GetEffectiveDevcontainer(repo)
 	if cmd.Flags().Changed("devcontainer") {
 		devcontainer = createDevcontainer
 	}
 
+	idleTimeout := cfg.GetEffectiveIdleTimeout(repo)
+	if cmd.Flags().Changed("idle-timeout") {
+		idleTimeout = createIdleTimeout
+	}
+
 	useDefaultPermissions := cfg.GetEffectiveDefaultPermissions(repo)
 	if cmd.Flags().Changed("default-permissions") {
 		useDefaultPermissions = createDefaultPermissions
 	}
 
-	// Run pre-create hooks
-	runHooks("pre-create", cfg.Hooks.PreCreate, "", repo, createBranch)
+	dotfiles := cfg.GetEffectiveDotfiles()
+	if cmd.Flags().Changed("dotfiles") {
+		dotfiles = createDotfiles
+	}
+	if dotfiles != "" {
+		if dotfiles, err = normalizeManualRepoInput(dotfiles); err != nil {
+			return fmt.Errorf("invalid dotfiles repo: %w", err)
+		}
+	}
+
+	waitPostCreate := cfg.Defaults.WaitForPostCreate
+	if cmd.Flags().Changed("wait-for-postcreate") {
+		waitPostCreate = createWaitPostCreate
+	}
 
-	// Build gh cs create command
-	createArgs := []string{"cs", "create",
-		"-R", repo,
-		"-m", machine,
-		"--devcontainer-path", devcontainer,
-		"--status",
+	createArgs := buildCreateArgs(repo, machine, devcontainer, createBranch, idleTimeout, useDefaultPermissions)
+	if createDisplayName != "" {
+		createArgs = append(createArgs, "--display-name", createDisplayName)
+	}
+
+	if createDryRun {
+		return writeCreatePlan(os.Stdout, createPlan{
+			repo:               repo,
+			machine:            machine,
+			devcontainer:       devcontainer,
+			branch:             createBranch,
+			idleTimeout:        idleTimeout,
+			defaultPermissions: useDefaultPermissions,
+			args:               createArgs,
+			secrets:            secretKeys(secrets),
+			preCreate:          cfg.Hooks.PreCreate,
+			postCreate:         cfg.Hooks.PostCreate,
+			onReady:            createOnReady,
+			wait:               createWait || createOnReady != "" || waitPostCreate,
+			waitPostCreate:     waitPostCreate,
+			copyTerminfo:       cfg.GetEffectiveCopyTerminfo() && !createNoTerminfo,
+			terminfoTerm:       terminfoTerm(cfg),
+			dotfiles:           dotfiles,
+			notify:             !createNoNotify && notificationsEnabled(cfg),
+			ssh:                !createNoSSH,
+			sshRetry:           cfg.GetEffectiveSSHRetry(repo),
+		})
+	}
+
+	// Hold a per-repo lock until gh cs create returns so racing invocations
+	// don't create duplicate codespaces
+	releaseLock, err := acquireCreateLock(repo)
+	if err != nil {
+		return err
 	}
-	if createBranch != "" {
-		createArgs = append(createArgs, "-b", createBranch)
+
+	fmt.Printf("Creating codespace for %s...\n", repo)
+
+	// Run pre-create hooks; any failure aborts before creating anything
+	if err := runRequiredHooks("pre-create", cfg.Hooks.PreCreate, hookVars{Repo: repo, Branch: createBranch}); err != nil {
+		releaseLock()
+		return fmt.Errorf("%w; codespace not created", err)
 	}
-	if useDefaultPermissions {
-		createArgs = append(createArgs, "--default-permissions")
+
+	if len(secrets) > 0 {
+		if err := uploadSecrets(secrets, repo); err != nil {
+			releaseLock()
+			return err
+		}
 	}
 
 	// Create the codespace
@@ -127,44 +326,82 @@ func runCreate(cmd *cobra.Command, args []string) error {
 	var stdout bytes.Buffer
 	ghCreateCmd.Stdout = &stdout
 	ghCreateCmd.Stderr = os.Stderr
+	if createStatusFollow {
+		// gh only renders provisioning progress when it thinks it's on a
+		// terminal. Echo stdout too so status gh writes there shows up live.
+		ghCreateCmd.Env = append(os.Environ(), "GH_FORCE_TTY=true")
+		ghCreateCmd.Stdout = io.MultiWriter(&stdout, os.Stderr)
+	}
 
-	if err := ghCreateCmd.Run(); err != nil {
+	err = gh.RunTraced(ghCreateCmd)
+	releaseLock()
+	gh.InvalidateCache()
+	if err != nil {
 		return fmt.Errorf("failed to create codespace: %w", err)
 	}
 
-	name := strings.TrimSpace(stdout.String())
+	name := extractCreatedCodespaceName(stdout.String())
 	if name == "" {
 		return fmt.Errorf("no codespace name returned")
 	}
 
 	fmt.Printf("Created codespace: %s\n", name)
+	recordStat(repo, stats.Create)
 
 	// Save as current codespace
 	if err := state.Set(name); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to save current codespace: %v\n", err)
 	}
 
-	// Copy Ghostty terminfo (check both flag and config)
-	copyTerminfoEnabled := cfg.GetEffectiveCopyTerminfo() && !createNoTerminfo
-	if copyTerminfoEnabled {
-		fmt.Println("Copying Ghostty terminfo...")
-		if err := copyTerminfo(name); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to copy terminfo: %v\n", err)
+	if createWait || createOnReady != "" || waitPostCreate {
+		fmt.Println("Waiting for codespace to be available...")
+		if _, err := waitForCodespaceAvailable(name, createWaitTimeout); err != nil {
+			return err
+		}
+	}
+
+	if waitPostCreate {
+		timeout := time.Duration(cfg.GetEffectivePostCreateTimeout()) * time.Minute
+		if createPostCreateTimeout > 0 {
+			timeout = createPostCreateTimeout
+		}
+		fmt.Println("Waiting for the devcontainer setup to finish...")
+		if err := waitForPostCreate(name, timeout); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v; continuing anyway\n", err)
+		}
+	}
+
+	// Copy the terminal's terminfo (check both flag and config)
+	if cfg.GetEffectiveCopyTerminfo() && !createNoTerminfo {
+		installTerminfo(name, terminfoTerm(cfg))
+	}
+
+	if dotfiles != "" {
+		fmt.Printf("Applying dotfiles from %s...\n", dotfiles)
+		if err := applyDotfiles(name, dotfiles); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to apply dotfiles: %v\n", err)
 		}
 	}
 
 	// Run post-create hooks
 	// Get codespace info for placeholders
 	cs, _ := gh.GetCodespace(name)
-	branch := ""
+	hookInfo := hookVars{Name: name, Repo: repo, Codespace: cs}
 	if cs != nil {
-		branch = cs.Branch
+		hookInfo.Branch = cs.Branch
+		cacheCodespaceInfo(cs)
 	}
-	runHooks("post-create", cfg.Hooks.PostCreate, name, repo, branch)
+	runHooks("post-create", cfg.Hooks.PostCreate, hookInfo)
 
 	// Send notification
 	if !createNoNotify {
-		sendNotification("Codespace ready", fmt.Sprintf("✅ %s", name))
+		sendNotification(cfg, "Codespace ready", fmt.Sprintf("✅ %s", name))
+	}
+
+	if createOnReady != "" {
+		if err := runHook(createOnReady, hookInfo); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: on-ready command failed: %v\n", err)
+		}
 	}
 
 	if createNoSSH {
@@ -188,6 +425,170 @@ func runCreate(cmd *cobra.Command, args []string) error {
 	return sshOnce(name, cfg, repo)
 }
 
+// autoSelectMachine picks the machine for 'machine: auto': the largest
+// available for repo within defaults.max_machine_cpus, or the configured
+// fallback if the machine types can't be listed or none fit.
+func autoSelectMachine(cfg *config.Config, repo string) string {
+	fallback := cfg.GetFallbackMachine()
+	machines, err := gh.ListMachines(repo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't list machine types for %s, using %s: %v\n", repo, fallback, err)
+		return fallback
+	}
+	machine, ok := pickLargestMachine(machines, cfg.Defaults.MaxMachineCPUs)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Warning: no machine type for %s fits max_machine_cpus, using %s\n", repo, fallback)
+		return fallback
+	}
+	fmt.Printf("Machine: auto-selected %s (%d cores)\n", machine.Name, machine.CPUs)
+	return machine.Name
+}
+
+// pickLargestMachine returns the machine with the most cores, then the
+// most memory, among those with at most maxCPUs cores (0 for no limit).
+func pickLargestMachine(machines []gh.Machine, maxCPUs int) (gh.Machine, bool) {
+	var best gh.Machine
+	found := false
+	for _, machine := range machines {
+		if maxCPUs > 0 && machine.CPUs > maxCPUs {
+			continue
+		}
+		if !found || machine.CPUs > best.CPUs || (machine.CPUs == best.CPUs && machine.MemoryInBytes > best.MemoryInBytes) {
+			best, found = machine, true
+		}
+	}
+	return best, found
+}
+
+// buildCreateArgs returns the gh arguments that create a codespace with
+// the given settings.
+func buildCreateArgs(repo, machine, devcontainer, branch string, idleTimeout int, defaultPermissions bool) []string {
+	args := []string{"cs", "create",
+		"-R", repo,
+		"-m", machine,
+		"--devcontainer-path", devcontainer,
+		"--status",
+	}
+	if branch != "" {
+		args = append(args, "-b", branch)
+	}
+	if idleTimeout > 0 {
+		args = append(args, "--idle-timeout", fmt.Sprintf("%dm", idleTimeout))
+	}
+	if defaultPermissions {
+		args = append(args, "--default-permissions")
+	}
+	return args
+}
+
+// createPlan is what 'gh csd create' would do, for --dry-run.
+type createPlan struct {
+	repo               string
+	machine            string
+	devcontainer       string
+	branch             string
+	idleTimeout        int
+	defaultPermissions bool
+	args               []string
+	secrets            []string
+
+	preCreate  []string
+	postCreate []string
+	onReady    string
+
+	wait           bool
+	waitPostCreate bool
+	copyTerminfo   bool
+	terminfoTerm   string
+	dotfiles       string
+	notify         bool
+	ssh            bool
+	sshRetry       bool
+}
+
+// writeCreatePlan prints plan for --dry-run.
+func writeCreatePlan(w io.Writer, plan createPlan) error {
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	orDefault := func(value, fallback string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	}
+
+	idleTimeout := "(GitHub default)"
+	if plan.idleTimeout > 0 {
+		idleTimeout = fmt.Sprintf("%dm", plan.idleTimeout)
+	}
+	ssh := yesNo(plan.ssh)
+	if plan.ssh && plan.sshRetry {
+		ssh = "yes, reconnecting on disconnect"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Repository:\t%s\n", plan.repo)
+	fmt.Fprintf(tw, "Machine:\t%s\n", plan.machine)
+	fmt.Fprintf(tw, "Devcontainer:\t%s\n", orDefault(plan.devcontainer, "(repository default)"))
+	fmt.Fprintf(tw, "Branch:\t%s\n", orDefault(plan.branch, "(default branch)"))
+	fmt.Fprintf(tw, "Idle timeout:\t%s\n", idleTimeout)
+	fmt.Fprintf(tw, "Default permissions:\t%s\n", yesNo(plan.defaultPermissions))
+	fmt.Fprintf(tw, "Command:\tgh %s\n", joinCommandForShell(plan.args))
+	if len(plan.secrets) > 0 {
+		fmt.Fprintf(tw, "Secrets:\t%s\n", strings.Join(plan.secrets, ", "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// pre-create hooks are shown as they would run; the others need the
+	// codespace for their placeholders and are shown as configured
+	var hooks []string
+	for _, hook := range plan.preCreate {
+		hooks = append(hooks, "pre_create: "+expandHook(hook, hookVars{Repo: plan.repo, Branch: plan.branch}))
+	}
+	for _, hook := range plan.postCreate {
+		hooks = append(hooks, "post_create: "+hook)
+	}
+	if plan.onReady != "" {
+		hooks = append(hooks, "on-ready: "+plan.onReady)
+	}
+	if len(hooks) == 0 {
+		hooks = append(hooks, "(none)")
+	}
+	fmt.Fprintln(w, "\nHooks:")
+	for _, hook := range hooks {
+		fmt.Fprintf(w, "  %s\n", hook)
+	}
+
+	fmt.Fprintln(w, "\nAfter creating:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  Wait until Available:\t%s\n", yesNo(plan.wait))
+	fmt.Fprintf(tw, "  Wait for postCreateCommand:\t%s\n", yesNo(plan.waitPostCreate))
+	terminfo := "no"
+	if plan.copyTerminfo && plan.terminfoTerm != "" {
+		terminfo = "yes (" + plan.terminfoTerm + ")"
+	}
+	fmt.Fprintf(tw, "  Copy terminfo:\t%s\n", terminfo)
+	dotfiles := "no"
+	if plan.dotfiles != "" {
+		dotfiles = "yes (" + plan.dotfiles + ")"
+	}
+	fmt.Fprintf(tw, "  Apply dotfiles:\t%s\n", dotfiles)
+	fmt.Fprintf(tw, "  Desktop notification:\t%s\n", yesNo(plan.notify))
+	fmt.Fprintf(tw, "  SSH:\t%s\n", ssh)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "\nDry run: nothing was created or uploaded.")
+	return nil
+}
+
 type createRepoOption struct {
 	label    string
 	repo     string
@@ -203,6 +604,9 @@ func selectCreateRepoInteractive(cfg *config.Config) (string, error) {
 		lookup[option.label] = option
 	}
 
+	if err := requireFzf(); err != nil {
+		return "", err
+	}
 	fzfCmd := exec.Command(
 		"fzf",
 		"--prompt", "Repo> ",
@@ -318,9 +722,136 @@ func expandRepoAlias(alias string) string {
 	return "github/" + alias
 }
 
-func copyTerminfo(name string) error {
-	// Get terminfo from local Ghostty
-	infocmp := exec.Command("infocmp", "-x")
+// resolveRepoInput resolves an alias to a full repo name, assuming the
+// github org for bare names.
+func resolveRepoInput(cfg *config.Config, input string) string {
+	return explainRepoInput(cfg, input).Repo
+}
+
+// repoResolution records how resolveRepoInput got from an input to a repo.
+type repoResolution struct {
+	Input string
+	// Alias is true when the input matched a configured alias.
+	Alias bool
+	// Prefixed is true when the github org was assumed for a bare name.
+	Prefixed bool
+	Repo     string
+}
+
+func explainRepoInput(cfg *config.Config, input string) repoResolution {
+	res := repoResolution{Input: input}
+	repo := cfg.ResolveAlias(input)
+	res.Alias = repo != input
+	if !strings.Contains(repo, "/") {
+		// Assume it's a GitHub org repo
+		repo = "github/" + repo
+		res.Prefixed = true
+	}
+	res.Repo = repo
+	return res
+}
+
+// createRepoFromTemplate creates a repository named name from template and
+// returns its full owner/repo name.
+func createRepoFromTemplate(template, name, visibility string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("--from-template requires --name for the new repository")
+	}
+	switch visibility {
+	case "public", "private", "internal":
+	default:
+		return "", fmt.Errorf("invalid --visibility %q (expected public, private, or internal)", visibility)
+	}
+
+	fmt.Printf("Creating repository %s from template %s...\n", name, template)
+	result, err := gh.RunWithStderr("repo", "create", name, "--template", template, "--"+visibility)
+	if err != nil {
+		return "", fmt.Errorf("failed to create repository from template: %w", err)
+	}
+
+	// gh prints the new repository's URL on success
+	return parseCreatedRepo(string(result.Stdout))
+}
+
+// parseCreatedRepo extracts owner/repo from gh repo create output.
+func parseCreatedRepo(output string) (string, error) {
+	for _, field := range strings.Fields(output) {
+		if strings.HasPrefix(field, "https://github.com/") {
+			return normalizeManualRepoInput(field)
+		}
+	}
+	return "", fmt.Errorf("could not determine the new repository from gh output: %q", strings.TrimSpace(output))
+}
+
+// ansiEscape matches ANSI CSI escape sequences such as colors.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// extractCreatedCodespaceName returns the codespace name from gh cs create
+// output. The name is the last line printed; any status or spinner output
+// interleaved before it (including \r-overwritten lines and colors) is skipped.
+func extractCreatedCodespaceName(output string) string {
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := lines[i]
+		if idx := strings.LastIndex(strings.TrimRight(line, "\r"), "\r"); idx >= 0 {
+			line = line[idx+1:]
+		}
+		line = strings.TrimSpace(ansiEscape.ReplaceAllString(line, ""))
+		if line != "" && !strings.ContainsAny(line, " \t") {
+			return line
+		}
+	}
+	return ""
+}
+
+// genericTerms are terminal types most systems ship terminfo for. They are
+// only copied when the codespace turns out not to have them.
+var genericTerms = map[string]bool{
+	"xterm":           true,
+	"xterm-256color":  true,
+	"screen":          true,
+	"screen-256color": true,
+	"tmux":            true,
+	"tmux-256color":   true,
+}
+
+// terminfoTerm returns the terminal whose terminfo is copied to new
+// codespaces: defaults.terminfo_term if set, otherwise $TERM.
+func terminfoTerm(cfg *config.Config) string {
+	if term := cfg.GetEffectiveTerminfoTerm(); term != "" {
+		return term
+	}
+	return os.Getenv("TERM")
+}
+
+// installTerminfo copies the terminfo for term to codespace name, printing
+// what it does. Failures are warnings, since the codespace works without
+// it.
+func installTerminfo(name, term string) {
+	if term == "" {
+		fmt.Println("Skipping terminfo: $TERM isn't set (set defaults.terminfo_term to pick a terminal)")
+		return
+	}
+	if genericTerms[term] && remoteHasTerminfo(name, term) {
+		fmt.Printf("Skipping terminfo: the codespace already has %s\n", term)
+		return
+	}
+
+	fmt.Printf("Copying %s terminfo...\n", term)
+	if err := copyTerminfo(name, term); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to copy %s terminfo: %v\n", term, err)
+	}
+}
+
+// remoteHasTerminfo reports whether codespace name has terminfo for term.
+// Errors count as missing, so the copy is attempted anyway.
+func remoteHasTerminfo(name, term string) bool {
+	return gh.RunTraced(exec.Command("gh", "cs", "ssh", "-c", name, "--", "infocmp", term)) == nil
+}
+
+func copyTerminfo(name, term string) error {
+	// Get the terminfo from the local machine
+	infocmp := exec.Command("infocmp", "-x", term)
 	var terminfo bytes.Buffer
 	infocmp.Stdout = &terminfo
 	if err := infocmp.Run(); err != nil {
@@ -341,7 +872,7 @@ func copyTerminfo(name string) error {
 		var stderr bytes.Buffer
 		sshCmd.Stderr = &stderr
 
-		if err := sshCmd.Run(); err != nil {
+		if err := gh.RunTraced(sshCmd); err != nil {
 			lastErr = fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
 			if attempt < maxRetries {
 				time.Sleep(retryDelay)
@@ -355,62 +886,326 @@ func copyTerminfo(name string) error {
 	return lastErr
 }
 
-func sendNotification(title, message string) {
-	switch runtime.GOOS {
-	case "darwin":
-		script := fmt.Sprintf(`display notification %q with title %q sound name "Glass"`, message, title)
-		exec.Command("osascript", "-e", script).Run()
-	case "linux":
-		exec.Command("notify-send", title, message).Run()
+// sendNotification sends a desktop notification with the backend from
+// cfg.Notifications. Failures only warn.
+func sendNotification(cfg *config.Config, title, message string) {
+	notifier, err := notify.New(notify.Options{
+		Backend: cfg.Notifications.Backend,
+		Command: cfg.Notifications.Command,
+		Sound:   cfg.Notifications.Sound,
+	})
+	if err == nil {
+		err = notifier.Notify(title, message)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to send notification: %v\n", err)
 	}
 }
 
-// runHook executes a hook command with placeholder substitution.
-// Supported placeholders: {name}, {repo}, {branch}, {short_repo}
-// For pre-create hooks, {name} is empty because the codespace doesn't exist yet.
-func runHook(hook, name, repo, branch string) error {
+// notificationsEnabled reports whether cfg sends notifications at all.
+func notificationsEnabled(cfg *config.Config) bool {
+	return cfg.Notifications.Command != "" || cfg.Notifications.Backend != notify.BackendNone
+}
+
+// hookVars are the values substituted into hook placeholders.
+type hookVars struct {
+	Name   string
+	Repo   string
+	Branch string
+	// Codespace provides {machine}, {state} and {display_name}. When nil,
+	// they expand to "".
+	Codespace *gh.Codespace
+}
+
+// codespacePlaceholders are the placeholders, and the matching
+// environment variables, that need Codespace.
+var codespacePlaceholders = []string{"{machine}", "{state}", "{display_name}", "CSD_MACHINE", "CSD_STATE", "CSD_DISPLAY_NAME"}
+
+// hookValue is one value available to hooks, both as a placeholder and
+// as an environment variable.
+type hookValue struct {
+	placeholder string
+	env         string
+	value       string
+}
+
+// hookValues returns the values of vars for a hook.
+func hookValues(vars hookVars) []hookValue {
 	// Extract short repo name
-	shortRepo := repo
-	if parts := strings.Split(repo, "/"); len(parts) > 1 {
+	shortRepo := vars.Repo
+	if parts := strings.Split(vars.Repo, "/"); len(parts) > 1 {
 		shortRepo = parts[len(parts)-1]
 	}
 
-	// Replace placeholders
-	cmd := hook
-	cmd = strings.ReplaceAll(cmd, "{name}", name)
-	cmd = strings.ReplaceAll(cmd, "{repo}", repo)
-	cmd = strings.ReplaceAll(cmd, "{branch}", branch)
-	cmd = strings.ReplaceAll(cmd, "{short_repo}", shortRepo)
+	var machine, csState, displayName string
+	if vars.Codespace != nil {
+		machine = vars.Codespace.MachineName
+		csState = vars.Codespace.State
+		displayName = vars.Codespace.DisplayName
+	}
+
+	return []hookValue{
+		{"{name}", "CSD_NAME", vars.Name},
+		{"{repo}", "CSD_REPO", vars.Repo},
+		{"{branch}", "CSD_BRANCH", vars.Branch},
+		{"{short_repo}", "CSD_SHORT_REPO", shortRepo},
+		{"{machine}", "CSD_MACHINE", machine},
+		{"{state}", "CSD_STATE", csState},
+		{"{display_name}", "CSD_DISPLAY_NAME", displayName},
+	}
+}
+
+// expandHook substitutes placeholders in a hook command.
+// Supported placeholders: {name}, {repo}, {branch}, {short_repo}, plus
+// {machine}, {state} and {display_name} from vars.Codespace.
+// For pre-create hooks, {name} is empty because the codespace doesn't exist yet.
+// Values are substituted as-is, so hooks that handle untrusted values
+// such as branch names should use the variables from hookEnv instead.
+func expandHook(hook string, vars hookVars) string {
+	var pairs []string
+	for _, v := range hookValues(vars) {
+		pairs = append(pairs, v.placeholder, v.value)
+	}
+	return strings.NewReplacer(pairs...).Replace(hook)
+}
+
+// hookEnv returns the hook values as environment variables (CSD_NAME,
+// CSD_REPO, ...), which a hook can quote like any other variable.
+func hookEnv(vars hookVars) []string {
+	var env []string
+	for _, v := range hookValues(vars) {
+		env = append(env, v.env+"="+v.value)
+	}
+	return env
+}
+
+// withHookCodespace fills in vars.Codespace with lookup, but only when
+// it's missing and one of hooks uses a placeholder that needs it, so
+// hooks that don't pay for the extra API call.
+func withHookCodespace(hooks []string, vars hookVars, lookup func(string) (*gh.Codespace, error)) hookVars {
+	if vars.Codespace != nil || vars.Name == "" {
+		return vars
+	}
+	for _, hook := range hooks {
+		for _, placeholder := range codespacePlaceholders {
+			if !strings.Contains(hook, placeholder) {
+				continue
+			}
+			cs, err := lookup(vars.Name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to look up %s for hook placeholders: %v\n", vars.Name, err)
+				return vars
+			}
+			vars.Codespace = cs
+			return vars
+		}
+	}
+	return vars
+}
+
+// runHook executes a hook command with placeholder substitution.
+func runHook(hook string, vars hookVars) error {
+	cmd := expandHook(hook, vars)
 
 	fmt.Printf("Running hook: %s\n", cmd)
 
 	// Execute via shell
 	hookCmd := exec.Command("sh", "-c", cmd)
+	hookCmd.Env = append(os.Environ(), hookEnv(vars)...)
 	hookCmd.Stdout = os.Stdout
 	hookCmd.Stderr = os.Stderr
 
 	return hookCmd.Run()
 }
 
-func runHooks(phase string, hooks []string, name, repo, branch string) {
+func getCreateLockPath(repo string) string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".csd", "locks", "create-"+strings.ReplaceAll(repo, "/", "_")+".lock")
+}
+
+// acquireCreateLock takes the create lock for repo, failing if another live
+// process holds it. Locks left behind by a process that died are taken
+// over. The returned func releases the lock.
+func acquireCreateLock(repo string) (func(), error) {
+	path := getCreateLockPath(repo)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			fmt.Fprintf(f, "%d", os.Getpid())
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock: %w", err)
+		}
+
+		data, _ := os.ReadFile(path)
+		pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+		if pid > 0 && processAlive(pid) {
+			return nil, fmt.Errorf("create already in progress for %s (pid %d)", repo, pid)
+		}
+		// Stale lock from a process that no longer exists
+		os.Remove(path)
+	}
+	return nil, fmt.Errorf("create already in progress for %s", repo)
+}
+
+// waitForCodespaceAvailable polls until the codespace reaches the Available
+// state, failing on a terminal state or after timeout.
+func waitForCodespaceAvailable(name string, timeout time.Duration) (*gh.Codespace, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		// Always poll fresh state rather than a cached listing
+		gh.InvalidateCache()
+		cs, err := gh.GetCodespace(name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch cs.State {
+		case "Available":
+			return cs, nil
+		case "Failed", "Deleted":
+			return nil, fmt.Errorf("codespace %s is %s", name, cs.State)
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for %s to be available (state: %s)", timeout, name, cs.State)
+		}
+		time.Sleep(createWaitInterval)
+	}
+}
+
+// waitForPostCreate polls the codespace's creation log until the
+// devcontainer setup has finished or failed, or timeout passes.
+func waitForPostCreate(name string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		// The log may not be readable yet while the codespace finishes booting
+		if result, err := gh.Run("cs", "logs", "-c", name); err == nil {
+			log := string(result.Stdout)
+			if failure := postCreateFailure(log); failure != "" {
+				return fmt.Errorf("the devcontainer setup of %s failed: %s", name, failure)
+			}
+			if postCreateFinished(log) {
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for the devcontainer setup of %s", timeout, name)
+		}
+		time.Sleep(createWaitInterval)
+	}
+}
+
+// postCreateFinished reports whether a creation log shows the devcontainer
+// setup has completed.
+func postCreateFinished(log string) bool {
+	return strings.Contains(log, postCreateDoneMarker)
+}
+
+// postCreateFailure returns the line of a creation log that reports a
+// failed setup command, or "" if there is none.
+func postCreateFailure(log string) string {
+	return strings.TrimSpace(postCreateFailedPattern.FindString(log))
+}
+
+func runHooks(phase string, hooks []string, vars hookVars) {
+	// Look the codespace up once for all hooks rather than per hook
+	vars = withHookCodespace(hooks, vars, gh.GetCodespace)
 	for _, hook := range hooks {
-		if err := runHook(hook, name, repo, branch); err != nil {
+		if err := runHook(hook, vars); err != nil {
 			fmt.Fprintf(os.Stderr, "Warning: %s hook failed: %v\n", phase, err)
 		}
 	}
 }
 
-// Helper function to check if a codespace with the given repo already exists
-func findExistingCodespace(repo string) (*gh.Codespace, error) {
+// runRequiredHooks is runHooks for phases where a failing hook must stop
+// the operation. Hooks after the failing one are not run.
+func runRequiredHooks(phase string, hooks []string, vars hookVars) error {
+	vars = withHookCodespace(hooks, vars, gh.GetCodespace)
+	for _, hook := range hooks {
+		if err := runHook(hook, vars); err != nil {
+			return fmt.Errorf("%s hook %q failed: %w", phase, hook, err)
+		}
+	}
+	return nil
+}
+
+// findExistingCodespace returns the most recently used codespace for repo,
+// on branch if it isn't empty, or nil if there is none.
+func findExistingCodespace(repo, branch string) (*gh.Codespace, error) {
 	codespaces, err := gh.ListCodespaces()
 	if err != nil {
 		return nil, err
 	}
+	return pickExistingCodespace(codespaces, repo, branch), nil
+}
 
-	for _, cs := range codespaces {
-		if cs.Repository == repo {
-			return &cs, nil
+// pickExistingCodespace returns the most recently used of codespaces for
+// repo, on branch if it isn't empty, or nil if none match.
+func pickExistingCodespace(codespaces []gh.Codespace, repo, branch string) *gh.Codespace {
+	var best *gh.Codespace
+	for i := range codespaces {
+		cs := &codespaces[i]
+		if cs.Repository != repo || (branch != "" && cs.Branch != branch) {
+			continue
 		}
+		if best == nil || lastUsedAt(*cs).After(lastUsedAt(*best)) {
+			best = cs
+		}
+	}
+	return best
+}
+
+// confirmReuseCodespace asks whether to reuse cs rather than create another
+// codespace for its repo. Without a terminal to ask on it returns false, so
+// scripted creates keep creating.
+func confirmReuseCodespace(cs *gh.Codespace) bool {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false
+	}
+
+	fmt.Printf("You already have a codespace for %s: %s (%s, %s)\n", cs.Repository, cs.Name, cs.DisplayBranch(), cs.State)
+	fmt.Print("Reuse it instead of creating another? [Y/n] ")
+
+	reader := bufio.NewReader(os.Stdin)
+	response, _ := reader.ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "" || response == "y" || response == "yes"
+}
+
+// connectExistingCodespace reuses cs instead of creating a codespace: it
+// starts cs if needed, makes it current and connects unless --no-ssh.
+func connectExistingCodespace(cfg *config.Config, cs *gh.Codespace) error {
+	fmt.Printf("Reusing codespace %s (%s @ %s)\n", cs.Name, cs.Repository, cs.DisplayBranch())
+
+	if cs.State != "Available" {
+		fmt.Printf("Starting %s... ", cs.Name)
+		if err := gh.StartCodespace(cs.Name); err != nil {
+			fmt.Println("FAILED")
+			return err
+		}
+		fmt.Println("done")
+	}
+
+	if err := setCurrentCodespace(cs); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to update current codespace: %v\n", err)
+	}
+
+	if createNoSSH {
+		return nil
+	}
+
+	fmt.Println("Connecting...")
+	sshNoRdm = false
+	if cfg.GetEffectiveSSHRetry(cs.Repository) {
+		return sshWithRetry(cs.Name, cs, cfg)
 	}
-	return nil, nil
+	return sshOnce(cs.Name, cfg, cs.Repository)
 }
@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/luanzeba/gh-csd/internal/config"
+	"github.com/luanzeba/gh-csd/internal/gh"
 )
 
 func TestBuildCreateRepoOptions(t *testing.T) {
@@ -87,3 +93,294 @@ func TestNormalizeManualRepoInput(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractCreatedCodespaceName(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{name: "plain", output: "super-robot-abc123\n", want: "super-robot-abc123"},
+		{name: "status lines before name", output: "Provisioning...\nStarting...\nsuper-robot-abc123\n", want: "super-robot-abc123"},
+		{name: "spinner overwritten with carriage returns", output: "⣾ Provisioning\r⣽ Provisioning\rsuper-robot-abc123\n", want: "super-robot-abc123"},
+		{name: "colored name", output: "\x1b[32msuper-robot-abc123\x1b[0m\n", want: "super-robot-abc123"},
+		{name: "trailing status line", output: "super-robot-abc123\nCodespace is ready\n", want: "super-robot-abc123"},
+		{name: "empty", output: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractCreatedCodespaceName(tt.output); got != tt.want {
+				t.Errorf("extractCreatedCodespaceName(%q) = %q, want %q", tt.output, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCreatedRepo(t *testing.T) {
+	got, err := parseCreatedRepo("https://github.com/luanzeba/new-project\n")
+	if err != nil || got != "luanzeba/new-project" {
+		t.Fatalf("parseCreatedRepo = %q, %v; want luanzeba/new-project", got, err)
+	}
+
+	if _, err := parseCreatedRepo("something unexpected\n"); err == nil {
+		t.Fatal("expected an error when no repository URL is printed")
+	}
+}
+
+func TestCreateRepoFromTemplateValidation(t *testing.T) {
+	if _, err := createRepoFromTemplate("luanzeba/template", "", "private"); err == nil || !strings.Contains(err.Error(), "--name") {
+		t.Fatalf("expected a missing --name error, got %v", err)
+	}
+	if _, err := createRepoFromTemplate("luanzeba/template", "new-project", "secret"); err == nil || !strings.Contains(err.Error(), "--visibility") {
+		t.Fatalf("expected an invalid --visibility error, got %v", err)
+	}
+}
+
+func TestAcquireCreateLock(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	release, err := acquireCreateLock("github/github")
+	if err != nil {
+		t.Fatalf("first acquire failed: %v", err)
+	}
+
+	if _, err := acquireCreateLock("github/github"); err == nil || !strings.Contains(err.Error(), "create already in progress for github/github") {
+		t.Fatalf("second acquire error = %v, want in-progress error", err)
+	}
+
+	// Other repos aren't blocked
+	releaseOther, err := acquireCreateLock("github/docs")
+	if err != nil {
+		t.Fatalf("acquire for another repo failed: %v", err)
+	}
+	releaseOther()
+
+	release()
+	release, err = acquireCreateLock("github/github")
+	if err != nil {
+		t.Fatalf("acquire after release failed: %v", err)
+	}
+	release()
+}
+
+func TestAcquireCreateLockStale(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	path := getCreateLockPath("github/github")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	// A pid far above any real pid_max stands in for a dead process
+	if err := os.WriteFile(path, []byte("999999999"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	release, err := acquireCreateLock("github/github")
+	if err != nil {
+		t.Fatalf("acquire over stale lock failed: %v", err)
+	}
+	release()
+}
+
+func TestPostCreateFinished(t *testing.T) {
+	running := "2026-10-16 09:00:01.000Z: Running the postCreateCommand from devcontainer.json...\n"
+	if postCreateFinished(running) {
+		t.Error("postCreateFinished() = true while postCreateCommand is running")
+	}
+	if !postCreateFinished(running + "2026-10-16 09:04:12.000Z: Finished configuring codespace.\n") {
+		t.Error("postCreateFinished() = false after the setup finished")
+	}
+}
+
+func TestPostCreateFailure(t *testing.T) {
+	running := "2026-10-16 09:00:01.000Z: Running the postCreateCommand from devcontainer.json...\n"
+	if got := postCreateFailure(running); got != "" {
+		t.Errorf("postCreateFailure() = %q while postCreateCommand is running", got)
+	}
+	failed := "2026-10-16 09:02:30.000Z: postCreateCommand from devcontainer.json failed with exit code 1. Skipping any further user-provided commands."
+	if got := postCreateFailure(running + failed + "\n"); got != failed {
+		t.Errorf("postCreateFailure() = %q, want %q", got, failed)
+	}
+}
+
+func TestRunRequiredHooks(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "ran")
+	hooks := []string{"true", "exit 3", "touch " + marker}
+
+	vars := hookVars{Repo: "github/github", Branch: "main"}
+	err := runRequiredHooks("pre-create", hooks, vars)
+	if err == nil || !strings.Contains(err.Error(), `pre-create hook "exit 3" failed`) {
+		t.Fatalf("runRequiredHooks() error = %v, want the failing hook", err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Error("hooks after the failing one should not run")
+	}
+
+	if err := runRequiredHooks("pre-create", hooks[:1], vars); err != nil {
+		t.Errorf("runRequiredHooks() with passing hooks = %v", err)
+	}
+}
+
+func TestBuildCreateArgs(t *testing.T) {
+	got := buildCreateArgs("github/github", "xLargePremiumLinux", ".devcontainer/devcontainer.json", "", 0, false)
+	want := []string{"cs", "create", "-R", "github/github", "-m", "xLargePremiumLinux", "--devcontainer-path", ".devcontainer/devcontainer.json", "--status"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildCreateArgs() = %v, want %v", got, want)
+	}
+
+	got = buildCreateArgs("github/github", "basicLinux32gb", "", "feature", 90, true)
+	want = []string{"cs", "create", "-R", "github/github", "-m", "basicLinux32gb", "--devcontainer-path", "", "--status", "-b", "feature", "--idle-timeout", "90m", "--default-permissions"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildCreateArgs() with options = %v, want %v", got, want)
+	}
+}
+
+func TestWriteCreatePlan(t *testing.T) {
+	var buf strings.Builder
+	err := writeCreatePlan(&buf, createPlan{
+		repo:       "github/github",
+		machine:    "xLargePremiumLinux",
+		branch:     "main",
+		args:       buildCreateArgs("github/github", "xLargePremiumLinux", "", "main", 0, false),
+		preCreate:  []string{"echo {short_repo}@{branch}"},
+		postCreate: []string{"echo {name}"},
+		dotfiles:   "octocat/dotfiles",
+		ssh:        true,
+		sshRetry:   true,
+	})
+	if err != nil {
+		t.Fatalf("writeCreatePlan() failed: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"Devcontainer:         (repository default)",
+		"Idle timeout:         (GitHub default)",
+		"Command:              gh 'cs' 'create' '-R' 'github/github'",
+		"pre_create: echo github@main",
+		"post_create: echo {name}",
+		"Copy terminfo:               no",
+		"Apply dotfiles:              yes (octocat/dotfiles)",
+		"SSH:                         yes, reconnecting on disconnect",
+		"Dry run: nothing was created",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("plan doesn't contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTerminfoTerm(t *testing.T) {
+	t.Setenv("TERM", "xterm-kitty")
+	cfg := config.DefaultConfig()
+	if got := terminfoTerm(cfg); got != "xterm-kitty" {
+		t.Errorf("terminfoTerm() = %q, want $TERM", got)
+	}
+
+	cfg.Defaults.TerminfoTerm = "wezterm"
+	if got := terminfoTerm(cfg); got != "wezterm" {
+		t.Errorf("terminfoTerm() = %q, want defaults.terminfo_term", got)
+	}
+}
+
+func TestDotfilesScript(t *testing.T) {
+	script := dotfilesScript("octocat/dotfiles")
+	for _, want := range []string{
+		`dir=$HOME/dotfiles;`,
+		`git clone --quiet --depth 1 'https://github.com/octocat/dotfiles.git' "$tmp"`,
+		`mv "$tmp" "$dir"`,
+		`for s in install.sh install bootstrap.sh`,
+		`ln -sf "$dir/$f" "$HOME/$f"`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script doesn't contain %q:\n%s", want, script)
+		}
+	}
+
+	// Run it against a local "codespace" to check the shell is valid and
+	// an existing checkout is left alone
+	home := t.TempDir()
+	if err := os.Mkdir(filepath.Join(home, "dotfiles"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("sh", "-c", script)
+	cmd.Env = append(os.Environ(), "HOME="+home)
+	if out, err := cmd.CombinedOutput(); err != nil || !strings.Contains(string(out), "already exists") {
+		t.Fatalf("script with existing ~/dotfiles = %v: %s", err, out)
+	}
+
+	// A clone that fails partway leaves nothing behind, so a retry can
+	// clone again
+	home = t.TempDir()
+	bin := t.TempDir()
+	fakeGit := "#!/bin/sh\nfor last; do :; done\nmkdir -p \"$last\"\nexit 128\n"
+	if err := os.WriteFile(filepath.Join(bin, "git"), []byte(fakeGit), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cmd = exec.Command("sh", "-c", script)
+	cmd.Env = append(os.Environ(), "HOME="+home, "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	if out, err := cmd.CombinedOutput(); err == nil {
+		t.Fatalf("script with a failing clone succeeded: %s", out)
+	}
+	if entries, _ := os.ReadDir(home); len(entries) > 0 {
+		t.Errorf("failed clone left %v in $HOME", entries[0].Name())
+	}
+}
+
+func TestPickLargestMachine(t *testing.T) {
+	const gb = 1 << 30
+	machines := []gh.Machine{
+		{Name: "basicLinux32gb", CPUs: 2, MemoryInBytes: 8 * gb},
+		{Name: "premiumLinux", CPUs: 8, MemoryInBytes: 32 * gb},
+		{Name: "largePremiumLinux", CPUs: 16, MemoryInBytes: 64 * gb},
+		{Name: "standardLinux32gb", CPUs: 4, MemoryInBytes: 16 * gb},
+		{Name: "premiumLinuxMemory", CPUs: 8, MemoryInBytes: 64 * gb},
+	}
+
+	for _, tt := range []struct {
+		maxCPUs int
+		want    string
+	}{
+		{0, "largePremiumLinux"},
+		{8, "premiumLinuxMemory"},
+		{4, "standardLinux32gb"},
+		{3, "basicLinux32gb"},
+	} {
+		got, ok := pickLargestMachine(machines, tt.maxCPUs)
+		if !ok || got.Name != tt.want {
+			t.Errorf("pickLargestMachine(max %d) = %s, %v; want %s", tt.maxCPUs, got.Name, ok, tt.want)
+		}
+	}
+	if _, ok := pickLargestMachine(machines, 1); ok {
+		t.Error("pickLargestMachine(max 1) should find nothing")
+	}
+}
+
+func TestPickExistingCodespace(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	codespaces := []gh.Codespace{
+		{Name: "old", Repository: "github/github", Branch: "main", LastUsedAt: now.Add(-48 * time.Hour)},
+		{Name: "recent", Repository: "github/github", Branch: "feature", LastUsedAt: now.Add(-time.Hour)},
+		{Name: "never-used", Repository: "github/github", Branch: "main", CreatedAt: now.Add(-24 * time.Hour)},
+		{Name: "other", Repository: "github/docs", Branch: "main", LastUsedAt: now},
+	}
+
+	for _, tt := range []struct {
+		repo, branch string
+		want         string
+	}{
+		{"github/github", "", "recent"},
+		{"github/github", "main", "never-used"},
+		{"github/github", "missing", ""},
+		{"github/other", "", ""},
+	} {
+		got := ""
+		if cs := pickExistingCodespace(codespaces, tt.repo, tt.branch); cs != nil {
+			got = cs.Name
+		}
+		if got != tt.want {
+			t.Errorf("pickExistingCodespace(%q, %q) = %q, want %q", tt.repo, tt.branch, got, tt.want)
+		}
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/luanzeba/gh-csd/internal/gh"
 	"github.com/luanzeba/gh-csd/internal/state"
+	"github.com/luanzeba/gh-csd/internal/stats"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
@@ -19,6 +23,12 @@ var (
 	deleteForce bool
 	deleteAll   bool
 	deleteList  bool
+
+	deleteConfirmEach    bool
+	deleteDiscardUnsaved bool
+
+	deleteStopped   bool
+	deleteOlderThan time.Duration
 )
 
 var deleteCmd = &cobra.Command{
@@ -29,10 +39,27 @@ var deleteCmd = &cobra.Command{
 Without arguments, deletes the currently selected codespace.
 Use --list to interactively select codespaces to delete with fzf (Tab to multi-select).
 
-If the codespace has unsaved changes, you will be prompted to confirm.
+Codespaces with uncommitted or unpushed changes are never deleted unless
+--discard-unsaved is given, even with --force. Without --force, the
+confirmation prompt then flags each codespace whose changes would be lost,
+and gh also prompts before discarding them.
+
 Use --force to skip all confirmation prompts.
+Use --interactive-confirm-each to review each codespace (repo, branch, state)
+and confirm it individually instead of confirming the whole batch.
+
+Use --stopped and --older-than to prune codespaces: --stopped only keeps
+those that are stopped (Shutdown, ShuttingDown or Stopped), and --older-than only those not used (or, if
+never used, created) within the given duration, e.g. 168h. Without names or
+--list they pick from all your codespaces; together they must both match.
+The matching codespaces are always listed before anything is deleted, even
+with --force.
 
-If the current codespace is deleted, the selection is cleared.`,
+If the current codespace is deleted, the selection is cleared.
+
+Examples:
+  gh csd delete --stopped
+  gh csd delete --stopped --older-than 336h --force`,
 	RunE: runDelete,
 }
 
@@ -40,10 +67,19 @@ func init() {
 	deleteCmd.Flags().BoolVarP(&deleteForce, "force", "f", false, "Skip confirmation prompt")
 	deleteCmd.Flags().BoolVar(&deleteAll, "all", false, "Delete all codespaces (requires --force)")
 	deleteCmd.Flags().BoolVar(&deleteList, "list", false, "Interactively select codespaces to delete")
+	deleteCmd.Flags().BoolVar(&deleteConfirmEach, "interactive-confirm-each", false, "Confirm each codespace individually (y/N/q)")
+	deleteCmd.Flags().BoolVar(&deleteStopped, "stopped", false, "Only delete stopped codespaces")
+	deleteCmd.Flags().DurationVar(&deleteOlderThan, "older-than", 0, "Only delete codespaces not used for this long (e.g. 168h)")
+	deleteCmd.Flags().BoolVar(&deleteDiscardUnsaved, "discard-unsaved", false, "Delete codespaces even if they have uncommitted or unpushed changes")
 	rootCmd.AddCommand(deleteCmd)
 }
 
 func runDelete(cmd *cobra.Command, args []string) error {
+	if deleteConfirmEach && deleteForce {
+		return fmt.Errorf("--interactive-confirm-each cannot be combined with --force")
+	}
+
+	filtering := deleteStopped || deleteOlderThan > 0
 	var toDelete []string
 
 	if deleteAll {
@@ -66,6 +102,14 @@ func runDelete(cmd *cobra.Command, args []string) error {
 		toDelete = selected
 	} else if len(args) > 0 {
 		toDelete = args
+	} else if filtering {
+		codespaces, err := gh.ListCodespaces()
+		if err != nil {
+			return err
+		}
+		for _, cs := range codespaces {
+			toDelete = append(toDelete, cs.Name)
+		}
 	} else {
 		// Default: delete the current codespace
 		name, err := state.Get()
@@ -78,17 +122,60 @@ func runDelete(cmd *cobra.Command, args []string) error {
 		toDelete = []string{name}
 	}
 
+	if filtering && len(toDelete) > 0 {
+		codespaces, err := gh.ListCodespaces()
+		if err != nil {
+			return err
+		}
+		matched := filterDeleteCandidates(toDelete, codespaces, deleteStopped, deleteOlderThan, time.Now())
+		if len(matched) == 0 {
+			fmt.Println("No codespaces match the filters.")
+			return nil
+		}
+		fmt.Printf("%d codespace(s) match the filters:\n", len(matched))
+		if err := writeDeleteCandidates(os.Stdout, matched, time.Now()); err != nil {
+			return err
+		}
+		fmt.Println()
+
+		toDelete = toDelete[:0]
+		for _, cs := range matched {
+			toDelete = append(toDelete, cs.Name)
+		}
+	}
+
 	if len(toDelete) == 0 {
 		fmt.Println("No codespaces selected.")
 		return nil
 	}
 
+	if !deleteDiscardUnsaved {
+		if err := checkUnsavedChanges(toDelete); err != nil {
+			return err
+		}
+	}
+
+	// The prompts flag codespaces with unsaved changes. Without
+	// --discard-unsaved the check above already refreshed the listing
+	// (and refused them), so only refresh it here
+	if deleteDiscardUnsaved && !deleteForce {
+		gh.InvalidateCache()
+	}
+
 	// Confirm deletion
-	if !deleteForce {
-		fmt.Printf("Delete %d codespace(s):\n", len(toDelete))
-		for _, name := range toDelete {
-			fmt.Printf("  - %s\n", name)
+	if deleteConfirmEach {
+		toDelete = confirmEachCodespace(toDelete, bufio.NewReader(os.Stdin))
+		if len(toDelete) == 0 {
+			fmt.Println("Nothing to delete.")
+			return nil
+		}
+	} else if !deleteForce {
+		codespaces, err := gh.ListCodespaces()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not check for unsaved changes: %v\n", err)
 		}
+		fmt.Printf("Delete %d codespace(s):\n", len(toDelete))
+		writeDeleteConfirmation(os.Stdout, toDelete, codespaces)
 		fmt.Print("\nConfirm? [y/N] ")
 
 		reader := bufio.NewReader(os.Stdin)
@@ -106,12 +193,14 @@ func runDelete(cmd *cobra.Command, args []string) error {
 	// Delete each codespace
 	var failed []string
 	for _, name := range toDelete {
+		repo := codespaceRepo(name)
 		fmt.Printf("Deleting %s... ", name)
 		if err := deleteCodespace(name); err != nil {
 			fmt.Printf("FAILED: %v\n", err)
 			failed = append(failed, name)
 		} else {
 			fmt.Println("done")
+			recordStat(repo, stats.Delete)
 			// Clear current selection if deleted
 			if name == currentCS {
 				state.Clear()
@@ -126,7 +215,115 @@ func runDelete(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// filterDeleteCandidates returns the codespaces named in names that match
+// --stopped and --older-than, in the order of names. Names that aren't in
+// codespaces can't be checked and are left out.
+func filterDeleteCandidates(names []string, codespaces []gh.Codespace, stopped bool, olderThan time.Duration, now time.Time) []gh.Codespace {
+	byName := make(map[string]gh.Codespace, len(codespaces))
+	for _, cs := range codespaces {
+		byName[cs.Name] = cs
+	}
+
+	var matched []gh.Codespace
+	for _, name := range names {
+		cs, ok := byName[name]
+		if !ok {
+			continue
+		}
+		if stopped && !isStoppedState(cs.State) {
+			continue
+		}
+		if olderThan > 0 {
+			lastUsed := lastUsedAt(cs)
+			if lastUsed.IsZero() || now.Sub(lastUsed) < olderThan {
+				continue
+			}
+		}
+		matched = append(matched, cs)
+	}
+	return matched
+}
+
+// lastUsedAt returns when cs was last used, falling back to when it was
+// created for codespaces that were never used.
+func lastUsedAt(cs gh.Codespace) time.Time {
+	if cs.LastUsedAt.IsZero() {
+		return cs.CreatedAt
+	}
+	return cs.LastUsedAt
+}
+
+// writeDeleteCandidates lists the codespaces matched by the delete filters.
+func writeDeleteCandidates(w io.Writer, codespaces []gh.Codespace, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  NAME\tREPOSITORY\tBRANCH\tSTATE\tLAST USED")
+	for _, cs := range codespaces {
+		lastUsed := "unknown"
+		if at := lastUsedAt(cs); !at.IsZero() {
+			lastUsed = now.Sub(at).Round(time.Minute).String() + " ago"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", cs.Name, cs.Repository, cs.DisplayBranch(), cs.State, lastUsed)
+	}
+	return tw.Flush()
+}
+
+// writeDeleteConfirmation lists the codespaces about to be deleted, flagging
+// those whose unsaved changes would be lost.
+func writeDeleteConfirmation(w io.Writer, names []string, codespaces []gh.Codespace) {
+	unsaved := make(map[string]string)
+	for _, cs := range unsavedCodespaces(names, codespaces) {
+		unsaved[cs.Name] = unsavedChanges(cs)
+	}
+
+	for _, name := range names {
+		if changes, ok := unsaved[name]; ok {
+			fmt.Fprintf(w, "  - %s  (WARNING: %s will be lost)\n", name, changes)
+		} else {
+			fmt.Fprintf(w, "  - %s\n", name)
+		}
+	}
+	if len(unsaved) > 0 {
+		fmt.Fprintf(w, "\n%d of them have unsaved changes.\n", len(unsaved))
+	}
+}
+
+// confirmEachCodespace prompts for each codespace individually and returns
+// the ones the user confirmed. Answering q stops prompting and drops the rest.
+func confirmEachCodespace(names []string, reader *bufio.Reader) []string {
+	var confirmed []string
+	for i, name := range names {
+		fmt.Printf("\n[%d/%d] %s\n", i+1, len(names), name)
+		if cs, err := gh.GetCodespace(name); err != nil {
+			fmt.Printf("  (details unavailable: %v)\n", err)
+		} else {
+			fmt.Printf("  Repository: %s\n", cs.Repository)
+			fmt.Printf("  Branch:     %s\n", cs.DisplayBranch())
+			fmt.Printf("  State:      %s\n", cs.State)
+			if cs.HasUnsavedChanges() {
+				fmt.Printf("  WARNING:    %s will be lost\n", unsavedChanges(*cs))
+			}
+		}
+		fmt.Print("Delete? [y/N/q] ")
+
+		response, _ := reader.ReadString('\n')
+		switch strings.TrimSpace(strings.ToLower(response)) {
+		case "y", "yes":
+			confirmed = append(confirmed, name)
+		case "q", "quit":
+			fmt.Println("Stopped confirming; remaining codespaces skipped.")
+			return confirmed
+		default:
+			fmt.Println("Skipped.")
+		}
+	}
+	return confirmed
+}
+
 func selectCodespacesForDeletion() ([]string, error) {
+	if err := requireFzf(); err != nil {
+		return nil, err
+	}
+
 	// Get terminal width (subtract 3 like select does)
 	width := 80 // default
 	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
@@ -181,7 +378,74 @@ func selectCodespacesForDeletion() ([]string, error) {
 	return selected, nil
 }
 
+// checkUnsavedChanges refuses to delete codespaces whose git status shows
+// uncommitted or unpushed changes, listing them. The listing is fetched
+// fresh, since cached git status may predate the latest work.
+func checkUnsavedChanges(names []string) error {
+	gh.InvalidateCache()
+	codespaces, err := gh.ListCodespaces()
+	if err != nil {
+		return fmt.Errorf("could not check for unsaved changes (use --discard-unsaved to delete anyway): %w", err)
+	}
+
+	unsaved := unsavedCodespaces(names, codespaces)
+	if len(unsaved) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: %d codespace(s) have unsaved changes:\n", len(unsaved))
+	for _, cs := range unsaved {
+		fmt.Fprintf(os.Stderr, "  - %s (%s @ %s): %s\n", cs.Name, cs.Repository, cs.DisplayBranch(), unsavedChanges(cs))
+	}
+	return fmt.Errorf("refusing to delete codespaces with unsaved changes (use --discard-unsaved to delete them anyway)")
+}
+
+// unsavedChanges describes the kinds of unsaved changes cs has, such as
+// "uncommitted and unpushed changes".
+func unsavedChanges(cs gh.Codespace) string {
+	var changes []string
+	if cs.HasUncommittedChanges {
+		changes = append(changes, "uncommitted")
+	}
+	if cs.HasUnpushedChanges {
+		changes = append(changes, "unpushed")
+	}
+	return strings.Join(changes, " and ") + " changes"
+}
+
+// unsavedCodespaces returns the codespaces named in names that have
+// unsaved changes, in the order of names.
+func unsavedCodespaces(names []string, codespaces []gh.Codespace) []gh.Codespace {
+	byName := make(map[string]gh.Codespace, len(codespaces))
+	for _, cs := range codespaces {
+		byName[cs.Name] = cs
+	}
+
+	var unsaved []gh.Codespace
+	for _, name := range names {
+		if cs, ok := byName[name]; ok && cs.HasUnsavedChanges() {
+			unsaved = append(unsaved, cs)
+		}
+	}
+	return unsaved
+}
+
+// codespaceRepo returns the repository of a codespace, preferring the
+// cached selection info over a (cached) listing. It returns "" if unknown.
+func codespaceRepo(name string) string {
+	if info, ok := state.GetInfo(name); ok && info.Repository != "" {
+		return info.Repository
+	}
+	cs, err := gh.GetCodespace(name)
+	if err != nil {
+		return ""
+	}
+	return cs.Repository
+}
+
 func deleteCodespace(name string) error {
+	defer gh.InvalidateCache()
+
 	args := []string{"cs", "delete", "-c", name}
 	if deleteForce {
 		args = append(args, "--force")
@@ -190,5 +454,5 @@ func deleteCodespace(name string) error {
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return gh.RunTraced(cmd)
 }
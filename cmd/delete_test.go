@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+)
+
+func TestUnsavedCodespaces(t *testing.T) {
+	codespaces := []gh.Codespace{
+		{Name: "clean"},
+		{Name: "dirty", HasUncommittedChanges: true},
+		{Name: "ahead", HasUnpushedChanges: true},
+		{Name: "not-selected", HasUncommittedChanges: true},
+	}
+
+	got := unsavedCodespaces([]string{"ahead", "clean", "dirty", "missing"}, codespaces)
+	if len(got) != 2 || got[0].Name != "ahead" || got[1].Name != "dirty" {
+		t.Errorf("unsavedCodespaces() = %v, want [ahead dirty]", got)
+	}
+}
+
+func TestFilterDeleteCandidates(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	codespaces := []gh.Codespace{
+		{Name: "active", State: "Available", LastUsedAt: now.Add(-time.Hour)},
+		{Name: "idle", State: "Shutdown", LastUsedAt: now.Add(-10 * 24 * time.Hour)},
+		{Name: "recent-stop", State: "Shutdown", LastUsedAt: now.Add(-2 * time.Hour)},
+		{Name: "never-used", State: "Shutdown", CreatedAt: now.Add(-30 * 24 * time.Hour)},
+		{Name: "old-available", State: "Available", LastUsedAt: now.Add(-10 * 24 * time.Hour)},
+		{Name: "starting", State: "Starting", LastUsedAt: now.Add(-10 * 24 * time.Hour)},
+	}
+	all := []string{"active", "idle", "recent-stop", "never-used", "old-available", "starting"}
+
+	names := func(codespaces []gh.Codespace) []string {
+		var out []string
+		for _, cs := range codespaces {
+			out = append(out, cs.Name)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		names     []string
+		stopped   bool
+		olderThan time.Duration
+		want      []string
+	}{
+		{name: "stopped", names: all, stopped: true, want: []string{"idle", "recent-stop", "never-used"}},
+		{name: "older than", names: all, olderThan: 7 * 24 * time.Hour, want: []string{"idle", "never-used", "old-available", "starting"}},
+		{name: "both", names: all, stopped: true, olderThan: 7 * 24 * time.Hour, want: []string{"idle", "never-used"}},
+		{name: "only named", names: []string{"recent-stop", "missing", "active"}, stopped: true, want: []string{"recent-stop"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(filterDeleteCandidates(tt.names, codespaces, tt.stopped, tt.olderThan, now))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterDeleteCandidates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteDeleteConfirmation(t *testing.T) {
+	codespaces := []gh.Codespace{
+		{Name: "clean"},
+		{Name: "dirty", HasUncommittedChanges: true, HasUnpushedChanges: true},
+		{Name: "ahead", HasUnpushedChanges: true},
+	}
+
+	var buf strings.Builder
+	writeDeleteConfirmation(&buf, []string{"clean", "dirty", "ahead", "missing"}, codespaces)
+	want := `  - clean
+  - dirty  (WARNING: uncommitted and unpushed changes will be lost)
+  - ahead  (WARNING: unpushed changes will be lost)
+  - missing
+
+2 of them have unsaved changes.
+`
+	if buf.String() != want {
+		t.Errorf("writeDeleteConfirmation() =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	// Without a listing nothing can be flagged
+	buf.Reset()
+	writeDeleteConfirmation(&buf, []string{"dirty"}, nil)
+	if buf.String() != "  - dirty\n" {
+		t.Errorf("writeDeleteConfirmation() without codespaces = %q", buf.String())
+	}
+}
@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/luanzeba/gh-csd/internal/config"
+	"github.com/luanzeba/gh-csd/internal/gh"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check gh-csd's dependencies and configuration",
+	Long: `Check the things gh-csd depends on and print a report with a hint for
+each problem found:
+
+  - gh is installed and logged in
+  - fzf is installed, for the interactive pickers
+  - the config file parses and passes validation
+  - the local server answers on its socket
+  - the server is installed as a service (macOS and Linux)
+  - $CSD_SOCKET, if set, matches the socket the service listens on
+  - infocmp is installed, to copy your terminal's terminfo to codespaces
+
+Inside a codespace, it instead checks that the socket forwarded by
+'gh csd ssh' answers, since the server and service run on your machine.
+
+Exits non-zero if a critical check fails: gh missing or logged out, or a
+config file that can't be parsed. The other checks only warn.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// doctorCheck is the outcome of one 'gh csd doctor' check.
+type doctorCheck struct {
+	name   string
+	ok     bool
+	detail string
+	// hint says how to fix a failed check
+	hint string
+	// critical failures make doctor exit non-zero; others only warn
+	critical bool
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	inCodespace := os.Getenv("CODESPACES") == "true"
+
+	var checks []doctorCheck
+	checks = append(checks, checkGH()...)
+	if !inCodespace {
+		checks = append(checks, checkFzf())
+	}
+	checks = append(checks, checkConfigFile())
+	if inCodespace {
+		checks = append(checks, checkForwardedSocket())
+		checks = append(checks, checkClientID())
+	} else {
+		checks = append(checks, checkServerSocket())
+		checks = append(checks, checkService()...)
+		checks = append(checks, checkInfocmp())
+	}
+
+	if failed := writeDoctorReport(os.Stdout, checks); failed > 0 {
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d critical check(s) failed", failed)
+	}
+	return nil
+}
+
+// writeDoctorReport prints checks with hints for the failed ones and
+// returns how many critical checks failed.
+func writeDoctorReport(w io.Writer, checks []doctorCheck) int {
+	failed, warned := 0, 0
+	for _, check := range checks {
+		mark := "ok"
+		switch {
+		case check.ok:
+		case check.critical:
+			mark = "FAIL"
+			failed++
+		default:
+			mark = "warn"
+			warned++
+		}
+		fmt.Fprintf(w, "[%-4s] %s: %s\n", mark, check.name, check.detail)
+		if !check.ok && check.hint != "" {
+			fmt.Fprintf(w, "       %s\n", check.hint)
+		}
+	}
+
+	fmt.Fprintln(w)
+	switch {
+	case failed > 0:
+		fmt.Fprintf(w, "%d critical problem(s), %d warning(s).\n", failed, warned)
+	case warned > 0:
+		fmt.Fprintf(w, "No critical problems, %d warning(s).\n", warned)
+	default:
+		fmt.Fprintln(w, "Everything looks good.")
+	}
+	return failed
+}
+
+// checkGH checks that gh is installed and logged in. The login check is
+// skipped without gh.
+func checkGH() []doctorCheck {
+	installed := doctorCheck{name: "gh", critical: true}
+	path, err := exec.LookPath("gh")
+	if err != nil {
+		installed.detail = "not found on your PATH"
+		installed.hint = "Install the GitHub CLI from https://cli.github.com"
+		return []doctorCheck{installed}
+	}
+	installed.ok = true
+	installed.detail = path
+
+	auth := doctorCheck{name: "gh auth", critical: true, ok: true, detail: "logged in"}
+	if _, err := gh.Run("auth", "status"); err != nil {
+		auth.ok = false
+		auth.detail = "not logged in"
+		auth.hint = "Run 'gh auth login', then 'gh auth refresh -s codespace' if codespace commands are denied"
+	}
+	return []doctorCheck{installed, auth}
+}
+
+func checkFzf() doctorCheck {
+	check := doctorCheck{name: "fzf"}
+	path, err := exec.LookPath("fzf")
+	if err != nil {
+		check.detail = "not found on your PATH"
+		check.hint = "Install it from https://github.com/junegunn/fzf for the interactive pickers, or always pass codespace names"
+		return check
+	}
+	check.ok = true
+	check.detail = path
+	return check
+}
+
+// checkConfigFile validates the config file. A missing file is fine, since
+// the defaults apply, and validation problems only warn, like they do for
+// other commands.
+func checkConfigFile() doctorCheck {
+	check := doctorCheck{name: "config", critical: true}
+	path, err := config.Path()
+	if err != nil {
+		check.detail = err.Error()
+		return check
+	}
+	return configFileCheck(path)
+}
+
+// configFileCheck is checkConfigFile for the config at path.
+func configFileCheck(path string) doctorCheck {
+	check := doctorCheck{name: "config", critical: true}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		check.ok = true
+		check.detail = path + " doesn't exist; using the defaults"
+		return check
+	}
+
+	problems, err := validateConfigFile(path, onlineChecks{})
+	if err != nil {
+		check.detail = err.Error()
+		check.hint = "Fix the file with 'gh csd config edit'"
+		return check
+	}
+	if len(problems) > 0 {
+		messages := make([]string, len(problems))
+		for i, problem := range problems {
+			messages[i] = problem.Error()
+		}
+		check.critical = false
+		check.detail = fmt.Sprintf("%s has %d problem(s): %s", path, len(problems), strings.Join(messages, "; "))
+		check.hint = "Run 'gh csd config validate' for details"
+		return check
+	}
+	check.ok = true
+	check.detail = path
+	return check
+}
+
+func checkServerSocket() doctorCheck {
+	socketPath := GetServerSocketPath()
+	check := doctorCheck{name: "server", detail: "answering on " + socketPath}
+	if _, err := checkLocalServer(socketPath, localHandshakeTimeout); err != nil {
+		check.detail = fmt.Sprintf("not answering on %s", socketPath)
+		check.hint = "Start it with 'gh csd server start', or run it on boot with 'gh csd service install'; 'gh csd local' needs it"
+		return check
+	}
+	check.ok = true
+	return check
+}
+
+func checkForwardedSocket() doctorCheck {
+	socketPath := getRemoteSocketPath()
+	check := doctorCheck{name: "server", detail: "answering on " + socketPath}
+	if _, err := checkLocalServer(socketPath, localHandshakeTimeout); err != nil {
+		check.detail = fmt.Sprintf("not answering on %s", socketPath)
+		check.hint = "Connect with 'gh csd ssh' from your machine, with the server running there, so the socket is forwarded"
+		return check
+	}
+	check.ok = true
+	return check
+}
+
+// checkClientID checks how requests from this codespace are attributed to
+// their 'gh csd ssh' session. $CSD_CLIENT only arrives if sshd accepts it;
+// otherwise the ID in ~/.csd/client is used, which concurrent sessions to
+// the same codespace overwrite.
+func checkClientID() doctorCheck {
+	check := doctorCheck{name: "client ID"}
+	if os.Getenv(clientEnvVar) != "" {
+		check.ok = true
+		check.detail = "$" + clientEnvVar + " is set by the ssh session"
+		return check
+	}
+	if sessionClientID() != "" {
+		check.detail = fmt.Sprintf("$%s isn't set, using %s from the latest 'gh csd ssh' session", clientEnvVar, getClientIDPath())
+		check.hint = fmt.Sprintf("sshd dropped $%s; add 'AcceptEnv %s' to /etc/ssh/sshd_config in the codespace to tell concurrent sessions apart", clientEnvVar, clientEnvVar)
+		return check
+	}
+	check.detail = "no client ID; requests are unidentified, and rejected by servers with server.require_client"
+	check.hint = "Reconnect with 'gh csd ssh', which writes the ID to " + getClientIDPath()
+	return check
+}
+
+// checkService checks the service is installed and, if $CSD_SOCKET is set,
+// that it points at the socket the service listens on. It returns nothing
+// where services aren't supported.
+func checkService() []doctorCheck {
+	service := collectServiceStatus()
+	if service.Manager == "" {
+		return nil
+	}
+
+	check := doctorCheck{name: "service", ok: service.Installed, detail: fmt.Sprintf("%s, %s (%s)", service.InstallState, service.RunState, service.Manager)}
+	if !service.Installed {
+		check.hint = "Run 'gh csd service install' to start the server on boot"
+	} else if !service.Running {
+		check.ok = false
+		check.hint = "Start it with 'gh csd service start'; 'gh csd logs' may say why it stopped"
+	}
+	checks := []doctorCheck{check}
+
+	if path := os.Getenv(socketEnvVar); path != "" && service.Installed {
+		home, _ := os.UserHomeDir()
+		checks = append(checks, socketMismatchCheck(path, filepath.Join(home, ".csd", "csd.socket")))
+	}
+	return checks
+}
+
+// socketMismatchCheck checks the $CSD_SOCKET path against the one the
+// service listens on, which doesn't see the variable.
+func socketMismatchCheck(envPath, servicePath string) doctorCheck {
+	check := doctorCheck{name: "socket", detail: fmt.Sprintf("$%s is %s", socketEnvVar, envPath)}
+	if filepath.Clean(envPath) == filepath.Clean(servicePath) {
+		check.ok = true
+		return check
+	}
+	check.detail += ", but the service listens on " + servicePath
+	check.hint = fmt.Sprintf("Unset $%s, or run the server yourself with 'gh csd server start' so both use the same socket", socketEnvVar)
+	return check
+}
+
+// checkInfocmp checks for infocmp, which 'gh csd create' uses to copy the
+// terminal's terminfo. tic runs in the codespace, so it isn't needed here.
+func checkInfocmp() doctorCheck {
+	check := doctorCheck{name: "infocmp"}
+	path, err := exec.LookPath("infocmp")
+	if err != nil {
+		check.detail = "not found on your PATH"
+		check.hint = "Install ncurses to copy your terminal's terminfo to new codespaces, or set defaults.copy_terminfo: false"
+		return check
+	}
+	check.ok = true
+	check.detail = path
+	return check
+}
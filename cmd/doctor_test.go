@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteDoctorReport(t *testing.T) {
+	checks := []doctorCheck{
+		{name: "gh", ok: true, detail: "/usr/bin/gh", critical: true},
+		{name: "gh auth", detail: "not logged in", hint: "Run 'gh auth login'", critical: true},
+		{name: "fzf", detail: "not found on your PATH", hint: "Install it"},
+	}
+
+	var buf strings.Builder
+	if failed := writeDoctorReport(&buf, checks); failed != 1 {
+		t.Errorf("writeDoctorReport() = %d failed, want 1", failed)
+	}
+	want := `[ok  ] gh: /usr/bin/gh
+[FAIL] gh auth: not logged in
+       Run 'gh auth login'
+[warn] fzf: not found on your PATH
+       Install it
+
+1 critical problem(s), 1 warning(s).
+`
+	if buf.String() != want {
+		t.Errorf("writeDoctorReport() =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if failed := writeDoctorReport(&buf, checks[:1]); failed != 0 || !strings.HasSuffix(buf.String(), "Everything looks good.\n") {
+		t.Errorf("writeDoctorReport() of passing checks = %d failed, output:\n%s", failed, buf.String())
+	}
+}
+
+func TestConfigFileCheck(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	if check := configFileCheck(filepath.Join(dir, "missing.yaml")); !check.ok {
+		t.Errorf("missing config: %+v, want ok", check)
+	}
+	if check := configFileCheck(write("valid.yaml", "defaults:\n  idle_timeout: 60\n")); !check.ok {
+		t.Errorf("valid config: %+v, want ok", check)
+	}
+	if check := configFileCheck(write("unknown.yaml", "defaults:\n  idle_timeut: 60\n")); check.ok || check.critical {
+		t.Errorf("config with an unknown key: %+v, want a warning", check)
+	}
+	if check := configFileCheck(write("broken.yaml", "defaults: [\n")); check.ok || !check.critical {
+		t.Errorf("unparseable config: %+v, want a critical failure", check)
+	}
+}
+
+func TestClientIDCheck(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(clientEnvVar, "")
+	if check := checkClientID(); check.ok || check.critical || !strings.Contains(check.detail, "no client ID") {
+		t.Errorf("without an ID: %+v, want a warning", check)
+	}
+
+	os.MkdirAll(filepath.Dir(getClientIDPath()), 0700)
+	if err := os.WriteFile(getClientIDPath(), []byte("abc\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if check := checkClientID(); check.ok || !strings.Contains(check.hint, "AcceptEnv") {
+		t.Errorf("with only ~/.csd/client: %+v, want a warning about AcceptEnv", check)
+	}
+	if got := sessionClientID(); got != "abc" {
+		t.Errorf("sessionClientID() = %q, want the ID from the file", got)
+	}
+
+	t.Setenv(clientEnvVar, "def")
+	if check := checkClientID(); !check.ok {
+		t.Errorf("with $%s: %+v, want ok", clientEnvVar, check)
+	}
+	if got := sessionClientID(); got != "def" {
+		t.Errorf("sessionClientID() = %q, want $%s to win", got, clientEnvVar)
+	}
+}
+
+func TestSocketMismatchCheck(t *testing.T) {
+	if check := socketMismatchCheck("/home/me/.csd/csd.socket", "/home/me/.csd/csd.socket"); !check.ok {
+		t.Errorf("same socket: %+v, want ok", check)
+	}
+	if check := socketMismatchCheck("/tmp/csd.socket", "/home/me/.csd/csd.socket"); check.ok || check.critical {
+		t.Errorf("different socket: %+v, want a warning", check)
+	}
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+)
+
+// dotfilesDir is where dotfiles are cloned in the codespace, matching
+// where most install scripts expect to find themselves.
+const dotfilesDir = "~/dotfiles"
+
+// dotfilesInstallScripts are tried in order, like Codespaces' own dotfiles
+// support. Without any of them, the repo's dotfiles are symlinked into ~.
+var dotfilesInstallScripts = []string{
+	"install.sh", "install",
+	"bootstrap.sh", "bootstrap", "script/bootstrap",
+	"setup.sh", "setup", "script/setup",
+}
+
+// dotfilesScript returns the command run in the codespace to clone repo
+// and install it. An existing ~/dotfiles is left alone, so the dotfiles
+// configured in GitHub's Codespaces settings win. The clone goes to a
+// temporary directory first so a failed one doesn't leave a partial
+// ~/dotfiles that would stop the next attempt.
+func dotfilesScript(repo string) string {
+	var script strings.Builder
+	fmt.Fprintf(&script, `dir=%s; `, strings.Replace(dotfilesDir, "~", "$HOME", 1))
+	script.WriteString(`if [ -e "$dir" ]; then echo "$dir already exists; not applying dotfiles" >&2; exit 0; fi; `)
+	fmt.Fprintf(&script, `tmp="$dir.csd-$$"; git clone --quiet --depth 1 %s "$tmp" || { rm -rf "$tmp"; exit 1; }; `, quoteForShell("https://github.com/"+repo+".git"))
+	script.WriteString(`mv "$tmp" "$dir" && cd "$dir" || exit 1; `)
+	fmt.Fprintf(&script, `for s in %s; do if [ -f "$s" ]; then chmod +x "$s" && exec "./$s"; fi; done; `, strings.Join(dotfilesInstallScripts, " "))
+	script.WriteString(`for f in .[!.]*; do [ "$f" = .git ] || ln -sf "$dir/$f" "$HOME/$f"; done`)
+	return script.String()
+}
+
+// applyDotfiles clones and installs the dotfiles repo in codespace name,
+// retrying when the SSH connection fails.
+func applyDotfiles(name, repo string) error {
+	const maxRetries = 3
+	const retryDelay = 2 * time.Second
+
+	var lastErr error
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		cmd := exec.Command("gh", "cs", "ssh", "-c", name, "--", dotfilesScript(repo))
+		cmd.Stdout = os.Stdout
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
+		err := gh.RunTraced(cmd)
+		if err == nil {
+			return nil
+		}
+		lastErr = fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+
+		// ssh exits 255 when it couldn't connect; anything else came from
+		// the clone or install script and won't go away on retry
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 255 {
+			return lastErr
+		}
+		if attempt < maxRetries {
+			time.Sleep(retryDelay)
+		}
+	}
+	return lastErr
+}
@@ -45,11 +45,13 @@ integrations). It:
   3. Reuses an SSH control master for fast subsequent calls
   4. Executes one remote command and exits with the same exit code
 
-Use '--' before the remote command so command flags are passed through
-without being parsed by gh-csd.
+Flag parsing stops at the first argument that isn't a gh-csd flag, so the
+remote command's own flags pass through untouched, like 'gh csd local'.
+'--' can still be used to mark where the remote command starts.
 
 Examples:
   gh csd exec -- pwd
+  gh csd exec git log --oneline -5
   gh csd exec -c my-codespace -- git status --short
   gh csd exec -C /workspaces/github -- bin/rails runner "puts :ok"`,
 	Args:          cobra.MinimumNArgs(1),
@@ -66,6 +68,8 @@ func init() {
 	execCmd.Flags().StringVar(&execControlPersist, "control-persist", "10m", "SSH ControlPersist value")
 	execCmd.Flags().BoolVar(&execNoMaster, "no-master", false, "Disable SSH control master reuse")
 	execCmd.Flags().BoolVar(&execRefreshConfig, "refresh-config", false, "Force refresh SSH config before executing")
+	// Everything from the first positional argument on is the remote command
+	execCmd.Flags().SetInterspersed(false)
 	rootCmd.AddCommand(execCmd)
 }
 
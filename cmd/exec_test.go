@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"errors"
+	"strings"
 	"testing"
 )
 
@@ -79,3 +80,18 @@ func TestShouldRetryConfigError(t *testing.T) {
 		})
 	}
 }
+
+func TestExecFlagsStopAtRemoteCommand(t *testing.T) {
+	flags := execCmd.Flags()
+	t.Cleanup(func() { execCodespace = "" })
+
+	if err := flags.Parse([]string{"-c", "my-cs", "git", "log", "--oneline", "-5"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if execCodespace != "my-cs" {
+		t.Errorf("codespace = %q, want my-cs", execCodespace)
+	}
+	if got := strings.Join(flags.Args(), " "); got != "git log --oneline -5" {
+		t.Errorf("remote command = %q, want %q", got, "git log --oneline -5")
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package cmd
+
+import "os"
+
+// lockFile is a no-op where flock isn't available; servers starting at the
+// same moment may then race to replace a stale socket.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 
+	"github.com/luanzeba/gh-csd/internal/config"
 	"github.com/luanzeba/gh-csd/internal/state"
 	"github.com/spf13/cobra"
 )
@@ -14,17 +17,40 @@ var getCmd = &cobra.Command{
 	Long: `Print the name of the currently selected codespace.
 
 This is useful for scripts and shell prompts.
-Exit code 1 if no codespace is selected.`,
+Exit code 1 if no codespace is selected.
+
+With --json, print {"name":"..."} instead, or {} if no codespace is
+selected.`,
 	Args: cobra.NoArgs,
 	RunE: runGet,
 }
 
+var getJSON bool
+
 func init() {
+	getCmd.Flags().BoolVar(&getJSON, "json", false, "Output the name as JSON")
 	rootCmd.AddCommand(getCmd)
 }
 
 func runGet(cmd *cobra.Command, args []string) error {
-	name, err := state.Get()
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+
+	name, err := getSelectedCodespace(cfg)
+	if getJSON {
+		if errors.Is(err, state.ErrNoCodespace) {
+			fmt.Println("{}")
+			os.Exit(1)
+		}
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(os.Stdout).Encode(struct {
+			Name string `json:"name"`
+		}{name})
+	}
 	if err != nil {
 		if errors.Is(err, state.ErrNoCodespace) {
 			return fmt.Errorf("no codespace selected (use 'gh csd select' to select one)")
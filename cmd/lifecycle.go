@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/luanzeba/gh-csd/internal/config"
+	"github.com/luanzeba/gh-csd/internal/gh"
+	"github.com/luanzeba/gh-csd/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lifecycleCodespace string
+	stopForce          bool
+	startSSH           bool
+)
+
+var stopCmd = &cobra.Command{
+	Use:   "stop [codespace-name]",
+	Short: "Stop a codespace without deleting it",
+	Long: `Stop a codespace to save compute. Its files are kept and it can be
+started again with 'gh csd start'.
+
+By default, stops the currently selected codespace.
+You will be asked to confirm unless --force is given.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runStop,
+}
+
+var startCmd = &cobra.Command{
+	Use:   "start [codespace-name]",
+	Short: "Start a stopped codespace",
+	Long: `Start a stopped codespace.
+
+By default, starts the currently selected codespace.
+Use --ssh to connect once it has been started (honoring the per-repo
+ssh_retry setting).`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runStart,
+}
+
+func init() {
+	stopCmd.Flags().StringVarP(&lifecycleCodespace, "codespace", "c", "", "Codespace name (overrides current selection)")
+	stopCmd.Flags().BoolVarP(&stopForce, "force", "f", false, "Skip confirmation prompt")
+	startCmd.Flags().StringVarP(&lifecycleCodespace, "codespace", "c", "", "Codespace name (overrides current selection)")
+	startCmd.Flags().BoolVar(&startSSH, "ssh", false, "SSH into the codespace after starting it")
+	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(startCmd)
+}
+
+// resolveLifecycleCodespace picks the codespace from -c, a positional
+// argument, or the current selection, in that order.
+func resolveLifecycleCodespace(args []string) (string, error) {
+	if lifecycleCodespace != "" {
+		return lifecycleCodespace, nil
+	}
+	if len(args) > 0 {
+		return args[0], nil
+	}
+
+	name, err := state.Get()
+	if err != nil {
+		if errors.Is(err, state.ErrNoCodespace) {
+			return "", fmt.Errorf("no codespace specified and none selected (use 'gh csd select' or provide a name)")
+		}
+		return "", err
+	}
+	return name, nil
+}
+
+func runStop(cmd *cobra.Command, args []string) error {
+	name, err := resolveLifecycleCodespace(args)
+	if err != nil {
+		return err
+	}
+
+	if !stopForce {
+		fmt.Printf("Stop codespace %s? [y/N] ", name)
+		reader := bufio.NewReader(os.Stdin)
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	fmt.Printf("Stopping %s... ", name)
+	if err := gh.StopCodespace(name); err != nil {
+		fmt.Println("FAILED")
+		return err
+	}
+	fmt.Println("done")
+	return nil
+}
+
+func runStart(cmd *cobra.Command, args []string) error {
+	name, err := resolveLifecycleCodespace(args)
+	if err != nil {
+		return err
+	}
+
+	cs, err := gh.GetCodespace(name)
+	if err != nil {
+		return err
+	}
+
+	if cs.State == "Available" {
+		fmt.Printf("%s is already running.\n", name)
+	} else {
+		fmt.Printf("Starting %s... ", name)
+		if err := gh.StartCodespace(name); err != nil {
+			fmt.Println("FAILED")
+			return err
+		}
+		fmt.Println("done")
+	}
+
+	if !startSSH {
+		return nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load config: %v\n", err)
+		cfg = config.DefaultConfig()
+	}
+
+	if err := setCurrentCodespace(cs); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to update current codespace: %v\n", err)
+	}
+
+	fmt.Printf("Connecting to %s (%s @ %s)...\n", cs.Name, cs.Repository, cs.DisplayBranch())
+	setTabTitleForCodespace(cs)
+	reportCodespaceCwd(cfg, cs)
+
+	if cfg.GetEffectiveSSHRetry(cs.Repository) {
+		return sshWithRetry(name, cs, cfg)
+	}
+	return sshOnce(name, cfg, cs.Repository)
+}
@@ -0,0 +1,17 @@
+package cmd
+
+import "testing"
+
+func TestResolveLifecycleCodespace(t *testing.T) {
+	defer func() { lifecycleCodespace = "" }()
+
+	lifecycleCodespace = "from-flag"
+	if got, err := resolveLifecycleCodespace([]string{"from-arg"}); err != nil || got != "from-flag" {
+		t.Fatalf("resolveLifecycleCodespace = %q, %v; want from-flag", got, err)
+	}
+
+	lifecycleCodespace = ""
+	if got, err := resolveLifecycleCodespace([]string{"from-arg"}); err != nil || got != "from-arg" {
+		t.Fatalf("resolveLifecycleCodespace = %q, %v; want from-arg", got, err)
+	}
+}
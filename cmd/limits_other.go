@@ -0,0 +1,11 @@
+//go:build !unix
+
+package cmd
+
+import "os/exec"
+
+// limitCommand is a no-op on platforms without Unix rlimits and process
+// priorities; commands run without limits.
+func limitCommand(cmd *exec.Cmd, limits execLimits) (func() error, error) {
+	return func() error { return nil }, nil
+}
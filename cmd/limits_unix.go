@@ -0,0 +1,62 @@
+//go:build unix
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// limitCommand makes cmd apply limits to itself before the command runs:
+// cmd is rewritten to start sh, which sets the rlimits and niceness of its
+// own process and then execs the command, so nothing it starts ever runs
+// without them. It returns a function to call once cmd has exited, which
+// returns why the limits couldn't be applied, in which case the command
+// didn't run.
+func limitCommand(cmd *exec.Cmd, limits execLimits) (func() error, error) {
+	if limits == (execLimits{}) {
+		return func() error { return nil }, nil
+	}
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		return nil, err
+	}
+
+	// Failures are reported on fd 3, which the command itself doesn't get
+	var script strings.Builder
+	step := func(command, failure string) {
+		fmt.Fprintf(&script, "%s >/dev/null 2>&1 || { echo '%s' >&3; exit 126; }\n", command, failure)
+	}
+	if limits.CPUSeconds > 0 {
+		step(fmt.Sprintf("ulimit -t %d", limits.CPUSeconds), fmt.Sprintf("failed to limit CPU time to %ds", limits.CPUSeconds))
+	}
+	if limits.MemoryMB > 0 {
+		step(fmt.Sprintf("ulimit -v %d", limits.MemoryMB*1024), fmt.Sprintf("failed to limit memory to %d MB", limits.MemoryMB))
+	}
+	if limits.Nice != 0 {
+		step(fmt.Sprintf("renice -n %d -p $$", limits.Nice), fmt.Sprintf("failed to set niceness %d", limits.Nice))
+	}
+	script.WriteString(`exec "$@" 3>&-`)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Args = append([]string{"sh", "-c", script.String(), "sh", cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = sh
+	cmd.ExtraFiles = []*os.File{w}
+
+	return func() error {
+		w.Close()
+		defer r.Close()
+		failure, _ := io.ReadAll(r)
+		if msg := strings.TrimSpace(string(failure)); msg != "" {
+			return errors.New(msg)
+		}
+		return nil
+	}, nil
+}
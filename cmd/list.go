@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+	"github.com/luanzeba/gh-csd/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listJSON bool
+	listRepo string
+	listOrg  string
+	listMine bool
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List your codespaces",
+	Long: `List your codespaces with their display name, repository, branch, state,
+and machine. Set a display name with 'gh csd rename'.
+
+The currently selected codespace is marked with '*'.
+Use --json for a stable, machine-readable format for scripts.
+Use --repo to only show codespaces for one repository.
+Use --org to only show codespaces for repositories owned by an org, or
+--mine for repositories owned by your own account.`,
+	Args: cobra.NoArgs,
+	RunE: runList,
+}
+
+func init() {
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Output codespaces as JSON")
+	listCmd.Flags().StringVarP(&listRepo, "repo", "R", "", "Only list codespaces for this repository (owner/repo)")
+	listCmd.Flags().StringVar(&listOrg, "org", "", "Only list codespaces for repositories owned by this org")
+	listCmd.Flags().BoolVar(&listMine, "mine", false, "Only list codespaces for repositories owned by your account")
+	rootCmd.AddCommand(listCmd)
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	if listOrg != "" && listMine {
+		return fmt.Errorf("--org and --mine cannot be combined")
+	}
+
+	owner := listOrg
+	if listMine {
+		login, err := gh.CurrentUser()
+		if err != nil {
+			return err
+		}
+		owner = login
+	}
+
+	codespaces, err := gh.ListCodespaces()
+	if err != nil {
+		return err
+	}
+
+	if listRepo != "" {
+		codespaces = filterCodespacesByRepo(codespaces, listRepo)
+	}
+	if owner != "" {
+		codespaces = filterCodespacesByOwner(codespaces, owner)
+	}
+
+	if listJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if codespaces == nil {
+			codespaces = []gh.Codespace{}
+		}
+		return encoder.Encode(codespaces)
+	}
+
+	if len(codespaces) == 0 {
+		fmt.Fprintln(os.Stderr, "No codespaces found.")
+		return nil
+	}
+
+	// A missing selection just means nothing gets marked
+	current, _ := state.Get()
+	return writeCodespaceTable(os.Stdout, codespaces, current)
+}
+
+// filterCodespacesByRepo returns the codespaces for repo (case-insensitive).
+func filterCodespacesByRepo(codespaces []gh.Codespace, repo string) []gh.Codespace {
+	var filtered []gh.Codespace
+	for _, cs := range codespaces {
+		if strings.EqualFold(cs.Repository, repo) {
+			filtered = append(filtered, cs)
+		}
+	}
+	return filtered
+}
+
+// filterCodespacesByOwner returns the codespaces whose repository is owned
+// by owner (case-insensitive).
+func filterCodespacesByOwner(codespaces []gh.Codespace, owner string) []gh.Codespace {
+	var filtered []gh.Codespace
+	for _, cs := range codespaces {
+		repoOwner, _, _ := strings.Cut(cs.Repository, "/")
+		if strings.EqualFold(repoOwner, owner) {
+			filtered = append(filtered, cs)
+		}
+	}
+	return filtered
+}
+
+// writeCodespaceTable prints codespaces as an aligned table, marking the
+// current codespace with '*'.
+func writeCodespaceTable(w io.Writer, codespaces []gh.Codespace, current string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  NAME\tDISPLAY NAME\tREPOSITORY\tBRANCH\tSTATE\tMACHINE")
+	for _, cs := range codespaces {
+		marker := " "
+		if cs.Name == current {
+			marker = "*"
+		}
+		displayName := cs.DisplayName
+		if displayName == "" {
+			displayName = "-"
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\t%s\t%s\t%s\t%s\n", marker, cs.Name, displayName, cs.Repository, cs.DisplayBranch(), cs.State, cs.MachineName)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+)
+
+func TestFilterCodespacesByRepo(t *testing.T) {
+	codespaces := []gh.Codespace{
+		{Name: "a", Repository: "github/github"},
+		{Name: "b", Repository: "luanzeba/gh-csd"},
+		{Name: "c", Repository: "GitHub/GitHub"},
+	}
+
+	got := filterCodespacesByRepo(codespaces, "github/github")
+	if len(got) != 2 || got[0].Name != "a" || got[1].Name != "c" {
+		t.Fatalf("unexpected filter result: %+v", got)
+	}
+
+	if got := filterCodespacesByRepo(codespaces, "other/repo"); len(got) != 0 {
+		t.Fatalf("expected no matches, got %+v", got)
+	}
+}
+
+func TestFilterCodespacesByOwner(t *testing.T) {
+	codespaces := []gh.Codespace{
+		{Name: "a", Repository: "github/github"},
+		{Name: "b", Repository: "luanzeba/gh-csd"},
+		{Name: "c", Repository: "GitHub/meuse"},
+		{Name: "d", Repository: "githubber/other"},
+	}
+
+	got := filterCodespacesByOwner(codespaces, "github")
+	if len(got) != 2 || got[0].Name != "a" || got[1].Name != "c" {
+		t.Fatalf("unexpected filter result: %+v", got)
+	}
+}
+
+func TestWriteCodespaceTable(t *testing.T) {
+	codespaces := []gh.Codespace{
+		{Name: "super-robot", DisplayName: "billing refactor", Repository: "github/github", Branch: "master", State: "Available", MachineName: "largePremiumLinux"},
+		{Name: "fuzzy-train", Repository: "luanzeba/gh-csd", State: "Shutdown", MachineName: "basicLinux32gb"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeCodespaceTable(&buf, codespaces, "fuzzy-train"); err != nil {
+		t.Fatalf("writeCodespaceTable failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got %q", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "  NAME") {
+		t.Errorf("unexpected header: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "  super-robot") {
+		t.Errorf("unselected row should not be marked: %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "* fuzzy-train") {
+		t.Errorf("current codespace should be marked: %q", lines[2])
+	}
+	if !strings.Contains(lines[2], gh.NoBranchPlaceholder) {
+		t.Errorf("missing branch placeholder: %q", lines[2])
+	}
+	if fields := strings.Fields(lines[1]); len(fields) < 3 || fields[1] != "billing" || fields[2] != "refactor" {
+		t.Errorf("display name should follow the name: %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); len(fields) < 3 || fields[2] != "-" {
+		t.Errorf("missing display name placeholder: %q", lines[2])
+	}
+}
@@ -4,16 +4,38 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/luanzeba/gh-csd/internal/config"
 	"github.com/luanzeba/gh-csd/internal/protocol"
 	"github.com/spf13/cobra"
 )
 
+// localHandshakeTimeout bounds the status check made before each command,
+// so a stale forwarded socket fails fast instead of hanging.
+const localHandshakeTimeout = 2 * time.Second
+
+// The status check is retried for up to localConnectTimeout (or
+// --connect-timeout), waiting localConnectBackoff and then twice as long
+// after each failure. Right after 'gh csd ssh' connects, the forwarded
+// socket can take a moment to appear.
+const (
+	localConnectTimeout = 5 * time.Second
+	localConnectBackoff = 500 * time.Millisecond
+)
+
 var localCmd = &cobra.Command{
 	Use:   "local <command> [args...]",
 	Short: "Execute command on local machine via forwarded socket",
@@ -28,6 +50,55 @@ Only 'gh' commands are allowed for security. This is useful for:
   - Creating issues in other repositories
   - Any gh command that needs your local machine's credentials
 
+The server runs in a different directory than your codespace, so gh can't
+infer the repository from git. For repo-context subcommands (pr, issue, run,
+...) without -R/--repo, the codespace's repository is detected from the git
+remote in the current directory and passed as -R. '-R .' is rewritten the
+same way. Pass --no-repo before the command to disable this. The subcommands
+can be configured with 'local.repo_subcommands' in config.
+
+Before running the command, gh-csd checks that the server answers,
+retrying for up to 5 seconds while the forwarded socket comes up. Pass
+--connect-timeout DURATION (e.g. 15s) before the command to change that.
+The command itself is never retried.
+
+Pass --timeout N before the command to have the server kill it after N
+seconds (exit code 124). Output it produced until then is still printed.
+By default commands have no time limit.
+
+Per-project defaults can be kept in a .csd-local.yaml file in the
+repository (found by searching up from the current directory):
+
+  repo: github/github      # injected as -R instead of the detected repo
+  timeout: 120             # default --timeout
+  forward_env: [GH_DEBUG]  # forwarded on top of local.forward_env; the
+                           # server must list it in server.allowed_env
+
+Flags given on the command line override the file.
+
+Pass --workdir before the command to run it in a directory on your local
+machine instead. A leading '~' is expanded on the local machine; use
+--workdir=~/path so the codespace shell leaves it alone.
+
+The socket is looked for at ~/.csd/csd.socket, where 'gh csd ssh'
+forwards it. Pass --socket PATH before the command, or set $CSD_SOCKET, to
+use another one; it must match the remote end of the forward.
+
+Pass --pipe before the command to pass binary data through it, such as
+an archive or image. stdin is forwarded and stdout returned byte for byte
+(base64-encoded over the socket), so the command can sit in a pipeline
+like any other. Piped stdin is read in full before the command starts
+and capped by local.max_stdin_bytes (512 KiB by default); it travels as
+base64, a third larger, so raise server.max_request_bytes on your machine
+along with it. stdout is streamed back without a limit. The server on
+your machine must be recent enough to know --pipe; with an older one the
+command is refused rather than run without its stdin.
+
+Commands that prompt or open an editor need a terminal. Pass --tty before
+the command to run it on a pseudo-terminal on your local machine, connected
+to this one. Without --tty, gh fails when it would have prompted, and the
+error suggests rerunning with --tty.
+
 Examples:
   # Create a PR in a different repo
   gh csd local gh pr create -R github/github-ui --title "Fix bug"
@@ -35,8 +106,26 @@ Examples:
   # Create an issue
   gh csd local gh issue create -R github/Copilot-Controls --title "Bug report"
 
-  # Check PR status
-  gh csd local gh pr status`,
+  # Check PR status for the codespace's repo
+  gh csd local gh pr status
+
+  # Pipe input to the command (size capped by local.max_stdin_bytes)
+  echo "LGTM" | gh csd local gh pr comment 42 --body-file -
+
+  # Download a private repo's tarball with your local credentials
+  gh csd local --pipe gh api repos/github/docs/tarball/main > docs.tar.gz
+
+  # Pipe a file through unchanged
+  cat notes.md | gh csd local --pipe gh gist create -
+
+  # Run without injecting the codespace's repo
+  gh csd local --no-repo gh pr status
+
+  # Run in a local checkout
+  gh csd local --workdir=~/code/myrepo gh pr create
+
+  # Fill in the PR interactively
+  gh csd local --tty gh pr create`,
 	Args:               cobra.MinimumNArgs(1),
 	RunE:               runLocal,
 	DisableFlagParsing: true, // Pass all args to the remote command
@@ -49,6 +138,10 @@ func init() {
 // getRemoteSocketPath returns the path where the socket is forwarded
 // inside a Codespace.
 func getRemoteSocketPath() string {
+	if path := os.Getenv(socketEnvVar); path != "" {
+		return path
+	}
+
 	// When in Codespace, the socket is forwarded to ~/.csd/csd.socket
 	// This matches the local path structure and avoids hardcoded paths
 	home, err := os.UserHomeDir()
@@ -59,11 +152,154 @@ func getRemoteSocketPath() string {
 	return home + "/.csd/csd.socket"
 }
 
+// localOptions holds gh-csd flags given before the remote command.
+type localOptions struct {
+	noRepo  bool
+	tty     bool
+	pipe    bool
+	socket  string
+	workdir string
+	// connectTimeout bounds retrying the connection; 0 uses
+	// localConnectTimeout.
+	connectTimeout time.Duration
+	timeout        int
+	// timeoutSet is true when --timeout was given, even as 0
+	timeoutSet bool
+}
+
+// parseLocalArgs splits leading gh-csd flags from the command to execute.
+// Flag parsing is disabled for 'local' so everything after the first
+// non-flag argument belongs to the remote command.
+func parseLocalArgs(args []string) (localOptions, []string, error) {
+	var opts localOptions
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--no-repo":
+			opts.noRepo = true
+		case arg == "--tty":
+			opts.tty = true
+		case arg == "--pipe":
+			opts.pipe = true
+		case arg == "--workdir" || strings.HasPrefix(arg, "--workdir="):
+			value, rest, err := localFlagValue(args[i:], "--workdir")
+			if err != nil {
+				return opts, nil, err
+			}
+			i += rest
+			opts.workdir = value
+		case arg == "--socket" || strings.HasPrefix(arg, "--socket="):
+			value, rest, err := localFlagValue(args[i:], "--socket")
+			if err != nil {
+				return opts, nil, err
+			}
+			i += rest
+			opts.socket = value
+		case arg == "--connect-timeout" || strings.HasPrefix(arg, "--connect-timeout="):
+			value, rest, err := localFlagValue(args[i:], "--connect-timeout")
+			if err != nil {
+				return opts, nil, err
+			}
+			i += rest
+			timeout, err := parseConnectTimeout(value)
+			if err != nil {
+				return opts, nil, err
+			}
+			opts.connectTimeout = timeout
+		case arg == "--timeout" || strings.HasPrefix(arg, "--timeout="):
+			value, rest, err := localFlagValue(args[i:], "--timeout")
+			if err != nil {
+				return opts, nil, err
+			}
+			i += rest
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds < 0 {
+				return opts, nil, fmt.Errorf("invalid --timeout %q (expected seconds)", value)
+			}
+			opts.timeout = seconds
+			opts.timeoutSet = true
+		case arg == "--":
+			return opts, args[i+1:], nil
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return opts, nil, fmt.Errorf("unknown flag for local: %s", arg)
+			}
+			return opts, args[i:], nil
+		}
+	}
+	return opts, nil, nil
+}
+
+// parseConnectTimeout parses a duration such as "10s", or plain seconds.
+func parseConnectTimeout(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid --connect-timeout %q (expected a duration such as 10s)", value)
+	}
+	return timeout, nil
+}
+
+// localFlagValue returns the value of flag from "--flag value" or
+// "--flag=value" at the start of args, and how many extra args it consumed.
+func localFlagValue(args []string, flag string) (string, int, error) {
+	if value, ok := strings.CutPrefix(args[0], flag+"="); ok {
+		return value, 0, nil
+	}
+	if len(args) < 2 {
+		return "", 0, fmt.Errorf("%s requires a value", flag)
+	}
+	return args[1], 1, nil
+}
+
 func runLocal(cmd *cobra.Command, args []string) error {
-	socketPath := getRemoteSocketPath()
+	opts, command, err := parseLocalArgs(args)
+	if err != nil {
+		return err
+	}
+	if len(command) == 0 {
+		return fmt.Errorf("no command specified")
+	}
+	if opts.pipe && opts.tty {
+		return fmt.Errorf("--pipe and --tty can't be combined")
+	}
 
-	// Check if socket exists
-	if _, err := os.Stat(socketPath); os.IsNotExist(err) {
+	project, err := loadLocalProject()
+	if err != nil {
+		return err
+	}
+	if !opts.timeoutSet {
+		opts.timeout = project.Timeout
+	}
+
+	if !opts.noRepo {
+		command = applyCodespaceRepo(command, project.Repo)
+	}
+
+	return execLocal(command, opts, project.ForwardEnv)
+}
+
+// execLocal runs command on the local machine through the forwarded
+// socket, streaming its output, and exits with its exit code if it fails.
+// opts.timeout is in seconds, 0 for none; forwardEnv adds to
+// local.forward_env.
+func execLocal(command []string, opts localOptions, forwardEnv []string) error {
+	socketPath := opts.socket
+	if socketPath == "" {
+		socketPath = getRemoteSocketPath()
+	}
+
+	// Check the daemon answers before sending anything. A forward left
+	// over from before the server restarted accepts connections but never
+	// replies, which would otherwise hang until the request times out.
+	connectTimeout := opts.connectTimeout
+	if connectTimeout == 0 {
+		connectTimeout = localConnectTimeout
+	}
+	status, err := connectLocalServer(socketPath, connectTimeout)
+	if _, statErr := os.Stat(socketPath); err != nil && os.IsNotExist(statErr) {
 		return fmt.Errorf(`socket not found at %s
 
 This command only works inside a Codespace connected via 'gh csd ssh'.
@@ -73,68 +309,439 @@ Make sure:
   2. Connect to Codespace:  gh csd ssh
   3. Then run:              gh csd local gh <command>`, socketPath)
 	}
-
-	// Connect to the socket
-	conn, err := net.DialTimeout("unix", socketPath, 5*time.Second)
 	if err != nil {
-		return fmt.Errorf(`failed to connect to local daemon at %s: %w
+		return fmt.Errorf(`local daemon at %s isn't responding: %w
 
 Make sure:
   1. gh csd server is running on your local machine
-  2. You connected via 'gh csd ssh' (not plain 'gh cs ssh')`, socketPath, err)
+  2. You connected via 'gh csd ssh' (not plain 'gh cs ssh')
+
+If the server was restarted since you connected, the forwarded socket is
+stale: reconnect with 'gh csd ssh' (or 'gh csd restart-session').`, socketPath, err)
+	}
+	if opts.pipe && !status.Supports(protocol.CapabilityPipe) {
+		return fmt.Errorf("the server on your machine doesn't support --pipe; upgrade gh-csd there and restart the server")
+	}
+
+	var stdin string
+	if !opts.tty {
+		if stdin, err = readPipedStdin(); err != nil {
+			return err
+		}
+	}
+
+	// Ctrl+C cancels the request; the server kills the command when the
+	// connection drops, so no orphaned process is left behind.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	req := &protocol.ExecRequest{
+		Command: command,
+		Workdir: opts.workdir,
+		Stdin:   stdin,
+		Timeout: opts.timeout,
+		Token:   clientToken(),
+		Client:  sessionClientID(),
+		Env:     forwardedEnv(forwardEnv),
+	}
+	if opts.pipe {
+		req.Pipe = true
+		req.Stdin, req.StdinRaw = "", []byte(stdin)
+	}
+	var exitCode int
+	stderr := &interactiveDetector{w: os.Stderr}
+	if opts.tty {
+		exitCode, err = execLocalTTY(ctx, socketPath, req)
+	} else {
+		// Stream output as it is produced; fall back to the buffered
+		// protocol for servers that predate exec-stream.
+		exitCode, err = execLocalStream(ctx, socketPath, req, stderr)
+		if errors.Is(err, errStreamUnsupported) {
+			exitCode, err = execLocalBuffered(ctx, socketPath, req, stderr)
+		}
+	}
+	if ctx.Err() != nil {
+		fmt.Fprintln(os.Stderr, "\nInterrupted, cancelled remote command.")
+		os.Exit(130)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Exit with same code as remote command
+	if exitCode != 0 {
+		if stderr.seen {
+			fmt.Fprintf(os.Stderr, "\nThe command needs a terminal; rerun it as 'gh csd local --tty %s'\n", strings.Join(command, " "))
+		}
+		os.Exit(exitCode)
 	}
 
-	// Create HTTP client that uses the Unix socket
-	client := &http.Client{
+	return nil
+}
+
+// errStreamUnsupported is returned when the server doesn't know exec-stream.
+var errStreamUnsupported = errors.New("server does not support streaming")
+
+// connectLocalServer retries checkLocalServer with backoff until it
+// succeeds or timeout has passed, and returns the server's status. Only
+// this check is retried, never the command itself, which may not be safe
+// to run twice.
+func connectLocalServer(socketPath string, timeout time.Duration) (*protocol.StatusResponse, error) {
+	deadline := time.Now().Add(timeout)
+	backoff := localConnectBackoff
+	for {
+		// Keep a floor: a client timeout of 0 would mean no timeout at all
+		attemptTimeout := max(min(localHandshakeTimeout, time.Until(deadline)), 100*time.Millisecond)
+		status, err := checkLocalServer(socketPath, attemptTimeout)
+		if err == nil {
+			return status, nil
+		}
+		if time.Until(deadline) < backoff {
+			return nil, err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// checkLocalServer sends a status request to the server at socketPath and
+// returns its answer, failing unless it reports running within timeout.
+func checkLocalServer(socketPath string, timeout time.Duration) (*protocol.StatusResponse, error) {
+	client := newSocketClient(socketPath, timeout)
+	resp, err := postLocalRequest(context.Background(), client, &protocol.ExecRequest{Type: "status"})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var status protocol.StatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to decode status: %w", err)
+	}
+	if status.Status != "running" {
+		return nil, fmt.Errorf("server reported status %q", status.Status)
+	}
+	return &status, nil
+}
+
+// newSocketClient returns an HTTP client that talks to the Unix socket.
+// A timeout of 0 means no timeout.
+func newSocketClient(socketPath string, timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	return &http.Client{
 		Transport: &http.Transport{
-			DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
-				return conn, nil
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socketPath)
 			},
 		},
-		Timeout: 60 * time.Second, // Commands might take a while
+		Timeout: timeout,
 	}
+}
 
-	// Build and send request
-	req := &protocol.ExecRequest{
-		Type:    "exec",
-		Command: args,
+func postLocalRequest(ctx context.Context, client *http.Client, req *protocol.ExecRequest) (*http.Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	body, err := json.Marshal(req)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://unix/", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	return resp, nil
+}
+
+// execLocalStream runs req via exec-stream, writing output frames to
+// stdout/stderr as they arrive, and returns the remote exit code.
+func execLocalStream(ctx context.Context, socketPath string, req *protocol.ExecRequest, stderr io.Writer) (int, error) {
+	// No client timeout: streamed commands can legitimately run for a long time
+	client := newSocketClient(socketPath, 0)
+	streamReq := *req
+	streamReq.Type = "exec-stream"
+	resp, err := postLocalRequest(ctx, client, &streamReq)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return readStreamFrames(resp.Body, os.Stdout, stderr)
+}
+
+// readStreamFrames copies output frames to stdout/stderr until the exit frame.
+func readStreamFrames(r io.Reader, stdout, stderr io.Writer) (int, error) {
+	decoder := json.NewDecoder(r)
+	for {
+		var frame protocol.StreamFrame
+		if err := decoder.Decode(&frame); err != nil {
+			if err == io.EOF {
+				return 0, fmt.Errorf("connection closed before command finished")
+			}
+			return 0, fmt.Errorf("failed to decode response: %w", err)
+		}
+
+		switch frame.Stream {
+		case "stdout":
+			if frame.Raw != nil {
+				stdout.Write(frame.Raw)
+			} else {
+				io.WriteString(stdout, frame.Data)
+			}
+		case "stderr":
+			io.WriteString(stderr, frame.Data)
+		case "exit":
+			if frame.Error != "" {
+				fmt.Fprintln(stderr, frame.Error)
+			}
+			return frame.ExitCode, nil
+		default:
+			// A plain ExecResponse: request rejected before streaming started
+			if strings.HasPrefix(frame.Error, "unknown request type") {
+				return 0, errStreamUnsupported
+			}
+			if frame.Error != "" {
+				fmt.Fprintln(stderr, frame.Error)
+			}
+			return frame.ExitCode, nil
+		}
 	}
+}
 
-	resp, err := client.Post("http://unix/", "application/json", bytes.NewReader(body))
+// execLocalBuffered runs req via the buffered exec request and prints
+// its output once it completes.
+func execLocalBuffered(ctx context.Context, socketPath string, req *protocol.ExecRequest, stderr io.Writer) (int, error) {
+	// The server enforces the command's timeout; leave room for it to report it
+	var clientTimeout time.Duration
+	if req.Timeout > 0 {
+		clientTimeout = time.Duration(req.Timeout)*time.Second + 30*time.Second
+	}
+	client := newSocketClient(socketPath, clientTimeout)
+	bufferedReq := *req
+	bufferedReq.Type = "exec"
+	resp, err := postLocalRequest(ctx, client, &bufferedReq)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return 0, err
 	}
 	defer resp.Body.Close()
 
 	// Parse response
 	var execResp protocol.ExecResponse
 	if err := json.NewDecoder(resp.Body).Decode(&execResp); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// Print output, including what a timed out command produced
+	if execResp.Stdout != "" {
+		fmt.Print(execResp.Stdout)
+	}
+	if len(execResp.StdoutRaw) > 0 {
+		os.Stdout.Write(execResp.StdoutRaw)
+	}
+	if execResp.Stderr != "" {
+		fmt.Fprint(stderr, execResp.Stderr)
 	}
 
 	// Handle error from server
 	if execResp.Error != "" {
-		fmt.Fprintln(os.Stderr, execResp.Error)
-		os.Exit(execResp.ExitCode)
+		fmt.Fprintln(stderr, execResp.Error)
 	}
 
-	// Print output
-	if execResp.Stdout != "" {
-		fmt.Print(execResp.Stdout)
+	return execResp.ExitCode, nil
+}
+
+// readPipedStdin returns piped stdin to forward to the server, or "" when
+// stdin isn't a pipe or file.
+func readPipedStdin() (string, error) {
+	if !isPipedInput(os.Stdin) {
+		return "", nil
 	}
-	if execResp.Stderr != "" {
-		fmt.Fprint(os.Stderr, execResp.Stderr)
+
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
 	}
+	return readLimitedStdin(os.Stdin, cfg.GetEffectiveLocalMaxStdinBytes())
+}
 
-	// Exit with same code as remote command
-	if execResp.ExitCode != 0 {
-		os.Exit(execResp.ExitCode)
+// isPipedInput reports whether f is a pipe or regular file. Anything else,
+// such as a terminal or a socket inherited from a process manager, may
+// never reach EOF, so reading it would hang before the command runs.
+func isPipedInput(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
 	}
+	return info.Mode()&os.ModeNamedPipe != 0 || info.Mode().IsRegular()
+}
 
-	return nil
+// loadLocalProject returns the .csd-local.yaml settings for the current
+// directory, or empty settings if there is no such file.
+func loadLocalProject() (*config.LocalProject, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return &config.LocalProject{}, nil
+	}
+	project, _, err := config.FindLocalProject(dir)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return &config.LocalProject{}, nil
+	}
+	return project, nil
+}
+
+// forwardedEnv returns the local.forward_env variables, plus extra from
+// the project file, that are set in this environment.
+func forwardedEnv(extra []string) map[string]string {
+	var keys []string
+	if cfg, err := config.Load(); err == nil {
+		keys = append(keys, cfg.Local.ForwardEnv...)
+	}
+	keys = append(keys, extra...)
+
+	var env map[string]string
+	for _, key := range keys {
+		if value, ok := os.LookupEnv(key); ok {
+			if env == nil {
+				env = make(map[string]string)
+			}
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// readLimitedStdin reads all of r, failing if it is longer than limit bytes.
+func readLimitedStdin(r io.Reader, limit int64) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read stdin: %w", err)
+	}
+	if int64(len(data)) > limit {
+		return "", fmt.Errorf("stdin exceeds %d bytes (raise local.max_stdin_bytes in config to allow more)", limit)
+	}
+	return string(data), nil
+}
+
+// applyCodespaceRepo injects the codespace's repository, or defaultRepo
+// from the project file if set, into repo-context gh commands. Failures to
+// detect the repo leave the command unchanged.
+func applyCodespaceRepo(command []string, defaultRepo string) []string {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+
+	if !needsRepoContext(command, cfg.GetEffectiveLocalRepoSubcommands()) {
+		return command
+	}
+
+	if defaultRepo != "" {
+		return injectRepoFlag(command, defaultRepo)
+	}
+
+	repo, err := detectCodespaceRepo()
+	if err != nil {
+		return command
+	}
+
+	return injectRepoFlag(command, repo)
+}
+
+// needsRepoContext reports whether command is a gh subcommand that infers
+// its repository from the current git directory.
+func needsRepoContext(command, subcommands []string) bool {
+	if len(command) < 2 || filepath.Base(command[0]) != "gh" {
+		return false
+	}
+	for _, sub := range subcommands {
+		if command[1] == sub {
+			return true
+		}
+	}
+	return false
+}
+
+// injectRepoFlag rewrites '-R .'/'--repo .' to repo, or adds '-R repo' when
+// no repo flag is present. Arguments after '--' are left untouched.
+func injectRepoFlag(command []string, repo string) []string {
+	result := make([]string, 0, len(command)+2)
+	found := false
+	insertAt := -1
+
+	for i := 0; i < len(command); i++ {
+		arg := command[i]
+		switch {
+		case arg == "--":
+			insertAt = len(result)
+			result = append(result, command[i:]...)
+			i = len(command)
+		case arg == "-R" || arg == "--repo":
+			found = true
+			result = append(result, arg)
+			if i+1 < len(command) {
+				i++
+				result = append(result, replaceDotRepo(command[i], repo))
+			}
+		case strings.HasPrefix(arg, "--repo="):
+			found = true
+			result = append(result, "--repo="+replaceDotRepo(strings.TrimPrefix(arg, "--repo="), repo))
+		case strings.HasPrefix(arg, "-R"):
+			found = true
+			value := strings.TrimPrefix(strings.TrimPrefix(arg, "-R"), "=")
+			result = append(result, "-R="+replaceDotRepo(value, repo))
+		default:
+			result = append(result, arg)
+		}
+	}
+
+	if found {
+		return result
+	}
+	if insertAt < 0 {
+		insertAt = len(result)
+	}
+
+	injected := make([]string, 0, len(result)+2)
+	injected = append(injected, result[:insertAt]...)
+	injected = append(injected, "-R", repo)
+	return append(injected, result[insertAt:]...)
+}
+
+func replaceDotRepo(value, repo string) string {
+	if value == "." {
+		return repo
+	}
+	return value
+}
+
+// detectCodespaceRepo returns the owner/repo for the current directory,
+// falling back to GITHUB_REPOSITORY which Codespaces sets for the main repo.
+func detectCodespaceRepo() (string, error) {
+	output, err := exec.Command("git", "remote", "get-url", "origin").Output()
+	if err == nil {
+		if repo, err := parseGitHubRemote(strings.TrimSpace(string(output))); err == nil {
+			return repo, nil
+		}
+	}
+
+	if repo := os.Getenv("GITHUB_REPOSITORY"); repo != "" {
+		return repo, nil
+	}
+
+	return "", fmt.Errorf("could not detect repository from git remote")
+}
+
+// parseGitHubRemote extracts owner/repo from an HTTPS or SSH GitHub remote URL.
+func parseGitHubRemote(remote string) (string, error) {
+	path := strings.TrimPrefix(remote, "ssh://")
+	path = strings.TrimPrefix(path, "git@github.com:")
+	path = strings.TrimPrefix(path, "git@github.com/")
+	return normalizeManualRepoInput(path)
 }
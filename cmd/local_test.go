@@ -0,0 +1,392 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luanzeba/gh-csd/internal/protocol"
+)
+
+func TestParseLocalArgs(t *testing.T) {
+	opts, command, err := parseLocalArgs([]string{"--no-repo", "gh", "pr", "status", "--json", "number"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !opts.noRepo {
+		t.Fatal("expected noRepo to be set")
+	}
+	want := []string{"gh", "pr", "status", "--json", "number"}
+	if !reflect.DeepEqual(command, want) {
+		t.Fatalf("unexpected command: want %v, got %v", want, command)
+	}
+
+	opts, command, err = parseLocalArgs([]string{"--tty", "gh", "pr", "create"})
+	if err != nil || !opts.tty {
+		t.Fatalf("parseLocalArgs(--tty) = %+v, %v; want tty set", opts, err)
+	}
+	if want := []string{"gh", "pr", "create"}; !reflect.DeepEqual(command, want) {
+		t.Fatalf("unexpected command: want %v, got %v", want, command)
+	}
+
+	opts, _, err = parseLocalArgs([]string{"--pipe", "gh", "gist", "create", "-"})
+	if err != nil || !opts.pipe {
+		t.Fatalf("parseLocalArgs(--pipe) = %+v, %v; want pipe set", opts, err)
+	}
+
+	if _, _, err := parseLocalArgs([]string{"--bogus", "gh"}); err == nil {
+		t.Fatal("expected an error for unknown flag")
+	}
+}
+
+func TestParseLocalArgsWorkdir(t *testing.T) {
+	for _, args := range [][]string{
+		{"--workdir", "~/code/repo", "gh", "pr", "create"},
+		{"--workdir=~/code/repo", "gh", "pr", "create"},
+	} {
+		opts, command, err := parseLocalArgs(args)
+		if err != nil {
+			t.Fatalf("parseLocalArgs(%v): unexpected error %v", args, err)
+		}
+		if opts.workdir != "~/code/repo" {
+			t.Errorf("parseLocalArgs(%v): workdir = %q", args, opts.workdir)
+		}
+		if want := []string{"gh", "pr", "create"}; !reflect.DeepEqual(command, want) {
+			t.Errorf("parseLocalArgs(%v): command = %v, want %v", args, command, want)
+		}
+	}
+
+	if _, _, err := parseLocalArgs([]string{"--workdir"}); err == nil {
+		t.Fatal("expected an error for --workdir without a value")
+	}
+}
+
+func TestNeedsRepoContext(t *testing.T) {
+	subcommands := []string{"pr", "issue"}
+
+	tests := []struct {
+		name    string
+		command []string
+		want    bool
+	}{
+		{name: "pr", command: []string{"gh", "pr", "status"}, want: true},
+		{name: "absolute gh", command: []string{"/usr/bin/gh", "issue", "list"}, want: true},
+		{name: "api", command: []string{"gh", "api", "user"}, want: false},
+		{name: "bare gh", command: []string{"gh"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsRepoContext(tt.command, subcommands); got != tt.want {
+				t.Fatalf("unexpected result: want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestInjectRepoFlag(t *testing.T) {
+	const repo = "github/github"
+
+	tests := []struct {
+		name    string
+		command []string
+		want    []string
+	}{
+		{
+			name:    "no repo flag",
+			command: []string{"gh", "pr", "status"},
+			want:    []string{"gh", "pr", "status", "-R", repo},
+		},
+		{
+			name:    "explicit repo kept",
+			command: []string{"gh", "pr", "list", "-R", "cli/cli"},
+			want:    []string{"gh", "pr", "list", "-R", "cli/cli"},
+		},
+		{
+			name:    "dot rewritten",
+			command: []string{"gh", "pr", "list", "--repo", "."},
+			want:    []string{"gh", "pr", "list", "--repo", repo},
+		},
+		{
+			name:    "dot with equals rewritten",
+			command: []string{"gh", "issue", "list", "--repo=."},
+			want:    []string{"gh", "issue", "list", "--repo=" + repo},
+		},
+		{
+			name:    "inserted before double dash",
+			command: []string{"gh", "pr", "create", "--", "-R"},
+			want:    []string{"gh", "pr", "create", "-R", repo, "--", "-R"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := injectRepoFlag(tt.command, repo)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("unexpected command\nwant: %v\n got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseGitHubRemote(t *testing.T) {
+	tests := []struct {
+		remote string
+		want   string
+	}{
+		{remote: "https://github.com/github/github.git", want: "github/github"},
+		{remote: "git@github.com:luanzeba/gh-csd.git", want: "luanzeba/gh-csd"},
+		{remote: "ssh://git@github.com/luanzeba/gh-csd", want: "luanzeba/gh-csd"},
+	}
+
+	for _, tt := range tests {
+		got, err := parseGitHubRemote(tt.remote)
+		if err != nil {
+			t.Fatalf("parseGitHubRemote(%q) returned error: %v", tt.remote, err)
+		}
+		if got != tt.want {
+			t.Fatalf("parseGitHubRemote(%q) = %q, want %q", tt.remote, got, tt.want)
+		}
+	}
+}
+
+func TestReadStreamFrames(t *testing.T) {
+	input := `{"stream":"stdout","data":"hello "}
+{"stream":"stderr","data":"warn\n"}
+{"stream":"stdout","data":"world\n"}
+{"stream":"exit","exit_code":2}
+`
+	var stdout, stderr bytes.Buffer
+	exitCode, err := readStreamFrames(strings.NewReader(input), &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if exitCode != 2 {
+		t.Fatalf("exit code = %d, want 2", exitCode)
+	}
+	if stdout.String() != "hello world\n" {
+		t.Fatalf("unexpected stdout: %q", stdout.String())
+	}
+	if stderr.String() != "warn\n" {
+		t.Fatalf("unexpected stderr: %q", stderr.String())
+	}
+}
+
+func TestReadStreamFramesRaw(t *testing.T) {
+	payload := []byte{0x1f, 0x8b, 0x08, 0x00, 0xff, 0xfe, '\n'}
+	frame, _ := json.Marshal(protocol.StreamFrame{Stream: "stdout", Raw: payload})
+	input := string(frame) + "\n" + `{"stream":"exit","exit_code":0}` + "\n"
+
+	var stdout, stderr bytes.Buffer
+	if _, err := readStreamFrames(strings.NewReader(input), &stdout, &stderr); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !bytes.Equal(stdout.Bytes(), payload) {
+		t.Fatalf("stdout = %q, want %q", stdout.Bytes(), payload)
+	}
+}
+
+func TestReadStreamFramesUnsupported(t *testing.T) {
+	input := `{"stdout":"","stderr":"","exit_code":1,"error":"unknown request type: exec-stream"}` + "\n"
+
+	var stdout, stderr bytes.Buffer
+	_, err := readStreamFrames(strings.NewReader(input), &stdout, &stderr)
+	if !errors.Is(err, errStreamUnsupported) {
+		t.Fatalf("expected errStreamUnsupported, got %v", err)
+	}
+}
+
+func TestReadStreamFramesTruncated(t *testing.T) {
+	input := `{"stream":"stdout","data":"partial"}` + "\n"
+
+	var stdout, stderr bytes.Buffer
+	if _, err := readStreamFrames(strings.NewReader(input), &stdout, &stderr); err == nil {
+		t.Fatal("expected an error when the exit frame is missing")
+	}
+}
+
+func TestIsPipedInput(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	if !isPipedInput(r) {
+		t.Error("isPipedInput(pipe) = false, want true")
+	}
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "input"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if !isPipedInput(file) {
+		t.Error("isPipedInput(regular file) = false, want true")
+	}
+
+	// A socket may never reach EOF
+	ln, err := net.Listen("unix", filepath.Join(t.TempDir(), "stdin.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	sock, err := ln.(*net.UnixListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sock.Close()
+	if isPipedInput(sock) {
+		t.Error("isPipedInput(socket) = true, want false")
+	}
+}
+
+func TestReadLimitedStdin(t *testing.T) {
+	got, err := readLimitedStdin(strings.NewReader("hello"), 5)
+	if err != nil || got != "hello" {
+		t.Fatalf("readLimitedStdin = %q, %v; want \"hello\", nil", got, err)
+	}
+
+	if _, err := readLimitedStdin(strings.NewReader("hello!"), 5); err == nil || !strings.Contains(err.Error(), "max_stdin_bytes") {
+		t.Fatalf("expected a size limit error, got %v", err)
+	}
+}
+
+func TestParseLocalArgsTimeout(t *testing.T) {
+	for _, args := range [][]string{
+		{"--timeout", "90", "gh", "pr", "checkout", "1"},
+		{"--timeout=90", "gh", "pr", "checkout", "1"},
+	} {
+		opts, _, err := parseLocalArgs(args)
+		if err != nil {
+			t.Fatalf("parseLocalArgs(%v): unexpected error %v", args, err)
+		}
+		if opts.timeout != 90 {
+			t.Errorf("parseLocalArgs(%v): timeout = %d, want 90", args, opts.timeout)
+		}
+	}
+
+	// An explicit 0 overrides the project file's timeout
+	opts, _, err := parseLocalArgs([]string{"--timeout=0", "gh"})
+	if err != nil || opts.timeout != 0 || !opts.timeoutSet {
+		t.Errorf("parseLocalArgs(--timeout=0) = %+v, %v; want timeout 0 marked as set", opts, err)
+	}
+
+	if _, _, err := parseLocalArgs([]string{"--timeout", "soon", "gh"}); err == nil {
+		t.Fatal("expected an error for a non-numeric --timeout")
+	}
+}
+
+func TestCheckLocalServer(t *testing.T) {
+	dir := t.TempDir()
+
+	// A running server answers the status request
+	running := filepath.Join(dir, "running.sock")
+	ln, err := net.Listen("unix", running)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: newServer("", log.New(io.Discard, "", 0))}
+	go srv.Serve(ln)
+	defer srv.Close()
+	if status, err := checkLocalServer(running, localHandshakeTimeout); err != nil {
+		t.Errorf("checkLocalServer() on a running server = %v", err)
+	} else if !status.Supports(protocol.CapabilityPipe) {
+		t.Errorf("server capabilities %v don't include %s", status.Capabilities, protocol.CapabilityPipe)
+	}
+
+	// A stale forward accepts connections but never replies
+	stale := filepath.Join(dir, "stale.sock")
+	staleLn, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer staleLn.Close()
+	go func() {
+		for {
+			conn, err := staleLn.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	start := time.Now()
+	if _, err := checkLocalServer(stale, 200*time.Millisecond); err == nil {
+		t.Error("checkLocalServer() on a stale socket should fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("checkLocalServer() took %v, want it to give up after its timeout", elapsed)
+	}
+}
+
+func TestParseLocalArgsSocket(t *testing.T) {
+	for _, args := range [][]string{
+		{"--socket", "/tmp/test.sock", "gh", "pr", "status"},
+		{"--socket=/tmp/test.sock", "gh", "pr", "status"},
+	} {
+		opts, command, err := parseLocalArgs(args)
+		if err != nil {
+			t.Fatalf("parseLocalArgs(%v): unexpected error %v", args, err)
+		}
+		if opts.socket != "/tmp/test.sock" {
+			t.Errorf("parseLocalArgs(%v): socket = %q", args, opts.socket)
+		}
+		if want := []string{"gh", "pr", "status"}; !reflect.DeepEqual(command, want) {
+			t.Errorf("parseLocalArgs(%v): command = %v, want %v", args, command, want)
+		}
+	}
+}
+
+func TestParseLocalArgsConnectTimeout(t *testing.T) {
+	for value, want := range map[string]time.Duration{"10": 10 * time.Second, "1500ms": 1500 * time.Millisecond, "2m": 2 * time.Minute} {
+		opts, _, err := parseLocalArgs([]string{"--connect-timeout", value, "gh", "pr", "status"})
+		if err != nil || opts.connectTimeout != want {
+			t.Errorf("--connect-timeout %s = %v, %v; want %v", value, opts.connectTimeout, err, want)
+		}
+	}
+	for _, value := range []string{"soon", "-1s"} {
+		if _, _, err := parseLocalArgs([]string{"--connect-timeout=" + value, "gh"}); err == nil {
+			t.Errorf("--connect-timeout=%s should fail", value)
+		}
+	}
+}
+
+func TestConnectLocalServerWaitsForSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "csd.sock")
+
+	// The forward comes up shortly after the first attempt
+	srv := &http.Server{Handler: newServer("", log.New(io.Discard, "", 0))}
+	defer srv.Close()
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		ln, err := net.Listen("unix", socketPath)
+		if err != nil {
+			return
+		}
+		srv.Serve(ln)
+	}()
+
+	if _, err := connectLocalServer(socketPath, 3*time.Second); err != nil {
+		t.Fatalf("connectLocalServer() = %v, want it to retry until the socket is up", err)
+	}
+
+	// Without a server it gives up once the timeout has passed
+	start := time.Now()
+	if _, err := connectLocalServer(filepath.Join(t.TempDir(), "missing.sock"), 700*time.Millisecond); err == nil {
+		t.Error("connectLocalServer() without a server should fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("connectLocalServer() took %v with a 700ms timeout", elapsed)
+	}
+}
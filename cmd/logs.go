@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsServer bool
+	logsAudit  bool
+	logsFollow bool
+	logsLines  int
+	logsSince  time.Duration
+	logsGrep   string
+
+	serverLogsFollow bool
+	serverLogsLines  int
+)
+
+// logsPollInterval is how often --follow checks the log for new output.
+const logsPollInterval = 500 * time.Millisecond
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "View the server and audit logs",
+	Long: `View the logs written by 'gh csd server'.
+
+By default, shows the server log (~/.csd/csd.log). Use --audit to show the
+audit log (~/.csd/audit.log) instead.
+
+Examples:
+  gh csd logs                  # last 50 lines of the server log
+  gh csd logs -f               # follow the server log
+  gh csd logs --audit --since 1h
+  gh csd logs --grep blocked -n 0`,
+	Args: cobra.NoArgs,
+	RunE: runLogs,
+}
+
+var serverLogsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Print the server log",
+	Long: `Print the server log (~/.csd/csd.log).
+
+Use --follow to keep printing new lines like 'tail -f'; the log is reopened
+if it is rotated. 'gh csd logs' offers more filters.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return showLog(getServerLogPath(), serverLogsLines, logFilter{}, serverLogsFollow, os.Stdout)
+	},
+}
+
+func init() {
+	serverLogsCmd.Flags().BoolVarP(&serverLogsFollow, "follow", "f", false, "Keep printing new lines as they are written")
+	serverLogsCmd.Flags().IntVarP(&serverLogsLines, "lines", "n", 0, "Only print the last N lines (default all)")
+	serverCmd.AddCommand(serverLogsCmd)
+
+	logsCmd.Flags().BoolVar(&logsServer, "server", false, "Show the server log (default)")
+	logsCmd.Flags().BoolVar(&logsAudit, "audit", false, "Show the audit log")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing new lines as they are written")
+	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", 50, "Number of lines to show, 0 for all")
+	logsCmd.Flags().DurationVar(&logsSince, "since", 0, "Only show lines newer than this duration (e.g. 30m, 2h)")
+	logsCmd.Flags().StringVar(&logsGrep, "grep", "", "Only show lines containing this text")
+	logsCmd.MarkFlagsMutuallyExclusive("server", "audit")
+	rootCmd.AddCommand(logsCmd)
+}
+
+func getAuditLogPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".csd", "audit.log")
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	path := getServerLogPath()
+	if logsAudit {
+		path = getAuditLogPath()
+	}
+
+	filter := logFilter{grep: logsGrep}
+	if logsSince > 0 {
+		filter.since = time.Now().Add(-logsSince)
+	}
+
+	return showLog(path, logsLines, filter, logsFollow, os.Stdout)
+}
+
+// showLog prints the last n matching lines of the log at path (all of them
+// if n is 0), then keeps printing new lines if follow is set.
+func showLog(path string, n int, filter logFilter, follow bool, w io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("no log at %s yet (is 'gh csd server' running?)", path)
+		}
+		return err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if filter.match(scanner.Text()) {
+			lines = append(lines, scanner.Text())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+
+	if !follow {
+		return nil
+	}
+	return followLog(f, path, filter, w)
+}
+
+// followLog prints lines appended to f until interrupted. If the file at
+// path is rotated or truncated, it is reopened and read from the start.
+func followLog(f *os.File, path string, filter logFilter, w io.Writer) error {
+	offset, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	var partial string
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			offset += int64(n)
+			chunk := partial + string(buf[:n])
+			lines := strings.Split(chunk, "\n")
+			// Hold back an incomplete last line until it is finished
+			partial = lines[len(lines)-1]
+			for _, line := range lines[:len(lines)-1] {
+				if filter.match(line) {
+					fmt.Fprintln(w, line)
+				}
+			}
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		time.Sleep(logsPollInterval)
+
+		if reopened, ok := reopenIfRotated(f, path, offset); ok {
+			f.Close()
+			f = reopened
+			offset = 0
+			partial = ""
+		}
+	}
+}
+
+// reopenIfRotated returns a fresh handle on path if the open file f no
+// longer is the file at path (moved away by logrotate) or has been
+// truncated below offset.
+func reopenIfRotated(f *os.File, path string, offset int64) (*os.File, bool) {
+	current, err := os.Stat(path)
+	if err != nil {
+		// Rotated away and not yet recreated; keep waiting
+		return nil, false
+	}
+	open, err := f.Stat()
+	if err != nil {
+		return nil, false
+	}
+	if os.SameFile(open, current) && current.Size() >= offset {
+		return nil, false
+	}
+
+	reopened, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	return reopened, true
+}
+
+// logFilter selects log lines by time and content. Zero values match
+// everything.
+type logFilter struct {
+	since time.Time
+	grep  string
+}
+
+func (lf logFilter) match(line string) bool {
+	if lf.grep != "" && !strings.Contains(line, lf.grep) {
+		return false
+	}
+	if !lf.since.IsZero() {
+		// Lines without a timestamp (e.g. wrapped output) are kept
+		if t, ok := parseLogTime(line); ok && t.Before(lf.since) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseLogTime extracts the timestamp from a log line. It understands the
+// server log's "[gh-csd] 2006/01/02 15:04:05 ..." format and JSON lines
+// with an RFC 3339 "time" field.
+func parseLogTime(line string) (time.Time, bool) {
+	if strings.HasPrefix(line, "{") {
+		var entry struct {
+			Time time.Time `json:"time"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil || entry.Time.IsZero() {
+			return time.Time{}, false
+		}
+		return entry.Time, true
+	}
+
+	rest := strings.TrimPrefix(line, "[gh-csd] ")
+	const layout = "2006/01/02 15:04:05"
+	if len(rest) < len(layout) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(layout, rest[:len(layout)], time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogTime(t *testing.T) {
+	tests := []struct {
+		line string
+		want time.Time
+		ok   bool
+	}{
+		{
+			line: "[gh-csd] 2026/10/16 09:30:00 executing: [gh pr view]",
+			want: time.Date(2026, 10, 16, 9, 30, 0, 0, time.Local),
+			ok:   true,
+		},
+		{
+			line: `{"time":"2026-10-16T09:30:00Z","command":["gh","pr","view"]}`,
+			want: time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC),
+			ok:   true,
+		},
+		{line: "  continuation of a previous line", ok: false},
+		{line: `{"command":["gh"]}`, ok: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseLogTime(tt.line)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parseLogTime(%q) = %v, %v; want %v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestShowLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "csd.log")
+	content := strings.Join([]string{
+		"[gh-csd] 2026/10/16 08:00:00 server listening",
+		"[gh-csd] 2026/10/16 09:00:00 executing: [gh pr view]",
+		"[gh-csd] 2026/10/16 09:30:00 blocked command: rm",
+		"[gh-csd] 2026/10/16 10:00:00 executing: [gh pr list]",
+	}, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		n      int
+		filter logFilter
+		want   []string
+	}{
+		{name: "last lines", n: 2, want: []string{"09:30:00", "10:00:00"}},
+		{name: "grep", filter: logFilter{grep: "executing"}, want: []string{"09:00:00", "10:00:00"}},
+		{
+			name:   "since",
+			filter: logFilter{since: time.Date(2026, 10, 16, 9, 15, 0, 0, time.Local)},
+			want:   []string{"09:30:00", "10:00:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := showLog(path, tt.n, tt.filter, false, &buf); err != nil {
+				t.Fatalf("showLog failed: %v", err)
+			}
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("got %d lines %q, want %d", len(lines), lines, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(lines[i], want) {
+					t.Errorf("line %d = %q, want it to contain %q", i, lines[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestShowLogMissing(t *testing.T) {
+	err := showLog(filepath.Join(t.TempDir(), "csd.log"), 10, logFilter{}, false, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "no log at") {
+		t.Fatalf("showLog() error = %v, want missing log message", err)
+	}
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+	"github.com/spf13/cobra"
+)
+
+var (
+	openWeb      bool
+	openInsiders bool
+	openEditor   string
+)
+
+// openEditors maps the --editor values to the --web and --insiders flags
+// of 'gh cs code'.
+var openEditors = map[string]struct{ web, insiders bool }{
+	"vscode":          {},
+	"vscode-insiders": {insiders: true},
+	"web":             {web: true},
+	"web-insiders":    {web: true, insiders: true},
+}
+
+var openCmd = &cobra.Command{
+	Use:   "open [codespace-name]",
+	Short: "Open the codespace in VS Code or the browser",
+	Long: `Open a codespace in VS Code, or in the web editor with --web. By
+default, opens the currently selected codespace.
+
+It runs 'gh cs code', so --web and --insiders can be combined to open VS
+Code Insiders in the browser. --editor picks the same variants by name:
+vscode, vscode-insiders, web or web-insiders.
+
+If there is no browser opener for --web, the URL is printed instead.
+
+Examples:
+  gh csd open
+  gh csd open --insiders
+  gh csd open --web
+  gh csd open --editor web-insiders`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runOpen,
+}
+
+func init() {
+	openCmd.Flags().StringVarP(&lifecycleCodespace, "codespace", "c", "", "Codespace name (overrides current selection)")
+	openCmd.Flags().BoolVar(&openWeb, "web", false, "Open the web editor in the browser")
+	openCmd.Flags().BoolVar(&openInsiders, "insiders", false, "Use VS Code Insiders")
+	openCmd.Flags().StringVar(&openEditor, "editor", "", "Editor to open: vscode, vscode-insiders, web or web-insiders")
+	openCmd.MarkFlagsMutuallyExclusive("editor", "web")
+	openCmd.MarkFlagsMutuallyExclusive("editor", "insiders")
+	rootCmd.AddCommand(openCmd)
+}
+
+func runOpen(cmd *cobra.Command, args []string) error {
+	web, insiders := openWeb, openInsiders
+	if openEditor != "" {
+		editor, ok := openEditors[openEditor]
+		if !ok {
+			return fmt.Errorf("unknown editor %q: use vscode, vscode-insiders, web or web-insiders", openEditor)
+		}
+		web, insiders = editor.web, editor.insiders
+	}
+
+	name, err := resolveLifecycleCodespace(args)
+	if err != nil {
+		return err
+	}
+
+	if web && !hasBrowserOpener() {
+		url, err := gh.CodespaceWebURL(name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "No browser opener found; open this URL instead:")
+		fmt.Println(url)
+		return nil
+	}
+
+	code := exec.Command("gh", codeArgs(name, web, insiders)...)
+	code.Stdin = os.Stdin
+	code.Stdout = os.Stdout
+	code.Stderr = os.Stderr
+	return gh.RunTraced(code)
+}
+
+// codeArgs returns the 'gh cs code' arguments that open codespace name.
+func codeArgs(name string, web, insiders bool) []string {
+	args := []string{"cs", "code", "-c", name}
+	if web {
+		args = append(args, "--web")
+	}
+	if insiders {
+		args = append(args, "--insiders")
+	}
+	return args
+}
+
+// browserCommand returns the command that opens url on goos, or nil if
+// there is no known opener.
+func browserCommand(goos, url string) []string {
+	switch goos {
+	case "darwin":
+		return []string{"open", url}
+	case "windows":
+		return []string{"rundll32", "url.dll,FileProtocolHandler", url}
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return []string{"xdg-open", url}
+	default:
+		return nil
+	}
+}
+
+// hasBrowserOpener reports whether gh can open a browser: one set in
+// $GH_BROWSER or $BROWSER, or the platform's opener.
+func hasBrowserOpener() bool {
+	if os.Getenv("GH_BROWSER") != "" || os.Getenv("BROWSER") != "" {
+		return true
+	}
+	args := browserCommand(runtime.GOOS, "")
+	if args == nil {
+		return false
+	}
+	_, err := exec.LookPath(args[0])
+	return err == nil
+}
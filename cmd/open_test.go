@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBrowserCommand(t *testing.T) {
+	url := "https://my-cs.github.dev"
+	for _, tt := range []struct {
+		goos string
+		want []string
+	}{
+		{"darwin", []string{"open", url}},
+		{"linux", []string{"xdg-open", url}},
+		{"windows", []string{"rundll32", "url.dll,FileProtocolHandler", url}},
+		{"plan9", nil},
+	} {
+		if got := browserCommand(tt.goos, url); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("browserCommand(%q) = %q, want %q", tt.goos, got, tt.want)
+		}
+	}
+}
+
+func TestCodeArgs(t *testing.T) {
+	for _, tt := range []struct {
+		editor string
+		want   []string
+	}{
+		{"vscode", []string{"cs", "code", "-c", "my-cs"}},
+		{"vscode-insiders", []string{"cs", "code", "-c", "my-cs", "--insiders"}},
+		{"web", []string{"cs", "code", "-c", "my-cs", "--web"}},
+		{"web-insiders", []string{"cs", "code", "-c", "my-cs", "--web", "--insiders"}},
+	} {
+		editor := openEditors[tt.editor]
+		if got := codeArgs("my-cs", editor.web, editor.insiders); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("codeArgs() for %s = %q, want %q", tt.editor, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	openPRTitle string
+	openPRBody  string
+	openPRBase  string
+	openPRHead  string
+	openPRRepo  string
+	openPRDraft bool
+	openPRPush  bool
+)
+
+var openPRCmd = &cobra.Command{
+	Use:   "open-pr",
+	Short: "Open a pull request for the current branch with your local credentials",
+	Long: `Open a pull request for the current branch from inside a Codespace. The
+'gh pr create' runs on your local machine through 'gh csd local', so it
+works in repositories the Codespace token can't create PRs in.
+
+The PR targets the repository from .csd-local.yaml if there is one, and the
+origin remote otherwise; --repo overrides both. The branch the current
+branch is pushed to is the head, even if it has another name. When the
+target isn't origin (e.g. origin is a fork), the head is passed as
+owner:branch.
+
+Your local machine can't push the branch for you, so it must be pushed
+first; --push pushes it to origin before opening the PR. Without --title,
+the title and body are taken from the last commit.
+
+This requires the same setup as 'gh csd local'.
+
+Examples:
+  gh csd open-pr
+  gh csd open-pr --push --draft --title "Fix flaky test"
+  gh csd open-pr --base release --body "Backport of #123"`,
+	Args: cobra.NoArgs,
+	RunE: runOpenPR,
+}
+
+func init() {
+	openPRCmd.Flags().StringVarP(&openPRTitle, "title", "t", "", "Title for the pull request (default: last commit subject)")
+	openPRCmd.Flags().StringVarP(&openPRBody, "body", "b", "", "Body for the pull request (default: last commit body)")
+	openPRCmd.Flags().StringVarP(&openPRBase, "base", "B", "", "Branch to merge into (default: the repository's default branch)")
+	openPRCmd.Flags().StringVarP(&openPRHead, "head", "H", "", "Branch with the changes (default: the current branch)")
+	openPRCmd.Flags().StringVarP(&openPRRepo, "repo", "R", "", "Repository to open the pull request in (default: detected)")
+	openPRCmd.Flags().BoolVarP(&openPRDraft, "draft", "d", false, "Open the pull request as a draft")
+	openPRCmd.Flags().BoolVar(&openPRPush, "push", false, "Push the current branch to origin first")
+	rootCmd.AddCommand(openPRCmd)
+}
+
+func runOpenPR(cmd *cobra.Command, args []string) error {
+	branch, err := gitOutput("rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+	if branch == "HEAD" {
+		return fmt.Errorf("not on a branch (detached HEAD); check out the branch to open a PR for")
+	}
+
+	origin, err := detectCodespaceRepo()
+	if err != nil {
+		return err
+	}
+	project, err := loadLocalProject()
+	if err != nil {
+		return err
+	}
+	repo := openPRRepo
+	if repo == "" {
+		repo = project.Repo
+	}
+	if repo == "" {
+		repo = origin
+	}
+
+	if openPRPush {
+		push := exec.Command("git", "push", "-u", "origin", branch)
+		push.Stdout = os.Stderr
+		push.Stderr = os.Stderr
+		if err := push.Run(); err != nil {
+			return fmt.Errorf("failed to push %s: %w", branch, err)
+		}
+	} else if err := checkBranchPushed(branch); err != nil {
+		return err
+	}
+
+	title, body := openPRTitle, openPRBody
+	if title == "" {
+		if title, err = gitOutput("log", "-1", "--format=%s"); err != nil {
+			return err
+		}
+		if !cmd.Flags().Changed("body") {
+			if body, err = gitOutput("log", "-1", "--format=%b"); err != nil {
+				return err
+			}
+		}
+	}
+
+	head := openPRHead
+	if head == "" {
+		upstream, err := upstreamBranch(branch)
+		if err != nil {
+			return err
+		}
+		head = pullRequestHead(upstream, origin, repo)
+	}
+
+	command := buildPRCreateCommand(repo, head, openPRBase, title, body, openPRDraft)
+	fmt.Fprintf(os.Stderr, "Opening a pull request for %s in %s...\n", head, repo)
+	return execLocal(command, localOptions{timeout: project.Timeout}, project.ForwardEnv)
+}
+
+// gitOutput runs git in the current directory and returns its trimmed output.
+func gitOutput(args ...string) (string, error) {
+	output, err := exec.Command("git", args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// checkBranchPushed fails if branch has no upstream or commits that
+// haven't been pushed to it, since gh on the local machine can't push them.
+func checkBranchPushed(branch string) error {
+	ahead, err := gitOutput("rev-list", "--count", "@{upstream}..HEAD")
+	if err != nil {
+		return fmt.Errorf("%s hasn't been pushed; push it first or pass --push", branch)
+	}
+	if ahead != "0" {
+		return fmt.Errorf("%s has %s unpushed commit(s); push them first or pass --push", branch, ahead)
+	}
+	return nil
+}
+
+// upstreamBranch returns the name of the remote branch that branch tracks,
+// which needn't match its local name.
+func upstreamBranch(branch string) (string, error) {
+	upstream, err := gitOutput("rev-parse", "--abbrev-ref", branch+"@{upstream}")
+	if err != nil {
+		return "", err
+	}
+	remote, err := gitOutput("config", "branch."+branch+".remote")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(upstream, remote+"/"), nil
+}
+
+// pullRequestHead returns the head to pass to 'gh pr create' for branch on
+// origin. Opening a PR in another repository needs the owner:branch form.
+func pullRequestHead(branch, origin, repo string) string {
+	if strings.EqualFold(origin, repo) {
+		return branch
+	}
+	owner, _, _ := strings.Cut(origin, "/")
+	return owner + ":" + branch
+}
+
+// buildPRCreateCommand returns the 'gh pr create' command to run locally.
+// The title and body are always passed so gh doesn't prompt for them.
+func buildPRCreateCommand(repo, head, base, title, body string, draft bool) []string {
+	command := []string{"gh", "pr", "create", "-R", repo, "--head", head, "--title", title, "--body", body}
+	if base != "" {
+		command = append(command, "--base", base)
+	}
+	if draft {
+		command = append(command, "--draft")
+	}
+	return command
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPullRequestHead(t *testing.T) {
+	tests := []struct {
+		name   string
+		origin string
+		repo   string
+		want   string
+	}{
+		{name: "same repo", origin: "github/github", repo: "github/github", want: "feature"},
+		{name: "case differs", origin: "GitHub/github", repo: "github/github", want: "feature"},
+		{name: "fork", origin: "octocat/github", repo: "github/github", want: "octocat:feature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pullRequestHead("feature", tt.origin, tt.repo); got != tt.want {
+				t.Errorf("pullRequestHead() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildPRCreateCommand(t *testing.T) {
+	got := buildPRCreateCommand("github/github", "feature", "", "Fix bug", "", false)
+	want := []string{"gh", "pr", "create", "-R", "github/github", "--head", "feature", "--title", "Fix bug", "--body", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildPRCreateCommand() = %v, want %v", got, want)
+	}
+
+	got = buildPRCreateCommand("github/github", "feature", "release", "Fix bug", "Details", true)
+	want = append(want[:len(want)-1], "Details", "--base", "release", "--draft")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildPRCreateCommand() with options = %v, want %v", got, want)
+	}
+}
+
+func TestCheckBranchPushed(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	remote := t.TempDir()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	git("init", "--bare", "-q", remote)
+	git("init", "-q", "-b", "feature")
+	git("remote", "add", "origin", remote)
+	git("commit", "-q", "--allow-empty", "-m", "first")
+
+	if err := checkBranchPushed("feature"); err == nil || !strings.Contains(err.Error(), "hasn't been pushed") {
+		t.Errorf("checkBranchPushed() without upstream = %v", err)
+	}
+
+	git("push", "-q", "-u", "origin", "feature")
+	if err := checkBranchPushed("feature"); err != nil {
+		t.Errorf("checkBranchPushed() after push = %v", err)
+	}
+
+	git("commit", "-q", "--allow-empty", "-m", "second")
+	if err := checkBranchPushed("feature"); err == nil || !strings.Contains(err.Error(), "1 unpushed commit") {
+		t.Errorf("checkBranchPushed() with a new commit = %v", err)
+	}
+
+	if got, err := upstreamBranch("feature"); err != nil || got != "feature" {
+		t.Errorf("upstreamBranch() = %q, %v; want feature", got, err)
+	}
+
+	// The upstream can have another name
+	git("push", "-q", "-u", "origin", "feature:renamed")
+	if err := checkBranchPushed("feature"); err != nil {
+		t.Errorf("checkBranchPushed() after pushing to renamed = %v", err)
+	}
+	if got, err := upstreamBranch("feature"); err != nil || got != "renamed" {
+		t.Errorf("upstreamBranch() = %q, %v; want renamed", got, err)
+	}
+}
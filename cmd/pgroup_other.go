@@ -0,0 +1,21 @@
+//go:build !unix
+
+package cmd
+
+import (
+	"os/exec"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+)
+
+// runInOwnGroup runs cmd, a gh command. Windows has no process groups to
+// signal, so cancelling only ends gh.
+func runInOwnGroup(cmd *exec.Cmd) error {
+	cmd.Cancel = func() error { return cmd.Process.Kill() }
+	return gh.RunTraced(cmd)
+}
+
+// interruptedByUser is always false; Ctrl+C reaches gh csd directly.
+func interruptedByUser(err error) bool {
+	return false
+}
@@ -0,0 +1,49 @@
+//go:build unix
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// runInOwnGroup runs cmd, a gh command, in a process group of its own, so
+// cancelling it signals ssh and anything else gh started, not only gh. On
+// a terminal the group becomes the foreground one, as a shell does for a
+// job, so ssh can still read from and configure the terminal, and the
+// terminal is handed back once it exits.
+func runInOwnGroup(cmd *exec.Cmd) error {
+	fd := int(os.Stdin.Fd())
+	tty := term.IsTerminal(fd)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Foreground: tty, Ctty: fd}
+	cmd.Cancel = func() error { return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM) }
+
+	err := gh.RunTraced(cmd)
+	if tty {
+		// Taking the terminal back from the background would stop us
+		// with SIGTTOU
+		signal.Ignore(syscall.SIGTTOU)
+		unix.IoctlSetPointerInt(fd, unix.TIOCSPGRP, syscall.Getpgrp())
+		signal.Reset(syscall.SIGTTOU)
+	}
+	return err
+}
+
+// interruptedByUser reports whether err is from a command killed by
+// Ctrl+C. Running in the foreground group, it gets the SIGINT instead of
+// gh csd.
+func interruptedByUser(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGINT
+}
@@ -0,0 +1,48 @@
+//go:build unix
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunInOwnGroupCancelsChildren(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", "sleep 30 & echo $!; wait")
+	cmd.Stdout = &stdout
+	cmd.WaitDelay = 5 * time.Second
+
+	done := make(chan error, 1)
+	go func() { done <- runInOwnGroup(cmd) }()
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	<-done
+
+	pid, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
+	if err != nil {
+		t.Fatalf("failed to read the child's pid: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for processAlive(pid) && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if processAlive(pid) {
+		t.Errorf("child %d outlived the cancelled command", pid)
+	}
+}
+
+func TestInterruptedByUser(t *testing.T) {
+	if err := exec.Command("sh", "-c", "kill -INT $$").Run(); !interruptedByUser(err) {
+		t.Errorf("interruptedByUser(%v) = false for a command killed by SIGINT", err)
+	}
+	if err := exec.Command("sh", "-c", "exit 255").Run(); interruptedByUser(err) {
+		t.Errorf("interruptedByUser(%v) = true for a failed command", err)
+	}
+}
@@ -0,0 +1,541 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+	"github.com/luanzeba/gh-csd/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var portsCodespace string
+
+// portForwardStartTimeout is how long 'gh csd ports add' waits for the
+// forward to accept connections.
+const portForwardStartTimeout = 30 * time.Second
+
+var portsCmd = &cobra.Command{
+	Use:   "ports",
+	Short: "List and manage port forwards for the current codespace",
+	Long: `List the ports of the current codespace, and forward more of them to
+this machine without reconnecting.
+
+Each 'gh csd ports add' runs 'gh cs ports forward' in the background, so
+the forward keeps running after the command returns, and records it in
+~/.csd/ports. It is also remembered in ~/.csd/forwards/<codespace>.json,
+and 'gh csd ssh --retry' starts it again on every reconnect if it has
+stopped. 'gh csd ports remove' stops it and forgets it. Ports forwarded
+from the 'ports' config while 'gh csd ssh' is connected stop with the
+session.
+
+Examples:
+  gh csd ports
+  gh csd ports add 3000
+  gh csd ports add 3000:8080   # codespace port 3000 on localhost:8080
+  gh csd ports remove 3000`,
+	Args: cobra.NoArgs,
+	RunE: runPortsList,
+}
+
+var portsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the codespace's ports and which are forwarded here",
+	Args:  cobra.NoArgs,
+	RunE:  runPortsList,
+}
+
+var portsAddCmd = &cobra.Command{
+	Use:   "add <port>[:<local-port>]",
+	Short: "Forward a codespace port to this machine in the background",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runPortsAdd,
+}
+
+var portsRemoveCmd = &cobra.Command{
+	Use:     "remove <port>",
+	Aliases: []string{"rm"},
+	Short:   "Stop a forward started with 'gh csd ports add'",
+	Long: `Stop a forward started with 'gh csd ports add'. The port can be the
+codespace port or the local one.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPortsRemove,
+}
+
+func init() {
+	portsCmd.PersistentFlags().StringVarP(&portsCodespace, "codespace", "c", "", "Codespace name (overrides current selection)")
+	portsCmd.AddCommand(portsListCmd)
+	portsCmd.AddCommand(portsAddCmd)
+	portsCmd.AddCommand(portsRemoveCmd)
+	rootCmd.AddCommand(portsCmd)
+}
+
+func getPortForwardsDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".csd", "ports")
+}
+
+// getSavedForwardsPath returns where the forwards added to codespace name
+// are remembered, so reconnects can restore them.
+func getSavedForwardsPath(name string) string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".csd", "forwards", name+".json")
+}
+
+// savedForward is a forward added with 'gh csd ports add'.
+type savedForward struct {
+	Remote int `json:"remote"`
+	Local  int `json:"local"`
+}
+
+// loadSavedForwards returns the forwards remembered at path.
+func loadSavedForwards(path string) ([]savedForward, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var saved []savedForward
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return saved, nil
+}
+
+// updateSavedForwards replaces the forwards remembered at path with the
+// result of update, removing the file when none are left.
+func updateSavedForwards(path string, update func([]savedForward) []savedForward) error {
+	saved, err := loadSavedForwards(path)
+	if err != nil {
+		return err
+	}
+	saved = update(saved)
+	if len(saved) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// portForward is a background forward started by 'gh csd ports add',
+// recorded in a file named after the pid of its gh process.
+type portForward struct {
+	PID       int       `json:"-"`
+	Codespace string    `json:"codespace"`
+	Remote    int       `json:"remote"`
+	Local     int       `json:"local"`
+	Log       string    `json:"log"`
+	Started   time.Time `json:"started"`
+}
+
+// listPortForwards returns the running forwards recorded in dir, removing
+// records left behind by forwards that have exited, including ones whose
+// pid now belongs to another process.
+func listPortForwards(dir string) ([]portForward, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var forwards []portForward
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var fwd portForward
+		if err := json.Unmarshal(data, &fwd); err != nil {
+			continue
+		}
+		fwd.PID = pid
+		if !isPortForwardProcess(pid) {
+			removePortForward(dir, fwd)
+			continue
+		}
+		forwards = append(forwards, fwd)
+	}
+	return forwards, nil
+}
+
+// removePortForward deletes the record of fwd and its log.
+func removePortForward(dir string, fwd portForward) {
+	os.Remove(filepath.Join(dir, strconv.Itoa(fwd.PID)))
+	if fwd.Log != "" {
+		os.Remove(fwd.Log)
+	}
+}
+
+// parsePortSpec parses "port" or "port:local-port".
+func parsePortSpec(spec string) (remote, local int, err error) {
+	remoteStr, localStr, hasLocal := strings.Cut(spec, ":")
+	if remote, err = parsePort(remoteStr); err != nil {
+		return 0, 0, err
+	}
+	local = remote
+	if hasLocal {
+		if local, err = parsePort(localStr); err != nil {
+			return 0, 0, err
+		}
+	}
+	return remote, local, nil
+}
+
+func parsePort(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port %q (expected 1-65535)", value)
+	}
+	return port, nil
+}
+
+// resolvePortsCodespace picks the codespace from -c or the current
+// selection.
+func resolvePortsCodespace() (string, error) {
+	if portsCodespace != "" {
+		return portsCodespace, nil
+	}
+	name, err := getSelectedCodespace(loadValidatedConfig())
+	if err != nil {
+		if errors.Is(err, state.ErrNoCodespace) {
+			return "", fmt.Errorf("no codespace specified and none selected (use 'gh csd select' or -c)")
+		}
+		return "", err
+	}
+	return name, nil
+}
+
+func runPortsList(cmd *cobra.Command, args []string) error {
+	name, err := resolvePortsCodespace()
+	if err != nil {
+		return err
+	}
+	ports, err := gh.ListPorts(name)
+	if err != nil {
+		return err
+	}
+	forwards, err := listPortForwards(getPortForwardsDir())
+	if err != nil {
+		return err
+	}
+
+	var ours []portForward
+	for _, fwd := range forwards {
+		if fwd.Codespace == name {
+			ours = append(ours, fwd)
+		}
+	}
+	if len(ports) == 0 && len(ours) == 0 {
+		fmt.Printf("No ports in %s.\n", name)
+		return nil
+	}
+	return writePortsTable(os.Stdout, ports, ours)
+}
+
+// writePortsTable prints the codespace's ports with the local port each is
+// forwarded to by 'gh csd ports add'. Forwards of ports the codespace
+// doesn't report (e.g. nothing listens there yet) are listed too.
+func writePortsTable(w io.Writer, ports []gh.Port, forwards []portForward) error {
+	type row struct {
+		port   int
+		label  string
+		vis    string
+		local  []string
+		url    string
+		listed bool
+	}
+	rows := make(map[int]*row)
+	for _, p := range ports {
+		rows[p.SourcePort] = &row{port: p.SourcePort, label: p.Label, vis: p.Visibility, url: p.BrowseURL, listed: true}
+	}
+	for _, fwd := range forwards {
+		r, ok := rows[fwd.Remote]
+		if !ok {
+			r = &row{port: fwd.Remote}
+			rows[fwd.Remote] = r
+		}
+		r.local = append(r.local, fmt.Sprintf("localhost:%d", fwd.Local))
+	}
+
+	sorted := make([]*row, 0, len(rows))
+	for _, r := range rows {
+		sorted = append(sorted, r)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].port < sorted[j].port })
+
+	dash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PORT\tLABEL\tVISIBILITY\tFORWARDED\tURL")
+	for _, r := range sorted {
+		sort.Strings(r.local)
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", r.port, dash(r.label), dash(r.vis), dash(strings.Join(r.local, ", ")), dash(r.url))
+	}
+	return tw.Flush()
+}
+
+func runPortsAdd(cmd *cobra.Command, args []string) error {
+	remote, local, err := parsePortSpec(args[0])
+	if err != nil {
+		return err
+	}
+	name, err := resolvePortsCodespace()
+	if err != nil {
+		return err
+	}
+
+	dir := getPortForwardsDir()
+	forwards, err := listPortForwards(dir)
+	if err != nil {
+		return err
+	}
+	for _, fwd := range forwards {
+		if fwd.Local == local {
+			return fmt.Errorf("localhost:%d is already forwarded to port %d of %s (pid %d)", local, fwd.Remote, fwd.Codespace, fwd.PID)
+		}
+	}
+
+	fmt.Printf("Forwarding port %d of %s to localhost:%d...\n", remote, name, local)
+	fwd, forward, err := startPortForward(dir, name, remote, local)
+	if err != nil {
+		return err
+	}
+	if err := waitForPortForward(forward, local, portForwardStartTimeout); err != nil {
+		stopProcess(fwd.PID)
+		output, _ := os.ReadFile(fwd.Log)
+		removePortForward(dir, fwd)
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+
+	err = updateSavedForwards(getSavedForwardsPath(name), func(saved []savedForward) []savedForward {
+		saved = slices.DeleteFunc(saved, func(s savedForward) bool { return s.Local == local })
+		return append(saved, savedForward{Remote: remote, Local: local})
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remember the forward for reconnects: %v\n", err)
+	}
+	fmt.Printf("Ready. Stop it with 'gh csd ports remove %d'.\n", remote)
+	return nil
+}
+
+// startPortForward starts forwarding port remote of codespace name to
+// localhost:local in the background and records it in dir.
+func startPortForward(dir, name string, remote, local int) (portForward, *exec.Cmd, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return portForward{}, nil, err
+	}
+	logFile, err := os.CreateTemp(dir, "forward-*.log")
+	if err != nil {
+		return portForward{}, nil, err
+	}
+	defer logFile.Close()
+
+	forward := exec.Command("gh", "cs", "ports", "forward", fmt.Sprintf("%d:%d", remote, local), "-c", name)
+	forward.Stdout = logFile
+	forward.Stderr = logFile
+	// Like the forwards of 'gh csd ssh', keep it from querying the terminal
+	forward.Env = append(os.Environ(), "TERM=dumb")
+	detachProcess(forward)
+	// It keeps running in the background, so only the start is logged
+	gh.Trace(forward.Args[1:])
+	if err := forward.Start(); err != nil {
+		os.Remove(logFile.Name())
+		return portForward{}, nil, fmt.Errorf("failed to start port forwarding: %w", err)
+	}
+
+	fwd := portForward{PID: forward.Process.Pid, Codespace: name, Remote: remote, Local: local, Log: logFile.Name(), Started: time.Now()}
+	data, err := json.Marshal(fwd)
+	if err != nil {
+		return portForward{}, nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, strconv.Itoa(fwd.PID)), data, 0600); err != nil {
+		forward.Process.Kill()
+		os.Remove(fwd.Log)
+		return portForward{}, nil, err
+	}
+	return fwd, forward, nil
+}
+
+// restorePortForwards starts the forwards remembered for codespace name
+// that aren't running anymore, e.g. because the connection dropped. Ports
+// something else is listening on are skipped. It returns the local ports
+// it started.
+func restorePortForwards(name string) []int {
+	saved, err := loadSavedForwards(getSavedForwardsPath(name))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to restore port forwards: %v\n", err)
+		return nil
+	}
+	if len(saved) == 0 {
+		return nil
+	}
+
+	dir := getPortForwardsDir()
+	running, err := listPortForwards(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to restore port forwards: %v\n", err)
+		return nil
+	}
+
+	var started []int
+	for _, s := range saved {
+		if slices.ContainsFunc(running, func(fwd portForward) bool { return fwd.Codespace == name && fwd.Local == s.Local }) {
+			continue
+		}
+		if !localPortFree(s.Local) {
+			fmt.Fprintf(os.Stderr, "Skipping port %d: localhost:%d is already in use\n", s.Remote, s.Local)
+			continue
+		}
+		_, forward, err := startPortForward(dir, name, s.Remote, s.Local)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to restore forward of port %d: %v\n", s.Remote, err)
+			continue
+		}
+		// Reap it if it exits while the session is still running, or it
+		// lingers as a zombie that processAlive still reports as running
+		go forward.Wait()
+		started = append(started, s.Local)
+	}
+	return started
+}
+
+// localPortFree reports whether nothing is listening on localhost:port.
+func localPortFree(port int) bool {
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return false
+	}
+	ln.Close()
+	return true
+}
+
+// waitForPortForward waits until localhost:port accepts connections,
+// failing if forward exits first.
+func waitForPortForward(forward *exec.Cmd, port int, timeout time.Duration) error {
+	exited := make(chan error, 1)
+	go func() { exited <- forward.Wait() }()
+
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		select {
+		case err := <-exited:
+			if err == nil {
+				return fmt.Errorf("port forwarding exited")
+			}
+			return fmt.Errorf("port forwarding failed: %w", err)
+		default:
+		}
+		if conn, err := net.DialTimeout("tcp", addr, time.Second); err == nil {
+			conn.Close()
+			return nil
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return fmt.Errorf("localhost:%d wasn't ready after %s", port, timeout)
+}
+
+func runPortsRemove(cmd *cobra.Command, args []string) error {
+	port, err := parsePort(args[0])
+	if err != nil {
+		return err
+	}
+	name, err := resolvePortsCodespace()
+	if err != nil {
+		return err
+	}
+
+	dir := getPortForwardsDir()
+	forwards, err := listPortForwards(dir)
+	if err != nil {
+		return err
+	}
+
+	stopped := 0
+	for _, fwd := range matchPortForwards(forwards, name, port) {
+		if err := stopProcess(fwd.PID); err != nil {
+			return fmt.Errorf("failed to stop forward %d: %w", fwd.PID, err)
+		}
+		removePortForward(dir, fwd)
+		fmt.Printf("Stopped forwarding port %d of %s to localhost:%d\n", fwd.Remote, name, fwd.Local)
+		stopped++
+	}
+
+	// Forget it even if it wasn't running, so reconnects don't bring it back
+	forgotten := 0
+	err = updateSavedForwards(getSavedForwardsPath(name), func(saved []savedForward) []savedForward {
+		before := len(saved)
+		saved = slices.DeleteFunc(saved, func(s savedForward) bool { return s.Local == port || s.Remote == port })
+		forgotten = before - len(saved)
+		return saved
+	})
+	if err != nil {
+		return err
+	}
+
+	if stopped == 0 && forgotten == 0 {
+		return fmt.Errorf("no forward of port %d started by 'gh csd ports add' for %s", port, name)
+	}
+	if stopped == 0 {
+		fmt.Printf("Forgot the forward of port %d of %s\n", port, name)
+	}
+	return nil
+}
+
+// matchPortForwards returns the forwards for codespace name whose local
+// port is port or, failing that, whose codespace port is.
+func matchPortForwards(forwards []portForward, name string, port int) []portForward {
+	var byLocal, byRemote []portForward
+	for _, fwd := range forwards {
+		if fwd.Codespace != name {
+			continue
+		}
+		if fwd.Local == port {
+			byLocal = append(byLocal, fwd)
+		} else if fwd.Remote == port {
+			byRemote = append(byRemote, fwd)
+		}
+	}
+	if len(byLocal) > 0 {
+		return byLocal
+	}
+	return byRemote
+}
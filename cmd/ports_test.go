@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+)
+
+func TestParsePortSpec(t *testing.T) {
+	for _, tt := range []struct {
+		spec          string
+		remote, local int
+	}{
+		{"3000", 3000, 3000},
+		{"3000:8080", 3000, 8080},
+	} {
+		remote, local, err := parsePortSpec(tt.spec)
+		if err != nil || remote != tt.remote || local != tt.local {
+			t.Errorf("parsePortSpec(%q) = %d, %d, %v; want %d, %d", tt.spec, remote, local, err, tt.remote, tt.local)
+		}
+	}
+
+	for _, spec := range []string{"", "web", "0", "70000", "3000:", "3000:x"} {
+		if _, _, err := parsePortSpec(spec); err == nil {
+			t.Errorf("parsePortSpec(%q) should fail", spec)
+		}
+	}
+}
+
+func TestListPortForwards(t *testing.T) {
+	dir := t.TempDir()
+	write := func(pid int, fwd portForward) string {
+		data, _ := json.Marshal(fwd)
+		path := filepath.Join(dir, strconv.Itoa(pid))
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	log := filepath.Join(dir, "forward-1.log")
+	os.WriteFile(log, nil, 0600)
+	// The forward's command line is all that's checked, so a shell whose
+	// arguments look like one stands in for it
+	forward := exec.Command("sh", "-c", "sleep 10; :", "gh", "cs", "ports", "forward", "3000:3000")
+	if err := forward.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		forward.Process.Kill()
+		forward.Wait()
+	})
+
+	write(forward.Process.Pid, portForward{Codespace: "my-cs", Remote: 3000, Local: 3000})
+	stale := write(999999999, portForward{Codespace: "my-cs", Remote: 4000, Local: 4000, Log: log})
+	reused := write(os.Getpid(), portForward{Codespace: "my-cs", Remote: 5000, Local: 5000})
+
+	forwards, err := listPortForwards(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(forwards) != 1 || forwards[0].PID != forward.Process.Pid || forwards[0].Remote != 3000 {
+		t.Fatalf("listPortForwards() = %+v, want only the live forward", forwards)
+	}
+	for _, path := range []string{stale, log, reused} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s of the exited forward wasn't removed", path)
+		}
+	}
+}
+
+func TestWritePortsTable(t *testing.T) {
+	ports := []gh.Port{
+		{SourcePort: 5432, Visibility: "private"},
+		{SourcePort: 3000, Label: "web", Visibility: "private", BrowseURL: "https://cs-3000.app.github.dev"},
+	}
+	forwards := []portForward{
+		{Codespace: "my-cs", Remote: 3000, Local: 8080},
+		{Codespace: "my-cs", Remote: 9000, Local: 9000},
+	}
+
+	var buf strings.Builder
+	if err := writePortsTable(&buf, ports, forwards); err != nil {
+		t.Fatal(err)
+	}
+	want := `PORT  LABEL  VISIBILITY  FORWARDED       URL
+3000  web    private     localhost:8080  https://cs-3000.app.github.dev
+5432  -      private     -               -
+9000  -      -           localhost:9000  -
+`
+	if buf.String() != want {
+		t.Errorf("writePortsTable() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestMatchPortForwards(t *testing.T) {
+	forwards := []portForward{
+		{PID: 1, Codespace: "my-cs", Remote: 3000, Local: 8080},
+		{PID: 2, Codespace: "my-cs", Remote: 8080, Local: 9090},
+		{PID: 3, Codespace: "other-cs", Remote: 3000, Local: 3000},
+	}
+
+	pids := func(forwards []portForward) []int {
+		var pids []int
+		for _, fwd := range forwards {
+			pids = append(pids, fwd.PID)
+		}
+		return pids
+	}
+	// The local port wins when both match
+	if got := pids(matchPortForwards(forwards, "my-cs", 8080)); len(got) != 1 || got[0] != 1 {
+		t.Errorf("match 8080 = %v, want [1]", got)
+	}
+	if got := pids(matchPortForwards(forwards, "my-cs", 3000)); len(got) != 1 || got[0] != 1 {
+		t.Errorf("match 3000 = %v, want [1]", got)
+	}
+	if got := matchPortForwards(forwards, "my-cs", 5000); len(got) != 0 {
+		t.Errorf("match 5000 = %v, want none", got)
+	}
+}
+
+func TestUpdateSavedForwards(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forwards", "my-cs.json")
+
+	add := func(s savedForward) func([]savedForward) []savedForward {
+		return func(saved []savedForward) []savedForward { return append(saved, s) }
+	}
+	if err := updateSavedForwards(path, add(savedForward{Remote: 3000, Local: 3000})); err != nil {
+		t.Fatal(err)
+	}
+	if err := updateSavedForwards(path, add(savedForward{Remote: 5432, Local: 15432})); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := loadSavedForwards(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []savedForward{{Remote: 3000, Local: 3000}, {Remote: 5432, Local: 15432}}
+	if !reflect.DeepEqual(saved, want) {
+		t.Errorf("loadSavedForwards() = %+v, want %+v", saved, want)
+	}
+
+	// Forgetting the last forward removes the file
+	if err := updateSavedForwards(path, func([]savedForward) []savedForward { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("%s wasn't removed with no forwards left", path)
+	}
+	if saved, err := loadSavedForwards(path); err != nil || saved != nil {
+		t.Errorf("loadSavedForwards() of a missing file = %v, %v; want nil, nil", saved, err)
+	}
+}
+
+func TestLocalPortFree(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	if localPortFree(port) {
+		t.Errorf("localPortFree(%d) = true while listening on it", port)
+	}
+	ln.Close()
+	if !localPortFree(port) {
+		t.Errorf("localPortFree(%d) = false after closing the listener", port)
+	}
+}
@@ -0,0 +1,39 @@
+//go:build !unix
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+)
+
+// processAlive reports whether a process with the given pid exists. On
+// Windows, FindProcess fails for processes that have exited.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
+
+// isPortForwardProcess reports whether the process with the given pid is
+// a 'gh cs ports forward'. Windows has no ps to ask, so it only checks that
+// the process exists.
+func isPortForwardProcess(pid int) bool {
+	return processAlive(pid)
+}
+
+// detachProcess is a no-op; processes already outlive their parent.
+func detachProcess(cmd *exec.Cmd) {}
+
+// stopProcess ends the process with the given pid. Windows has no SIGTERM,
+// so it is killed.
+func stopProcess(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
@@ -0,0 +1,44 @@
+//go:build unix
+
+package cmd
+
+import (
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// processAlive reports whether a process with the given pid exists.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// isPortForwardProcess reports whether the process with the given pid is
+// a 'gh cs ports forward', so a record whose pid has since been reused by
+// another process isn't mistaken for a running forward. If ps can't be
+// run, it only checks that the process exists.
+func isPortForwardProcess(pid int) bool {
+	out, err := exec.Command("ps", "-o", "args=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return false
+		}
+		return processAlive(pid)
+	}
+	return strings.Contains(string(out), "ports forward")
+}
+
+// detachProcess starts cmd in its own session, so it keeps running after
+// this process exits and isn't hung up when the terminal closes.
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// stopProcess asks the process with the given pid to exit.
+func stopProcess(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
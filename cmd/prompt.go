@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/luanzeba/gh-csd/internal/config"
+	"github.com/luanzeba/gh-csd/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var promptFormat string
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Print a compact codespace summary for shell prompts",
+	Long: `Print a short summary of the current codespace for embedding in a shell
+prompt, e.g. "[gh:main]".
+
+This only reads local files (no network calls), so it is cheap enough to run
+on every prompt render. Repository and branch come from details cached when
+the codespace was last selected, created, or connected to.
+
+Prints nothing (and exits 0) when no codespace is selected.
+
+Supported placeholders:
+  {alias}       repo alias from config, or the short repo name
+  {repo}        full repository name (e.g. "github/github")
+  {short_repo}  repository name without the owner
+  {branch}      branch name
+  {name}        codespace name
+
+If no details are cached, repository placeholders fall back to the codespace
+name and {branch} to "?".
+
+The format can be set with --format or 'prompt.format' in config.
+
+Example (bash):
+  PS1='$(gh csd prompt) \w \$ '`,
+	Args:          cobra.NoArgs,
+	RunE:          runPrompt,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	promptCmd.Flags().StringVar(&promptFormat, "format", "", "Prompt template (default from config, \"[{alias}:{branch}]\")")
+	rootCmd.AddCommand(promptCmd)
+}
+
+func runPrompt(cmd *cobra.Command, args []string) error {
+	name, err := state.Get()
+	if err != nil {
+		// Nothing selected (or unreadable state): print nothing so the
+		// prompt stays clean
+		return nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+
+	format := promptFormat
+	if format == "" {
+		format = cfg.GetEffectivePromptFormat()
+	}
+
+	info, ok := state.GetInfo(name)
+	if !ok {
+		info = state.Info{Name: name}
+	}
+
+	fmt.Println(formatPrompt(format, info, cfg))
+	return nil
+}
+
+// formatPrompt renders format for the codespace described by info.
+func formatPrompt(format string, info state.Info, cfg *config.Config) string {
+	repo, shortRepo, alias, branch := info.Name, info.Name, info.Name, "?"
+	if info.Repository != "" {
+		repo = info.Repository
+		shortRepo = repo
+		if idx := strings.LastIndex(repo, "/"); idx >= 0 {
+			shortRepo = repo[idx+1:]
+		}
+		alias = shortRepo
+		if repoCfg := cfg.GetRepoConfig(repo); repoCfg != nil && repoCfg.Alias != "" {
+			alias = repoCfg.Alias
+		}
+		branch = info.Branch
+		if branch == "" {
+			branch = "?"
+		}
+	}
+
+	replacer := strings.NewReplacer(
+		"{alias}", alias,
+		"{repo}", repo,
+		"{short_repo}", shortRepo,
+		"{branch}", branch,
+		"{name}", info.Name,
+	)
+	return replacer.Replace(format)
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/luanzeba/gh-csd/internal/config"
+	"github.com/luanzeba/gh-csd/internal/state"
+)
+
+func TestFormatPrompt(t *testing.T) {
+	cfg := &config.Config{
+		Repos: map[string]config.Repo{
+			"github/github": {Alias: "gh"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		info   state.Info
+		want   string
+	}{
+		{
+			name:   "alias from config",
+			format: "[{alias}:{branch}]",
+			info:   state.Info{Name: "super-robot", Repository: "github/github", Branch: "main"},
+			want:   "[gh:main]",
+		},
+		{
+			name:   "short repo when no alias",
+			format: "[{alias}:{branch}]",
+			info:   state.Info{Name: "fuzzy-train", Repository: "luanzeba/gh-csd", Branch: "feature"},
+			want:   "[gh-csd:feature]",
+		},
+		{
+			name:   "all placeholders",
+			format: "{repo} {short_repo} {name}",
+			info:   state.Info{Name: "super-robot", Repository: "github/github", Branch: "main"},
+			want:   "github/github github super-robot",
+		},
+		{
+			name:   "no cached info",
+			format: "[{alias}:{branch}]",
+			info:   state.Info{Name: "super-robot"},
+			want:   "[super-robot:?]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatPrompt(tt.format, tt.info, cfg); got != tt.want {
+				t.Errorf("formatPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/luanzeba/gh-csd/internal/config"
+	"github.com/luanzeba/gh-csd/internal/gh"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rebuildFull       bool
+	rebuildForce      bool
+	rebuildRunHooks   bool
+	rebuildNoTerminfo bool
+)
+
+var rebuildCmd = &cobra.Command{
+	Use:   "rebuild [codespace-name]",
+	Short: "Rebuild a codespace's dev container in place",
+	Long: `Rebuild the dev container of a codespace, e.g. after changing its
+devcontainer configuration. By default, rebuilds the currently selected
+codespace. Use --full to rebuild without using the container cache.
+
+A rebuild wipes container state, so your terminal's terminfo is copied again
+afterward (unless copy_terminfo is disabled or --no-terminfo is given).
+Use --run-hooks to re-run the post_create hooks as well.
+
+You will be asked to confirm unless --force is given.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRebuild,
+}
+
+func init() {
+	rebuildCmd.Flags().StringVarP(&lifecycleCodespace, "codespace", "c", "", "Codespace name (overrides current selection)")
+	rebuildCmd.Flags().BoolVar(&rebuildFull, "full", false, "Perform a full rebuild without the container cache")
+	rebuildCmd.Flags().BoolVarP(&rebuildForce, "force", "f", false, "Skip confirmation prompt")
+	rebuildCmd.Flags().BoolVar(&rebuildRunHooks, "run-hooks", false, "Re-run post_create hooks after the rebuild")
+	rebuildCmd.Flags().BoolVar(&rebuildNoTerminfo, "no-terminfo", false, "Don't copy the terminal's terminfo")
+	rootCmd.AddCommand(rebuildCmd)
+}
+
+func runRebuild(cmd *cobra.Command, args []string) error {
+	name, err := resolveLifecycleCodespace(args)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load config: %v\n", err)
+		cfg = config.DefaultConfig()
+	}
+
+	if !rebuildForce {
+		fmt.Printf("Rebuild codespace %s? Container state will be lost. [y/N] ", name)
+		reader := bufio.NewReader(os.Stdin)
+		response, _ := reader.ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			fmt.Println("Cancelled.")
+			return nil
+		}
+	}
+
+	fmt.Printf("Rebuilding %s...\n", name)
+	if err := gh.RebuildCodespace(name, rebuildFull); err != nil {
+		return err
+	}
+
+	cs, err := waitForCodespaceAvailable(name, createWaitTimeout)
+	if err != nil {
+		return err
+	}
+	cacheCodespaceInfo(cs)
+
+	if cfg.GetEffectiveCopyTerminfo() && !rebuildNoTerminfo {
+		installTerminfo(name, terminfoTerm(cfg))
+	}
+
+	if rebuildRunHooks {
+		runHooks("post-create", cfg.Hooks.PostCreate, hookVars{Name: name, Repo: cs.Repository, Branch: cs.Branch, Codespace: cs})
+	}
+
+	fmt.Printf("Rebuilt %s.\n", name)
+	return nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+	"github.com/luanzeba/gh-csd/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var recentCmd = &cobra.Command{
+	Use:   "recent",
+	Short: "List recently selected codespaces",
+	Long: `List the codespaces you selected most recently, newest first, marking
+the current one. Codespaces that no longer exist are dropped from the list.
+
+Switch back to the previously selected codespace with 'gh csd select -'.`,
+	Args: cobra.NoArgs,
+	RunE: runRecent,
+}
+
+func init() {
+	rootCmd.AddCommand(recentCmd)
+}
+
+func runRecent(cmd *cobra.Command, args []string) error {
+	entries, err := state.Recent()
+	if err != nil {
+		return fmt.Errorf("failed to read recent codespaces: %w", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No codespaces selected yet.")
+		return nil
+	}
+
+	codespaces, err := gh.ListCodespaces()
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]gh.Codespace, len(codespaces))
+	for _, cs := range codespaces {
+		existing[cs.Name] = cs
+	}
+
+	kept := pruneRecent(entries, existing)
+	if len(kept) != len(entries) {
+		if err := state.SetRecent(kept); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to prune recent codespaces: %v\n", err)
+		}
+	}
+	if len(kept) == 0 {
+		fmt.Println("None of the recently selected codespaces exist anymore.")
+		return nil
+	}
+
+	current, _ := state.Get()
+	return writeRecentTable(os.Stdout, kept, existing, current, time.Now())
+}
+
+// pruneRecent drops entries whose codespace isn't in existing.
+func pruneRecent(entries []state.RecentEntry, existing map[string]gh.Codespace) []state.RecentEntry {
+	var kept []state.RecentEntry
+	for _, entry := range entries {
+		if _, ok := existing[entry.Name]; ok {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+func writeRecentTable(w io.Writer, entries []state.RecentEntry, codespaces map[string]gh.Codespace, current string, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tNAME\tREPOSITORY\tBRANCH\tSELECTED")
+	for _, entry := range entries {
+		marker := ""
+		if entry.Name == current {
+			marker = "*"
+		}
+		cs := codespaces[entry.Name]
+		ago := now.Sub(entry.SelectedAt).Round(time.Minute)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s ago\n", marker, entry.Name, cs.Repository, cs.DisplayBranch(), ago)
+	}
+	return tw.Flush()
+}
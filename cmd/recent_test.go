@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+	"github.com/luanzeba/gh-csd/internal/state"
+)
+
+func TestPruneRecent(t *testing.T) {
+	entries := []state.RecentEntry{{Name: "a"}, {Name: "deleted"}, {Name: "b"}}
+	existing := map[string]gh.Codespace{"a": {Name: "a"}, "b": {Name: "b"}}
+
+	kept := pruneRecent(entries, existing)
+	if len(kept) != 2 || kept[0].Name != "a" || kept[1].Name != "b" {
+		t.Errorf("pruneRecent() = %v, want [a b]", kept)
+	}
+}
+
+func TestWriteRecentTable(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	entries := []state.RecentEntry{
+		{Name: "cs-new", SelectedAt: now.Add(-5 * time.Minute)},
+		{Name: "cs-old", SelectedAt: now.Add(-2 * time.Hour)},
+	}
+	codespaces := map[string]gh.Codespace{
+		"cs-new": {Name: "cs-new", Repository: "github/github", Branch: "main"},
+		"cs-old": {Name: "cs-old", Repository: "github/meuse"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeRecentTable(&buf, entries, codespaces, "cs-new", now); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3:\n%s", len(lines), buf.String())
+	}
+	if !strings.HasPrefix(lines[1], "*") || !strings.Contains(lines[1], "5m0s ago") {
+		t.Errorf("current entry line = %q", lines[1])
+	}
+	if strings.HasPrefix(lines[2], "*") || !strings.Contains(lines[2], gh.NoBranchPlaceholder) {
+		t.Errorf("older entry line = %q", lines[2])
+	}
+}
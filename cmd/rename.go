@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+	"github.com/luanzeba/gh-csd/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename <display-name>",
+	Short: "Set a codespace's display name",
+	Long: `Give a codespace a memorable display name, shown by 'gh csd list', the
+picker and the GitHub UI.
+
+By default, renames the currently selected codespace; use -c for another.
+Only the display name changes: the codespace's name, which commands and
+the selection use, stays the same. To name a codespace when creating it,
+use 'gh csd create --display-name'.
+
+Examples:
+  gh csd rename "billing refactor"
+  gh csd rename -c fuzzy-train-abc123 "docs review"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRename,
+}
+
+func init() {
+	renameCmd.Flags().StringVarP(&lifecycleCodespace, "codespace", "c", "", "Codespace name (overrides current selection)")
+	rootCmd.AddCommand(renameCmd)
+}
+
+func runRename(cmd *cobra.Command, args []string) error {
+	displayName := strings.TrimSpace(args[0])
+	if displayName == "" {
+		return fmt.Errorf("display name must not be empty")
+	}
+
+	name, err := resolveLifecycleCodespace(nil)
+	if err != nil {
+		return err
+	}
+
+	if err := gh.RenameCodespace(name, displayName); err != nil {
+		return err
+	}
+	fmt.Printf("Renamed %s to %q\n", name, displayName)
+
+	// Refresh the cached details of the selection with the new listing
+	if current, _ := state.Get(); current == name {
+		if cs, err := gh.GetCodespace(name); err == nil {
+			cacheCodespaceInfo(cs)
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: failed to refresh codespace details: %v\n", err)
+		}
+	}
+	return nil
+}
@@ -1,9 +1,34 @@
 package cmd
 
 import (
+	"runtime/debug"
+	"time"
+
+	"github.com/luanzeba/gh-csd/internal/config"
+	"github.com/luanzeba/gh-csd/internal/gh"
 	"github.com/spf13/cobra"
 )
 
+var (
+	noCache bool
+	verbose bool
+)
+
+// version is set at build time with -ldflags "-X .../cmd.version=v1.2.3".
+var version string
+
+// Version returns the gh-csd version, falling back to the module version
+// recorded by 'go install' and then "dev".
+func Version() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "gh-csd",
 	Short: "Codespace development workflow tool",
@@ -13,7 +38,69 @@ It provides commands to create, connect to, and manage codespaces with features
 - Automatic SSH reconnection on disconnect
 - rdm integration for clipboard/open support
 - Repo aliases for quick access
-- Ghostty tab title integration`,
+- Ghostty tab title integration
+
+Pass --verbose (-v) to any command to see the gh commands it runs.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		gh.Verbose = verbose
+		configureListCache(cmd, args)
+		if needsGH(cmd) {
+			return gh.EnsureGH()
+		}
+		return nil
+	},
+}
+
+// noGHCommands are the top-level commands that work without gh installed,
+// such as those run inside a codespace or by the local server.
+var noGHCommands = map[string]bool{
+	"alias":           true,
+	"audit":           true,
+	"completion":      true,
+	"config":          true,
+	"doctor":          true,
+	"get":             true,
+	"help":            true,
+	"local":           true,
+	"logs":            true,
+	"open-pr":         true,
+	"prompt":          true,
+	"restart-session": true,
+	"server":          true,
+	"service":         true,
+	"stats":           true,
+	"token":           true,
+}
+
+// needsGH reports whether cmd shells out to gh, so a missing gh should be
+// reported up front.
+func needsGH(cmd *cobra.Command) bool {
+	for ; cmd.HasParent(); cmd = cmd.Parent() {
+		if !cmd.Parent().HasParent() {
+			return !noGHCommands[cmd.Name()]
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Log each gh command gh-csd runs, and how long it took, to stderr")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Always fetch fresh codespace data instead of reusing recent 'gh cs list' results")
+}
+
+// configureListCache sets how long 'gh cs list' results are reused, from
+// config and --no-cache.
+func configureListCache(cmd *cobra.Command, args []string) {
+	if noCache {
+		gh.ListCacheTTL = 0
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	gh.ListCacheTTL = time.Duration(cfg.GetEffectiveCacheTTLSeconds()) * time.Second
 }
 
 func Execute() error {
@@ -0,0 +1,29 @@
+package cmd
+
+import "testing"
+
+func TestNeedsGH(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want bool
+	}{
+		{[]string{"ssh"}, true},
+		{[]string{"ports", "add"}, true},
+		{[]string{"local"}, false},
+		{[]string{"prompt"}, false},
+		{[]string{"server", "start"}, false},
+		{[]string{"config", "get"}, false},
+		{[]string{"doctor"}, false},
+	} {
+		cmd, _, err := rootCmd.Find(tt.args)
+		if err != nil {
+			t.Fatalf("Find(%v): %v", tt.args, err)
+		}
+		if got := needsGH(cmd); got != tt.want {
+			t.Errorf("needsGH(%v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+	if needsGH(rootCmd) {
+		t.Error("needsGH(root) = true, want false")
+	}
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+)
+
+// envFileVar is one KEY=VALUE entry from an env file.
+type envFileVar struct {
+	Key   string
+	Value string
+}
+
+// secretNamePattern matches names GitHub accepts for secrets.
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseEnvFile parses KEY=VALUE lines. Blank lines, # comments and an
+// "export " prefix are allowed, and values may be wrapped in single or
+// double quotes.
+func parseEnvFile(r io.Reader) ([]envFileVar, error) {
+	var vars []envFileVar
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNum)
+		}
+		key = strings.TrimSpace(key)
+		if !secretNamePattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: invalid secret name %q", lineNum, key)
+		}
+		if strings.HasPrefix(strings.ToUpper(key), "GITHUB_") {
+			return nil, fmt.Errorf("line %d: secret names can't start with GITHUB_", lineNum)
+		}
+
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		vars = append(vars, envFileVar{Key: key, Value: value})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// readSecretsFile parses the env file at path for --secrets-from.
+func readSecretsFile(path string) ([]envFileVar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open secrets file: %w", err)
+	}
+	defer f.Close()
+
+	vars, err := parseEnvFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(vars) == 0 {
+		return nil, fmt.Errorf("%s: no secrets found", path)
+	}
+	return vars, nil
+}
+
+// uploadSecrets sets vars as Codespaces user secrets available to repo.
+func uploadSecrets(vars []envFileVar, repo string) error {
+	fmt.Fprintf(os.Stderr, "Uploading %d secret(s) to GitHub as Codespaces user secrets for %s: %s\n", len(vars), repo, strings.Join(secretKeys(vars), ", "))
+	for _, v := range vars {
+		if err := gh.SetCodespacesUserSecret(v.Key, v.Value, repo); err != nil {
+			return fmt.Errorf("failed to set secret %s: %w", v.Key, err)
+		}
+	}
+	return nil
+}
+
+func secretKeys(vars []envFileVar) []string {
+	keys := make([]string, len(vars))
+	for i, v := range vars {
+		keys[i] = v.Key
+	}
+	return keys
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	input := `# database
+DB_PASSWORD=hunter2
+export API_KEY="abc 123"
+
+QUOTED='single'
+EMPTY=
+URL=https://example.com/?a=b
+`
+	vars, err := parseEnvFile(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("parseEnvFile failed: %v", err)
+	}
+
+	want := []envFileVar{
+		{"DB_PASSWORD", "hunter2"},
+		{"API_KEY", "abc 123"},
+		{"QUOTED", "single"},
+		{"EMPTY", ""},
+		{"URL", "https://example.com/?a=b"},
+	}
+	if len(vars) != len(want) {
+		t.Fatalf("got %d vars %v, want %d", len(vars), vars, len(want))
+	}
+	for i := range want {
+		if vars[i] != want[i] {
+			t.Errorf("var %d = %+v, want %+v", i, vars[i], want[i])
+		}
+	}
+}
+
+func TestParseEnvFileErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "NO_EQUALS", want: "line 1: expected KEY=VALUE"},
+		{input: "OK=1\n1BAD=x", want: "line 2: invalid secret name"},
+		{input: "GITHUB_TOKEN=x", want: "can't start with GITHUB_"},
+	}
+	for _, tt := range tests {
+		_, err := parseEnvFile(strings.NewReader(tt.input))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseEnvFile(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
@@ -1,41 +1,117 @@
 package cmd
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 
+	"github.com/luanzeba/gh-csd/internal/config"
 	"github.com/luanzeba/gh-csd/internal/gh"
 	"github.com/luanzeba/gh-csd/internal/state"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 )
 
+var (
+	selectNext bool
+	selectPrev bool
+	selectRepo string
+)
+
 var selectCmd = &cobra.Command{
-	Use:   "select [codespace-name]",
+	Use:   "select [codespace-name | --repo <repo>]",
 	Short: "Select the current codespace",
 	Long: `Select a codespace as the current working codespace.
 
 If no codespace name is provided, an interactive fzf picker is shown.
-The selected codespace is stored in ~/.csd/current and used by other commands.`,
+When run inside a codespace, that codespace ($CODESPACE_NAME) is offered
+first, or selected directly if defaults.auto_select_codespace is enabled.
+The selected codespace is stored in ~/.csd/current and used by other commands.
+With $CSD_PROFILE set, it is stored in ~/.csd/current.<profile> instead, so
+each terminal can keep its own selection.
+
+Use 'gh csd select -' to switch back to the previously selected codespace
+(see 'gh csd recent').
+
+--next and --prev step through your codespaces in the order 'gh csd list'
+shows them, wrapping around at the ends. Without a current selection,
+--next selects the first codespace and --prev the last.
+
+--repo selects your codespace for a repository (an alias, owner/repo, or
+a bare name in the github org, like 'gh csd create'). With several, the
+picker shows only that repository's codespaces.
+
+Examples:
+  gh csd select --repo gh
+  gh csd select -R github/docs`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runSelect,
 }
 
 func init() {
+	selectCmd.Flags().BoolVar(&selectNext, "next", false, "Select the codespace after the current one")
+	selectCmd.Flags().BoolVar(&selectPrev, "prev", false, "Select the codespace before the current one")
+	selectCmd.Flags().StringVarP(&selectRepo, "repo", "R", "", "Select a codespace for this repository (alias or owner/repo)")
+	selectCmd.MarkFlagsMutuallyExclusive("next", "prev", "repo")
 	rootCmd.AddCommand(selectCmd)
 }
 
 func runSelect(cmd *cobra.Command, args []string) error {
 	var name string
 
-	if len(args) > 0 {
+	if selectNext || selectPrev {
+		if len(args) > 0 {
+			return fmt.Errorf("--next and --prev can't be combined with a codespace name")
+		}
+		codespaces, err := gh.ListCodespaces()
+		if err != nil {
+			return err
+		}
+		if len(codespaces) == 0 {
+			return fmt.Errorf("no codespaces found")
+		}
+		names := make([]string, len(codespaces))
+		for i, cs := range codespaces {
+			names[i] = cs.Name
+		}
+		current, _ := state.Get()
+		step := 1
+		if selectPrev {
+			step = -1
+		}
+		name = cycleCodespace(names, current, step)
+	} else if selectRepo != "" {
+		if len(args) > 0 {
+			return fmt.Errorf("--repo can't be combined with a codespace name")
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			cfg = config.DefaultConfig()
+		}
+		selected, err := selectCodespaceForRepo(resolveRepoInput(cfg, selectRepo))
+		if err != nil {
+			return err
+		}
+		name = selected
+	} else if len(args) > 0 && args[0] == "-" {
+		current, _ := state.Get()
+		previous, ok := state.Previous(current)
+		if !ok {
+			return fmt.Errorf("no previously selected codespace (see 'gh csd recent')")
+		}
+		name = previous
+	} else if len(args) > 0 {
 		name = args[0]
+	} else if ambient := ambientCodespace(); ambient != "" && offerAmbientCodespace(ambient) {
+		name = ambient
 	} else {
 		// Interactive selection with fzf
-		selected, err := selectCodespaceInteractive()
+		selected, err := selectCodespaceInteractive("")
 		if err != nil {
 			return err
 		}
@@ -43,16 +119,13 @@ func runSelect(cmd *cobra.Command, args []string) error {
 	}
 
 	// Verify the codespace exists
-	exists, err := gh.CodespaceExists(name)
+	cs, err := gh.GetCodespace(name)
 	if err != nil {
 		return err
 	}
-	if !exists {
-		return fmt.Errorf("codespace %q not found", name)
-	}
 
 	// Save selection
-	if err := state.Set(name); err != nil {
+	if err := setCurrentCodespace(cs); err != nil {
 		return fmt.Errorf("failed to save selection: %w", err)
 	}
 
@@ -60,7 +133,46 @@ func runSelect(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func selectCodespaceInteractive() (string, error) {
+// cycleCodespace returns the name step places from current in names,
+// wrapping around at the ends. If current isn't in names, step 1 returns
+// the first name and step -1 the last.
+func cycleCodespace(names []string, current string, step int) string {
+	index := slices.Index(names, current)
+	if index < 0 {
+		if step < 0 {
+			return names[len(names)-1]
+		}
+		return names[0]
+	}
+	n := len(names)
+	return names[((index+step)%n+n)%n]
+}
+
+// selectCodespaceForRepo returns repo's codespace, or lets the user pick
+// among them with fzf if there are several.
+func selectCodespaceForRepo(repo string) (string, error) {
+	codespaces, err := gh.ListCodespaces()
+	if err != nil {
+		return "", err
+	}
+	matches := filterCodespacesByRepo(codespaces, repo)
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no codespaces found for %s (create one with 'gh csd create %s')", repo, repo)
+	case 1:
+		return matches[0].Name, nil
+	}
+	// gh's --repo filter wants the repository as the API spells it
+	return selectCodespaceInteractive(matches[0].Repository)
+}
+
+// selectCodespaceInteractive lets the user pick a codespace with fzf,
+// only showing repo's codespaces if repo isn't empty.
+func selectCodespaceInteractive(repo string) (string, error) {
+	if err := requireFzf(); err != nil {
+		return "", err
+	}
+
 	// Get terminal width (subtract 3 like csw does)
 	width := 80 // default
 	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
@@ -69,7 +181,11 @@ func selectCodespaceInteractive() (string, error) {
 
 	// Run gh cs list with TTY forcing for colored, aligned output
 	env := []string{fmt.Sprintf("GH_FORCE_TTY=%d", width)}
-	result, err := gh.RunWithEnv(env, "cs", "list")
+	listArgs := []string{"cs", "list"}
+	if repo != "" {
+		listArgs = append(listArgs, "--repo", repo)
+	}
+	result, err := gh.RunWithEnv(env, listArgs...)
 	if err != nil {
 		return "", err
 	}
@@ -102,3 +218,73 @@ func selectCodespaceInteractive() (string, error) {
 
 	return fields[0], nil
 }
+
+// ambientCodespace returns the codespace gh-csd is running inside, from the
+// CODESPACE_NAME variable Codespaces sets, or "" elsewhere.
+func ambientCodespace() string {
+	return os.Getenv("CODESPACE_NAME")
+}
+
+// offerAmbientCodespace reports whether 'gh csd select' should pick the
+// codespace it runs inside: always with auto_select_codespace, otherwise
+// after confirming on a terminal.
+func offerAmbientCodespace(ambient string) bool {
+	cfg, err := config.Load()
+	if err == nil && cfg.Defaults.AutoSelectCodespace {
+		return true
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false
+	}
+
+	fmt.Printf("Select %s (the codespace you're in)? [Y/n] ", ambient)
+	reader := bufio.NewReader(os.Stdin)
+	response, _ := reader.ReadString('\n')
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "" || response == "y" || response == "yes"
+}
+
+// getSelectedCodespace returns the current selection. When nothing is
+// selected inside a codespace, that codespace is selected and returned if
+// defaults.auto_select_codespace is enabled; otherwise it is suggested.
+func getSelectedCodespace(cfg *config.Config) (string, error) {
+	name, err := state.Get()
+	ambient := ambientCodespace()
+	if !errors.Is(err, state.ErrNoCodespace) || ambient == "" {
+		return name, err
+	}
+
+	if !cfg.Defaults.AutoSelectCodespace {
+		fmt.Fprintf(os.Stderr, "Note: running inside codespace %s; select it with 'gh csd select %s' or set defaults.auto_select_codespace\n", ambient, ambient)
+		return "", err
+	}
+	if err := state.Set(ambient); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save current codespace: %v\n", err)
+	}
+	return ambient, nil
+}
+
+// setCurrentCodespace saves cs as the current selection and caches its
+// details for 'gh csd prompt'.
+func setCurrentCodespace(cs *gh.Codespace) error {
+	if err := state.Set(cs.Name); err != nil {
+		return err
+	}
+	cacheCodespaceInfo(cs)
+	return nil
+}
+
+// cacheCodespaceInfo caches cs's details for 'gh csd prompt'. The cache is
+// only an optimization, so failures are ignored.
+func cacheCodespaceInfo(cs *gh.Codespace) {
+	state.SetInfo(state.Info{Name: cs.Name, Repository: cs.Repository, Branch: cs.Branch})
+}
+
+// requireFzf returns a friendly error if fzf, used by the interactive
+// pickers, isn't installed.
+func requireFzf() error {
+	if _, err := exec.LookPath("fzf"); err != nil {
+		return fmt.Errorf("fzf is needed for the interactive picker but isn't on your PATH; install it from https://github.com/junegunn/fzf or pass a name instead")
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/luanzeba/gh-csd/internal/config"
+	"github.com/luanzeba/gh-csd/internal/state"
+)
+
+func TestGetSelectedCodespaceAmbient(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("CODESPACE_NAME", "inside-cs")
+
+	cfg := config.DefaultConfig()
+	if _, err := getSelectedCodespace(cfg); !errors.Is(err, state.ErrNoCodespace) {
+		t.Fatalf("without auto select: err = %v, want ErrNoCodespace", err)
+	}
+
+	cfg.Defaults.AutoSelectCodespace = true
+	name, err := getSelectedCodespace(cfg)
+	if err != nil || name != "inside-cs" {
+		t.Fatalf("with auto select: got %q, %v; want inside-cs", name, err)
+	}
+	if saved, _ := state.Get(); saved != "inside-cs" {
+		t.Errorf("auto selection not saved: state = %q", saved)
+	}
+
+	// An explicit selection wins over the ambient codespace
+	if err := state.Set("chosen-cs"); err != nil {
+		t.Fatal(err)
+	}
+	if name, _ := getSelectedCodespace(cfg); name != "chosen-cs" {
+		t.Errorf("got %q, want the explicit selection chosen-cs", name)
+	}
+}
+
+func TestCycleCodespace(t *testing.T) {
+	names := []string{"cs-a", "cs-b", "cs-c"}
+
+	tests := []struct {
+		current string
+		step    int
+		want    string
+	}{
+		{current: "cs-a", step: 1, want: "cs-b"},
+		{current: "cs-c", step: 1, want: "cs-a"},
+		{current: "cs-b", step: -1, want: "cs-a"},
+		{current: "cs-a", step: -1, want: "cs-c"},
+		{current: "", step: 1, want: "cs-a"},
+		{current: "deleted-cs", step: -1, want: "cs-c"},
+	}
+
+	for _, tt := range tests {
+		if got := cycleCodespace(names, tt.current, tt.step); got != tt.want {
+			t.Errorf("cycleCodespace(%q, %d) = %q, want %q", tt.current, tt.step, got, tt.want)
+		}
+	}
+
+	if got := cycleCodespace([]string{"only"}, "only", 1); got != "only" {
+		t.Errorf("cycleCodespace() with one codespace = %q", got)
+	}
+}
@@ -3,7 +3,10 @@ package cmd
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -13,10 +16,16 @@ import (
 	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
+	"github.com/luanzeba/gh-csd/internal/config"
 	"github.com/luanzeba/gh-csd/internal/protocol"
 	"github.com/spf13/cobra"
 )
@@ -35,22 +44,158 @@ Usage:
   2. Connect via SSH:   gh csd ssh  (socket forwarding is automatic)
   3. In Codespace:      gh csd local gh pr create --title "My PR"
 
-The server can also be installed as a launchd service to start on boot:
-  gh csd server install`,
+The server can also be installed as a service (launchd on macOS, systemd
+on Linux) to start on boot:
+  gh csd server install
+'gh csd server uninstall' removes it again. These are the same as
+'gh csd service install' and 'gh csd service uninstall'. To start or stop
+the installed service, pass --service to 'server start' or 'server stop';
+without it they run the server in the foreground and stop whichever server
+is running.
+
+Retries:
+  Failed commands can be retried when stderr looks like a transient gh/API
+  failure (timeouts, 5xx responses). Retries are off by default and only
+  apply to subcommands listed in 'server.retry_subcommands'.
+
+  IMPORTANT: only list idempotent subcommands. Retrying a command such as
+  'gh pr create' after a timeout can create duplicate PRs or issues, because
+  the first attempt may have succeeded on GitHub's side.
+
+Approved commands:
+  For the strictest policy, list command signatures in
+  'server.approved_commands'; the server then runs only those exact
+  commands, without stdin, --workdir, forwarded variables or --tty.
+  Generate a signature with:
+    gh csd server sign -R owner/repo -- gh pr view --json title
+
+Socket:
+  A socket left behind by a server that crashed is detected on start (no
+  server answers on it) and replaced, so the service comes back after an
+  unclean shutdown. While running, the server also binds the socket again
+  if its file is removed. Both happen under ~/.csd/csd.socket.lock so
+  servers starting together don't replace each other's socket.
+
+Clients:
+  Each 'gh csd ssh' session registers a client ID and passes it to the
+  codespace in ~/.csd/client and $CSD_CLIENT, so requests are attributed to the
+  codespace they came from in the log, the audit log and
+  'gh csd server status'. 'server.client_rate_limit' caps requests per
+  client per minute, and 'server.require_client' rejects requests that
+  don't come from a known session.
+
+Token:
+  With 'server.require_token: true', a token is generated in ~/.csd/token
+  on first start and every request must carry it. 'gh csd ssh' copies it
+  into the codespace, where 'gh csd local' sends it. Delete the file and
+  restart the server to regenerate it.
+
+Tokens:
+  With 'server.token_minting' configured, 'gh csd token' in a codespace can
+  ask for a short-lived GitHub App token limited to the allowed
+  repositories and permissions, instead of running commands here.
+
+Signals:
+  SIGINT, SIGTERM  shut down gracefully
+  SIGHUP           reopen ~/.csd/csd.log and audit.log (for logrotate)
+
+View the log with 'gh csd server logs -f'. Every executed or blocked
+command is also recorded in ~/.csd/audit.log; see 'gh csd server audit'.`,
 }
 
 var serverStartCmd = &cobra.Command{
 	Use:   "start",
-	Short: "Start the server in the foreground",
+	Short: "Start the server in the foreground, or the service with --service",
 	RunE:  runServerStart,
 }
 
+var serverInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the server as a service to run on boot (same as 'service install')",
+	Args:  cobra.NoArgs,
+	Run:   runServiceInstall,
+}
+
+var serverUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the installed service (same as 'service uninstall')",
+	Args:  cobra.NoArgs,
+	Run:   runServiceUninstall,
+}
+
+// serverUseService makes 'server start' and 'server stop' act on the
+// installed service, like 'gh csd service start' and 'service stop'.
+var serverUseService bool
+
+var (
+	serverExecRetries int
+	serverNice        int
+)
+
 var serverStopCmd = &cobra.Command{
 	Use:   "stop",
-	Short: "Stop a running server",
+	Short: "Stop a running server, or the service with --service",
 	RunE:  runServerStop,
 }
 
+var serverStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the server is running and what each codespace has requested",
+	Long: `Show whether the server is running, and the requests it has received
+from each client since it started.
+
+Unlike 'gh csd service status', this checks that something answers on the
+socket, and whether that is the service's process ("managed by service")
+or one started with 'gh csd server start' ("foreground").
+
+Each 'gh csd ssh' session is a separate client, identified by the ID it
+passes to the codespace. Requests that carry no known ID are
+shown together as "(unidentified)".`,
+	Args: cobra.NoArgs,
+	RunE: runServerStatus,
+}
+
+// serverSignRepo is the codespace repository 'server sign' signs for.
+var serverSignRepo string
+
+var serverSignCmd = &cobra.Command{
+	Use:   "sign [-R owner/repo] -- <command> [args...]",
+	Short: "Print the signature of a command for server.approved_commands",
+	Long: `Print the signature of a command for server.approved_commands.
+
+The signature covers the command exactly as the server receives it. For
+subcommands that infer their repository (local.repo_subcommands, such as
+'pr view'), 'gh csd local' adds -R with the codespace's repository before
+sending them, so pass that repository with -R to sign what it will send.
+The signed command is printed to stderr.
+
+Examples:
+  gh csd server sign -R github/github -- gh pr view --json title,url
+  gh csd server sign -- gh api user`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintf(os.Stderr, "Signing: %s\n", strings.Join(signedCommand(args, serverSignRepo), " "))
+		fmt.Println(commandSignature(signedCommand(args, serverSignRepo)))
+	},
+}
+
+// signedCommand returns command as 'gh csd local' in a codespace of repo
+// would send it, with -R added for repo subcommands. An empty repo leaves
+// it unchanged.
+func signedCommand(command []string, repo string) []string {
+	if repo == "" {
+		return command
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	if !needsRepoContext(command, cfg.GetEffectiveLocalRepoSubcommands()) {
+		return command
+	}
+	return injectRepoFlag(command, repo)
+}
+
 var serverSocketCmd = &cobra.Command{
 	Use:   "socket",
 	Short: "Print the socket path",
@@ -72,15 +217,39 @@ var commonPaths = []string{
 	"/bin",
 }
 
+// socketEnvVar overrides the default socket path, for the server and for
+// 'gh csd local' alike.
+const socketEnvVar = "CSD_SOCKET"
+
+// serverSocket is the --socket flag of the server commands.
+var serverSocket string
+
 func init() {
+	serverCmd.PersistentFlags().StringVar(&serverSocket, "socket", "", "Socket path (default $"+socketEnvVar+" or ~/.csd/csd.socket)")
+	serverStartCmd.Flags().IntVar(&serverExecRetries, "exec-retries", 0, "Retries for transient command failures (default from config; idempotent subcommands only)")
+	serverStartCmd.Flags().IntVar(&serverNice, "nice", 0, "Niceness for executed commands, 1-19 lowers their priority (default from config)")
+	serverStartCmd.Flags().BoolVar(&serverUseService, "service", false, "Start the installed service instead (same as 'service start')")
+	serverStopCmd.Flags().BoolVar(&serverUseService, "service", false, "Stop the installed service instead (same as 'service stop')")
 	serverCmd.AddCommand(serverStartCmd)
 	serverCmd.AddCommand(serverStopCmd)
+	serverCmd.AddCommand(serverInstallCmd)
+	serverCmd.AddCommand(serverUninstallCmd)
+	serverCmd.AddCommand(serverStatusCmd)
 	serverCmd.AddCommand(serverSocketCmd)
+	serverSignCmd.Flags().StringVarP(&serverSignRepo, "repo", "R", "", "Sign the command as 'gh csd local' sends it from a codespace of this repository")
+	serverCmd.AddCommand(serverSignCmd)
 	rootCmd.AddCommand(serverCmd)
 }
 
-// GetServerSocketPath returns the path to the server's Unix socket.
+// GetServerSocketPath returns the path to the server's Unix socket: the
+// --socket flag, then $CSD_SOCKET, then ~/.csd/csd.socket.
 func GetServerSocketPath() string {
+	if serverSocket != "" {
+		return serverSocket
+	}
+	if path := os.Getenv(socketEnvVar); path != "" {
+		return path
+	}
 	home, _ := os.UserHomeDir()
 	return filepath.Join(home, ".csd", "csd.socket")
 }
@@ -90,22 +259,71 @@ func getServerLogPath() string {
 	return filepath.Join(home, ".csd", "csd.log")
 }
 
-func getPidPath() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".csd", "csd.pid")
+// getPidPath returns the PID file of the server on socketPath. It sits
+// next to the socket, so servers on different sockets don't share one.
+func getPidPath(socketPath string) string {
+	return socketPath + ".pid"
 }
 
+const (
+	// execRetryBaseDelay is the initial backoff between command retries.
+	execRetryBaseDelay = time.Second
+	// defaultMaxRequestBytes caps request bodies when not configured.
+	defaultMaxRequestBytes = 1 << 20
+)
+
 // Server handles incoming command execution requests.
 type Server struct {
 	socketPath string
 	logger     *log.Logger
+	started    time.Time
 	httpServer *http.Server
 	cancel     context.CancelFunc
+	audit      *auditLog
+
+	// socketMu guards socketFile, the socket file this server bound
+	socketMu   sync.Mutex
+	socketFile os.FileInfo
+
+	// ExecRetries is how many times a failed command is retried when it looks
+	// like a transient failure. Only RetrySubcommands are ever retried.
+	ExecRetries int
+	// RetrySubcommands lists idempotent subcommands (e.g. "pr view").
+	RetrySubcommands []string
+	// MaxRequestBytes caps the size of a request body.
+	MaxRequestBytes int64
+	// Nice is the niceness applied to executed commands so heavy commands
+	// don't compete with foreground work. 0 leaves the priority unchanged.
+	Nice int
+	// MaxCPUSeconds and MaxMemoryMB cap the CPU time and memory of each
+	// executed command. 0 means no limit.
+	MaxCPUSeconds int
+	MaxMemoryMB   int
+	// ApprovedSignatures, when non-empty, limits execution to commands whose
+	// commandSignature is in the set.
+	ApprovedSignatures map[string]bool
+	// Token, when set, must accompany every request except "status".
+	Token string
+	// AllowedEnv lists the only environment variables clients may set
+	// through ExecRequest.Env.
+	AllowedEnv []string
+	// Minter answers "token" requests; nil means tokens aren't minted.
+	Minter *tokenMinter
+	// Clients attributes requests to the 'gh csd ssh' session they came
+	// through and enforces per-client limits.
+	Clients *clientTracker
 }
 
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	body, err := io.ReadAll(r.Body)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.MaxRequestBytes))
 	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			s.logger.Printf("request body too large (limit %d bytes)", maxErr.Limit)
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			writeErrorResponse(w, fmt.Sprintf("request too large (limit %d bytes)", maxErr.Limit), 1)
+			return
+		}
 		s.logger.Printf("could not read request body: %v", err)
 		writeErrorResponse(w, "failed to read request", 1)
 		return
@@ -121,11 +339,60 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	s.logger.Printf("received request: type=%s command=%v", req.Type, req.Command)
 
+	tokenErr := checkToken(s.Token, req.Token)
+	if req.Type != "status" && tokenErr != nil {
+		s.logger.Printf("rejected request: %v", tokenErr)
+		w.WriteHeader(http.StatusUnauthorized)
+		writeErrorResponse(w, tokenErr.Error(), 1)
+		return
+	}
+
+	if req.Type == "exec" || req.Type == "exec-stream" || req.Type == "exec-tty" || req.Type == "token" {
+		command := req.Command
+		if req.Type == "token" {
+			command = tokenAuditCommand(&req)
+		}
+		if err := s.Clients.admit(req.Client, command, time.Now()); err != nil {
+			s.logger.Printf("rejected request: %v", err)
+			s.recordAudit(auditEntry{Time: time.Now(), Command: command, Client: s.Clients.name(req.Client), ExitCode: 1, Blocked: true, Error: err.Error()})
+			s.Clients.block(req.Client)
+			if errors.Is(err, errRateLimited) {
+				w.WriteHeader(http.StatusTooManyRequests)
+			} else {
+				w.WriteHeader(http.StatusForbidden)
+			}
+			writeErrorResponse(w, err.Error(), 1)
+			return
+		}
+		if name := s.Clients.name(req.Client); name != "" {
+			s.logger.Printf("request from codespace %s", name)
+		}
+	}
+
 	switch req.Type {
 	case "exec":
-		s.handleExec(w, &req)
+		s.handleExec(r.Context(), w, &req)
+	case "exec-stream":
+		s.handleExecStream(r.Context(), w, &req)
+	case "exec-tty":
+		s.handleExecTTY(r.Context(), w, &req)
+	case "token":
+		s.handleToken(r.Context(), w, &req)
 	case "status":
-		w.Write([]byte(`{"status":"running"}`))
+		resp := protocol.StatusResponse{
+			Status:          "running",
+			Version:         Version(),
+			PID:             os.Getpid(),
+			StartedAt:       s.started,
+			AllowedCommands: allowedCommands,
+			Capabilities:    []string{protocol.CapabilityPipe},
+		}
+		// Client activity includes commands, so only share it with
+		// clients that could run them
+		if tokenErr == nil {
+			resp.Clients = s.Clients.status()
+		}
+		json.NewEncoder(w).Encode(resp)
 	case "stop":
 		s.logger.Println("received stop command")
 		w.Write([]byte(`{"status":"stopping"}`))
@@ -136,17 +403,62 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (s *Server) handleExec(w http.ResponseWriter, req *protocol.ExecRequest) {
+// prepareExec validates an exec request and resolves the command path.
+// On failure it writes an error response and returns false.
+func (s *Server) prepareExec(w http.ResponseWriter, req *protocol.ExecRequest) (string, bool) {
 	if len(req.Command) == 0 {
 		writeErrorResponse(w, "no command specified", 1)
-		return
+		return "", false
 	}
 
 	// Security check: only allow specific commands
 	if !isAllowedCommand(req.Command[0]) {
 		s.logger.Printf("blocked command: %s (allowed: %s)", req.Command[0], strings.Join(allowedCommands, ", "))
-		writeErrorResponse(w, fmt.Sprintf("command %q not allowed (allowed: %s)", req.Command[0], strings.Join(allowedCommands, ", ")), 1)
-		return
+		msg := fmt.Sprintf("command %q not allowed (allowed: %s)", req.Command[0], strings.Join(allowedCommands, ", "))
+		s.recordBlocked(req, msg)
+		writeErrorResponse(w, msg, 1)
+		return "", false
+	}
+
+	if len(s.ApprovedSignatures) > 0 {
+		signature := commandSignature(req.Command)
+		if !s.ApprovedSignatures[signature] {
+			s.logger.Printf("blocked unapproved command: %v (signature %s)", req.Command, signature)
+			msg := fmt.Sprintf("command not in server.approved_commands (signature %s)", signature)
+			s.recordBlocked(req, msg)
+			writeErrorResponse(w, msg, 1)
+			return "", false
+		}
+		// The signature only covers the argv, so nothing else may change
+		// what an approved command does
+		if extra := unsignedInputs(req); len(extra) > 0 {
+			s.logger.Printf("blocked approved command with %s: %v", strings.Join(extra, ", "), req.Command)
+			msg := fmt.Sprintf("approved commands run without %s (server.approved_commands is set)", strings.Join(extra, ", "))
+			s.recordBlocked(req, msg)
+			writeErrorResponse(w, msg, 1)
+			return "", false
+		}
+	}
+
+	allowedEnv := s.AllowedEnv
+	if req.Type == "exec-tty" {
+		// The pseudo-terminal needs the client's terminal type
+		allowedEnv = append(slices.Clip(allowedEnv), "TERM")
+	}
+	if rejected := rejectedEnvKeys(req.Env, allowedEnv); len(rejected) > 0 {
+		s.logger.Printf("rejected env vars not in server.allowed_env: %s", strings.Join(rejected, ", "))
+		writeErrorResponse(w, fmt.Sprintf("refusing to set environment variables not in server.allowed_env: %s", strings.Join(rejected, ", ")), 1)
+		return "", false
+	}
+
+	if req.Workdir != "" {
+		workdir, err := resolveWorkdir(req.Workdir)
+		if err != nil {
+			s.logger.Printf("invalid workdir: %v", err)
+			writeErrorResponse(w, err.Error(), 1)
+			return "", false
+		}
+		req.Workdir = workdir
 	}
 
 	s.logger.Printf("executing: %v", req.Command)
@@ -154,36 +466,461 @@ func (s *Server) handleExec(w http.ResponseWriter, req *protocol.ExecRequest) {
 	// Resolve command path (launchd services have minimal PATH)
 	cmdPath := resolveCommand(req.Command[0])
 	s.logger.Printf("resolved command path: %s -> %s", req.Command[0], cmdPath)
+	return cmdPath, true
+}
 
-	// Execute command
-	cmd := exec.Command(cmdPath, req.Command[1:]...)
-	if req.Workdir != "" {
-		cmd.Dir = req.Workdir
+// recordBlocked adds a command rejected by policy to the audit log.
+func (s *Server) recordBlocked(req *protocol.ExecRequest, reason string) {
+	s.Clients.block(req.Client)
+	s.recordAudit(auditEntry{Time: time.Now(), Command: req.Command, Workdir: req.Workdir, Client: s.Clients.name(req.Client), ExitCode: 1, Blocked: true, Error: reason})
+}
+
+// recordExec adds a command that was run to the audit log.
+func (s *Server) recordExec(req *protocol.ExecRequest, start time.Time, exitCode int, errMsg string) {
+	s.recordAudit(auditEntry{
+		Time:       start,
+		Command:    req.Command,
+		Workdir:    req.Workdir,
+		Client:     s.Clients.name(req.Client),
+		ExitCode:   exitCode,
+		DurationMs: time.Since(start).Milliseconds(),
+		Error:      errMsg,
+	})
+}
+
+func (s *Server) recordAudit(entry auditEntry) {
+	if err := s.audit.Record(entry); err != nil {
+		s.logger.Printf("failed to write audit log: %v", err)
 	}
+}
+
+// handleExec runs a command and writes its buffered output. ctx is the
+// request context; it is cancelled when the client disconnects, which kills
+// the command.
+func (s *Server) handleExec(ctx context.Context, w http.ResponseWriter, req *protocol.ExecRequest) {
+	cmdPath, ok := s.prepareExec(w, req)
+	if !ok {
+		return
+	}
+
+	// The command's own timeout replaces the server's fixed WriteTimeout,
+	// which would otherwise cut off long-running commands
+	ctx, cancel := withExecTimeout(ctx, req.Timeout)
+	defer cancel()
+	http.NewResponseController(w).SetWriteDeadline(execWriteDeadline(req.Timeout))
 
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	start := time.Now()
+	exitCode, auditErr, outcome := s.runWithRetries(ctx, cmdPath, req, s.execRetries(req), &stdout, &stderr)
+	s.recordExec(req, start, exitCode, auditErr)
+
+	switch outcome {
+	case execTimedOut:
+		writeTimeoutResponse(w, req, auditErr, &stdout, &stderr)
+	case execFailed:
+		writeErrorResponse(w, auditErr, exitCode)
+	case execCompleted:
+		resp := bufferedResponse(req, &stdout, &stderr)
+		resp.ExitCode = exitCode
+		json.NewEncoder(w).Encode(resp)
+	}
+}
 
-	err := cmd.Run()
-	exitCode := 0
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			exitCode = exitErr.ExitCode()
-		} else {
+// execRetries returns how often req may be retried after a transient
+// failure: server.exec_retries for server.retry_subcommands, else 0.
+func (s *Server) execRetries(req *protocol.ExecRequest) int {
+	if isRetryableSubcommand(req.Command, s.RetrySubcommands) {
+		return s.ExecRetries
+	}
+	return 0
+}
+
+// execOutcome says how runWithRetries ended.
+type execOutcome int
+
+const (
+	execCompleted execOutcome = iota
+	execTimedOut
+	execDisconnected
+	// execFailed means the command couldn't be run at all
+	execFailed
+)
+
+// runWithRetries runs a command into stdout and stderr, running it again
+// up to retries times while it fails with a transient error. Only the last
+// attempt's output is kept. It returns the exit code and, unless the
+// command completed, the error to record in the audit log.
+func (s *Server) runWithRetries(ctx context.Context, cmdPath string, req *protocol.ExecRequest, retries int, stdout, stderr *bytes.Buffer) (int, string, execOutcome) {
+	delay := execRetryBaseDelay
+	for attempt := 0; ; attempt++ {
+		stdout.Reset()
+		stderr.Reset()
+
+		exitCode, err := runServerCommand(ctx, cmdPath, req.Command[1:], req.Workdir, req.Input(), req.Env, s.execLimits(), stdout, stderr)
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			s.logger.Printf("command timed out after %ds: %v", req.Timeout, req.Command)
+			return execTimeoutExitCode, timeoutMessage(req.Timeout), execTimedOut
+		}
+		if ctx.Err() != nil {
+			s.logger.Printf("client disconnected, command cancelled: %v", req.Command)
+			return exitCode, "client disconnected", execDisconnected
+		}
+		if err != nil {
 			s.logger.Printf("command failed: %v", err)
-			writeErrorResponse(w, fmt.Sprintf("command failed: %v", err), 1)
+			return 1, fmt.Sprintf("command failed: %v", err), execFailed
+		}
+
+		if exitCode == 0 || attempt >= retries || !isTransientExecFailure(stderr.String()) {
+			s.logger.Printf("command completed: exit_code=%d stdout_len=%d stderr_len=%d", exitCode, stdout.Len(), stderr.Len())
+			return exitCode, "", execCompleted
+		}
+
+		s.logger.Printf("transient failure (exit_code=%d), retrying in %s (attempt %d/%d)", exitCode, delay, attempt+1, retries)
+		select {
+		case <-ctx.Done():
+			s.logger.Printf("retry cancelled (%v): %v", ctx.Err(), req.Command)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return execTimeoutExitCode, timeoutMessage(req.Timeout), execTimedOut
+			}
+			return exitCode, "client disconnected", execDisconnected
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// handleExecStream runs a command and writes its output as newline-delimited
+// StreamFrames while it runs. Output that has been sent can't be taken
+// back, so commands that may be retried are buffered instead and their
+// final attempt's output sent once they finish.
+func (s *Server) handleExecStream(ctx context.Context, w http.ResponseWriter, req *protocol.ExecRequest) {
+	cmdPath, ok := s.prepareExec(w, req)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := withExecTimeout(ctx, req.Timeout)
+	defer cancel()
+
+	// Streamed commands may run longer than the server's WriteTimeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "application/x-ndjson")
+
+	frames := &frameWriter{w: w, flush: rc.Flush}
+	stdout := &streamWriter{frames: frames, stream: "stdout", raw: req.Pipe}
+	stderr := &streamWriter{frames: frames, stream: "stderr"}
+	// exit sends what the writers held back, then the exit frame
+	exit := func(frame *protocol.StreamFrame) {
+		stdout.Close()
+		stderr.Close()
+		frames.write(frame)
+	}
+
+	if retries := s.execRetries(req); retries > 0 {
+		var stdoutBuf, stderrBuf bytes.Buffer
+		start := time.Now()
+		exitCode, auditErr, outcome := s.runWithRetries(ctx, cmdPath, req, retries, &stdoutBuf, &stderrBuf)
+		s.recordExec(req, start, exitCode, auditErr)
+		if outcome == execDisconnected {
 			return
 		}
+		if stdoutBuf.Len() > 0 {
+			stdout.Write(stdoutBuf.Bytes())
+		}
+		if stderrBuf.Len() > 0 {
+			stderr.Write(stderrBuf.Bytes())
+		}
+		exit(&protocol.StreamFrame{Stream: "exit", ExitCode: exitCode, Error: auditErr, Timeout: outcome == execTimedOut})
+		return
 	}
 
-	s.logger.Printf("command completed: exit_code=%d stdout_len=%d stderr_len=%d", exitCode, stdout.Len(), stderr.Len())
+	start := time.Now()
+	exitCode, err := runServerCommand(ctx, cmdPath, req.Command[1:], req.Workdir, req.Input(), req.Env, s.execLimits(), stdout, stderr)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		s.logger.Printf("command timed out after %ds: %v", req.Timeout, req.Command)
+		s.recordExec(req, start, execTimeoutExitCode, timeoutMessage(req.Timeout))
+		exit(&protocol.StreamFrame{Stream: "exit", ExitCode: execTimeoutExitCode, Error: timeoutMessage(req.Timeout), Timeout: true})
+		return
+	}
+	if ctx.Err() != nil {
+		s.logger.Printf("client disconnected, command cancelled: %v", req.Command)
+		s.recordExec(req, start, exitCode, "client disconnected")
+		return
+	}
+	if err != nil {
+		s.logger.Printf("command failed: %v", err)
+		s.recordExec(req, start, 1, fmt.Sprintf("command failed: %v", err))
+		exit(&protocol.StreamFrame{Stream: "exit", ExitCode: 1, Error: fmt.Sprintf("command failed: %v", err)})
+		return
+	}
+	s.recordExec(req, start, exitCode, "")
 
-	resp := protocol.ExecResponse{
-		Stdout:   stdout.String(),
-		Stderr:   stderr.String(),
-		ExitCode: exitCode,
+	s.logger.Printf("command completed: exit_code=%d stdout_len=%d stderr_len=%d", exitCode, stdout.n, stderr.n)
+	exit(&protocol.StreamFrame{Stream: "exit", ExitCode: exitCode})
+}
+
+// execTimeoutExitCode is returned for commands killed by their timeout,
+// matching timeout(1).
+const execTimeoutExitCode = 124
+
+// withExecTimeout derives a context that expires after timeoutSeconds.
+// A timeout of 0 means no limit.
+func withExecTimeout(ctx context.Context, timeoutSeconds int) (context.Context, context.CancelFunc) {
+	if timeoutSeconds <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(timeoutSeconds)*time.Second)
+}
+
+// execWriteDeadline returns the write deadline for a buffered response: a
+// little after the command's timeout, or none if it has no timeout.
+func execWriteDeadline(timeoutSeconds int) time.Time {
+	if timeoutSeconds <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(time.Duration(timeoutSeconds)*time.Second + 30*time.Second)
+}
+
+func timeoutMessage(timeoutSeconds int) string {
+	return fmt.Sprintf("command timed out after %ds", timeoutSeconds)
+}
+
+// frameWriter serializes StreamFrames to a response or connection,
+// flushing after each frame so the other side sees output as it is
+// produced.
+type frameWriter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	flush func() error // nil if w is unbuffered
+}
+
+func (f *frameWriter) write(frame *protocol.StreamFrame) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := protocol.WriteFrame(f.w, frame); err != nil {
+		return err
+	}
+	if f.flush != nil {
+		return f.flush()
+	}
+	return nil
+}
+
+// streamWriter adapts a frameWriter to io.Writer for one output stream.
+// With raw set, output is sent as bytes rather than text. Otherwise a
+// UTF-8 character split across writes is held back until it is complete,
+// since JSON would turn each half into U+FFFD; Close sends what is left.
+type streamWriter struct {
+	frames  *frameWriter
+	stream  string
+	raw     bool
+	n       int
+	pending []byte
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	frame := &protocol.StreamFrame{Stream: s.stream}
+	if s.raw {
+		frame.Raw = p
+	} else {
+		data := append(s.pending, p...)
+		complete := len(data) - incompleteRuneSuffix(data)
+		s.pending = append([]byte(nil), data[complete:]...)
+		if complete == 0 {
+			s.n += len(p)
+			return len(p), nil
+		}
+		frame.Data = string(data[:complete])
+	}
+	if err := s.frames.write(frame); err != nil {
+		return 0, err
+	}
+	s.n += len(p)
+	return len(p), nil
+}
+
+// Close sends any bytes held back by Write, complete or not.
+func (s *streamWriter) Close() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	data := s.pending
+	s.pending = nil
+	return s.frames.write(&protocol.StreamFrame{Stream: s.stream, Data: string(data)})
+}
+
+// incompleteRuneSuffix returns the length of the start of a UTF-8
+// character at the end of p that needs more bytes, or 0.
+func incompleteRuneSuffix(p []byte) int {
+	for i := 1; i < utf8.UTFMax && i <= len(p); i++ {
+		start := len(p) - i
+		if utf8.RuneStart(p[start]) {
+			if utf8.FullRune(p[start:]) {
+				return 0
+			}
+			return i
+		}
+	}
+	return 0
+}
+
+// resolveWorkdir expands a leading '~' in workdir and checks that it is an
+// existing directory.
+func resolveWorkdir(workdir string) (string, error) {
+	if workdir == "~" || strings.HasPrefix(workdir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot expand workdir %q: %w", workdir, err)
+		}
+		workdir = filepath.Join(home, strings.TrimPrefix(workdir, "~"))
+	}
+
+	info, err := os.Stat(workdir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("workdir %q does not exist on the local machine", workdir)
+		}
+		return "", fmt.Errorf("cannot access workdir %q: %w", workdir, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("workdir %q is not a directory", workdir)
+	}
+	return workdir, nil
+}
+
+// execLimits are the niceness and resource limits applied to the commands
+// the server runs. Zero values leave them unchanged.
+type execLimits struct {
+	Nice       int
+	CPUSeconds int
+	MemoryMB   int
+}
+
+// execLimits returns the limits configured for executed commands.
+func (s *Server) execLimits() execLimits {
+	return execLimits{Nice: s.Nice, CPUSeconds: s.MaxCPUSeconds, MemoryMB: s.MaxMemoryMB}
+}
+
+// runServerCommand runs a command and returns its exit code. A non-nil error
+// means the command could not be run at all, including when limits can't be
+// applied to it. A non-empty stdin is piped to the command. Cancelling ctx
+// sends the command SIGTERM, followed by SIGKILL if it doesn't exit
+// promptly.
+func runServerCommand(ctx context.Context, cmdPath string, args []string, workdir, stdin string, env map[string]string, limits execLimits, stdout, stderr io.Writer) (int, error) {
+	cmd := exec.CommandContext(ctx, cmdPath, args...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = 5 * time.Second
+	if workdir != "" {
+		cmd.Dir = workdir
+	}
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	if len(env) > 0 {
+		cmd.Env = os.Environ()
+		for key, value := range env {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	checkLimits, err := limitCommand(cmd, limits)
+	if err != nil {
+		return 0, fmt.Errorf("failed to apply limits: %w", err)
+	}
+
+	err = cmd.Start()
+	if err == nil {
+		err = cmd.Wait()
+	}
+	if err := checkLimits(); err != nil {
+		return 0, err
+	}
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode(), nil
+		}
+		return 0, err
+	}
+	return 0, nil
+}
+
+// isRetryableSubcommand reports whether command's subcommand (e.g. "pr view")
+// is in the list of subcommands that are safe to retry.
+func isRetryableSubcommand(command []string, subcommands []string) bool {
+	if len(command) < 2 {
+		return false
+	}
+	for _, sub := range subcommands {
+		fields := strings.Fields(sub)
+		if len(fields) == 0 || len(fields) > len(command)-1 {
+			continue
+		}
+		match := true
+		for i, field := range fields {
+			if command[i+1] != field {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// isTransientExecFailure reports whether stderr looks like a network or
+// server-side failure that may succeed on retry.
+func isTransientExecFailure(stderr string) bool {
+	msg := strings.ToLower(stderr)
+	transientIndicators := []string{
+		"timeout",
+		"timed out",
+		"connection reset",
+		"connection refused",
+		"unexpected eof",
+		"http 502",
+		"http 503",
+		"http 504",
+		"bad gateway",
+		"service unavailable",
+		"gateway timeout",
+		"something went wrong",
 	}
+
+	for _, indicator := range transientIndicators {
+		if strings.Contains(msg, indicator) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bufferedResponse returns the response to a buffered exec with its
+// output, with stdout as bytes for Pipe requests.
+func bufferedResponse(req *protocol.ExecRequest, stdout, stderr *bytes.Buffer) protocol.ExecResponse {
+	resp := protocol.ExecResponse{Stderr: stderr.String()}
+	if req.Pipe {
+		resp.StdoutRaw = stdout.Bytes()
+	} else {
+		resp.Stdout = stdout.String()
+	}
+	return resp
+}
+
+// writeTimeoutResponse answers a buffered exec whose command timed out,
+// with the output it produced before it was killed.
+func writeTimeoutResponse(w http.ResponseWriter, req *protocol.ExecRequest, errMsg string, stdout, stderr *bytes.Buffer) {
+	resp := bufferedResponse(req, stdout, stderr)
+	resp.ExitCode = execTimeoutExitCode
+	resp.Error = errMsg
+	resp.Timeout = true
 	json.NewEncoder(w).Encode(resp)
 }
 
@@ -205,6 +942,50 @@ func isAllowedCommand(cmd string) bool {
 	return false
 }
 
+// rejectedEnvKeys returns the sorted keys of env that aren't in allowed.
+// Too many variables make gh run a program of the client's choosing on
+// this machine (GH_BROWSER, GH_PAGER, EDITOR, GIT_SSH_COMMAND, PATH, ...)
+// for a denylist to be safe, so only allowed ones may be set.
+func rejectedEnvKeys(env map[string]string, allowed []string) []string {
+	var rejected []string
+	for key := range env {
+		if !slices.Contains(allowed, key) {
+			rejected = append(rejected, key)
+		}
+	}
+	sort.Strings(rejected)
+	return rejected
+}
+
+// commandSignature identifies an exact command line for
+// server.approved_commands: the hex SHA-256 of its NUL-separated argv. The
+// command name is reduced to its base name, matching isAllowedCommand.
+func commandSignature(argv []string) string {
+	normalized := append([]string{filepath.Base(argv[0])}, argv[1:]...)
+	sum := sha256.Sum256([]byte(strings.Join(normalized, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// unsignedInputs names the parts of req besides its argv that would
+// affect the command: stdin, a workdir, environment variables or a
+// terminal.
+func unsignedInputs(req *protocol.ExecRequest) []string {
+	var extra []string
+	if req.Input() != "" {
+		extra = append(extra, "stdin")
+	}
+	if req.Workdir != "" {
+		extra = append(extra, "--workdir")
+	}
+	if len(req.Env) > 0 {
+		extra = append(extra, "forwarded environment variables")
+	}
+	if req.Type == "exec-tty" {
+		extra = append(extra, "--tty")
+	}
+	return extra
+}
+
 // resolveCommand finds the full path to a command.
 // It first checks if the command is already an absolute path,
 // then searches in common paths, and finally falls back to exec.LookPath.
@@ -263,26 +1044,172 @@ func (s *Server) Listen(ctx context.Context) error {
 		return fmt.Errorf("failed to create socket directory: %w", err)
 	}
 
-	// Try to listen on the socket
-	listener, err := net.Listen("unix", s.socketPath)
+	listener, err := s.bindSocket()
+	if err != nil {
+		return err
+	}
+	defer s.removeSocket()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.watchSocket(ctx, cancel)
+
+	return s.Serve(ctx, listener)
+}
+
+// socketCheckInterval is how often a running server checks that its socket
+// file is still in place.
+const socketCheckInterval = 30 * time.Second
+
+// getSocketLockPath returns the lock file that serializes servers binding
+// socketPath.
+func getSocketLockPath(socketPath string) string {
+	return socketPath + ".lock"
+}
+
+// withSocketLock runs fn while holding the socket lock, so two servers
+// starting at once (e.g. the service and a manual start after a crash)
+// can't both find the socket stale and replace each other's.
+func (s *Server) withSocketLock(fn func() error) error {
+	path := getSocketLockPath(s.socketPath)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
 	if err != nil {
-		// If socket exists and is in use, check if server is running
-		if isAddressInUse(err) {
+		return fmt.Errorf("failed to open socket lock: %w", err)
+	}
+	defer f.Close()
+
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	defer unlockFile(f)
+	return fn()
+}
+
+// bindSocket listens on the server socket. A socket file left behind by a
+// server that didn't shut down cleanly is removed and bound again, but
+// only once no live server answers on it.
+func (s *Server) bindSocket() (net.Listener, error) {
+	var listener net.Listener
+	err := s.withSocketLock(func() error {
+		var err error
+		listener, err = net.Listen("unix", s.socketPath)
+		if err != nil && isAddressInUse(err) {
 			if isServerRunning(s.socketPath) {
 				return fmt.Errorf("server already running on %s", s.socketPath)
 			}
-			// Stale socket, remove it
-			s.logger.Printf("removing stale socket: %s", s.socketPath)
-			os.Remove(s.socketPath)
+			s.logger.Printf("healing stale socket %s left by a server that didn't shut down cleanly", s.socketPath)
+			if err := os.Remove(s.socketPath); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove stale socket: %w", err)
+			}
 			listener, err = net.Listen("unix", s.socketPath)
 		}
 		if err != nil {
 			return fmt.Errorf("failed to listen on socket: %w", err)
 		}
+		return s.trackSocket(listener)
+	})
+	return listener, err
+}
+
+// trackSocket records the socket file listener created, so the server can
+// notice it being removed or replaced. The file is removed by
+// removeSocket rather than when the listener closes, since by then it may
+// belong to another listener or server.
+func (s *Server) trackSocket(listener net.Listener) error {
+	if unixListener, ok := listener.(*net.UnixListener); ok {
+		unixListener.SetUnlinkOnClose(false)
+	}
+	info, err := os.Stat(s.socketPath)
+	if err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to stat socket: %w", err)
 	}
-	defer os.Remove(s.socketPath)
 
-	return s.Serve(ctx, listener)
+	s.socketMu.Lock()
+	defer s.socketMu.Unlock()
+	s.socketFile = info
+	return nil
+}
+
+// ownsSocket reports whether the file at the socket path is the one this
+// server bound.
+func (s *Server) ownsSocket() bool {
+	info, err := os.Stat(s.socketPath)
+	if err != nil {
+		return false
+	}
+
+	s.socketMu.Lock()
+	defer s.socketMu.Unlock()
+	return s.socketFile != nil && os.SameFile(s.socketFile, info)
+}
+
+// removeSocket removes the socket file on shutdown, unless another server
+// has since taken the path over.
+func (s *Server) removeSocket() {
+	s.withSocketLock(func() error {
+		if s.ownsSocket() {
+			os.Remove(s.socketPath)
+		}
+		return nil
+	})
+}
+
+// watchSocket checks the socket every socketCheckInterval until ctx is
+// done. See checkSocket.
+func (s *Server) watchSocket(ctx context.Context, shutdown context.CancelFunc) {
+	ticker := time.NewTicker(socketCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.checkSocket(); err != nil {
+				s.logger.Printf("%v; shutting down", err)
+				shutdown()
+				return
+			}
+		}
+	}
+}
+
+// checkSocket heals a socket file that was removed while the server runs
+// (e.g. by cleaning up ~/.csd), which would otherwise leave the server
+// running but unreachable, by binding it again. It fails if another server
+// has taken the socket over.
+func (s *Server) checkSocket() error {
+	if s.ownsSocket() {
+		return nil
+	}
+
+	// The lock file lives next to the socket, so it can't be taken if
+	// the whole directory was removed
+	if err := os.MkdirAll(filepath.Dir(s.socketPath), 0700); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	return s.withSocketLock(func() error {
+		if s.ownsSocket() {
+			return nil
+		}
+		if isServerRunning(s.socketPath) {
+			return fmt.Errorf("another server took over %s", s.socketPath)
+		}
+
+		s.logger.Printf("healing socket %s, which was removed or replaced; binding it again", s.socketPath)
+		if err := os.Remove(s.socketPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+		listener, err := net.Listen("unix", s.socketPath)
+		if err != nil {
+			return fmt.Errorf("failed to listen on socket: %w", err)
+		}
+		if err := s.trackSocket(listener); err != nil {
+			return err
+		}
+		go s.httpServer.Serve(listener)
+		return nil
+	})
 }
 
 func isAddressInUse(err error) bool {
@@ -307,8 +1234,11 @@ func isServerRunning(socketPath string) bool {
 
 func newServer(socketPath string, logger *log.Logger) *Server {
 	server := &Server{
-		socketPath: socketPath,
-		logger:     logger,
+		socketPath:      socketPath,
+		logger:          logger,
+		started:         time.Now(),
+		MaxRequestBytes: defaultMaxRequestBytes,
+		Clients:         newClientTracker(getClientsDir()),
 	}
 	server.httpServer = &http.Server{
 		Handler:      server,
@@ -320,6 +1250,10 @@ func newServer(socketPath string, logger *log.Logger) *Server {
 }
 
 func runServerStart(cmd *cobra.Command, args []string) error {
+	if serverUseService {
+		runServiceStart(cmd, args)
+		return nil
+	}
 	socketPath := GetServerSocketPath()
 
 	// Setup logging
@@ -329,7 +1263,7 @@ func runServerStart(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create log directory: %w", err)
 	}
 
-	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	logFile, err := openReopenableFile(logPath)
 	if err != nil {
 		return fmt.Errorf("failed to open log file: %w", err)
 	}
@@ -340,25 +1274,98 @@ func runServerStart(cmd *cobra.Command, args []string) error {
 	logger := log.New(multiWriter, "[gh-csd] ", log.LstdFlags)
 
 	// Write PID file
-	pidPath := getPidPath()
+	pidPath := getPidPath(socketPath)
+	os.MkdirAll(filepath.Dir(pidPath), 0700)
 	if err := os.WriteFile(pidPath, []byte(fmt.Sprintf("%d", os.Getpid())), 0644); err != nil {
 		logger.Printf("warning: failed to write PID file: %v", err)
 	}
 	defer os.Remove(pidPath)
 
+	cfg, warnings, err := config.LoadAndValidate()
+	if err != nil {
+		logger.Printf("warning: failed to load config: %v", err)
+		cfg = config.DefaultConfig()
+	}
+	for _, warning := range warnings {
+		logger.Printf("warning: config: %v", warning)
+	}
+
+	audit, err := openAuditLog(getAuditLogPath())
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer audit.Close()
+
 	server := newServer(socketPath, logger)
+	server.audit = audit
+	server.ExecRetries = cfg.Server.ExecRetries
+	if cmd.Flags().Changed("exec-retries") {
+		server.ExecRetries = serverExecRetries
+	}
+	server.RetrySubcommands = cfg.GetEffectiveServerRetrySubcommands()
+	if cfg.Server.MaxRequestBytes > 0 {
+		server.MaxRequestBytes = cfg.Server.MaxRequestBytes
+	}
+	server.Nice = cfg.Server.Nice
+	if cmd.Flags().Changed("nice") {
+		server.Nice = serverNice
+	}
+	server.MaxCPUSeconds = cfg.Server.MaxCPUSeconds
+	server.MaxMemoryMB = cfg.Server.MaxMemoryMB
+	server.AllowedEnv = cfg.Server.AllowedEnv
+	server.Clients.RateLimit = cfg.Server.ClientRateLimit
+	server.Clients.RequireClient = cfg.Server.RequireClient
+	if server.Clients.RequireClient {
+		logger.Printf("only accepting requests from registered 'gh csd ssh' sessions")
+	}
+	if cfg.Server.RequireToken {
+		server.Token, err = loadOrCreateToken(getTokenPath())
+		if err != nil {
+			return err
+		}
+		logger.Printf("requiring the token from %s", getTokenPath())
+	}
+	if cfg.Server.TokenMinting != nil {
+		server.Minter, err = newTokenMinter(cfg.Server.TokenMinting)
+		if err != nil {
+			return err
+		}
+		logger.Printf("minting tokens for %s", strings.Join(cfg.Server.TokenMinting.Repos, ", "))
+	}
+	if len(cfg.Server.ApprovedCommands) > 0 {
+		server.ApprovedSignatures = make(map[string]bool, len(cfg.Server.ApprovedCommands))
+		for _, signature := range cfg.Server.ApprovedCommands {
+			server.ApprovedSignatures[strings.ToLower(strings.TrimSpace(signature))] = true
+		}
+		logger.Printf("only %d approved command(s) may run", len(server.ApprovedSignatures))
+	}
 
 	// Handle signals for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// SIGINT/SIGTERM shut down; SIGHUP reopens the log file so logrotate
+	// can move it away without us writing to a deleted inode
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 
 	go func() {
-		sig := <-sigChan
-		logger.Printf("received signal: %v", sig)
-		cancel()
+		for sig := range sigChan {
+			if sig == syscall.SIGHUP {
+				if err := logFile.Reopen(); err != nil {
+					logger.Printf("failed to reopen log file: %v", err)
+				} else {
+					logger.Printf("reopened log file %s", logPath)
+				}
+				if err := audit.Reopen(); err != nil {
+					logger.Printf("failed to reopen audit log: %v", err)
+				}
+				continue
+			}
+			logger.Printf("received signal: %v", sig)
+			cancel()
+			return
+		}
 	}()
 
 	fmt.Printf("Starting gh-csd server on %s\n", socketPath)
@@ -367,14 +1374,63 @@ func runServerStart(cmd *cobra.Command, args []string) error {
 	return server.Listen(ctx)
 }
 
+// reopenableFile is an append-only log file that can be closed and opened
+// again at the same path, e.g. after logrotate has moved it.
+type reopenableFile struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+func openReopenableFile(path string) (*reopenableFile, error) {
+	f := &reopenableFile{path: path}
+	if err := f.Reopen(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *reopenableFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Write(p)
+}
+
+// Reopen closes the current handle and opens the path again, creating it
+// if it was moved away.
+func (f *reopenableFile) Reopen() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file != nil {
+		f.file.Close()
+	}
+	f.file = file
+	return nil
+}
+
+func (f *reopenableFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
 func runServerStop(cmd *cobra.Command, args []string) error {
+	if serverUseService {
+		runServiceStop(cmd, args)
+		return nil
+	}
 	socketPath := GetServerSocketPath()
 
 	// Try to connect and send stop command
 	conn, err := net.DialTimeout("unix", socketPath, 2*time.Second)
 	if err != nil {
 		// Try PID file as fallback
-		pidPath := getPidPath()
+		pidPath := getPidPath(socketPath)
 		data, err := os.ReadFile(pidPath)
 		if err != nil {
 			return fmt.Errorf("no server running (cannot connect to socket and no PID file)")
@@ -408,7 +1464,7 @@ func runServerStop(cmd *cobra.Command, args []string) error {
 		Timeout: 5 * time.Second,
 	}
 
-	req := protocol.ExecRequest{Type: "stop"}
+	req := protocol.ExecRequest{Type: "stop", Token: readToken(getTokenPath())}
 	body, _ := json.Marshal(req)
 
 	resp, err := client.Post("http://unix/", "application/json", bytes.NewReader(body))
@@ -420,3 +1476,87 @@ func runServerStop(cmd *cobra.Command, args []string) error {
 	fmt.Println("Server stopped")
 	return nil
 }
+
+func runServerStatus(cmd *cobra.Command, args []string) error {
+	socketPath := GetServerSocketPath()
+	serviceRunning, servicePID := serviceRunState()
+
+	if !isServerRunning(socketPath) {
+		fmt.Printf("Server: not running on %s\n", socketPath)
+		if serviceRunning {
+			fmt.Println("  The service reports running, but nothing is serving the socket.")
+			fmt.Println("  Restart it with 'gh csd service stop' and 'gh csd service start'.")
+		}
+		return fmt.Errorf("server not running (start it with 'gh csd server start' or 'gh csd service start')")
+	}
+
+	client := newSocketClient(socketPath, 5*time.Second)
+	req := protocol.ExecRequest{Type: "status", Token: readToken(getTokenPath())}
+	resp, err := postLocalRequest(context.Background(), client, &req)
+	if err != nil {
+		fmt.Printf("Server: not responding on %s\n", socketPath)
+		return fmt.Errorf("the socket accepts connections but the server didn't answer: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var status protocol.StatusResponse
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// Servers older than the PID in the status response wrote it to a file
+	serverPID := status.PID
+	if serverPID == 0 {
+		serverPID = readServerPID(socketPath)
+	}
+
+	fmt.Printf("Server: %s on %s\n", serverMode(status.Status, serverPID, servicePID), socketPath)
+	writeServerDetails(os.Stdout, status, time.Now())
+	if serviceRunning && servicePID != 0 && servicePID != serverPID {
+		fmt.Printf("  The service is also running (PID %d) but isn't the process serving the socket.\n", servicePID)
+	}
+	fmt.Println()
+	if len(status.Clients) == 0 {
+		fmt.Println("No requests from codespaces yet.")
+		return nil
+	}
+	return writeClientStatus(os.Stdout, status.Clients, time.Now())
+}
+
+// serverMode describes a server answering on the socket as running in the
+// foreground or under the service, by comparing its PID with the service's.
+func serverMode(status string, serverPID, servicePID int) string {
+	if serverPID != 0 && serverPID == servicePID {
+		return status + " (managed by service)"
+	}
+	return status + " (foreground)"
+}
+
+// readServerPID returns the PID in the PID file of the server on
+// socketPath, 0 if unknown.
+func readServerPID(socketPath string) int {
+	data, err := os.ReadFile(getPidPath(socketPath))
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
+
+// writeServerDetails prints the diagnostics in a status response, skipping
+// those an older server didn't send.
+func writeServerDetails(w io.Writer, status protocol.StatusResponse, now time.Time) {
+	if status.Version != "" {
+		fmt.Fprintf(w, "  Version:          %s\n", status.Version)
+	}
+	if status.PID != 0 {
+		fmt.Fprintf(w, "  PID:              %d\n", status.PID)
+	}
+	if !status.StartedAt.IsZero() {
+		uptime := now.Sub(status.StartedAt).Round(time.Second)
+		fmt.Fprintf(w, "  Uptime:           %s (since %s)\n", uptime, status.StartedAt.Local().Format(time.DateTime))
+	}
+	if len(status.AllowedCommands) > 0 {
+		fmt.Fprintf(w, "  Allowed commands: %s\n", strings.Join(status.AllowedCommands, ", "))
+	}
+}
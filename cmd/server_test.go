@@ -0,0 +1,660 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luanzeba/gh-csd/internal/protocol"
+	"github.com/spf13/cobra"
+)
+
+func TestIsRetryableSubcommand(t *testing.T) {
+	subcommands := []string{"pr view", "pr list", "api"}
+
+	tests := []struct {
+		name    string
+		command []string
+		want    bool
+	}{
+		{name: "pr view", command: []string{"gh", "pr", "view", "42"}, want: true},
+		{name: "single word", command: []string{"gh", "api", "user"}, want: true},
+		{name: "pr create", command: []string{"gh", "pr", "create", "--title", "x"}, want: false},
+		{name: "too short", command: []string{"gh", "pr"}, want: false},
+		{name: "bare gh", command: []string{"gh"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableSubcommand(tt.command, subcommands); got != tt.want {
+				t.Fatalf("unexpected result: want %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIsTransientExecFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		stderr string
+		want   bool
+	}{
+		{name: "gateway", stderr: "HTTP 502: Bad Gateway (https://api.github.com/graphql)", want: true},
+		{name: "timeout", stderr: "Post \"https://api.github.com/graphql\": net/http: TLS handshake timeout", want: true},
+		{name: "not found", stderr: "GraphQL: Could not resolve to a PullRequest with the number of 42.", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientExecFailure(tt.stderr); got != tt.want {
+				t.Fatalf("unexpected result for %q: want %v, got %v", tt.stderr, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestServeHTTPRejectsOversizedBody(t *testing.T) {
+	server := newServer("", log.New(io.Discard, "", 0))
+	server.MaxRequestBytes = 16
+
+	body := strings.NewReader(`{"type":"exec","command":["gh","pr","view","1234567890"]}`)
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	rec := httptest.NewRecorder()
+
+	server.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	var resp protocol.ExecResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !strings.Contains(resp.Error, "too large") {
+		t.Fatalf("unexpected error message: %q", resp.Error)
+	}
+}
+
+func TestRunServerCommandCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := runServerCommand(ctx, "sleep", []string{"10"}, "", "", nil, execLimits{}, io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("expected the command to start, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("command was not killed on cancel (ran %s)", elapsed)
+	}
+}
+
+func TestRunServerCommandStdin(t *testing.T) {
+	var stdout bytes.Buffer
+	exitCode, err := runServerCommand(context.Background(), "cat", nil, "", "piped body\n", nil, execLimits{}, &stdout, io.Discard)
+	if err != nil || exitCode != 0 {
+		t.Fatalf("runServerCommand = %d, %v", exitCode, err)
+	}
+	if stdout.String() != "piped body\n" {
+		t.Fatalf("stdout = %q, want stdin echoed back", stdout.String())
+	}
+}
+
+func TestResolveWorkdir(t *testing.T) {
+	dir := t.TempDir()
+	if got, err := resolveWorkdir(dir); err != nil || got != dir {
+		t.Fatalf("resolveWorkdir(%q) = %q, %v", dir, got, err)
+	}
+
+	missing := filepath.Join(dir, "missing")
+	if _, err := resolveWorkdir(missing); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("expected a does-not-exist error, got %v", err)
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolveWorkdir(file); err == nil || !strings.Contains(err.Error(), "not a directory") {
+		t.Fatalf("expected a not-a-directory error, got %v", err)
+	}
+
+	t.Setenv("HOME", dir)
+	if got, err := resolveWorkdir("~"); err != nil || got != dir {
+		t.Fatalf("resolveWorkdir(~) = %q, %v; want %q", got, err, dir)
+	}
+}
+
+func TestRunServerCommandLimits(t *testing.T) {
+	var stdout bytes.Buffer
+	limits := execLimits{Nice: 5, CPUSeconds: 30}
+	exitCode, err := runServerCommand(context.Background(), "sh", []string{"-c", "nice; ulimit -t"}, "", "", nil, limits, &stdout, io.Discard)
+	if err != nil || exitCode != 0 {
+		t.Fatalf("runServerCommand = %d, %v", exitCode, err)
+	}
+	if got, want := strings.Fields(stdout.String()), []string{"5", "30"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("niceness and limits = %v, want %v", got, want)
+	}
+
+	// Raising the priority needs privileges, so it fails unless run as root
+	if os.Geteuid() != 0 {
+		_, err := runServerCommand(context.Background(), "sh", []string{"-c", "echo ran"}, "", "", nil, execLimits{Nice: -5}, &stdout, io.Discard)
+		if err == nil || !strings.Contains(err.Error(), "failed to set niceness -5") {
+			t.Errorf("runServerCommand with an unsettable niceness = %v, want it to refuse", err)
+		}
+	}
+}
+
+func TestRunServerCommandMemoryLimit(t *testing.T) {
+	// Other shells, such as macOS's sh, don't support ulimit -v
+	if runtime.GOOS != "linux" {
+		t.Skip("ulimit -v is only relied on under Linux")
+	}
+
+	var stdout bytes.Buffer
+	exitCode, err := runServerCommand(context.Background(), "sh", []string{"-c", "ulimit -v"}, "", "", nil, execLimits{MemoryMB: 4096}, &stdout, io.Discard)
+	if err != nil || exitCode != 0 {
+		t.Fatalf("runServerCommand = %d, %v", exitCode, err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "4194304" {
+		t.Fatalf("memory limit = %q, want 4194304", got)
+	}
+}
+
+func TestReopenableFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "csd.log")
+
+	f, err := openReopenableFile(path)
+	if err != nil {
+		t.Fatalf("openReopenableFile failed: %v", err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("before\n"))
+
+	// Simulate logrotate moving the file away
+	rotated := path + ".1"
+	if err := os.Rename(path, rotated); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reopen(); err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	f.Write([]byte("after\n"))
+
+	if data, _ := os.ReadFile(rotated); string(data) != "before\n" {
+		t.Errorf("rotated file = %q, want %q", data, "before\n")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "after\n" {
+		t.Errorf("new log file = %q, want %q", data, "after\n")
+	}
+}
+
+func TestWithExecTimeout(t *testing.T) {
+	ctx, cancel := withExecTimeout(context.Background(), 1)
+	defer cancel()
+
+	_, err := runServerCommand(ctx, "sleep", []string{"10"}, "", "", nil, execLimits{}, io.Discard, io.Discard)
+	if err != nil {
+		t.Fatalf("expected the command to start, got %v", err)
+	}
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Fatalf("ctx.Err() = %v, want DeadlineExceeded", ctx.Err())
+	}
+
+	ctx, cancel = withExecTimeout(context.Background(), 0)
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Fatal("expected no deadline for a zero timeout")
+	}
+}
+
+func TestCommandSignature(t *testing.T) {
+	sig := commandSignature([]string{"gh", "pr", "view", "--json", "title"})
+	if len(sig) != 64 {
+		t.Fatalf("signature %q is not a hex SHA-256", sig)
+	}
+	if got := commandSignature([]string{"/opt/homebrew/bin/gh", "pr", "view", "--json", "title"}); got != sig {
+		t.Errorf("absolute command path changed the signature: %s != %s", got, sig)
+	}
+	if got := commandSignature([]string{"gh", "pr", "view", "--json title"}); got == sig {
+		t.Error("argument boundaries should change the signature")
+	}
+}
+
+func TestServeHTTPRejectsUnapprovedCommand(t *testing.T) {
+	server := newServer("", log.New(io.Discard, "", 0))
+	server.ApprovedSignatures = map[string]bool{
+		commandSignature([]string{"gh", "pr", "view"}): true,
+	}
+
+	body := strings.NewReader(`{"type":"exec","command":["gh","pr","create","--fill"]}`)
+	req := httptest.NewRequest(http.MethodPost, "/", body)
+	rec := httptest.NewRecorder()
+
+	server.ServeHTTP(rec, req)
+
+	var resp protocol.ExecResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ExitCode != 1 || !strings.Contains(resp.Error, "approved_commands") {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestServeHTTPApprovedCommandRunsAsSigned(t *testing.T) {
+	server := newServer("", log.New(io.Discard, "", 0))
+	server.ApprovedSignatures = map[string]bool{
+		commandSignature([]string{"gh", "pr", "view"}): true,
+	}
+
+	for name, body := range map[string]string{
+		"stdin":   `{"type":"exec","command":["gh","pr","view"],"stdin":"x"}`,
+		"workdir": `{"type":"exec","command":["gh","pr","view"],"workdir":"/tmp"}`,
+		"tty":     `{"type":"exec-tty","command":["gh","pr","view"]}`,
+	} {
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+		var resp protocol.ExecResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", name, err)
+		}
+		if resp.ExitCode != 1 || !strings.Contains(resp.Error, "approved commands run without") {
+			t.Errorf("%s: unexpected response: %+v", name, resp)
+		}
+	}
+}
+
+func TestSignedCommand(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	got := signedCommand([]string{"gh", "pr", "view", "--json", "title"}, "github/github")
+	if want := []string{"gh", "pr", "view", "--json", "title", "-R", "github/github"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("signedCommand() = %v, want %v", got, want)
+	}
+	if got := signedCommand([]string{"gh", "api", "user"}, "github/github"); !reflect.DeepEqual(got, []string{"gh", "api", "user"}) {
+		t.Errorf("signedCommand() added -R to a command without repo context: %v", got)
+	}
+}
+
+func TestServeHTTPRequiresToken(t *testing.T) {
+	server := newServer("", log.New(io.Discard, "", 0))
+	server.Token = "secret"
+
+	body := strings.NewReader(`{"type":"exec","command":["gh","pr","view"]}`)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", body))
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	// Status checks don't need the token
+	rec = httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"type":"status"}`)))
+	if !strings.Contains(rec.Body.String(), "running") {
+		t.Fatalf("status response = %q, want running", rec.Body.String())
+	}
+}
+
+func TestRejectedEnvKeys(t *testing.T) {
+	env := map[string]string{
+		"GH_REPO":      "owner/repo",
+		"PATH":         "/tmp/evil",
+		"GH_TOKEN":     "x",
+		"MY_SECRET":    "y",
+		"LD_PRELOAD":   "/tmp/evil.so",
+		"EDITOR":       "vim",
+		"GITHUB_TOKEN": "z",
+	}
+
+	got := rejectedEnvKeys(env, []string{"GH_REPO", "GITHUB_TOKEN"})
+	want := []string{"EDITOR", "GH_TOKEN", "LD_PRELOAD", "MY_SECRET", "PATH"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("rejectedEnvKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestServeHTTPRejectsUnlistedEnv(t *testing.T) {
+	server := newServer("", log.New(io.Discard, "", 0))
+	server.AllowedEnv = []string{"GH_DEBUG"}
+
+	body := strings.NewReader(`{"type":"exec","command":["gh","pr","view"],"env":{"GH_DEBUG":"1","GH_BROWSER":"/tmp/evil"}}`)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", body))
+
+	var resp protocol.ExecResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ExitCode != 1 || !strings.Contains(resp.Error, "GH_BROWSER") || strings.Contains(resp.Error, "GH_DEBUG") {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestRunServerCommandEnv(t *testing.T) {
+	var stdout bytes.Buffer
+	_, err := runServerCommand(context.Background(), "sh", []string{"-c", "echo $GH_REPO"}, "", "", map[string]string{"GH_REPO": "owner/repo"}, execLimits{}, &stdout, io.Discard)
+	if err != nil {
+		t.Fatalf("runServerCommand failed: %v", err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "owner/repo" {
+		t.Errorf("GH_REPO = %q, want owner/repo", got)
+	}
+}
+
+func TestBindSocketHealsStaleSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "csd.socket")
+
+	// A socket file nobody listens on, as left by a crashed server
+	stale, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	var logs bytes.Buffer
+	server := newServer(socketPath, log.New(&logs, "", 0))
+	listener, err := server.bindSocket()
+	if err != nil {
+		t.Fatalf("bindSocket() failed: %v", err)
+	}
+	defer listener.Close()
+	if !strings.Contains(logs.String(), "healing stale socket") {
+		t.Errorf("healing wasn't logged: %q", logs.String())
+	}
+	if !server.ownsSocket() {
+		t.Error("ownsSocket() = false after binding")
+	}
+
+	// A live server is left alone
+	other := newServer(socketPath, log.New(io.Discard, "", 0))
+	if _, err := other.bindSocket(); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Errorf("bindSocket() with a live server = %v, want already running", err)
+	}
+	if !server.ownsSocket() {
+		t.Error("the live server's socket was replaced")
+	}
+}
+
+func TestCheckSocketRecreatesDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".csd")
+	socketPath := filepath.Join(dir, "csd.socket")
+	if err := os.Mkdir(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	server := newServer(socketPath, log.New(io.Discard, "", 0))
+	listener, err := server.bindSocket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	os.RemoveAll(dir)
+	if err := server.checkSocket(); err != nil {
+		t.Fatalf("checkSocket() after removing the directory = %v", err)
+	}
+	defer server.httpServer.Close()
+	if !server.ownsSocket() || !isServerRunning(socketPath) {
+		t.Fatal("socket wasn't bound again")
+	}
+}
+
+func TestCheckSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "csd.socket")
+	server := newServer(socketPath, log.New(io.Discard, "", 0))
+	listener, err := server.bindSocket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	if err := server.checkSocket(); err != nil {
+		t.Fatalf("checkSocket() on an intact socket = %v", err)
+	}
+
+	os.Remove(socketPath)
+	if err := server.checkSocket(); err != nil {
+		t.Fatalf("checkSocket() after removal = %v", err)
+	}
+	if !server.ownsSocket() || !isServerRunning(socketPath) {
+		t.Fatal("socket wasn't bound again")
+	}
+	defer server.httpServer.Close()
+
+	// Another server took the path over
+	os.Remove(socketPath)
+	other, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if err := server.checkSocket(); err == nil || !strings.Contains(err.Error(), "took over") {
+		t.Errorf("checkSocket() with another server = %v, want took over", err)
+	}
+
+	server.removeSocket()
+	if _, err := os.Stat(socketPath); err != nil {
+		t.Error("removeSocket() removed another server's socket")
+	}
+}
+
+func TestHandleExecTimeoutKeepsPartialOutput(t *testing.T) {
+	// Only gh may run, so stand in for it with a script that hangs
+	gh := filepath.Join(t.TempDir(), "gh")
+	script := "#!/bin/sh\necho partial output\necho warning >&2\nexec sleep 10\n"
+	if err := os.WriteFile(gh, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	server := newServer("", log.New(io.Discard, "", 0))
+	body, _ := json.Marshal(protocol.ExecRequest{Type: "exec", Command: []string{gh}, Timeout: 1})
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+
+	var resp protocol.ExecResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Timeout || resp.ExitCode != execTimeoutExitCode || !strings.Contains(resp.Error, "timed out") {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if resp.Stdout != "partial output\n" || resp.Stderr != "warning\n" {
+		t.Fatalf("partial output lost: stdout=%q stderr=%q", resp.Stdout, resp.Stderr)
+	}
+}
+
+func TestHandleExecStreamRetries(t *testing.T) {
+	// A stand-in gh that times out on its first run only
+	dir := t.TempDir()
+	gh := filepath.Join(dir, "gh")
+	script := "#!/bin/sh\nif [ ! -e " + dir + "/ran ]; then touch " + dir + "/ran; echo partial; echo 'request timed out' >&2; exit 1; fi\necho ok\n"
+	if err := os.WriteFile(gh, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	server := newServer("", log.New(io.Discard, "", 0))
+	server.ExecRetries = 1
+	server.RetrySubcommands = []string{"pr view"}
+	body, _ := json.Marshal(protocol.ExecRequest{Type: "exec-stream", Command: []string{gh, "pr", "view"}})
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+
+	var stdout, stderr bytes.Buffer
+	exitCode, err := readStreamFrames(rec.Body, &stdout, &stderr)
+	if err != nil || exitCode != 0 {
+		t.Fatalf("readStreamFrames = %d, %v; stderr %q", exitCode, err, stderr.String())
+	}
+	if stdout.String() != "ok\n" || stderr.Len() != 0 {
+		t.Fatalf("want only the retry's output, got stdout=%q stderr=%q", stdout.String(), stderr.String())
+	}
+}
+
+func TestHandleExecPipeBinary(t *testing.T) {
+	// Only gh may run, so stand in for it with one that echoes stdin
+	gh := filepath.Join(t.TempDir(), "gh")
+	if err := os.WriteFile(gh, []byte("#!/bin/sh\nexec cat\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	payload := make([]byte, 256)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	for _, reqType := range []string{"exec", "exec-stream"} {
+		t.Run(reqType, func(t *testing.T) {
+			server := newServer("", log.New(io.Discard, "", 0))
+			body, _ := json.Marshal(protocol.ExecRequest{Type: reqType, Command: []string{gh}, Pipe: true, StdinRaw: payload})
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+
+			var stdout bytes.Buffer
+			if reqType == "exec" {
+				var resp protocol.ExecResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if resp.Stdout != "" {
+					t.Errorf("Stdout = %q, want the output in StdoutRaw only", resp.Stdout)
+				}
+				stdout.Write(resp.StdoutRaw)
+			} else if _, err := readStreamFrames(rec.Body, &stdout, io.Discard); err != nil {
+				t.Fatalf("readStreamFrames: %v", err)
+			}
+			if !bytes.Equal(stdout.Bytes(), payload) {
+				t.Fatalf("output corrupted: got %d bytes %q", stdout.Len(), stdout.Bytes())
+			}
+		})
+	}
+}
+
+func TestStreamWriterSplitRune(t *testing.T) {
+	var buf bytes.Buffer
+	frames := &frameWriter{w: &buf}
+	stdout := &streamWriter{frames: frames, stream: "stdout"}
+	for _, b := range []byte("é!") {
+		if n, err := stdout.Write([]byte{b}); n != 1 || err != nil {
+			t.Fatalf("Write() = %d, %v", n, err)
+		}
+	}
+	// A character cut off by the end of the output is still sent, as U+FFFD
+	stdout.Write([]byte("é")[:1])
+	stdout.Close()
+	frames.write(&protocol.StreamFrame{Stream: "exit"})
+
+	var out bytes.Buffer
+	if _, err := readStreamFrames(&buf, &out, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := out.String(), "é!\uFFFD"; got != want {
+		t.Errorf("decoded output = %q, want %q", got, want)
+	}
+}
+
+func TestStatusResponseDiagnostics(t *testing.T) {
+	server := newServer("", log.New(io.Discard, "", 0))
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"type":"status"}`)))
+
+	var status protocol.StatusResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	if status.Status != "running" || status.PID != os.Getpid() || status.Version == "" || status.StartedAt.IsZero() {
+		t.Errorf("status = %+v, want running with version, pid and start time", status)
+	}
+	if !reflect.DeepEqual(status.AllowedCommands, allowedCommands) {
+		t.Errorf("AllowedCommands = %v, want %v", status.AllowedCommands, allowedCommands)
+	}
+}
+
+func TestWriteServerDetails(t *testing.T) {
+	started := time.Date(2026, 1, 2, 10, 0, 0, 0, time.Local)
+	status := protocol.StatusResponse{Status: "running", Version: "v1.2.3", PID: 42, StartedAt: started, AllowedCommands: []string{"gh"}}
+
+	var buf strings.Builder
+	writeServerDetails(&buf, status, started.Add(90*time.Minute))
+	want := `  Version:          v1.2.3
+  PID:              42
+  Uptime:           1h30m0s (since 2026-01-02 10:00:00)
+  Allowed commands: gh
+`
+	if buf.String() != want {
+		t.Errorf("details =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	// An older server only sends its status
+	buf.Reset()
+	writeServerDetails(&buf, protocol.StatusResponse{Status: "running"}, started)
+	if buf.String() != "" {
+		t.Errorf("details for an older server = %q, want none", buf.String())
+	}
+}
+
+func TestServerMode(t *testing.T) {
+	for _, tt := range []struct {
+		serverPID, servicePID int
+		want                  string
+	}{
+		{100, 100, "running (managed by service)"},
+		{100, 200, "running (foreground)"},
+		{100, 0, "running (foreground)"},
+		{0, 0, "running (foreground)"},
+	} {
+		if got := serverMode("running", tt.serverPID, tt.servicePID); got != tt.want {
+			t.Errorf("serverMode(%d, %d) = %q, want %q", tt.serverPID, tt.servicePID, got, tt.want)
+		}
+	}
+}
+
+func TestGetServerSocketPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(socketEnvVar, "")
+	defer func() { serverSocket = "" }()
+
+	if got, want := GetServerSocketPath(), filepath.Join(home, ".csd", "csd.socket"); got != want {
+		t.Errorf("default socket = %q, want %q", got, want)
+	}
+	t.Setenv(socketEnvVar, "/tmp/env.sock")
+	if got := GetServerSocketPath(); got != "/tmp/env.sock" {
+		t.Errorf("socket with $%s = %q, want /tmp/env.sock", socketEnvVar, got)
+	}
+	serverSocket = "/tmp/flag.sock"
+	if got := GetServerSocketPath(); got != "/tmp/flag.sock" {
+		t.Errorf("socket with --socket = %q, want the flag to win", got)
+	}
+}
+
+func TestServerServiceCommands(t *testing.T) {
+	// The server help points at 'gh csd server install', so it has to exist
+	for _, name := range []string{"install", "uninstall"} {
+		cmd, _, err := rootCmd.Find([]string{"server", name})
+		if err != nil || cmd.Parent() != serverCmd || cmd.Name() != name {
+			t.Errorf("Find(server %s) = %v, %v; want the server subcommand", name, cmd, err)
+		}
+	}
+	for _, cmd := range []*cobra.Command{serverStartCmd, serverStopCmd} {
+		if cmd.Flags().Lookup("service") == nil {
+			t.Errorf("server %s has no --service flag", cmd.Name())
+		}
+	}
+}
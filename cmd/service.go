@@ -5,6 +5,9 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
 	"time"
 
 	"github.com/brasic/launchd"
@@ -14,8 +17,9 @@ import (
 
 var serviceCmd = &cobra.Command{
 	Use:   "service",
-	Short: "Manage gh-csd as a launchd system service",
-	Long: `Manage gh-csd as a launchd system service.
+	Short: "Manage gh-csd as a system service",
+	Long: `Manage gh-csd as a system service: a launchd LaunchAgent on macOS or a
+systemd user service on Linux.
 
 This allows the gh-csd server to start automatically on boot.
 
@@ -25,43 +29,37 @@ Usage:
   gh csd service start      Start the service
   gh csd service stop       Stop the service
   gh csd service status     Show service status`,
-	Run: func(cmd *cobra.Command, args []string) {
-		svc := csdService()
-		fmt.Println(prettyStatus(svc))
-	},
+	Run: runServiceStatus,
 }
 
 var serviceInstallCmd = &cobra.Command{
 	Use:   "install",
-	Short: "Install gh-csd to run on boot as a macOS LaunchAgent",
+	Short: "Install gh-csd to run on boot (LaunchAgent or systemd user service)",
 	Run:   runServiceInstall,
 }
 
 var serviceUninstallCmd = &cobra.Command{
 	Use:   "uninstall",
-	Short: "Remove a previously installed LaunchAgent",
+	Short: "Remove a previously installed service",
 	Run:   runServiceUninstall,
 }
 
 var serviceStartCmd = &cobra.Command{
 	Use:   "start",
-	Short: "Start the launchd service",
+	Short: "Start the service",
 	Run:   runServiceStart,
 }
 
 var serviceStopCmd = &cobra.Command{
 	Use:   "stop",
-	Short: "Stop the launchd service",
+	Short: "Stop the service",
 	Run:   runServiceStop,
 }
 
 var serviceStatusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show the service status",
-	Run: func(cmd *cobra.Command, args []string) {
-		svc := csdService()
-		fmt.Println(prettyStatus(svc))
-	},
+	Run:   runServiceStatus,
 }
 
 func init() {
@@ -73,6 +71,76 @@ func init() {
 	rootCmd.AddCommand(serviceCmd)
 }
 
+// dispatchService runs the darwin or linux implementation of a service
+// subcommand, exiting with an error on other platforms.
+func dispatchService(darwin, linux func()) {
+	switch runtime.GOOS {
+	case "darwin":
+		darwin()
+	case "linux":
+		linux()
+	default:
+		fmt.Fprintf(os.Stderr, "gh csd service is not supported on %s (supported: macOS launchd, Linux systemd)\n", runtime.GOOS)
+		os.Exit(1)
+	}
+}
+
+func runServiceInstall(cmd *cobra.Command, args []string) {
+	dispatchService(runLaunchdInstall, runSystemdInstall)
+}
+
+func runServiceUninstall(cmd *cobra.Command, args []string) {
+	dispatchService(runLaunchdUninstall, runSystemdUninstall)
+}
+
+func runServiceStart(cmd *cobra.Command, args []string) {
+	dispatchService(runLaunchdStart, runSystemdStart)
+}
+
+func runServiceStop(cmd *cobra.Command, args []string) {
+	dispatchService(runLaunchdStop, runSystemdStop)
+}
+
+func runServiceStatus(cmd *cobra.Command, args []string) {
+	dispatchService(func() {
+		fmt.Println(prettyStatus(csdService()))
+	}, runSystemdStatus)
+}
+
+// serviceRunState reports whether the launchd or systemd service is running
+// and the PID of its process, 0 if unknown.
+func serviceRunState() (running bool, pid int) {
+	switch runtime.GOOS {
+	case "darwin":
+		svc := csdService()
+		if !svc.RunState().Is(state.Running) {
+			return false, 0
+		}
+		output, _ := svc.Print()
+		return true, parseLaunchctlPID(output)
+	case "linux":
+		if systemdQuery("is-active") != "active" {
+			return false, 0
+		}
+		return true, systemdMainPID()
+	default:
+		return false, 0
+	}
+}
+
+var launchctlPIDPattern = regexp.MustCompile(`(?m)^\s*pid = (\d+)$`)
+
+// parseLaunchctlPID extracts the PID from 'launchctl print' output, 0 if
+// the service has no process.
+func parseLaunchctlPID(output []byte) int {
+	match := launchctlPIDPattern.FindSubmatch(output)
+	if match == nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(string(match[1]))
+	return pid
+}
+
 // csdService returns a launchd.Service for gh-csd.
 func csdService() *launchd.Service {
 	return launchd.ForRunningProgram("com.github.luanzeba.gh-csd", []string{"server", "start"})
@@ -90,7 +158,7 @@ func prettyStatus(svc *launchd.Service) string {
 	)
 }
 
-func runServiceInstall(cmd *cobra.Command, args []string) {
+func runLaunchdInstall() {
 	logger := log.New(os.Stdout, "", 0)
 	svc := csdService()
 
@@ -116,7 +184,7 @@ func runServiceInstall(cmd *cobra.Command, args []string) {
 	logger.Printf("Uninstall using: %s service uninstall\n", currentExecutableName())
 }
 
-func runServiceUninstall(cmd *cobra.Command, args []string) {
+func runLaunchdUninstall() {
 	logger := log.New(os.Stdout, "", 0)
 	svc := csdService()
 
@@ -133,7 +201,7 @@ func runServiceUninstall(cmd *cobra.Command, args []string) {
 	logger.Println("Service uninstalled.")
 }
 
-func runServiceStart(cmd *cobra.Command, args []string) {
+func runLaunchdStart() {
 	logger := log.New(os.Stdout, "", 0)
 	svc := csdService()
 
@@ -161,7 +229,7 @@ func runServiceStart(cmd *cobra.Command, args []string) {
 	logger.Println("Service started.")
 }
 
-func runServiceStop(cmd *cobra.Command, args []string) {
+func runLaunchdStop() {
 	logger := log.New(os.Stdout, "", 0)
 	svc := csdService()
 
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// systemdUnitName is the systemd user unit that runs the gh-csd server.
+const systemdUnitName = "gh-csd.service"
+
+// systemdUnitPath returns ~/.config/systemd/user/gh-csd.service, honoring
+// XDG_CONFIG_HOME.
+func systemdUnitPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "systemd", "user", systemdUnitName), nil
+}
+
+// systemdUnit returns the unit file contents for running exe as the server.
+func systemdUnit(exe string) string {
+	return fmt.Sprintf(`[Unit]
+Description=gh-csd local command server
+
+[Service]
+ExecStart=%q server start
+ExecReload=/bin/kill -HUP $MAINPID
+Restart=on-failure
+
+[Install]
+WantedBy=default.target
+`, exe)
+}
+
+// systemctl runs 'systemctl --user' with args, returning its combined output
+// in the error on failure.
+func systemctl(args ...string) error {
+	output, err := exec.Command("systemctl", append([]string{"--user"}, args...)...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("systemctl --user %s failed: %w\n%s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// systemdUnitInstalled reports whether the unit file exists.
+func systemdUnitInstalled() bool {
+	path, err := systemdUnitPath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// systemdQuery returns the output of a systemctl --user query such as
+// is-active, which exits non-zero for negative answers.
+func systemdQuery(query string) string {
+	output, _ := exec.Command("systemctl", "--user", query, systemdUnitName).Output()
+	if state := strings.TrimSpace(string(output)); state != "" {
+		return state
+	}
+	return "unknown"
+}
+
+// systemdMainPID returns the PID of the service's process, 0 if it has
+// none.
+func systemdMainPID() int {
+	output, _ := exec.Command("systemctl", "--user", "show", "-p", "MainPID", "--value", systemdUnitName).Output()
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(output)))
+	return pid
+}
+
+func runSystemdInstall() {
+	logger := log.New(os.Stdout, "", 0)
+
+	exe, err := os.Executable()
+	if err != nil {
+		logger.Printf("Problem locating executable: %v\n", err)
+		os.Exit(1)
+	}
+
+	path, err := systemdUnitPath()
+	if err != nil {
+		logger.Printf("Problem locating systemd user directory: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		logger.Printf("Problem creating %s: %v\n", filepath.Dir(path), err)
+		os.Exit(1)
+	}
+
+	// Install the unit to run `gh-csd server start` at login
+	if err := os.WriteFile(path, []byte(systemdUnit(exe)), 0644); err != nil {
+		logger.Printf("Problem installing: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := systemctl("daemon-reload"); err != nil {
+		logger.Printf("Problem reloading systemd: %v\n", err)
+		os.Exit(1)
+	}
+	if err := systemctl("enable", "--now", systemdUnitName); err != nil {
+		logger.Printf("Problem starting: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger.Printf("Service installed and started (%s).\n", path)
+	logger.Printf("The server will now start automatically on login.\n")
+	logger.Printf("Uninstall using: %s service uninstall\n", currentExecutableName())
+}
+
+func runSystemdUninstall() {
+	logger := log.New(os.Stdout, "", 0)
+
+	if !systemdUnitInstalled() {
+		logger.Println("Service is not installed.")
+		return
+	}
+
+	if err := systemctl("disable", "--now", systemdUnitName); err != nil {
+		logger.Printf("Problem uninstalling: %v\n", err)
+		os.Exit(1)
+	}
+
+	path, err := systemdUnitPath()
+	if err == nil {
+		err = os.Remove(path)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Printf("Problem removing unit file: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := systemctl("daemon-reload"); err != nil {
+		logger.Printf("Warning: %v\n", err)
+	}
+
+	logger.Println("Service uninstalled.")
+}
+
+func runSystemdStart() {
+	logger := log.New(os.Stdout, "", 0)
+
+	if !systemdUnitInstalled() {
+		logger.Println("Service is not installed. Run 'gh csd service install' first.")
+		os.Exit(1)
+	}
+
+	if systemdQuery("is-active") == "active" {
+		logger.Println("Service is already running.")
+		return
+	}
+
+	if err := systemctl("start", systemdUnitName); err != nil {
+		logger.Printf("Problem starting: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger.Println("Service started.")
+}
+
+func runSystemdStop() {
+	logger := log.New(os.Stdout, "", 0)
+
+	if systemdQuery("is-active") != "active" {
+		logger.Println("Service is not running.")
+		return
+	}
+
+	if err := systemctl("stop", systemdUnitName); err != nil {
+		logger.Printf("Problem stopping: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger.Println("Service stopped.")
+}
+
+func runSystemdStatus() {
+	installState := "not installed"
+	if systemdUnitInstalled() {
+		installState = "installed (" + systemdQuery("is-enabled") + ")"
+	}
+
+	fmt.Printf("Service: %s (systemd user)\n  Install state: %s\n  Run state:     %s\n",
+		systemdUnitName,
+		installState,
+		systemdQuery("is-active"),
+	)
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSystemdUnit(t *testing.T) {
+	unit := systemdUnit("/home/me/.local/share/gh/extensions/gh-csd/gh-csd")
+
+	for _, want := range []string{
+		`ExecStart="/home/me/.local/share/gh/extensions/gh-csd/gh-csd" server start`,
+		"Restart=on-failure",
+		"WantedBy=default.target",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("unit missing %q:\n%s", want, unit)
+		}
+	}
+}
+
+func TestSystemdUnitPath(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG_CONFIG_HOME is only honored on Linux")
+	}
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	path, err := systemdUnitPath()
+	if err != nil {
+		t.Fatalf("systemdUnitPath failed: %v", err)
+	}
+	if want := filepath.Join(dir, "systemd", "user", "gh-csd.service"); path != want {
+		t.Fatalf("systemdUnitPath = %q, want %q", path, want)
+	}
+}
@@ -0,0 +1,19 @@
+package cmd
+
+import "testing"
+
+func TestParseLaunchctlPID(t *testing.T) {
+	output := []byte(`gui/501/com.github.luanzeba.gh-csd = {
+	active count = 1
+	state = running
+	program = /opt/homebrew/bin/gh-csd
+	pid = 4242
+	last exit code = (never exited)
+}`)
+	if pid := parseLaunchctlPID(output); pid != 4242 {
+		t.Errorf("parseLaunchctlPID() = %d, want 4242", pid)
+	}
+	if pid := parseLaunchctlPID([]byte("state = not running\n")); pid != 0 {
+		t.Errorf("parseLaunchctlPID() without a pid = %d, want 0", pid)
+	}
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/luanzeba/gh-csd/internal/config"
+	"github.com/luanzeba/gh-csd/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var restartSessionAll bool
+
+var restartSessionCmd = &cobra.Command{
+	Use:   "restart-session [codespace-name]",
+	Short: "Make a running 'gh csd ssh --retry' session reconnect now",
+	Long: `Make a running 'gh csd ssh --retry' session drop its connection and
+reconnect immediately, without waiting for the retry delay or counting it
+as a failed attempt. Useful when a connection is sluggish but hasn't
+dropped.
+
+Run it from another terminal. By default it restarts the sessions connected
+to the current codespace; use --all for every session.
+
+Each retry session records its pid in ~/.csd/sessions. Sending SIGUSR1 to
+that pid (kill -USR1 <pid>) does the same thing. Sessions without --retry
+aren't affected.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runRestartSession,
+}
+
+func init() {
+	restartSessionCmd.Flags().BoolVar(&restartSessionAll, "all", false, "Restart every retry session")
+	rootCmd.AddCommand(restartSessionCmd)
+}
+
+func getSessionsDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".csd", "sessions")
+}
+
+// registerSession records that this process runs a retry session for
+// name, so restart-session can find it. The returned func removes the
+// record.
+func registerSession(name string) (func(), error) {
+	dir := getSessionsDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, strconv.Itoa(os.Getpid()))
+	if err := os.WriteFile(path, []byte(name+"\n"), 0600); err != nil {
+		return nil, err
+	}
+	return func() { os.Remove(path) }, nil
+}
+
+// activeSession is a retry session found in the sessions directory.
+type activeSession struct {
+	pid  int
+	name string
+}
+
+// listSessions returns the live sessions in dir, removing records left
+// behind by processes that no longer exist.
+func listSessions(dir string) ([]activeSession, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var sessions []activeSession
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if !processAlive(pid) {
+			os.Remove(path)
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, activeSession{pid: pid, name: strings.TrimSpace(string(data))})
+	}
+	return sessions, nil
+}
+
+func runRestartSession(cmd *cobra.Command, args []string) error {
+	name := ""
+	if !restartSessionAll {
+		if len(args) > 0 {
+			name = args[0]
+		} else {
+			cfg, err := config.Load()
+			if err != nil {
+				cfg = config.DefaultConfig()
+			}
+			name, err = getSelectedCodespace(cfg)
+			if err != nil {
+				if errors.Is(err, state.ErrNoCodespace) {
+					return fmt.Errorf("no codespace selected (name one, or use --all)")
+				}
+				return err
+			}
+		}
+	}
+
+	sessions, err := listSessions(getSessionsDir())
+	if err != nil {
+		return err
+	}
+
+	restarted := 0
+	for _, session := range sessions {
+		if name != "" && session.name != name {
+			continue
+		}
+		if err := sendReconnect(session.pid); err != nil {
+			return fmt.Errorf("failed to signal session %d: %w", session.pid, err)
+		}
+		fmt.Printf("Restarting session %d (%s)\n", session.pid, session.name)
+		restarted++
+	}
+
+	if restarted == 0 {
+		if name != "" {
+			return fmt.Errorf("no 'gh csd ssh --retry' session running for %s", name)
+		}
+		return fmt.Errorf("no 'gh csd ssh --retry' sessions running")
+	}
+	return nil
+}
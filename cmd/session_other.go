@@ -0,0 +1,16 @@
+//go:build !unix
+
+package cmd
+
+import (
+	"errors"
+	"os"
+)
+
+// notifyReconnect is a no-op on platforms without SIGUSR1.
+func notifyReconnect(c chan<- os.Signal) {}
+
+// sendReconnect is unsupported on platforms without SIGUSR1.
+func sendReconnect(pid int) error {
+	return errors.New("restarting sessions is not supported on this platform")
+}
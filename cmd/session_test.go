@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListSessions(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	unregister, err := registerSession("my-cs")
+	if err != nil {
+		t.Fatalf("registerSession failed: %v", err)
+	}
+
+	dir := getSessionsDir()
+	// A pid far above any real pid_max stands in for a dead process
+	stale := filepath.Join(dir, "999999999")
+	if err := os.WriteFile(stale, []byte("old-cs\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := listSessions(dir)
+	if err != nil {
+		t.Fatalf("listSessions failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].pid != os.Getpid() || sessions[0].name != "my-cs" {
+		t.Errorf("listSessions() = %+v, want only this process for my-cs", sessions)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("stale session record was not removed")
+	}
+
+	unregister()
+	if sessions, _ := listSessions(dir); len(sessions) != 0 {
+		t.Errorf("listSessions() after unregister = %+v, want none", sessions)
+	}
+}
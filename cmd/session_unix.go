@@ -0,0 +1,19 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyReconnect relays the restart-session signal (SIGUSR1) to c.
+func notifyReconnect(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGUSR1)
+}
+
+// sendReconnect asks the retry session running as pid to reconnect.
+func sendReconnect(pid int) error {
+	return syscall.Kill(pid, syscall.SIGUSR1)
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/luanzeba/gh-csd/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var setupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Interactively configure the most common settings",
+	Long: `Walk through the most common settings and save them to the config file.
+
+Prompts for the default machine, idle timeout, SSH retry, terminfo copying,
+and terminal tab title, then lets you add repository aliases. Press Enter
+to keep the value shown in brackets (your current setting, or the built-in
+default).
+
+The config is rewritten as plain YAML, so comments in an existing file are
+not kept. Use 'gh csd config --edit' for everything else.`,
+	Args: cobra.NoArgs,
+	RunE: runSetup,
+}
+
+func init() {
+	rootCmd.AddCommand(setupCmd)
+}
+
+func runSetup(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load existing config: %w", err)
+	}
+
+	if err := runSetupWizard(bufio.NewReader(os.Stdin), os.Stdout, cfg); err != nil {
+		return err
+	}
+
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	path, _ := config.Path()
+	fmt.Printf("\nSaved config to %s\n", path)
+	return nil
+}
+
+// runSetupWizard prompts on w, reading answers from r, and updates cfg.
+func runSetupWizard(r *bufio.Reader, w io.Writer, cfg *config.Config) error {
+	fmt.Fprintln(w, "Defaults for new codespaces")
+	cfg.Defaults.Machine = promptString(r, w, "Machine type", cfg.Defaults.Machine)
+	cfg.Defaults.IdleTimeout = promptInt(r, w, "Idle timeout in minutes (max 240)", cfg.Defaults.IdleTimeout, 1, 240)
+	cfg.Defaults.SSHRetry = promptBool(r, w, "Reconnect SSH automatically", cfg.Defaults.SSHRetry)
+	copyTerminfo := promptBool(r, w, "Copy your terminal's terminfo after creating", cfg.GetEffectiveCopyTerminfo())
+	cfg.Defaults.CopyTerminfo = &copyTerminfo
+
+	fmt.Fprintln(w, "\nTerminal")
+	cfg.Terminal.SetTabTitle = promptBool(r, w, "Set the tab title when connecting", cfg.Terminal.SetTabTitle)
+	if cfg.Terminal.SetTabTitle {
+		cfg.Terminal.TitleFormat = promptString(r, w, "Tab title format", cfg.Terminal.TitleFormat)
+	}
+
+	fmt.Fprintln(w, "\nRepository aliases (leave the repository empty to finish)")
+	for {
+		repo := promptString(r, w, "Repository (owner/repo)", "")
+		if repo == "" {
+			break
+		}
+		if !strings.Contains(repo, "/") {
+			fmt.Fprintln(w, "  Expected owner/repo")
+			continue
+		}
+
+		if cfg.Repos == nil {
+			cfg.Repos = make(map[string]config.Repo)
+		}
+		repoCfg := cfg.Repos[repo]
+		repoCfg.Alias = promptString(r, w, "  Alias", repoCfg.Alias)
+		cfg.Repos[repo] = repoCfg
+	}
+	return nil
+}
+
+// promptString asks for a value, returning def when the answer is empty.
+func promptString(r *bufio.Reader, w io.Writer, label, def string) string {
+	if def != "" {
+		fmt.Fprintf(w, "%s [%s]: ", label, def)
+	} else {
+		fmt.Fprintf(w, "%s: ", label)
+	}
+	answer, _ := r.ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return def
+	}
+	return answer
+}
+
+// promptInt asks for a number between low and high, asking again until
+// the answer is one.
+func promptInt(r *bufio.Reader, w io.Writer, label string, def, low, high int) int {
+	for {
+		answer := promptString(r, w, label, strconv.Itoa(def))
+		n, err := strconv.Atoi(answer)
+		if err == nil && n >= low && n <= high {
+			return n
+		}
+		fmt.Fprintf(w, "  Enter a whole number from %d to %d\n", low, high)
+		if _, err := r.Peek(1); err != nil {
+			// No more input; don't loop forever
+			return def
+		}
+	}
+}
+
+// promptBool asks a yes/no question, returning def on an empty answer.
+func promptBool(r *bufio.Reader, w io.Writer, label string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	fmt.Fprintf(w, "%s [%s]: ", label, hint)
+	answer, _ := r.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	}
+	return def
+}
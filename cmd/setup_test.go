@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/luanzeba/gh-csd/internal/config"
+)
+
+func TestRunSetupWizard(t *testing.T) {
+	answers := strings.Join([]string{
+		"basicLinux32gb", // machine
+		"abc",            // idle timeout (invalid, asked again)
+		"300",            // idle timeout (over the maximum, asked again)
+		"60",             // idle timeout
+		"y",              // ssh retry
+		"",               // copy terminfo (keep default)
+		"n",              // tab title
+		"luanzeba/gh-csd",
+		"csd",
+		"",
+	}, "\n") + "\n"
+
+	cfg := config.DefaultConfig()
+	if err := runSetupWizard(bufio.NewReader(strings.NewReader(answers)), io.Discard, cfg); err != nil {
+		t.Fatalf("runSetupWizard failed: %v", err)
+	}
+
+	if cfg.Defaults.Machine != "basicLinux32gb" {
+		t.Errorf("Machine = %q, want basicLinux32gb", cfg.Defaults.Machine)
+	}
+	if cfg.Defaults.IdleTimeout != 60 {
+		t.Errorf("IdleTimeout = %d, want 60", cfg.Defaults.IdleTimeout)
+	}
+	if !cfg.Defaults.SSHRetry {
+		t.Error("SSHRetry = false, want true")
+	}
+	if !cfg.GetEffectiveCopyTerminfo() {
+		t.Error("CopyTerminfo changed from its default")
+	}
+	if cfg.Terminal.SetTabTitle {
+		t.Error("SetTabTitle = true, want false")
+	}
+	if got := cfg.Repos["luanzeba/gh-csd"].Alias; got != "csd" {
+		t.Errorf("alias = %q, want csd", got)
+	}
+	if got := cfg.Repos["github/github"].Alias; got != "gh" {
+		t.Errorf("existing alias changed to %q", got)
+	}
+}
@@ -1,21 +1,29 @@
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/luanzeba/gh-csd/internal/config"
 	"github.com/luanzeba/gh-csd/internal/gh"
 	"github.com/luanzeba/gh-csd/internal/state"
+	"github.com/luanzeba/gh-csd/internal/stats"
 	"github.com/luanzeba/gh-csd/internal/terminal"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 var (
@@ -24,6 +32,17 @@ var (
 	sshMaxRetries int
 	sshNoRdm      bool
 	sshCodespace  string
+	sshNew        bool
+	sshNoClear    bool
+	sshProfile    string
+	sshStats      bool
+
+	sshRetryBackoff bool
+
+	sshWriteForwards string
+	sshCommand       string
+
+	sshRemoteKeepalive bool
 )
 
 var sshCmd = &cobra.Command{
@@ -32,7 +51,36 @@ var sshCmd = &cobra.Command{
 	Long: `SSH into a codespace with socket forwarding for rdm and local command execution.
 
 By default, connects to the currently selected codespace.
-Use --retry to automatically reconnect on disconnect.
+Use --new to connect to the most recently created codespace instead.
+Use --retry to automatically reconnect on disconnect. Each reconnect prints
+a timestamped banner so earlier output stays distinguishable. Run
+'gh csd restart-session' from another terminal to make a retry session
+reconnect right away.
+If the codespace has stopped by the time it reconnects, --retry asks
+whether to start it again (or starts it right away with ssh.auto_start).
+Use --retry-backoff (or ssh.retry_backoff) on flaky networks to double the
+delay after each failed reconnect, starting at --retry-delay and capped at
+ssh.retry_max_delay (default 60) seconds. A connection that stays up for a
+minute resets it.
+Use --no-clear to keep remote programs from wiping your scrollback.
+Use --sshd-keepalive-from-remote (or ssh.remote_keepalive) to keep the
+codespace from idling out during quiet stretches of a session. It runs a
+small loop in the codespace over a second connection that only lives as
+long as the session, and gives up after ssh.remote_keepalive_minutes
+(default 240) so a forgotten terminal doesn't keep the codespace running.
+Use --stats to print how long you were connected and how often the session
+reconnected when it ends (or set ssh.stats in config).
+Use --profile to apply a named bundle of these options from 'ssh_profiles'
+in config. Flags given explicitly still override the profile.
+Use --write-forwards FILE to record the session's socket forwards as JSON
+for other tools, such as an editor extension. The file is written when the
+session starts and removed when it ends, reconnects included. It lists the
+forwards requested from ssh, which may not all be working yet.
+Use --command (-C) to run one command in the codespace and exit with its
+exit code instead of opening a shell. The sockets are still forwarded, so
+the command can use 'gh csd local', and it is never retried. Status lines
+go to stderr and the tab title is left alone, so stdout only has the
+command's output.
 
 The --retry flag can be set as a default for specific repos in config:
 
@@ -56,16 +104,29 @@ func init() {
 	sshCmd.Flags().BoolVar(&sshRetry, "retry", false, "Automatically reconnect on disconnect")
 	sshCmd.Flags().IntVar(&sshRetryDelay, "retry-delay", 3, "Seconds to wait before reconnecting")
 	sshCmd.Flags().IntVar(&sshMaxRetries, "max-retries", 0, "Maximum reconnection attempts (0 = unlimited)")
+	sshCmd.Flags().BoolVar(&sshRetryBackoff, "retry-backoff", false, "Double the retry delay after each failed reconnect, up to ssh.retry_max_delay")
 	sshCmd.Flags().BoolVar(&sshNoRdm, "no-rdm", false, "Disable rdm socket forwarding")
 	sshCmd.Flags().StringVarP(&sshCodespace, "codespace", "c", "", "Codespace name (overrides current selection)")
+	sshCmd.Flags().BoolVar(&sshNew, "new", false, "Connect to the most recently created codespace")
+	sshCmd.Flags().BoolVar(&sshNoClear, "no-clear", false, "Strip remote escape sequences that clear terminal scrollback")
+	sshCmd.Flags().BoolVar(&sshStats, "stats", false, "Print a session summary (duration, reconnects) on exit")
+	sshCmd.Flags().BoolVar(&sshRemoteKeepalive, "sshd-keepalive-from-remote", false, "Keep the codespace from idling out while the session is open")
+	sshCmd.Flags().StringVar(&sshProfile, "profile", "", "Apply a named bundle of SSH options from config (ssh_profiles)")
+	sshCmd.Flags().StringVarP(&sshCommand, "command", "C", "", "Run this command in the codespace instead of a shell, then exit with its exit code")
+	sshCmd.Flags().StringVar(&sshWriteForwards, "write-forwards", "", "Write the session's socket forwards as JSON to this file while connected")
 	rootCmd.AddCommand(sshCmd)
 }
 
 func runSSH(cmd *cobra.Command, args []string) error {
-	cfg, err := config.Load()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to load config: %v\n", err)
-		cfg = config.DefaultConfig()
+	cfg := loadValidatedConfig()
+
+	var profile *config.SSHProfile
+	if sshProfile != "" {
+		profile = cfg.GetSSHProfile(sshProfile)
+		if profile == nil {
+			return fmt.Errorf("ssh profile %q not found (define it under ssh_profiles in config)", sshProfile)
+		}
+		applySSHProfile(profile, cfg, cmd.Flags().Changed)
 	}
 
 	// Determine which codespace to connect to
@@ -73,9 +134,23 @@ func runSSH(cmd *cobra.Command, args []string) error {
 	if name == "" && len(args) > 0 {
 		name = args[0]
 	}
+	if sshNew {
+		if name != "" {
+			return fmt.Errorf("--new cannot be combined with a codespace name")
+		}
+		codespaces, err := gh.ListCodespaces()
+		if err != nil {
+			return err
+		}
+		newest := newestCodespace(codespaces)
+		if newest == nil {
+			return fmt.Errorf("no codespaces found")
+		}
+		name = newest.Name
+	}
 	if name == "" {
 		var err error
-		name, err = state.Get()
+		name, err = getSelectedCodespace(cfg)
 		if err != nil {
 			if errors.Is(err, state.ErrNoCodespace) {
 				return fmt.Errorf("no codespace specified and none selected (use 'gh csd select' or provide a name)")
@@ -91,32 +166,112 @@ func runSSH(cmd *cobra.Command, args []string) error {
 	}
 
 	// Update current selection
-	if err := state.Set(name); err != nil {
+	if err := setCurrentCodespace(cs); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to update current codespace: %v\n", err)
 	}
 
-	fmt.Printf("Connecting to %s (%s @ %s)...\n", cs.Name, cs.Repository, cs.Branch)
+	fmt.Fprintf(sshStatusOutput(), "Connecting to %s (%s @ %s)...\n", cs.Name, cs.Repository, cs.DisplayBranch())
+
+	// Set terminal tab title if configured. The escape sequences would end
+	// up in the output of --command, so it leaves the terminal alone.
+	if sshCommand == "" {
+		setTabTitleForCodespace(cs)
+		reportCodespaceCwd(cfg, cs)
+	}
 
-	// Set terminal tab title if configured
-	setTabTitleForCodespace(cs)
+	if cfg.Server.RequireToken {
+		if token := readToken(getTokenPath()); token != "" {
+			if err := syncTokenToCodespace(name, token); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to copy server token to codespace: %v\n", err)
+			}
+		}
+	}
 
-	// Determine if we should use retry: flag overrides config
+	// Determine if we should use retry: flag overrides profile, which overrides config
 	useRetry := sshRetry
-	if !cmd.Flags().Changed("retry") {
-		// Use per-repo config if flag not explicitly set
+	if !cmd.Flags().Changed("retry") && (profile == nil || profile.Retry == nil) {
+		// Use per-repo config if neither flag nor profile set it
 		useRetry = cfg.GetEffectiveSSHRetry(cs.Repository)
 	}
 
+	if sshCommand != "" {
+		// A one-shot command could have had side effects, so it's never
+		// retried; pass its exit code on instead
+		err := sshOnce(name, cfg, cs.Repository)
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+			os.Exit(exitErr.ExitCode())
+		}
+		return err
+	}
+
 	if useRetry {
 		return sshWithRetry(name, cs, cfg)
 	}
 	return sshOnce(name, cfg, cs.Repository)
 }
 
+// applySSHProfile sets the ssh options from profile, skipping any whose
+// flag was given explicitly (as reported by changed).
+func applySSHProfile(profile *config.SSHProfile, cfg *config.Config, changed func(name string) bool) {
+	if profile.Retry != nil && !changed("retry") {
+		sshRetry = *profile.Retry
+	}
+	if profile.RetryDelay != nil && !changed("retry-delay") {
+		sshRetryDelay = *profile.RetryDelay
+	}
+	if profile.MaxRetries != nil && !changed("max-retries") {
+		sshMaxRetries = *profile.MaxRetries
+	}
+	if profile.NoRdm != nil && !changed("no-rdm") {
+		sshNoRdm = *profile.NoRdm
+	}
+	if profile.NoClear != nil && !changed("no-clear") {
+		sshNoClear = *profile.NoClear
+	}
+
+	// These are also set in the ssh section, which the flags are OR'd
+	// with, so the profile overrides that instead of the flags.
+	if profile.Stats != nil && !changed("stats") {
+		cfg.SSH.Stats = *profile.Stats
+	}
+	if profile.RemoteKeepalive != nil && !changed("sshd-keepalive-from-remote") {
+		cfg.SSH.RemoteKeepalive = *profile.RemoteKeepalive
+	}
+	if profile.RetryBackoff != nil && !changed("retry-backoff") {
+		cfg.SSH.RetryBackoff = *profile.RetryBackoff
+	}
+	if profile.RetryMaxDelay != nil {
+		cfg.SSH.RetryMaxDelay = *profile.RetryMaxDelay
+	}
+}
+
+// newestCodespace returns the codespace with the latest CreatedAt, or nil.
+func newestCodespace(codespaces []gh.Codespace) *gh.Codespace {
+	var newest *gh.Codespace
+	for i := range codespaces {
+		if newest == nil || codespaces[i].CreatedAt.After(newest.CreatedAt) {
+			newest = &codespaces[i]
+		}
+	}
+	return newest
+}
+
 func sshOnce(name string, cfg *config.Config, repo string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Ctrl+C reaches ssh too; outlive it so the deferred cleanup runs
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	defer runPostConnectHooks(cfg, name, repo)
+	if sshCommand == "" {
+		defer resetTabTitle(cfg)
+		defer resetReportedCwd(cfg)
+		go refreshTabTitle(ctx, name, cfg.GetEffectiveRefreshTitleSeconds())
+	}
+
 	// Start port forwarding if configured
 	var ports []int
 	if repoCfg := cfg.GetRepoConfig(repo); repoCfg != nil {
@@ -125,13 +280,101 @@ func sshOnce(name string, cfg *config.Config, repo string) error {
 	portFwdCmd := startPortForwarding(ctx, name, ports)
 	defer stopPortForwarding(portFwdCmd)
 
-	args := buildSSHArgs(name)
-	cmd := exec.Command("gh", args...)
+	keepaliveCmd := startRemoteKeepalive(ctx, name, remoteKeepaliveLimit(cfg))
+	defer stopRemoteKeepalive(keepaliveCmd)
+
+	session := &sshSession{name: name, repo: repo}
+	defer session.finish(cfg)
+
+	forwards := sessionForwards(cfg.GetEffectiveForwardSockets(repo))
+	defer writeSessionForwards(name, forwards)()
+
+	clientID, unregisterClient := registerSessionClient(name, repo, forwards)
+	defer unregisterClient()
+
+	recordStat(repo, stats.Session)
+	return session.track(func() error {
+		return runSSHCommand(ctx, name, forwards, clientID)
+	})
+}
+
+// sshSession tracks how long a gh csd ssh session was connected and how
+// often it reconnected.
+type sshSession struct {
+	name       string
+	repo       string
+	connected  time.Duration
+	reconnects int
+}
+
+// track runs one connection, adding its duration to the session.
+func (s *sshSession) track(connect func() error) error {
+	start := time.Now()
+	err := connect()
+	s.connected += time.Since(start)
+	return err
+}
+
+// finish records the connected time in the usage stats and prints the
+// summary when --stats or ssh.stats is set.
+func (s *sshSession) finish(cfg *config.Config) {
+	_ = stats.AddConnectedTime(s.repo, s.connected) // best-effort, like recordStat
+	if sshStats || cfg.SSH.Stats {
+		branch := ""
+		if info, ok := state.GetInfo(s.name); ok {
+			branch = info.Branch
+		}
+		fmt.Fprintln(sshStatusOutput(), s.summary(branch))
+	}
+}
+
+func (s *sshSession) summary(branch string) string {
+	target := s.repo
+	if branch != "" {
+		target += " @ " + branch
+	}
+	return fmt.Sprintf("Session %s (%s): connected %s, %d reconnect(s)", s.name, target, s.connected.Round(time.Second), s.reconnects)
+}
+
+// registerSessionClient registers the client ID of a session to codespace
+// name and, when the csd socket is forwarded, writes it to the codespace
+// too, since sshd drops $CSD_CLIENT unless it is configured to accept it.
+// The returned func unregisters the ID. If registration fails, the ID is
+// "" and requests from the session go unidentified.
+func registerSessionClient(name, repo string, forwards sshForwards) (string, func()) {
+	clientID, unregister, err := registerClient(name, repo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to register client with the local server: %v\n", err)
+		return "", func() {}
+	}
+	if forwards.CSD != nil {
+		if err := syncClientIDToCodespace(name, clientID); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to copy client ID to codespace: %v\n", err)
+		}
+	}
+	return clientID, unregister
+}
+
+// runSSHCommand runs a single gh cs ssh session attached to the terminal.
+// With --no-clear, remote output is filtered to preserve scrollback.
+// Cancelling ctx ends the session, ssh included, with SIGTERM so ssh
+// restores the terminal.
+func runSSHCommand(ctx context.Context, name string, forwards sshForwards, clientID string) error {
+	cmd := exec.CommandContext(ctx, "gh", buildSSHArgs(name, forwards, clientID, sshCommand)...)
+	cmd.WaitDelay = 5 * time.Second
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	return cmd.Run()
+	if !sshNoClear {
+		return runInOwnGroup(cmd)
+	}
+
+	filter := terminal.NewClearFilter(os.Stdout)
+	cmd.Stdout = filter
+	err := runInOwnGroup(cmd)
+	filter.Flush()
+	return err
 }
 
 func sshWithRetry(name string, cs *gh.Codespace, cfg *config.Config) error {
@@ -140,32 +383,108 @@ func sshWithRetry(name string, cs *gh.Codespace, cfg *config.Config) error {
 	// Handle Ctrl+C gracefully
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	defer runPostConnectHooks(cfg, name, cs.Repository)
+	defer resetTabTitle(cfg)
+	defer resetReportedCwd(cfg)
 
-	// Get ports config once
+	// Get ports and socket forwards config once
 	var ports []int
 	if repoCfg := cfg.GetRepoConfig(cs.Repository); repoCfg != nil {
 		ports = repoCfg.Ports
 	}
+	// Resolved once so every reconnect asks for the same forwards as
+	// the --write-forwards file lists
+	forwards := sessionForwards(cfg.GetEffectiveForwardSockets(cs.Repository))
+	defer writeSessionForwards(name, forwards)()
 
-	for {
-		// Refresh tab title on reconnect
+	session := &sshSession{name: name, repo: cs.Repository}
+	defer session.finish(cfg)
+
+	// 'gh csd restart-session' signals us to drop the connection and
+	// reconnect right away
+	restartChan := make(chan os.Signal, 1)
+	notifyReconnect(restartChan)
+	defer signal.Stop(restartChan)
+	if unregister, err := registerSession(name); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to register session for restart-session: %v\n", err)
+	} else {
+		defer unregister()
+	}
+
+	// One client ID covers every reconnect of this session
+	clientID, unregisterClient := registerSessionClient(name, cs.Repository, forwards)
+	defer unregisterClient()
+
+	// Consecutive failed connections, for the backoff
+	failures := 0
+	backoff := sshRetryBackoff || cfg.SSH.RetryBackoff
+	maxDelay := time.Duration(cfg.GetEffectiveRetryMaxDelay()) * time.Second
+
+	// The keepalive limit covers the whole session, not each connection
+	var keepaliveDeadline time.Time
+	if limit := remoteKeepaliveLimit(cfg); limit > 0 {
+		keepaliveDeadline = time.Now().Add(limit)
+	}
+
+	for connections := 0; ; connections++ {
+		// Refresh tab title on reconnect, with the branch as it is now
+		if connections > 0 {
+			if fresh, err := gh.GetCodespace(name); err == nil {
+				cs = fresh
+			}
+		}
 		setTabTitleForCodespace(cs)
+		reportCodespaceCwd(cfg, cs)
 
-		// Start port forwarding for this connection attempt
+		// Start port forwarding for this connection attempt, and bring back
+		// the forwards added with 'gh csd ports add' that dropped with it
 		ctx, cancel := context.WithCancel(context.Background())
 		portFwdCmd := startPortForwarding(ctx, name, ports)
+		if restored := restorePortForwards(name); len(restored) > 0 {
+			fmt.Printf("Restored port forwards: %s\n", joinPorts(restored))
+		}
+		var keepaliveCmd *exec.Cmd
+		if !keepaliveDeadline.IsZero() {
+			keepaliveCmd = startRemoteKeepalive(ctx, name, time.Until(keepaliveDeadline))
+		}
+		go refreshTabTitle(ctx, name, cfg.GetEffectiveRefreshTitleSeconds())
 
-		args := buildSSHArgs(name)
-		cmd := exec.Command("gh", args...)
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		restarted := make(chan struct{})
+		go func() {
+			select {
+			case <-restartChan:
+				close(restarted)
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
 
-		err := cmd.Run()
+		if connections == 0 {
+			recordStat(cs.Repository, stats.Session)
+		} else {
+			recordStat(cs.Repository, stats.Reconnect)
+			session.reconnects++
+		}
+		connected := time.Now()
+		err := session.track(func() error {
+			return runSSHCommand(ctx, name, forwards, clientID)
+		})
+		if time.Since(connected) >= sshBackoffResetAfter {
+			failures = 0
+		}
 
-		// Stop port forwarding when SSH exits
+		// Stop port forwarding and the keepalive when SSH exits
 		cancel()
 		stopPortForwarding(portFwdCmd)
+		stopRemoteKeepalive(keepaliveCmd)
+
+		select {
+		case <-restarted:
+			fmt.Println(restartBanner(name, time.Now()))
+			continue
+		default:
+		}
 
 		// Check for intentional exit (exit code 0 or user interrupt)
 		if err == nil {
@@ -180,13 +499,30 @@ func sshWithRetry(name string, cs *gh.Codespace, cfg *config.Config) error {
 			return nil
 		default:
 		}
+		if interruptedByUser(err) {
+			fmt.Println("\nDisconnected.")
+			return nil
+		}
 
 		retries++
 		if sshMaxRetries > 0 && retries >= sshMaxRetries {
 			return fmt.Errorf("max retries (%d) reached, giving up", sshMaxRetries)
 		}
 
-		fmt.Printf("\nConnection lost. Reconnecting in %d seconds... (attempt %d", sshRetryDelay, retries+1)
+		// A codespace that stopped (idle timeout, or stopped from elsewhere)
+		// won't come back however often ssh is retried
+		if state := codespaceState(name); isStoppedState(state) {
+			resume, err := resumeStoppedCodespace(name, state, cfg, sigChan)
+			if err != nil || !resume {
+				return err
+			}
+			fmt.Println(reconnectBanner(name, retries+1, time.Now()))
+			continue
+		}
+
+		failures++
+		delay := sshRetryWait(time.Duration(sshRetryDelay)*time.Second, maxDelay, failures, backoff)
+		fmt.Printf("\nConnection lost. Reconnecting in %d seconds... (attempt %d", int(delay.Seconds()), retries+1)
 		if sshMaxRetries > 0 {
 			fmt.Printf("/%d", sshMaxRetries)
 		}
@@ -197,32 +533,196 @@ func sshWithRetry(name string, cs *gh.Codespace, cfg *config.Config) error {
 		case <-sigChan:
 			fmt.Println("\nReconnection cancelled.")
 			return nil
-		case <-time.After(time.Duration(sshRetryDelay) * time.Second):
+		case <-time.After(delay):
 		}
+
+		fmt.Println(reconnectBanner(name, retries+1, time.Now()))
 	}
 }
 
-func buildSSHArgs(name string) []string {
-	args := []string{"cs", "ssh", "-c", name}
+// sshBackoffResetAfter is how long a connection has to stay up for the
+// retry backoff to start over from the base delay.
+const sshBackoffResetAfter = time.Minute
 
-	var sshArgs []string
+// sshRetryWait returns how long to wait before reconnecting after failures
+// consecutive failed connections: base, or with backoff, base doubled for
+// each failure after the first, up to max (but never below base).
+func sshRetryWait(base, max time.Duration, failures int, backoff bool) time.Duration {
+	if !backoff || base <= 0 || max <= base {
+		return base
+	}
+	delay := base
+	for i := 1; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// codespaceState returns the codespace's current state, or "" if it can't
+// be fetched, such as while the network is still down.
+func codespaceState(name string) string {
+	gh.InvalidateCache()
+	cs, err := gh.GetCodespace(name)
+	if err != nil {
+		return ""
+	}
+	return cs.State
+}
+
+// isStoppedState reports whether a codespace in state has to be started
+// before it accepts SSH connections again.
+func isStoppedState(state string) bool {
+	switch state {
+	case "Shutdown", "ShuttingDown", "Stopped":
+		return true
+	}
+	return false
+}
+
+// resumeStoppedCodespace starts a codespace found stopped while
+// reconnecting, without asking if ssh.auto_start is set. It returns false
+// when the session should end instead, because the user declined or
+// interrupted, or there's no terminal to ask on.
+func resumeStoppedCodespace(name, state string, cfg *config.Config, sigChan <-chan os.Signal) (bool, error) {
+	fmt.Printf("\nCodespace %s is stopped (state: %s).\n", name, state)
+
+	if !cfg.SSH.AutoStart {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return false, fmt.Errorf("codespace %s is stopped; start it with 'gh csd start --ssh'", name)
+		}
+		fmt.Print("Start it and reconnect? [Y/n] ")
+
+		answer := make(chan string, 1)
+		go func() {
+			response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			answer <- strings.TrimSpace(strings.ToLower(response))
+		}()
+		select {
+		case <-sigChan:
+			fmt.Println("\nReconnection cancelled.")
+			return false, nil
+		case response := <-answer:
+			if response != "" && response != "y" && response != "yes" {
+				fmt.Println("Not reconnecting. Run 'gh csd start --ssh' to start it and connect later.")
+				return false, nil
+			}
+		}
+	}
+
+	fmt.Printf("Starting %s... ", name)
+	if err := gh.StartCodespace(name); err != nil {
+		fmt.Println("FAILED")
+		return false, err
+	}
+	if _, err := waitForCodespaceAvailable(name, createWaitTimeout); err != nil {
+		fmt.Println("FAILED")
+		return false, err
+	}
+	fmt.Println("done")
+	return true, nil
+}
+
+// restartBanner returns the delimiter printed when restart-session forces
+// a reconnect.
+func restartBanner(name string, now time.Time) string {
+	return fmt.Sprintf("\n──── restarting session to %s at %s ────\n", name, now.Format("2006-01-02 15:04:05"))
+}
+
+// reconnectBanner returns the delimiter printed before each reconnect so
+// output from the previous session is easy to tell apart.
+func reconnectBanner(name string, attempt int, now time.Time) string {
+	return fmt.Sprintf("\n──── reconnecting to %s (attempt %d) at %s ────\n", name, attempt, now.Format("2006-01-02 15:04:05"))
+}
+
+// sshForward is a -R forward from Remote in the codespace to the Local
+// socket on this machine.
+type sshForward struct {
+	Remote string `json:"remote"`
+	Local  string `json:"local"`
+}
+
+// sshForwards are the forwards of an SSH session. It is also the format
+// of the --write-forwards file.
+type sshForwards struct {
+	// RDM forwards rdm's socket to the TCP port rdm clients use
+	RDM *sshForward `json:"rdm,omitempty"`
+	// CSD forwards the server socket for 'gh csd local'
+	CSD *sshForward `json:"csd,omitempty"`
+	// Sockets are the ssh.forward_sockets whose local socket exists
+	Sockets []sshForward `json:"sockets,omitempty"`
+}
+
+// sessionForwards returns the forwards for a session, leaving out those
+// whose local socket isn't there (e.g. the agent or server isn't running).
+func sessionForwards(forwards []config.ForwardSocket) sshForwards {
+	var resolved sshForwards
 
 	if !sshNoRdm {
-		// Add rdm TCP port forwarding for clipboard/open
 		// rdm clients in SSH sessions connect to localhost:7391
-		rdmSocket := getRdmSocketPath()
-		if rdmSocket != "" {
-			sshArgs = append(sshArgs, "-R", fmt.Sprintf("127.0.0.1:7391:%s", rdmSocket))
+		if rdmSocket := getRdmSocketPath(); rdmSocket != "" {
+			resolved.RDM = &sshForward{Remote: "127.0.0.1:7391", Local: rdmSocket}
 		}
 	}
 
-	// Add csd socket forwarding for local command execution
-	// Forward to ~/.csd/csd.socket in the Codespace (matches local path structure)
+	// Forward to ~/.csd/csd.socket in the Codespace (matches local path
+	// structure); SSH expands ~ on the remote side. The local end follows
+	// --socket/$CSD_SOCKET, but the remote end is fixed: it must match
+	// getRemoteSocketPath, so $CSD_SOCKET shouldn't be set in the codespace.
 	csdSocket := GetServerSocketPath()
-	if _, err := os.Stat(csdSocket); err == nil {
-		// Use $HOME/.csd/csd.socket as the remote path
-		// SSH will expand ~ on the remote side
-		sshArgs = append(sshArgs, "-R", fmt.Sprintf("~/.csd/csd.socket:%s", csdSocket))
+	if _, err := os.Stat(csdSocket); err == nil && sshSupportsUnixForwards() {
+		resolved.CSD = &sshForward{Remote: "~/.csd/csd.socket", Local: csdSocket}
+	}
+
+	for _, fwd := range forwards {
+		if fwd.Remote == "" || fwd.Local == "" {
+			continue
+		}
+		local := expandLocalHome(fwd.Local)
+		if _, err := os.Stat(local); err != nil || !sshSupportsUnixForwards() {
+			continue
+		}
+		resolved.Sockets = append(resolved.Sockets, sshForward{Remote: fwd.Remote, Local: local})
+	}
+
+	return resolved
+}
+
+// buildSSHArgs returns the gh arguments for an SSH session. Everything
+// after "--" is passed by gh cs ssh to ssh ahead of the destination, so
+// the -R forwards can be given in any order.
+// A non-empty clientID is set as $CSD_CLIENT in the codespace along with
+// the csd socket forward, so the server can tell concurrent sessions
+// apart where sshd accepts it; registerSessionClient covers the rest. A
+// non-empty command follows the options; gh cs ssh splits it off and runs
+// it after the destination instead of a shell.
+func buildSSHArgs(name string, forwards sshForwards, clientID, command string) []string {
+	args := []string{"cs", "ssh", "-c", name}
+
+	var sshArgs []string
+
+	// rdm enables clipboard/open
+	if fwd := forwards.RDM; fwd != nil {
+		sshArgs = append(sshArgs, "-R", fwd.Remote+":"+fwd.Local)
+	}
+
+	// csd enables local command execution
+	if fwd := forwards.CSD; fwd != nil {
+		sshArgs = append(sshArgs, "-R", fwd.Remote+":"+fwd.Local)
+		if clientID != "" {
+			sshArgs = append(sshArgs, "-o", fmt.Sprintf("SetEnv=%s=%s", clientEnvVar, clientID))
+		}
+	}
+
+	for _, fwd := range forwards.Sockets {
+		sshArgs = append(sshArgs, "-R", fwd.Remote+":"+fwd.Local)
+	}
+
+	// ssh runs anything after its options as the remote command
+	if command != "" {
+		sshArgs = append(sshArgs, command)
 	}
 
 	if len(sshArgs) > 0 {
@@ -233,6 +733,103 @@ func buildSSHArgs(name string) []string {
 	return args
 }
 
+// writeSessionForwards writes the --write-forwards file, if asked for,
+// and returns a function that removes it once the session ends.
+func writeSessionForwards(name string, forwards sshForwards) func() {
+	if sshWriteForwards == "" {
+		return func() {}
+	}
+	if err := writeForwardsFile(sshWriteForwards, name, forwards); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write forwards to %s: %v\n", sshWriteForwards, err)
+		return func() {}
+	}
+	path := sshWriteForwards
+	return func() { os.Remove(path) }
+}
+
+// forwardsStatus is the status recorded in the --write-forwards file.
+// ssh sets the forwards up after the file is written and doesn't report
+// whether each one worked, so they are only known to be requested.
+const forwardsStatus = "requested"
+
+// writeForwardsFile writes the forwards of the session to codespace name
+// to path for --write-forwards. It is replaced atomically so readers never
+// see a partial file.
+func writeForwardsFile(path, name string, forwards sshForwards) error {
+	data, err := json.MarshalIndent(struct {
+		Codespace string `json:"codespace"`
+		PID       int    `json:"pid"`
+		Status    string `json:"status"`
+		sshForwards
+	}{name, os.Getpid(), forwardsStatus, forwards}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// expandLocalHome expands a leading ~ to the local home directory.
+func expandLocalHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
+
+var (
+	unixForwardsOnce      sync.Once
+	unixForwardsSupported bool
+)
+
+// sshSupportsUnixForwards reports whether the local ssh can forward Unix
+// sockets (OpenSSH 6.7+), warning once if it can't. Unrecognized ssh
+// versions are assumed to support it.
+func sshSupportsUnixForwards() bool {
+	unixForwardsOnce.Do(func() {
+		unixForwardsSupported = true
+
+		// ssh -V prints its version to stderr
+		output, err := exec.Command("ssh", "-V").CombinedOutput()
+		if err != nil {
+			return
+		}
+		major, minor, ok := parseOpenSSHVersion(string(output))
+		if !ok || major > 6 || (major == 6 && minor >= 7) {
+			return
+		}
+
+		unixForwardsSupported = false
+		fmt.Fprintf(os.Stderr, "Warning: %s can't forward Unix sockets (needs OpenSSH 6.7+); 'gh csd local' won't be available in this session\n", strings.TrimSpace(string(output)))
+	})
+	return unixForwardsSupported
+}
+
+// parseOpenSSHVersion extracts the version from 'ssh -V' output such as
+// "OpenSSH_9.6p1, LibreSSL 3.3.6".
+func parseOpenSSHVersion(output string) (major, minor int, ok bool) {
+	_, rest, found := strings.Cut(output, "OpenSSH_")
+	if !found {
+		return 0, 0, false
+	}
+	if _, err := fmt.Sscanf(rest, "%d.%d", &major, &minor); err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
 func getRdmSocketPath() string {
 	// Get the actual rdm socket path by running `rdm socket`
 	// rdm uses os.TempDir() + "/rdm.sock" which varies by system
@@ -253,9 +850,28 @@ func getRdmSocketPath() string {
 	return ""
 }
 
+// sshStatusOutput is where gh csd ssh prints its own status lines: stderr
+// with --command, so stdout only has the command's output.
+func sshStatusOutput() io.Writer {
+	if sshCommand != "" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
 // startPortForwarding starts gh cs ports forward in the background.
 // Returns the exec.Cmd (for cleanup) or nil if no ports configured.
 func startPortForwarding(ctx context.Context, codespaceName string, ports []int) *exec.Cmd {
+	// One port that's taken would fail the whole forward, so skip it
+	var free []int
+	for _, port := range ports {
+		if !localPortFree(port) {
+			fmt.Fprintf(os.Stderr, "Skipping port %d: localhost:%d is already in use\n", port, port)
+			continue
+		}
+		free = append(free, port)
+	}
+	ports = free
 	if len(ports) == 0 {
 		return nil
 	}
@@ -272,20 +888,28 @@ func startPortForwarding(ctx context.Context, codespaceName string, ports []int)
 	// (gh cs ports forward may query cursor position, causing ^[[...R responses)
 	cmd.Stdout = nil
 	cmd.Stderr = nil
+	// A dumb TERM stops it from querying the terminal at all
+	cmd.Env = append(os.Environ(), "TERM=dumb")
 
+	// It runs until the session ends, so only the start is logged
+	gh.Trace(args)
 	if err := cmd.Start(); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to start port forwarding: %v\n", err)
 		return nil
 	}
 
 	// Log which ports are being forwarded (we print our own message since gh output is discarded)
+	fmt.Fprintf(sshStatusOutput(), "Forwarding ports: %s\n", joinPorts(ports))
+
+	return cmd
+}
+
+func joinPorts(ports []int) string {
 	portStrs := make([]string, len(ports))
 	for i, p := range ports {
-		portStrs[i] = fmt.Sprintf("%d", p)
+		portStrs[i] = strconv.Itoa(p)
 	}
-	fmt.Printf("Forwarding ports: %s\n", strings.Join(portStrs, ", "))
-
-	return cmd
+	return strings.Join(portStrs, ", ")
 }
 
 // stopPortForwarding gracefully stops the port forwarding process.
@@ -304,6 +928,55 @@ func stopPortForwarding(cmd *exec.Cmd) {
 	}
 }
 
+// remoteKeepaliveInterval is how often the remote keepalive writes.
+const remoteKeepaliveInterval = 30 * time.Second
+
+// remoteKeepaliveLimit returns how long the remote keepalive may run for
+// a session, or 0 if it is disabled.
+func remoteKeepaliveLimit(cfg *config.Config) time.Duration {
+	if !sshRemoteKeepalive && !cfg.SSH.RemoteKeepalive {
+		return 0
+	}
+	return time.Duration(cfg.GetEffectiveRemoteKeepaliveMinutes()) * time.Minute
+}
+
+// remoteKeepaliveScript returns the loop run in the codespace. It writes
+// a newline every interval so the connection carries traffic, and exits
+// once limit has passed or as soon as a write fails because the
+// connection is gone, so it can't outlive the session.
+func remoteKeepaliveScript(limit, interval time.Duration) string {
+	return fmt.Sprintf(`end=$(($(date +%%s) + %d)); while [ "$(date +%%s)" -lt "$end" ]; do echo || exit 0; sleep %d; done`,
+		int(limit.Seconds()), int(interval.Seconds()))
+}
+
+// startRemoteKeepalive runs the keepalive loop in the codespace over its
+// own gh cs ssh connection until ctx is done or limit has passed. It
+// returns nil if limit isn't positive or the loop couldn't be started.
+func startRemoteKeepalive(ctx context.Context, name string, limit time.Duration) *exec.Cmd {
+	if limit <= 0 {
+		return nil
+	}
+
+	cmd := exec.CommandContext(ctx, "gh", "cs", "ssh", "-c", name, "--", remoteKeepaliveScript(limit, remoteKeepaliveInterval))
+	// Like port forwarding, keep its output out of the interactive session
+	cmd.Stdout = nil
+	cmd.Stderr = nil
+	cmd.Env = append(os.Environ(), "TERM=dumb")
+
+	gh.Trace(cmd.Args[1:])
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to start remote keepalive: %v\n", err)
+		return nil
+	}
+	return cmd
+}
+
+// stopRemoteKeepalive ends the keepalive connection; the remote loop
+// exits on its next write.
+func stopRemoteKeepalive(cmd *exec.Cmd) {
+	stopPortForwarding(cmd)
+}
+
 func setTabTitleForCodespace(cs *gh.Codespace) {
 	cfg, err := config.Load()
 	if err != nil {
@@ -318,6 +991,89 @@ func setTabTitleForCodespace(cs *gh.Codespace) {
 		return
 	}
 
-	title := terminal.FormatTitle(cfg.Terminal.TitleFormat, cs.Repository, cs.Branch, cs.Name)
+	title := terminal.FormatTitle(cfg.Terminal.TitleFormat, cs.Repository, cs.DisplayBranch(), cs.Name)
 	terminal.SetTabTitle(title)
 }
+
+// reportCodespaceCwd reports cs's workspace folder as the terminal's
+// working directory, as file://<name>/workspaces/<repo>, if
+// terminal.report_cwd is enabled.
+func reportCodespaceCwd(cfg *config.Config, cs *gh.Codespace) {
+	if !cfg.Terminal.ReportCwd || cs.Repository == "" {
+		return
+	}
+	terminal.SetWorkingDirectory(terminal.WorkingDirectoryURI(cs.Name, codespaceWorkspacePath(cs.Repository)))
+}
+
+// resetReportedCwd reports the local working directory again when a
+// session ends, undoing reportCodespaceCwd.
+func resetReportedCwd(cfg *config.Config) {
+	if cfg.Terminal.ReportCwd {
+		terminal.ResetWorkingDirectory()
+	}
+}
+
+// codespaceWorkspacePath returns where Codespaces clones repo, such as
+// /workspaces/github for github/github.
+func codespaceWorkspacePath(repo string) string {
+	_, name, _ := strings.Cut(repo, "/")
+	if name == "" {
+		name = repo
+	}
+	return "/workspaces/" + name
+}
+
+// runPostConnectHooks runs the post_connect hooks once the session to name
+// has ended for good; reconnects within a --retry session don't count.
+func runPostConnectHooks(cfg *config.Config, name, repo string) {
+	branch := ""
+	if info, ok := state.GetInfo(name); ok {
+		branch = info.Branch
+	}
+	runHooks("post-connect", cfg.Hooks.PostConnect, hookVars{Name: name, Repo: repo, Branch: branch})
+}
+
+// resetTabTitle restores the tab title when a session ends, to
+// terminal.default_title or the terminal's own title. Like
+// setTabTitleForCodespace, it only acts when tab titles are enabled.
+func resetTabTitle(cfg *config.Config) {
+	if !cfg.Terminal.SetTabTitle || !terminal.IsSupportedTerminal() {
+		return
+	}
+	if cfg.Terminal.DefaultTitle != "" {
+		terminal.SetTabTitle(cfg.Terminal.DefaultTitle)
+		return
+	}
+	terminal.ResetTabTitle()
+}
+
+// refreshTabTitle re-queries the codespace every interval seconds until
+// ctx is done, updating the tab title when its branch changes. An interval
+// of 0 disables refreshing.
+func refreshTabTitle(ctx context.Context, name string, interval int) {
+	if interval <= 0 {
+		return
+	}
+
+	var branch string
+	if info, ok := state.GetInfo(name); ok {
+		branch = info.Branch
+	}
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cs, err := gh.GetCodespace(name)
+		if err != nil || cs.Branch == "" || cs.Branch == branch {
+			continue
+		}
+		branch = cs.Branch
+		setTabTitleForCodespace(cs)
+	}
+}
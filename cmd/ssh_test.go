@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luanzeba/gh-csd/internal/config"
+	"github.com/luanzeba/gh-csd/internal/gh"
+)
+
+func TestNewestCodespace(t *testing.T) {
+	now := time.Now()
+	codespaces := []gh.Codespace{
+		{Name: "old", CreatedAt: now.Add(-48 * time.Hour)},
+		{Name: "newest", CreatedAt: now},
+		{Name: "middle", CreatedAt: now.Add(-time.Hour)},
+	}
+
+	got := newestCodespace(codespaces)
+	if got == nil || got.Name != "newest" {
+		t.Fatalf("expected newest codespace, got %+v", got)
+	}
+
+	if got := newestCodespace(nil); got != nil {
+		t.Fatalf("expected nil for empty list, got %+v", got)
+	}
+}
+
+func TestReconnectBanner(t *testing.T) {
+	now := time.Date(2026, 3, 4, 15, 4, 5, 0, time.UTC)
+	got := reconnectBanner("super-robot", 2, now)
+
+	for _, want := range []string{"super-robot", "attempt 2", "2026-03-04 15:04:05"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("banner %q missing %q", got, want)
+		}
+	}
+}
+
+func TestApplySSHProfile(t *testing.T) {
+	oldRetry, oldDelay, oldNoRdm, oldNoClear := sshRetry, sshRetryDelay, sshNoRdm, sshNoClear
+	defer func() {
+		sshRetry, sshRetryDelay, sshNoRdm, sshNoClear = oldRetry, oldDelay, oldNoRdm, oldNoClear
+	}()
+
+	sshRetry, sshRetryDelay, sshNoRdm, sshNoClear = false, 3, false, true
+
+	retry, noRdm := true, true
+	delay := 10
+	profile := &config.SSHProfile{Retry: &retry, RetryDelay: &delay, NoRdm: &noRdm}
+
+	// --retry-delay was given explicitly, so the profile must not override it
+	applySSHProfile(profile, &config.Config{}, func(name string) bool { return name == "retry-delay" })
+
+	if !sshRetry {
+		t.Error("expected profile to enable retry")
+	}
+	if sshRetryDelay != 3 {
+		t.Errorf("sshRetryDelay = %d, want explicit flag value 3", sshRetryDelay)
+	}
+	if !sshNoRdm {
+		t.Error("expected profile to disable rdm")
+	}
+	if !sshNoClear {
+		t.Error("unset profile field should leave no-clear unchanged")
+	}
+}
+
+func TestApplySSHProfileOverridesConfig(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.SSH.Stats = true
+	cfg.SSH.RetryMaxDelay = 30
+
+	stats, backoff, keepalive := false, true, true
+	maxDelay := 120
+	profile := &config.SSHProfile{Stats: &stats, RetryBackoff: &backoff, RemoteKeepalive: &keepalive, RetryMaxDelay: &maxDelay}
+
+	// --sshd-keepalive-from-remote was given explicitly
+	applySSHProfile(profile, cfg, func(name string) bool { return name == "sshd-keepalive-from-remote" })
+
+	if cfg.SSH.Stats {
+		t.Error("expected profile to turn off ssh.stats")
+	}
+	if !cfg.SSH.RetryBackoff {
+		t.Error("expected profile to enable ssh.retry_backoff")
+	}
+	if cfg.SSH.RemoteKeepalive {
+		t.Error("profile must not override an explicit --sshd-keepalive-from-remote")
+	}
+	if cfg.SSH.RetryMaxDelay != 120 {
+		t.Errorf("ssh.retry_max_delay = %d, want profile value 120", cfg.SSH.RetryMaxDelay)
+	}
+}
+
+func TestParseOpenSSHVersion(t *testing.T) {
+	tests := []struct {
+		output       string
+		major, minor int
+		ok           bool
+	}{
+		{output: "OpenSSH_9.6p1, LibreSSL 3.3.6", major: 9, minor: 6, ok: true},
+		{output: "OpenSSH_6.6.1p1 Ubuntu-2ubuntu2, OpenSSL 1.0.1f 6 Jan 2014", major: 6, minor: 6, ok: true},
+		{output: "OpenSSH_for_Windows_8.1p1, LibreSSL 3.0.2", ok: false},
+		{output: "Dropbear v2022.83", ok: false},
+	}
+
+	for _, tt := range tests {
+		major, minor, ok := parseOpenSSHVersion(tt.output)
+		if ok != tt.ok || major != tt.major || minor != tt.minor {
+			t.Errorf("parseOpenSSHVersion(%q) = %d, %d, %v; want %d, %d, %v", tt.output, major, minor, ok, tt.major, tt.minor, tt.ok)
+		}
+	}
+}
+
+func TestBuildSSHArgsForwardSockets(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	oldNoRdm := sshNoRdm
+	sshNoRdm = true
+	t.Cleanup(func() { sshNoRdm = oldNoRdm })
+
+	if err := os.WriteFile(filepath.Join(home, "agent.sock"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	args := buildSSHArgs("my-cs", sessionForwards([]config.ForwardSocket{
+		{Remote: "~/.agent.sock", Local: "~/agent.sock"},
+		{Remote: "~/.missing.sock", Local: "~/missing.sock"},
+	}), "", "")
+
+	got := strings.Join(args, " ")
+	want := "cs ssh -c my-cs -- -R ~/.agent.sock:" + filepath.Join(home, "agent.sock")
+	if got != want {
+		t.Errorf("buildSSHArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestBuildSSHArgsClientID(t *testing.T) {
+	forwards := sshForwards{
+		RDM: &sshForward{Remote: "127.0.0.1:7391", Local: "/tmp/rdm.sock"},
+		CSD: &sshForward{Remote: "~/.csd/csd.socket", Local: "/home/me/.csd/csd.socket"},
+	}
+	got := strings.Join(buildSSHArgs("my-cs", forwards, "abc123", ""), " ")
+	want := "cs ssh -c my-cs -- -R 127.0.0.1:7391:/tmp/rdm.sock -R ~/.csd/csd.socket:/home/me/.csd/csd.socket -o SetEnv=CSD_CLIENT=abc123"
+	if got != want {
+		t.Errorf("buildSSHArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestWriteForwardsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "forwards.json")
+	forwards := sshForwards{
+		CSD:     &sshForward{Remote: "~/.csd/csd.socket", Local: "/home/me/.csd/csd.socket"},
+		Sockets: []sshForward{{Remote: "~/.agent.sock", Local: "/home/me/agent.sock"}},
+	}
+	if err := writeForwardsFile(path, "my-cs", forwards); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Codespace string       `json:"codespace"`
+		PID       int          `json:"pid"`
+		Status    string       `json:"status"`
+		RDM       *sshForward  `json:"rdm"`
+		CSD       *sshForward  `json:"csd"`
+		Sockets   []sshForward `json:"sockets"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid JSON %s: %v", data, err)
+	}
+	if got.Codespace != "my-cs" || got.PID != os.Getpid() {
+		t.Errorf("codespace, pid = %q, %d", got.Codespace, got.PID)
+	}
+	if got.Status != "requested" {
+		t.Errorf("status = %q, want requested", got.Status)
+	}
+	if got.RDM != nil {
+		t.Errorf("rdm = %+v, want it left out", got.RDM)
+	}
+	if got.CSD == nil || *got.CSD != *forwards.CSD {
+		t.Errorf("csd = %+v, want %+v", got.CSD, forwards.CSD)
+	}
+	if len(got.Sockets) != 1 || got.Sockets[0] != forwards.Sockets[0] {
+		t.Errorf("sockets = %+v", got.Sockets)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestSSHSessionSummary(t *testing.T) {
+	session := &sshSession{name: "my-cs", repo: "github/github", connected: 90*time.Minute + 400*time.Millisecond, reconnects: 2}
+
+	want := "Session my-cs (github/github @ main): connected 1h30m0s, 2 reconnect(s)"
+	if got := session.summary("main"); got != want {
+		t.Errorf("summary() = %q, want %q", got, want)
+	}
+	if got := session.summary(""); !strings.Contains(got, "(github/github)") {
+		t.Errorf("summary without branch = %q", got)
+	}
+}
+
+func TestRemoteKeepaliveLimit(t *testing.T) {
+	cfg := config.DefaultConfig()
+	if got := remoteKeepaliveLimit(cfg); got != 0 {
+		t.Errorf("remoteKeepaliveLimit() when disabled = %v, want 0", got)
+	}
+
+	cfg.SSH.RemoteKeepalive = true
+	cfg.SSH.RemoteKeepaliveMinutes = 90
+	if got := remoteKeepaliveLimit(cfg); got != 90*time.Minute {
+		t.Errorf("remoteKeepaliveLimit() = %v, want 1h30m", got)
+	}
+}
+
+func TestRemoteKeepaliveScript(t *testing.T) {
+	got := remoteKeepaliveScript(2*time.Hour, 30*time.Second)
+	for _, want := range []string{"+ 7200))", "echo || exit 0", "sleep 30"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("remoteKeepaliveScript() = %q, missing %q", got, want)
+		}
+	}
+}
+
+func TestBuildSSHArgsCommand(t *testing.T) {
+	forwards := sshForwards{CSD: &sshForward{Remote: "~/.csd/csd.socket", Local: "/home/me/.csd/csd.socket"}}
+	got := strings.Join(buildSSHArgs("my-cs", forwards, "", "make test"), " ")
+	want := "cs ssh -c my-cs -- -R ~/.csd/csd.socket:/home/me/.csd/csd.socket make test"
+	if got != want {
+		t.Errorf("buildSSHArgs() = %q, want %q", got, want)
+	}
+
+	if got := buildSSHArgs("my-cs", sshForwards{}, "", "make test"); !reflect.DeepEqual(got, []string{"cs", "ssh", "-c", "my-cs", "--", "make test"}) {
+		t.Errorf("buildSSHArgs() without forwards = %q", got)
+	}
+}
+
+func TestIsStoppedState(t *testing.T) {
+	for _, state := range []string{"Shutdown", "ShuttingDown", "Stopped"} {
+		if !isStoppedState(state) {
+			t.Errorf("isStoppedState(%q) = false, want true", state)
+		}
+	}
+	// An unknown state (the lookup failed) or one that's coming up on its
+	// own is left to the normal retry
+	for _, state := range []string{"", "Available", "Starting", "Rebuilding"} {
+		if isStoppedState(state) {
+			t.Errorf("isStoppedState(%q) = true, want false", state)
+		}
+	}
+}
+
+func TestSSHRetryWait(t *testing.T) {
+	for _, tt := range []struct {
+		base, max time.Duration
+		failures  int
+		backoff   bool
+		want      time.Duration
+	}{
+		{3 * time.Second, time.Minute, 5, false, 3 * time.Second},
+		{3 * time.Second, time.Minute, 1, true, 3 * time.Second},
+		{3 * time.Second, time.Minute, 2, true, 6 * time.Second},
+		{3 * time.Second, time.Minute, 4, true, 24 * time.Second},
+		{3 * time.Second, time.Minute, 5, true, 48 * time.Second},
+		{3 * time.Second, time.Minute, 6, true, time.Minute},
+		{3 * time.Second, time.Minute, 1000, true, time.Minute},
+		// A base above the cap is used as is
+		{2 * time.Minute, time.Minute, 3, true, 2 * time.Minute},
+		{0, time.Minute, 3, true, 0},
+	} {
+		if got := sshRetryWait(tt.base, tt.max, tt.failures, tt.backoff); got != tt.want {
+			t.Errorf("sshRetryWait(%v, %v, %d, %v) = %v, want %v", tt.base, tt.max, tt.failures, tt.backoff, got, tt.want)
+		}
+	}
+}
+
+func TestCodespaceWorkspacePath(t *testing.T) {
+	tests := map[string]string{
+		"github/github":   "/workspaces/github",
+		"octocat/my-repo": "/workspaces/my-repo",
+		"dotfiles":        "/workspaces/dotfiles",
+	}
+	for repo, want := range tests {
+		if got := codespaceWorkspacePath(repo); got != want {
+			t.Errorf("codespaceWorkspacePath(%q) = %q, want %q", repo, got, want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/luanzeba/gh-csd/internal/stats"
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show local usage stats per repository",
+	Long: `Show how often each repository's codespaces were created, connected
+to, reconnected and deleted, and the total time spent connected, most
+active first.
+
+Stats are recorded locally in ~/.csd/stats.json and never sent anywhere.`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	s, err := stats.Load()
+	if err != nil {
+		return fmt.Errorf("failed to read stats: %w", err)
+	}
+
+	if len(s.Repos) == 0 {
+		fmt.Println("No usage recorded yet.")
+		return nil
+	}
+	return writeStatsTable(os.Stdout, s.Sorted())
+}
+
+func writeStatsTable(w io.Writer, counts []stats.RepoCount) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REPOSITORY\tCREATES\tSSH\tRECONNECTS\tCONNECTED\tDELETES\tLAST USED")
+	for _, c := range counts {
+		connected := time.Duration(c.ConnectedSeconds) * time.Second
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%d\t%s\n", c.Repo, c.Creates, c.Sessions, c.Reconnects, connected, c.Deletes, c.LastUsed.Format("2006-01-02 15:04"))
+	}
+	return tw.Flush()
+}
+
+// recordStat counts a lifecycle event. Stats are best-effort, so failures
+// are ignored rather than interrupting the command.
+func recordStat(repo string, event stats.Event) {
+	_ = stats.Record(repo, event)
+}
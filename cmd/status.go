@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/brasic/launchd/state"
+	"github.com/luanzeba/gh-csd/internal/config"
+	"github.com/luanzeba/gh-csd/internal/gh"
+	csdstate "github.com/luanzeba/gh-csd/internal/state"
+	"github.com/spf13/cobra"
+)
+
+var statusJSON bool
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the selected codespace, local server and service status",
+	Long: `Show an overview of your gh-csd setup:
+
+  - the selected codespace (name, repository, branch and state)
+  - whether the local server is running on its socket
+  - whether the server is installed and running as a service
+
+Exits non-zero if no codespace is selected or the selected codespace
+can't be found.
+
+Use --json for the same details as a single JSON object, e.g. for status
+bars. Its "codespace" is omitted when none is selected, and
+"codespaceError" says why it's missing.`,
+	Args: cobra.NoArgs,
+	RunE: runStatus,
+}
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Output status as JSON")
+	rootCmd.AddCommand(statusCmd)
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+
+	if statusJSON {
+		report, codespaceErr := collectStatus(cfg)
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+		if codespaceErr != nil {
+			cmd.SilenceUsage = true
+			return codespaceErr
+		}
+		return nil
+	}
+
+	codespaceErr := printCodespaceStatus(cfg)
+	fmt.Println()
+
+	socketPath := GetServerSocketPath()
+	if isServerRunning(socketPath) {
+		fmt.Printf("Server: running on %s\n", socketPath)
+	} else {
+		fmt.Printf("Server: not running (start it with 'gh csd server' or 'gh csd service install')\n")
+	}
+	fmt.Println()
+
+	printServiceStatus()
+
+	if codespaceErr != nil {
+		cmd.SilenceUsage = true
+		return codespaceErr
+	}
+	return nil
+}
+
+// printCodespaceStatus prints the selected codespace's details, returning
+// an error if none is selected or it no longer exists.
+func printCodespaceStatus(cfg *config.Config) error {
+	name, err := getSelectedCodespace(cfg)
+	if err != nil {
+		if errors.Is(err, csdstate.ErrNoCodespace) {
+			fmt.Println("Codespace: none selected (use 'gh csd select' to select one)")
+			return errors.New("no codespace selected")
+		}
+		fmt.Printf("Codespace: %v\n", err)
+		return err
+	}
+
+	cs, err := gh.GetCodespace(name)
+	if err != nil {
+		fmt.Printf("Codespace: %s (not found)\n", name)
+		return fmt.Errorf("selected codespace %s not found: %w", name, err)
+	}
+
+	fmt.Printf("Codespace: %s\n", cs.Name)
+	fmt.Printf("  Repository: %s\n", cs.Repository)
+	fmt.Printf("  Branch:     %s\n", cs.DisplayBranch())
+	fmt.Printf("  State:      %s\n", cs.State)
+	return nil
+}
+
+// printServiceStatus prints the launchd or systemd service status, like
+// 'gh csd service status', without exiting on unsupported platforms.
+func printServiceStatus() {
+	switch runtime.GOOS {
+	case "darwin":
+		fmt.Println(prettyStatus(csdService()))
+	case "linux":
+		runSystemdStatus()
+	default:
+		fmt.Printf("Service: not supported on %s\n", runtime.GOOS)
+	}
+}
+
+// statusReport is the output of 'gh csd status --json'.
+type statusReport struct {
+	Codespace      *statusCodespace `json:"codespace,omitempty"`
+	CodespaceError string           `json:"codespaceError,omitempty"`
+	Server         statusServer     `json:"server"`
+	Service        statusService    `json:"service"`
+}
+
+type statusCodespace struct {
+	Name       string `json:"name"`
+	Repository string `json:"repository,omitempty"`
+	Branch     string `json:"branch,omitempty"`
+	State      string `json:"state,omitempty"`
+}
+
+type statusServer struct {
+	Running bool   `json:"running"`
+	Socket  string `json:"socket"`
+}
+
+type statusService struct {
+	// Manager is "launchd" or "systemd", or empty where services aren't
+	// supported.
+	Manager      string `json:"manager,omitempty"`
+	Installed    bool   `json:"installed"`
+	Running      bool   `json:"running"`
+	InstallState string `json:"installState,omitempty"`
+	RunState     string `json:"runState,omitempty"`
+}
+
+// collectStatus gathers what runStatus prints, returning the same error
+// for a missing codespace.
+func collectStatus(cfg *config.Config) (statusReport, error) {
+	var report statusReport
+
+	name, err := getSelectedCodespace(cfg)
+	if err == nil {
+		var cs *gh.Codespace
+		if cs, err = gh.GetCodespace(name); err != nil {
+			report.Codespace = &statusCodespace{Name: name}
+			err = fmt.Errorf("selected codespace %s not found: %w", name, err)
+		} else {
+			report.Codespace = &statusCodespace{Name: cs.Name, Repository: cs.Repository, Branch: cs.Branch, State: cs.State}
+		}
+	} else if errors.Is(err, csdstate.ErrNoCodespace) {
+		err = errors.New("no codespace selected")
+	}
+	if err != nil {
+		report.CodespaceError = err.Error()
+	}
+
+	socketPath := GetServerSocketPath()
+	report.Server = statusServer{Running: isServerRunning(socketPath), Socket: socketPath}
+	report.Service = collectServiceStatus()
+	return report, err
+}
+
+// collectServiceStatus is printServiceStatus for 'gh csd status --json'.
+func collectServiceStatus() statusService {
+	switch runtime.GOOS {
+	case "darwin":
+		svc := csdService()
+		return statusService{
+			Manager:      "launchd",
+			Installed:    svc.InstallState().Is(state.Installed),
+			Running:      svc.RunState().Is(state.Running),
+			InstallState: svc.InstallState().String(),
+			RunState:     svc.RunState().String(),
+		}
+	case "linux":
+		installed := systemdUnitInstalled()
+		installState := "not installed"
+		if installed {
+			installState = systemdQuery("is-enabled")
+		}
+		runState := systemdQuery("is-active")
+		return statusService{
+			Manager:      "systemd",
+			Installed:    installed,
+			Running:      runState == "active",
+			InstallState: installState,
+			RunState:     runState,
+		}
+	default:
+		return statusService{}
+	}
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStatusReportJSON(t *testing.T) {
+	report := statusReport{
+		CodespaceError: "no codespace selected",
+		Server:         statusServer{Socket: "/tmp/csd.sock"},
+		Service:        statusService{Manager: "systemd", InstallState: "not installed", RunState: "inactive"},
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"codespaceError":"no codespace selected","server":{"running":false,"socket":"/tmp/csd.sock"},"service":{"manager":"systemd","installed":false,"running":false,"installState":"not installed","runState":"inactive"}}`
+	if string(data) != want {
+		t.Errorf("json =\n%s\nwant\n%s", data, want)
+	}
+
+	report = statusReport{Codespace: &statusCodespace{Name: "my-cs", Repository: "github/github", Branch: "main", State: "Available"}}
+	data, _ = json.Marshal(report)
+	var decoded map[string]any
+	json.Unmarshal(data, &decoded)
+	if _, ok := decoded["codespaceError"]; ok {
+		t.Errorf("codespaceError should be omitted when the codespace was found: %s", data)
+	}
+	if cs, _ := decoded["codespace"].(map[string]any); cs["name"] != "my-cs" || cs["branch"] != "main" {
+		t.Errorf("codespace = %v, want my-cs on main", decoded["codespace"])
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/luanzeba/gh-csd/internal/gh"
+)
+
+// tokenEnvVar overrides the token file for 'gh csd local'.
+const tokenEnvVar = "CSD_TOKEN"
+
+func getTokenPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".csd", "token")
+}
+
+// readToken returns the token stored at path, or "" if there is none.
+func readToken(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// loadOrCreateToken returns the token at path, generating and saving a new
+// one (readable only by the owner) the first time.
+func loadOrCreateToken(path string) (string, error) {
+	if token := readToken(path); token != "" {
+		return token, nil
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to save token: %w", err)
+	}
+	return token, nil
+}
+
+// clientToken returns the token 'gh csd local' sends: $CSD_TOKEN if set,
+// otherwise the contents of ~/.csd/token.
+func clientToken() string {
+	if token := os.Getenv(tokenEnvVar); token != "" {
+		return token
+	}
+	return readToken(getTokenPath())
+}
+
+// checkToken compares a request's token against the server's.
+func checkToken(want, got string) error {
+	if want == "" {
+		return nil
+	}
+	if got == "" {
+		return errors.New("this server requires a token; reconnect with 'gh csd ssh' to copy ~/.csd/token into the codespace")
+	}
+	if subtle.ConstantTimeCompare([]byte(want), []byte(got)) != 1 {
+		return errors.New("token mismatch (the server's token may have been regenerated); reconnect with 'gh csd ssh' to copy the current ~/.csd/token into the codespace")
+	}
+	return nil
+}
+
+// syncTokenToCodespace copies the local server token to ~/.csd/token in
+// the codespace, where 'gh csd local' reads it.
+func syncTokenToCodespace(name, token string) error {
+	cmd := exec.Command("gh", "cs", "ssh", "-c", name, "--",
+		"umask 077 && mkdir -p ~/.csd && cat > ~/.csd/token")
+	cmd.Stdin = strings.NewReader(token + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := gh.RunTraced(cmd); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadOrCreateToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".csd", "token")
+
+	token, err := loadOrCreateToken(path)
+	if err != nil {
+		t.Fatalf("loadOrCreateToken failed: %v", err)
+	}
+	if len(token) != 64 {
+		t.Errorf("token %q is not 32 hex-encoded bytes", token)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("token file mode = %o, want 600", perm)
+	}
+
+	again, err := loadOrCreateToken(path)
+	if err != nil || again != token {
+		t.Errorf("second load = %q, %v; want the saved token", again, err)
+	}
+}
+
+func TestCheckToken(t *testing.T) {
+	if err := checkToken("", ""); err != nil {
+		t.Errorf("no server token: got %v, want nil", err)
+	}
+	if err := checkToken("secret", "secret"); err != nil {
+		t.Errorf("matching token: got %v, want nil", err)
+	}
+	if err := checkToken("secret", ""); err == nil || !strings.Contains(err.Error(), "requires a token") {
+		t.Errorf("missing token: got %v", err)
+	}
+	if err := checkToken("secret", "stale"); err == nil || !strings.Contains(err.Error(), "token mismatch") {
+		t.Errorf("wrong token: got %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/luanzeba/gh-csd/internal/protocol"
+	"golang.org/x/term"
+)
+
+// ttyDrainTimeout is how long the server keeps reading a terminal after
+// its command exits, for output still in flight. Processes the command
+// left running in the background can hold it open indefinitely.
+const ttyDrainTimeout = time.Second
+
+// interactiveMarkers are what gh prints when it would have prompted, but
+// can't because it isn't attached to a terminal.
+var interactiveMarkers = []string{
+	"when not running interactively",
+	"could not prompt",
+}
+
+// handleExecTTY runs a command on a pseudo-terminal and streams it to the
+// client over the upgraded connection (see protocol.TTYUpgrade), so
+// commands that open an editor or prompt work.
+func (s *Server) handleExecTTY(ctx context.Context, w http.ResponseWriter, req *protocol.ExecRequest) {
+	cmdPath, ok := s.prepareExec(w, req)
+	if !ok {
+		return
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		s.logger.Printf("failed to take over connection for tty: %v", err)
+		writeErrorResponse(w, "server could not start a terminal session", 1)
+		return
+	}
+	defer conn.Close()
+	// Sessions are interactive, so the server's timeouts don't apply
+	conn.SetDeadline(time.Time{})
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: %s\r\n\r\n", protocol.TTYUpgrade)
+	frames := &frameWriter{w: rw, flush: rw.Flush}
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	ctx, cancel := withExecTimeout(ctx, req.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, cmdPath, req.Command[1:]...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = 5 * time.Second
+	cmd.Dir = req.Workdir
+	cmd.Env = os.Environ()
+	for key, value := range req.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	start := time.Now()
+	checkLimits, err := limitCommand(cmd, s.execLimits())
+	if err != nil {
+		err = fmt.Errorf("failed to apply limits: %w", err)
+	}
+	var terminal *os.File
+	if err == nil {
+		if terminal, err = startPTY(cmd, req.Rows, req.Cols); err != nil {
+			checkLimits()
+		}
+	}
+	if err != nil {
+		s.logger.Printf("command failed: %v", err)
+		s.recordExec(req, start, 1, fmt.Sprintf("command failed: %v", err))
+		frames.write(&protocol.StreamFrame{Stream: "exit", ExitCode: 1, Error: fmt.Sprintf("command failed: %v", err)})
+		return
+	}
+	defer terminal.Close()
+
+	// Input from the client; the connection closing means it went away
+	go func() {
+		decoder := json.NewDecoder(rw.Reader)
+		for {
+			var frame protocol.StreamFrame
+			if err := decoder.Decode(&frame); err != nil {
+				cancel()
+				return
+			}
+			switch frame.Stream {
+			case "stdin":
+				terminal.Write(frame.Raw)
+			case "resize":
+				resizePTY(terminal, frame.Rows, frame.Cols)
+			}
+		}
+	}()
+
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := terminal.Read(buf)
+			if n > 0 {
+				frames.write(&protocol.StreamFrame{Stream: "tty", Raw: bytes.Clone(buf[:n])})
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	exitCode := 0
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			exitCode = 1
+		} else {
+			exitCode = exitErr.ExitCode()
+		}
+	}
+	select {
+	case <-outputDone:
+	case <-time.After(ttyDrainTimeout):
+	}
+	limitsErr := checkLimits()
+
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		s.logger.Printf("command timed out after %ds: %v", req.Timeout, req.Command)
+		s.recordExec(req, start, execTimeoutExitCode, timeoutMessage(req.Timeout))
+		frames.write(&protocol.StreamFrame{Stream: "exit", ExitCode: execTimeoutExitCode, Error: timeoutMessage(req.Timeout), Timeout: true})
+	case ctx.Err() != nil:
+		s.logger.Printf("client disconnected, command cancelled: %v", req.Command)
+		s.recordExec(req, start, exitCode, "client disconnected")
+	case limitsErr != nil:
+		s.logger.Printf("command failed: %v", limitsErr)
+		s.recordExec(req, start, 1, fmt.Sprintf("command failed: %v", limitsErr))
+		frames.write(&protocol.StreamFrame{Stream: "exit", ExitCode: 1, Error: fmt.Sprintf("command failed: %v", limitsErr)})
+	default:
+		s.logger.Printf("command completed: exit_code=%d (tty)", exitCode)
+		s.recordExec(req, start, exitCode, "")
+		frames.write(&protocol.StreamFrame{Stream: "exit", ExitCode: exitCode})
+	}
+}
+
+// execLocalTTY runs req as an "exec-tty" session, connecting this
+// terminal to the command's until it exits, and returns its exit code.
+func execLocalTTY(ctx context.Context, socketPath string, req *protocol.ExecRequest) (int, error) {
+	stdinFd := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdinFd) {
+		return 0, fmt.Errorf("--tty needs an interactive terminal")
+	}
+
+	ttyReq := *req
+	ttyReq.Type = "exec-tty"
+	ttyReq.Stdin = ""
+	if cols, rows, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		ttyReq.Rows, ttyReq.Cols = rows, cols
+	}
+	if termType := os.Getenv("TERM"); termType != "" {
+		env := make(map[string]string, len(req.Env)+1)
+		for key, value := range req.Env {
+			env[key] = value
+		}
+		env["TERM"] = termType
+		ttyReq.Env = env
+	}
+
+	body, err := json.Marshal(&ttyReq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://unix/", bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Connection", "Upgrade")
+	httpReq.Header.Set("Upgrade", protocol.TTYUpgrade)
+
+	resp, err := newSocketClient(socketPath, 0).Do(httpReq)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if resp.StatusCode != http.StatusSwitchingProtocols || !ok {
+		// Rejected before the session started
+		var execResp protocol.ExecResponse
+		if err := json.NewDecoder(resp.Body).Decode(&execResp); err != nil {
+			return 0, fmt.Errorf("failed to decode response: %w", err)
+		}
+		if strings.HasPrefix(execResp.Error, "unknown request type") {
+			return 0, fmt.Errorf("the server doesn't support --tty; update gh-csd on your local machine and restart 'gh csd server'")
+		}
+		if execResp.Error != "" {
+			fmt.Fprintln(os.Stderr, execResp.Error)
+		}
+		return execResp.ExitCode, nil
+	}
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	oldState, err := term.MakeRaw(stdinFd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to put the terminal in raw mode: %w", err)
+	}
+	frames := &frameWriter{w: conn}
+
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if n > 0 {
+				if frames.write(&protocol.StreamFrame{Stream: "stdin", Raw: bytes.Clone(buf[:n])}) != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+	defer signal.Stop(resized)
+	go func() {
+		for range resized {
+			if cols, rows, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+				frames.write(&protocol.StreamFrame{Stream: "resize", Rows: rows, Cols: cols})
+			}
+		}
+	}()
+
+	exit, err := readTTYFrames(conn, os.Stdout)
+	term.Restore(stdinFd, oldState)
+	if err != nil {
+		return 0, err
+	}
+	if exit.Error != "" {
+		fmt.Fprintln(os.Stderr, exit.Error)
+	}
+	return exit.ExitCode, nil
+}
+
+// readTTYFrames copies "tty" output to w until the exit frame, which it
+// returns.
+func readTTYFrames(r io.Reader, w io.Writer) (protocol.StreamFrame, error) {
+	decoder := json.NewDecoder(r)
+	for {
+		var frame protocol.StreamFrame
+		if err := decoder.Decode(&frame); err != nil {
+			if err == io.EOF {
+				return frame, fmt.Errorf("connection closed before command finished")
+			}
+			return frame, fmt.Errorf("failed to decode response: %w", err)
+		}
+
+		switch frame.Stream {
+		case "tty":
+			w.Write(frame.Raw)
+		case "exit":
+			return frame, nil
+		}
+	}
+}
+
+// interactiveDetector passes output through while watching for gh saying
+// it needed a terminal, so 'gh csd local' can suggest --tty.
+type interactiveDetector struct {
+	w    io.Writer
+	tail string
+	seen bool
+}
+
+func (d *interactiveDetector) Write(p []byte) (int, error) {
+	// Keep the end of the previous write so markers split across writes
+	// are still found
+	text := d.tail + string(p)
+	for _, marker := range interactiveMarkers {
+		if strings.Contains(text, marker) {
+			d.seen = true
+		}
+	}
+	if keep := 64; len(text) > keep {
+		text = text[len(text)-keep:]
+	}
+	d.tail = text
+	return d.w.Write(p)
+}
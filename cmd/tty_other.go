@@ -0,0 +1,23 @@
+//go:build !unix
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+var errPTYUnsupported = errors.New("terminal sessions are not supported on this platform")
+
+func startPTY(cmd *exec.Cmd, rows, cols int) (*os.File, error) {
+	return nil, errPTYUnsupported
+}
+
+func resizePTY(terminal *os.File, rows, cols int) error {
+	return errPTYUnsupported
+}
+
+// notifyResize is a no-op where there is no SIGWINCH; the session keeps its
+// initial size.
+func notifyResize(c chan<- os.Signal) {}
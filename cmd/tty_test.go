@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/luanzeba/gh-csd/internal/protocol"
+)
+
+func TestInteractiveDetector(t *testing.T) {
+	var out bytes.Buffer
+	d := &interactiveDetector{w: &out}
+	io.WriteString(d, "some output\n")
+	if d.seen {
+		t.Fatal("detected a prompt in ordinary output")
+	}
+
+	// gh's message, split across writes
+	io.WriteString(d, "must provide `--title` and `--body` when not run")
+	io.WriteString(d, "ning interactively\n")
+	if !d.seen {
+		t.Fatal("expected the prompt failure to be detected")
+	}
+	if !strings.HasSuffix(out.String(), "when not running interactively\n") {
+		t.Fatalf("output wasn't passed through: %q", out.String())
+	}
+}
+
+func TestReadTTYFrames(t *testing.T) {
+	input := `{"stream":"tty","raw":"aGVsbG8g"}
+{"stream":"tty","raw":"d29ybGQNCg=="}
+{"stream":"exit","exit_code":3}
+`
+	var out bytes.Buffer
+	exit, err := readTTYFrames(strings.NewReader(input), &out)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if exit.ExitCode != 3 {
+		t.Fatalf("exit code = %d, want 3", exit.ExitCode)
+	}
+	if out.String() != "hello world\r\n" {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+
+	if _, err := readTTYFrames(strings.NewReader(`{"stream":"tty","raw":"aGk="}`+"\n"), io.Discard); err == nil {
+		t.Fatal("expected an error when the exit frame is missing")
+	}
+}
+
+func TestHandleExecTTY(t *testing.T) {
+	// Only gh may run, so stand in for it with a script that prompts
+	gh := filepath.Join(t.TempDir(), "gh")
+	script := "#!/bin/sh\nprintf 'Title? '\nread title\necho \"got $title\"\nexit 3\n"
+	if err := os.WriteFile(gh, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(newServer("", log.New(io.Discard, "", 0)))
+	defer ts.Close()
+
+	body, _ := json.Marshal(protocol.ExecRequest{Type: "exec-tty", Command: []string{gh}, Rows: 24, Cols: 80})
+	req, _ := http.NewRequest(http.MethodPost, ts.URL, bytes.NewReader(body))
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", protocol.TTYUpgrade)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %d, want 101", resp.StatusCode)
+	}
+	conn := resp.Body.(io.ReadWriteCloser)
+
+	if err := protocol.WriteFrame(conn, &protocol.StreamFrame{Stream: "stdin", Raw: []byte("Fix bug\n")}); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	exit, err := readTTYFrames(conn, &out)
+	if err != nil {
+		t.Fatalf("readTTYFrames: %v", err)
+	}
+	if exit.ExitCode != 3 {
+		t.Fatalf("exit code = %d, want 3 (%s)", exit.ExitCode, exit.Error)
+	}
+	// The terminal echoes input and translates newlines
+	if !strings.Contains(out.String(), "Title? ") || !strings.Contains(out.String(), "got Fix bug\r\n") {
+		t.Fatalf("unexpected terminal output: %q", out.String())
+	}
+}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/creack/pty"
+)
+
+// startPTY starts cmd attached to a new pseudo-terminal of the given size
+// and returns the terminal's controlling end. A zero size leaves the
+// default.
+func startPTY(cmd *exec.Cmd, rows, cols int) (*os.File, error) {
+	if rows == 0 || cols == 0 {
+		return pty.Start(cmd)
+	}
+	return pty.StartWithSize(cmd, &pty.Winsize{Rows: uint16(rows), Cols: uint16(cols)})
+}
+
+func resizePTY(terminal *os.File, rows, cols int) error {
+	return pty.Setsize(terminal, &pty.Winsize{Rows: uint16(rows), Cols: uint16(cols)})
+}
+
+// notifyResize relays terminal size changes to c.
+func notifyResize(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGWINCH)
+}
@@ -20,6 +20,19 @@ type Config struct {
 	Repos    map[string]Repo `yaml:"repos"`
 	Hooks    Hooks           `yaml:"hooks"`
 	Terminal Terminal        `yaml:"terminal"`
+	Local    Local           `yaml:"local"`
+	Server   Server          `yaml:"server"`
+	Prompt   Prompt          `yaml:"prompt"`
+	Cache    Cache           `yaml:"cache"`
+	SSH      SSH             `yaml:"ssh"`
+
+	SSHProfiles map[string]SSHProfile `yaml:"ssh_profiles,omitempty"`
+
+	// Templates are shared repo settings that repos inherit with
+	// 'template: <name>'.
+	Templates map[string]Repo `yaml:"templates,omitempty"`
+
+	Notifications Notifications `yaml:"notifications,omitempty"`
 }
 
 // Defaults are the default settings for codespace creation.
@@ -30,30 +43,271 @@ type Defaults struct {
 	DefaultPermissions bool   `yaml:"default_permissions"`
 	SSHRetry           bool   `yaml:"ssh_retry"`
 	CopyTerminfo       *bool  `yaml:"copy_terminfo"` // pointer to distinguish unset from false
+	// TerminfoTerm is the terminal whose terminfo is copied, instead of
+	// $TERM.
+	TerminfoTerm string `yaml:"terminfo_term,omitempty"`
+	// Dotfiles is a repo (owner/repo) that 'gh csd create' clones and
+	// installs in new codespaces.
+	Dotfiles string `yaml:"dotfiles,omitempty"`
+	// AutoSelectCodespace selects the codespace gh-csd runs inside
+	// (CODESPACE_NAME) when nothing else is selected.
+	AutoSelectCodespace bool `yaml:"auto_select_codespace,omitempty"`
+	// WaitForPostCreate makes 'gh csd create' wait for the devcontainer's
+	// setup (postCreateCommand etc.) to finish before connecting.
+	WaitForPostCreate bool `yaml:"wait_for_postcreate,omitempty"`
+	// PostCreateTimeout is how many minutes to wait for the setup. 0 means
+	// use the default.
+	PostCreateTimeout int `yaml:"postcreate_timeout,omitempty"`
+	// MaxMachineCPUs caps the machine 'machine: auto' picks. 0 means no
+	// cap.
+	MaxMachineCPUs int `yaml:"max_machine_cpus,omitempty"`
 }
 
+// AutoMachine as a machine type picks the largest one available for the
+// repo, up to defaults.max_machine_cpus.
+const AutoMachine = "auto"
+
+// defaultRemoteKeepaliveMinutes matches the longest idle timeout
+// Codespaces allows, so the keepalive never outlasts what the user could
+// have configured anyway.
+const defaultRemoteKeepaliveMinutes = 240
+
+// defaultRetryMaxDelay keeps a backed-off reconnect within a minute of the
+// network coming back.
+const defaultRetryMaxDelay = 60
+
+// defaultPostCreateTimeout covers most devcontainer setups without
+// waiting forever on one that hangs.
+const defaultPostCreateTimeout = 30
+
 // Repo is per-repository configuration.
 type Repo struct {
 	Alias              string `yaml:"alias,omitempty"`
 	Machine            string `yaml:"machine,omitempty"`
 	Devcontainer       string `yaml:"devcontainer,omitempty"`
+	IdleTimeout        int    `yaml:"idle_timeout,omitempty"`        // minutes; 0 means use the default
 	DefaultPermissions *bool  `yaml:"default_permissions,omitempty"` // pointer to allow per-repo override
 	SSHRetry           *bool  `yaml:"ssh_retry,omitempty"`           // pointer to allow per-repo override
 	Ports              []int  `yaml:"ports,omitempty"`
+
+	// ForwardSockets replaces ssh.forward_sockets for this repo when set.
+	ForwardSockets []ForwardSocket `yaml:"forward_sockets,omitempty"`
+
+	// Template names an entry in templates whose settings apply unless
+	// set here. Templates can name a template of their own.
+	Template string `yaml:"template,omitempty"`
+}
+
+// SSH configures 'gh csd ssh' connections.
+type SSH struct {
+	// ForwardSockets are extra Unix sockets forwarded into the codespace.
+	ForwardSockets []ForwardSocket `yaml:"forward_sockets,omitempty"`
+	// Stats prints a session summary when 'gh csd ssh' exits, like --stats.
+	Stats bool `yaml:"stats,omitempty"`
+	// RemoteKeepalive keeps the codespace from idling out while a session
+	// is open, like --sshd-keepalive-from-remote.
+	RemoteKeepalive bool `yaml:"remote_keepalive,omitempty"`
+	// RemoteKeepaliveMinutes caps how long the keepalive runs per
+	// session, so a forgotten terminal doesn't keep the codespace up
+	// forever. 0 means use the default.
+	RemoteKeepaliveMinutes int `yaml:"remote_keepalive_minutes,omitempty"`
+	// RetryBackoff doubles the delay before each consecutive reconnect
+	// attempt, like --retry-backoff.
+	RetryBackoff bool `yaml:"retry_backoff,omitempty"`
+	// RetryMaxDelay caps the backed-off delay, in seconds. 0 means use
+	// the default.
+	RetryMaxDelay int `yaml:"retry_max_delay,omitempty"`
+	// AutoStart starts the codespace again without asking when
+	// 'gh csd ssh --retry' finds it stopped after losing the connection.
+	AutoStart bool `yaml:"auto_start,omitempty"`
+}
+
+// ForwardSocket forwards the local socket at Local to Remote inside the
+// codespace. ~ in Remote is expanded on the remote side.
+type ForwardSocket struct {
+	Remote string `yaml:"remote"`
+	Local  string `yaml:"local"`
+}
+
+// SSHProfile is a named bundle of 'gh csd ssh' options, applied with
+// --profile. Unset fields leave the option at its usual value, and
+// explicit flags always win.
+type SSHProfile struct {
+	Retry           *bool `yaml:"retry,omitempty"`
+	RetryDelay      *int  `yaml:"retry_delay,omitempty"`
+	MaxRetries      *int  `yaml:"max_retries,omitempty"`
+	NoRdm           *bool `yaml:"no_rdm,omitempty"`
+	NoClear         *bool `yaml:"no_clear,omitempty"`
+	Stats           *bool `yaml:"stats,omitempty"`
+	RemoteKeepalive *bool `yaml:"remote_keepalive,omitempty"`
+	RetryBackoff    *bool `yaml:"retry_backoff,omitempty"`
+	RetryMaxDelay   *int  `yaml:"retry_max_delay,omitempty"`
 }
 
 // Hooks defines commands to run at various lifecycle points.
 type Hooks struct {
-	PreCreate  []string `yaml:"pre_create,omitempty"`
+	// PreCreate runs before 'gh cs create'; a failing hook aborts creation.
+	PreCreate []string `yaml:"pre_create,omitempty"`
+	// PostCreate runs after creation, before connecting.
 	PostCreate []string `yaml:"post_create,omitempty"`
+	// PostConnect runs locally after an SSH session ends.
+	PostConnect []string `yaml:"post_connect,omitempty"`
 }
 
 // Terminal configures terminal integration.
 type Terminal struct {
 	SetTabTitle bool   `yaml:"set_tab_title"`
 	TitleFormat string `yaml:"title_format"`
+	// RefreshTitleSeconds, when positive, re-queries the codespace this
+	// often during 'gh csd ssh' and updates the tab title if the branch
+	// changed. Each refresh is a 'gh cs list' API call.
+	RefreshTitleSeconds int `yaml:"refresh_title_seconds,omitempty"`
+	// DefaultTitle is the tab title restored when an SSH session ends.
+	// Empty clears the title so the terminal shows its own.
+	DefaultTitle string `yaml:"default_title,omitempty"`
+	// ReportCwd reports the codespace's workspace folder as the working
+	// directory with OSC 7 while connected over SSH.
+	ReportCwd bool `yaml:"report_cwd,omitempty"`
+}
+
+// Notifications configures the desktop notifications 'gh csd create' sends.
+type Notifications struct {
+	// Backend is osascript, terminal-notifier, notify-send or none. Empty
+	// means osascript on macOS and notify-send on Linux.
+	Backend string `yaml:"backend,omitempty"`
+	// Command is a custom notification command, run with sh -c after
+	// substituting {title} and {message}, which are also set in
+	// $CSD_TITLE and $CSD_MESSAGE. It takes precedence over Backend.
+	Command string `yaml:"command,omitempty"`
+	// Sound is the sound osascript and terminal-notifier play. Empty
+	// means Glass; "none" plays no sound.
+	Sound string `yaml:"sound,omitempty"`
+}
+
+// minRefreshTitleSeconds keeps title refreshes from hammering the API.
+const minRefreshTitleSeconds = 30
+
+// Local configures 'gh csd local' behavior inside a codespace.
+type Local struct {
+	// RepoSubcommands lists gh subcommands that get -R injected from the
+	// codespace's git remote when no repo is given. nil means use defaults.
+	RepoSubcommands []string `yaml:"repo_subcommands,omitempty"`
+	// MaxStdinBytes caps how much piped stdin is forwarded to the server.
+	// 0 means use the built-in default.
+	MaxStdinBytes int64 `yaml:"max_stdin_bytes,omitempty"`
+	// ForwardEnv lists environment variables sent along with each command
+	// when they are set in the codespace (e.g. GH_REPO).
+	ForwardEnv []string `yaml:"forward_env,omitempty"`
+}
+
+// Cache configures the short-lived cache of 'gh cs list' results.
+type Cache struct {
+	// TTLSeconds is how long results are reused. 0 means use the default;
+	// a negative value disables the cache.
+	TTLSeconds int `yaml:"ttl_seconds,omitempty"`
 }
 
+// defaultCacheTTLSeconds keeps cached results short-lived enough that
+// state changes made outside gh-csd show up quickly.
+const defaultCacheTTLSeconds = 10
+
+// Prompt configures 'gh csd prompt' output.
+type Prompt struct {
+	// Format is the prompt template. Empty means use the default.
+	Format string `yaml:"format,omitempty"`
+}
+
+// defaultPromptFormat renders e.g. "[gh:main]".
+const defaultPromptFormat = "[{alias}:{branch}]"
+
+// Server configures the local 'gh csd server' daemon.
+type Server struct {
+	// ExecRetries is how many times a failed command is retried when its
+	// stderr looks like a transient failure. 0 disables retries.
+	ExecRetries int `yaml:"exec_retries,omitempty"`
+	// RetrySubcommands lists gh subcommands (e.g. "pr view") that are safe to
+	// retry. Only idempotent commands belong here. nil means use defaults.
+	RetrySubcommands []string `yaml:"retry_subcommands,omitempty"`
+	// MaxRequestBytes caps the size of a request body. 0 means use the
+	// server's built-in default.
+	MaxRequestBytes int64 `yaml:"max_request_bytes,omitempty"`
+	// Nice is the niceness (1-19 lowers priority) applied to executed
+	// commands. 0 leaves their priority unchanged.
+	Nice int `yaml:"nice,omitempty"`
+	// MaxCPUSeconds caps the CPU time of each executed command, which is
+	// killed when it runs out. 0 means no limit.
+	MaxCPUSeconds int `yaml:"max_cpu_seconds,omitempty"`
+	// MaxMemoryMB caps the memory (address space) of each executed command.
+	// 0 means no limit.
+	MaxMemoryMB int `yaml:"max_memory_mb,omitempty"`
+	// ApprovedCommands, when non-empty, restricts the server to exactly
+	// these commands, identified by the signature printed by
+	// 'gh csd server sign'.
+	ApprovedCommands []string `yaml:"approved_commands,omitempty"`
+	// RequireToken makes the server reject requests that don't carry the
+	// token from ~/.csd/token.
+	RequireToken bool `yaml:"require_token,omitempty"`
+	// AllowedEnv lists the environment variables clients may set with
+	// local.forward_env; requests setting any other are refused.
+	AllowedEnv []string `yaml:"allowed_env,omitempty"`
+	// TokenMinting lets codespaces request short-lived GitHub App tokens
+	// with 'gh csd token'. nil disables the "token" request.
+	TokenMinting *TokenMinting `yaml:"token_minting,omitempty"`
+	// ClientRateLimit caps how many requests each client ('gh csd ssh'
+	// session) may make per minute. 0 means no limit.
+	ClientRateLimit int `yaml:"client_rate_limit,omitempty"`
+	// RequireClient rejects requests that don't come from a session
+	// registered by 'gh csd ssh' on this machine.
+	RequireClient bool `yaml:"require_client,omitempty"`
+}
+
+// TokenMinting configures the GitHub App the server mints installation
+// tokens from, and the limits on what it will mint.
+type TokenMinting struct {
+	AppID int64 `yaml:"app_id"`
+	// InstallationID, if set, restricts minting to that installation.
+	// Otherwise each repo's owner's installation of the app is used.
+	InstallationID int64  `yaml:"installation_id,omitempty"`
+	PrivateKeyPath string `yaml:"private_key_path"`
+	// Repos lists the repositories tokens may be minted for, as owner/repo
+	// or owner/* for all of an owner's repositories.
+	Repos []string `yaml:"repos"`
+	// Permissions maps each grantable permission (e.g. "contents") to the
+	// highest level that may be requested ("read" or "write").
+	Permissions map[string]string `yaml:"permissions"`
+}
+
+// defaultServerRetrySubcommands are read-only gh subcommands that are safe
+// to run more than once.
+var defaultServerRetrySubcommands = []string{
+	"pr view",
+	"pr list",
+	"pr status",
+	"pr checks",
+	"pr diff",
+	"issue view",
+	"issue list",
+	"issue status",
+	"run view",
+	"run list",
+	"repo view",
+}
+
+// defaultLocalRepoSubcommands are gh subcommands that infer the repo from
+// the current git directory.
+var defaultLocalRepoSubcommands = []string{
+	"pr",
+	"issue",
+	"run",
+	"workflow",
+	"release",
+	"label",
+}
+
+// defaultLocalMaxStdinBytes leaves headroom under the server's default
+// request size cap for JSON escaping of the forwarded stdin.
+const defaultLocalMaxStdinBytes = 512 << 10
+
 // DefaultConfig returns a config with sensible defaults.
 func DefaultConfig() *Config {
 	copyTerminfo := true
@@ -85,8 +339,9 @@ func DefaultConfig() *Config {
 			},
 		},
 		Hooks: Hooks{
-			PreCreate:  []string{},
-			PostCreate: []string{},
+			PreCreate:   []string{},
+			PostCreate:  []string{},
+			PostConnect: []string{},
 		},
 		Terminal: Terminal{
 			SetTabTitle: true,
@@ -180,14 +435,71 @@ func (c *Config) ResolveAlias(alias string) string {
 	return alias
 }
 
-// GetRepoConfig returns the configuration for a specific repo.
+// GetRepoConfig returns the configuration for a specific repo, with the
+// settings of its template filled in.
 func (c *Config) GetRepoConfig(repo string) *Repo {
 	if cfg, ok := c.Repos[repo]; ok {
+		cfg = c.applyTemplates(cfg)
 		return &cfg
 	}
 	return nil
 }
 
+// applyTemplates fills in the settings cfg leaves unset from its template,
+// then that template's template, and so on. A missing template or a cycle
+// ends the chain; Validate reports both.
+func (c *Config) applyTemplates(cfg Repo) Repo {
+	seen := make(map[string]bool)
+	name := cfg.Template
+	for name != "" && !seen[name] {
+		seen[name] = true
+		tmpl, ok := c.Templates[name]
+		if !ok {
+			break
+		}
+		cfg = inheritRepo(cfg, tmpl)
+		name = tmpl.Template
+	}
+	return cfg
+}
+
+// inheritRepo returns cfg with its unset fields taken from base. Aliases
+// name a single repo, so they are never inherited.
+func inheritRepo(cfg, base Repo) Repo {
+	if cfg.Machine == "" {
+		cfg.Machine = base.Machine
+	}
+	if cfg.Devcontainer == "" {
+		cfg.Devcontainer = base.Devcontainer
+	}
+	if cfg.IdleTimeout == 0 {
+		cfg.IdleTimeout = base.IdleTimeout
+	}
+	if cfg.DefaultPermissions == nil {
+		cfg.DefaultPermissions = base.DefaultPermissions
+	}
+	if cfg.SSHRetry == nil {
+		cfg.SSHRetry = base.SSHRetry
+	}
+	// An empty list (ports: []) overrides the template, like it does
+	// ssh.forward_sockets
+	if cfg.Ports == nil {
+		cfg.Ports = base.Ports
+	}
+	if cfg.ForwardSockets == nil {
+		cfg.ForwardSockets = base.ForwardSockets
+	}
+	return cfg
+}
+
+// GetSSHProfile returns the named SSH profile, or nil if it isn't defined.
+func (c *Config) GetSSHProfile(name string) *SSHProfile {
+	if profile, ok := c.SSHProfiles[name]; ok {
+		return &profile
+	}
+	return nil
+}
+
 // GetEffectiveMachine returns the machine type for a repo,
 // falling back to the default if not specified.
 func (c *Config) GetEffectiveMachine(repo string) string {
@@ -197,6 +509,16 @@ func (c *Config) GetEffectiveMachine(repo string) string {
 	return c.Defaults.Machine
 }
 
+// GetFallbackMachine returns the machine type to use when 'auto' can't
+// pick one: defaults.machine, or the built-in default if that is 'auto'
+// too.
+func (c *Config) GetFallbackMachine() string {
+	if c.Defaults.Machine != AutoMachine {
+		return c.Defaults.Machine
+	}
+	return DefaultConfig().Defaults.Machine
+}
+
 // GetEffectiveDevcontainer returns the devcontainer path for a repo,
 // falling back to the default if not specified.
 func (c *Config) GetEffectiveDevcontainer(repo string) string {
@@ -206,6 +528,15 @@ func (c *Config) GetEffectiveDevcontainer(repo string) string {
 	return c.Defaults.Devcontainer
 }
 
+// GetEffectiveIdleTimeout returns the idle timeout in minutes for a repo,
+// falling back to the default if not specified.
+func (c *Config) GetEffectiveIdleTimeout(repo string) int {
+	if repoCfg := c.GetRepoConfig(repo); repoCfg != nil && repoCfg.IdleTimeout > 0 {
+		return repoCfg.IdleTimeout
+	}
+	return c.Defaults.IdleTimeout
+}
+
 // GetEffectiveDefaultPermissions returns whether to auto-accept permissions for a repo,
 // falling back to the default if not specified.
 func (c *Config) GetEffectiveDefaultPermissions(repo string) bool {
@@ -224,6 +555,15 @@ func (c *Config) GetEffectiveSSHRetry(repo string) bool {
 	return c.Defaults.SSHRetry
 }
 
+// GetEffectiveForwardSockets returns the extra sockets to forward over SSH
+// for a repository. A per-repo list replaces the global one.
+func (c *Config) GetEffectiveForwardSockets(repo string) []ForwardSocket {
+	if repoCfg := c.GetRepoConfig(repo); repoCfg != nil && repoCfg.ForwardSockets != nil {
+		return repoCfg.ForwardSockets
+	}
+	return c.SSH.ForwardSockets
+}
+
 // GetEffectiveCopyTerminfo returns whether to copy terminfo after creation.
 func (c *Config) GetEffectiveCopyTerminfo() bool {
 	if c.Defaults.CopyTerminfo != nil {
@@ -231,3 +571,102 @@ func (c *Config) GetEffectiveCopyTerminfo() bool {
 	}
 	return true // default to true if not set
 }
+
+// GetEffectiveTerminfoTerm returns the terminal to copy terminfo for, or
+// "" to use $TERM.
+func (c *Config) GetEffectiveTerminfoTerm() string {
+	return c.Defaults.TerminfoTerm
+}
+
+// GetEffectiveDotfiles returns the dotfiles repo to apply to new
+// codespaces, or "" for none.
+func (c *Config) GetEffectiveDotfiles() string {
+	return c.Defaults.Dotfiles
+}
+
+// GetEffectivePostCreateTimeout returns how many minutes 'gh csd create'
+// waits for the devcontainer setup to finish.
+func (c *Config) GetEffectivePostCreateTimeout() int {
+	if c.Defaults.PostCreateTimeout > 0 {
+		return c.Defaults.PostCreateTimeout
+	}
+	return defaultPostCreateTimeout
+}
+
+// GetEffectiveLocalRepoSubcommands returns the gh subcommands that should
+// have the codespace repo injected by 'gh csd local'.
+func (c *Config) GetEffectiveLocalRepoSubcommands() []string {
+	if c.Local.RepoSubcommands != nil {
+		return c.Local.RepoSubcommands
+	}
+	return defaultLocalRepoSubcommands
+}
+
+// GetEffectiveLocalMaxStdinBytes returns the maximum number of stdin bytes
+// 'gh csd local' forwards to the server.
+func (c *Config) GetEffectiveLocalMaxStdinBytes() int64 {
+	if c.Local.MaxStdinBytes > 0 {
+		return c.Local.MaxStdinBytes
+	}
+	return defaultLocalMaxStdinBytes
+}
+
+// GetEffectivePromptFormat returns the template for 'gh csd prompt'.
+func (c *Config) GetEffectivePromptFormat() string {
+	if c.Prompt.Format != "" {
+		return c.Prompt.Format
+	}
+	return defaultPromptFormat
+}
+
+// GetEffectiveCacheTTLSeconds returns how long 'gh cs list' results are
+// cached. 0 means caching is disabled.
+func (c *Config) GetEffectiveCacheTTLSeconds() int {
+	switch {
+	case c.Cache.TTLSeconds < 0:
+		return 0
+	case c.Cache.TTLSeconds > 0:
+		return c.Cache.TTLSeconds
+	}
+	return defaultCacheTTLSeconds
+}
+
+// GetEffectiveRetryMaxDelay returns the longest delay, in seconds, that
+// reconnect backoff waits.
+func (c *Config) GetEffectiveRetryMaxDelay() int {
+	if c.SSH.RetryMaxDelay > 0 {
+		return c.SSH.RetryMaxDelay
+	}
+	return defaultRetryMaxDelay
+}
+
+// GetEffectiveRemoteKeepaliveMinutes returns how long the remote
+// keepalive runs per SSH session.
+func (c *Config) GetEffectiveRemoteKeepaliveMinutes() int {
+	if c.SSH.RemoteKeepaliveMinutes > 0 {
+		return c.SSH.RemoteKeepaliveMinutes
+	}
+	return defaultRemoteKeepaliveMinutes
+}
+
+// GetEffectiveRefreshTitleSeconds returns how often the tab title is
+// refreshed during an SSH session, at least minRefreshTitleSeconds. 0 means
+// the title is only set when connecting.
+func (c *Config) GetEffectiveRefreshTitleSeconds() int {
+	switch {
+	case c.Terminal.RefreshTitleSeconds <= 0:
+		return 0
+	case c.Terminal.RefreshTitleSeconds < minRefreshTitleSeconds:
+		return minRefreshTitleSeconds
+	}
+	return c.Terminal.RefreshTitleSeconds
+}
+
+// GetEffectiveServerRetrySubcommands returns the gh subcommands the server
+// may retry on transient failure.
+func (c *Config) GetEffectiveServerRetrySubcommands() []string {
+	if c.Server.RetrySubcommands != nil {
+		return c.Server.RetrySubcommands
+	}
+	return defaultServerRetrySubcommands
+}
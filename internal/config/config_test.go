@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -42,6 +43,10 @@ func TestDefaultConfig(t *testing.T) {
 		t.Error("Default post_create hooks should be initialized")
 	}
 
+	if cfg.Hooks.PostConnect == nil {
+		t.Error("Default post_connect hooks should be initialized")
+	}
+
 	// github/github should have special defaults
 	ghRepo := cfg.GetRepoConfig("github/github")
 	if ghRepo == nil {
@@ -133,6 +138,67 @@ func TestGetRepoConfig(t *testing.T) {
 	}
 }
 
+func TestGetRepoConfigTemplates(t *testing.T) {
+	yes := true
+	cfg := &Config{
+		Defaults: Defaults{Machine: "basicLinux32gb"},
+		Templates: map[string]Repo{
+			"base": {Machine: "largePremiumLinux", IdleTimeout: 60, SSHRetry: &yes},
+			"web":  {Template: "base", Ports: []int{3000}, Alias: "ignored"},
+		},
+		Repos: map[string]Repo{
+			"github/app":  {Alias: "app", Template: "web", IdleTimeout: 120},
+			"github/api":  {Template: "web", Ports: []int{}},
+			"github/docs": {Template: "missing", Machine: "basicLinux"},
+		},
+	}
+
+	app := cfg.GetRepoConfig("github/app")
+	if app.Alias != "app" || app.Machine != "largePremiumLinux" || app.IdleTimeout != 120 || !reflect.DeepEqual(app.Ports, []int{3000}) {
+		t.Errorf("GetRepoConfig(github/app) = %+v, want the template's machine and ports with its own alias and idle timeout", app)
+	}
+	if !cfg.GetEffectiveSSHRetry("github/app") {
+		t.Error("GetEffectiveSSHRetry(github/app) = false, want true from the base template")
+	}
+
+	// An empty list overrides the template
+	if api := cfg.GetRepoConfig("github/api"); len(api.Ports) != 0 || api.Alias != "" {
+		t.Errorf("GetRepoConfig(github/api) = %+v, want no ports and no alias", api)
+	}
+
+	if got := cfg.GetEffectiveMachine("github/docs"); got != "basicLinux" {
+		t.Errorf("GetEffectiveMachine(github/docs) = %q, want basicLinux", got)
+	}
+
+	// The stored config is left as written
+	if cfg.Repos["github/app"].Machine != "" {
+		t.Error("GetRepoConfig modified the stored repo config")
+	}
+}
+
+func TestGetSSHProfile(t *testing.T) {
+	retry := true
+	cfg := DefaultConfig()
+	cfg.SSHProfiles = map[string]SSHProfile{
+		"dev": {Retry: &retry},
+	}
+
+	profile := cfg.GetSSHProfile("dev")
+	if profile == nil {
+		t.Fatal("GetSSHProfile(dev) returned nil")
+	}
+	if profile.Retry == nil || !*profile.Retry {
+		t.Errorf("GetSSHProfile(dev).Retry = %v, want true", profile.Retry)
+	}
+	if profile.NoRdm != nil {
+		t.Errorf("GetSSHProfile(dev).NoRdm = %v, want unset", profile.NoRdm)
+	}
+
+	if profile := cfg.GetSSHProfile("missing"); profile != nil {
+		t.Errorf("GetSSHProfile(missing) = %v, want nil", profile)
+	}
+}
+
 func TestEffectiveSettings(t *testing.T) {
 	cfg := DefaultConfig()
 
@@ -155,6 +221,55 @@ func TestEffectiveSettings(t *testing.T) {
 		}
 	})
 
+	// Test GetEffectiveForwardSockets
+	t.Run("GetEffectiveForwardSockets", func(t *testing.T) {
+		global := ForwardSocket{Remote: "~/.agent.sock", Local: "~/.agent.sock"}
+		cfg.SSH.ForwardSockets = []ForwardSocket{global}
+		if got := cfg.GetEffectiveForwardSockets("unknown/repo"); len(got) != 1 || got[0] != global {
+			t.Errorf("GetEffectiveForwardSockets(unknown/repo) = %v, want %v", got, []ForwardSocket{global})
+		}
+
+		// An empty per-repo list disables the global forwards
+		cfg.Repos["nofwd/repo"] = Repo{ForwardSockets: []ForwardSocket{}}
+		if got := cfg.GetEffectiveForwardSockets("nofwd/repo"); len(got) != 0 {
+			t.Errorf("GetEffectiveForwardSockets(nofwd/repo) = %v, want none", got)
+		}
+	})
+
+	t.Run("GetEffectiveRemoteKeepaliveMinutes", func(t *testing.T) {
+		if got := cfg.GetEffectiveRemoteKeepaliveMinutes(); got != defaultRemoteKeepaliveMinutes {
+			t.Errorf("GetEffectiveRemoteKeepaliveMinutes() = %d, want %d", got, defaultRemoteKeepaliveMinutes)
+		}
+		cfg.SSH.RemoteKeepaliveMinutes = 60
+		if got := cfg.GetEffectiveRemoteKeepaliveMinutes(); got != 60 {
+			t.Errorf("GetEffectiveRemoteKeepaliveMinutes() = %d, want 60", got)
+		}
+	})
+
+	t.Run("GetEffectiveRetryMaxDelay", func(t *testing.T) {
+		if got := cfg.GetEffectiveRetryMaxDelay(); got != defaultRetryMaxDelay {
+			t.Errorf("GetEffectiveRetryMaxDelay() = %d, want %d", got, defaultRetryMaxDelay)
+		}
+		cfg.SSH.RetryMaxDelay = 300
+		if got := cfg.GetEffectiveRetryMaxDelay(); got != 300 {
+			t.Errorf("GetEffectiveRetryMaxDelay() = %d, want 300", got)
+		}
+	})
+
+	// Test GetEffectiveIdleTimeout
+	t.Run("GetEffectiveIdleTimeout", func(t *testing.T) {
+		// Unknown repo should use default
+		if got := cfg.GetEffectiveIdleTimeout("unknown/repo"); got != 240 {
+			t.Errorf("GetEffectiveIdleTimeout(unknown/repo) = %d, want 240", got)
+		}
+
+		// Add a repo with custom idle timeout
+		cfg.Repos["idle/repo"] = Repo{IdleTimeout: 30}
+		if got := cfg.GetEffectiveIdleTimeout("idle/repo"); got != 30 {
+			t.Errorf("GetEffectiveIdleTimeout(idle/repo) = %d, want 30", got)
+		}
+	})
+
 	// Test GetEffectiveDefaultPermissions
 	t.Run("GetEffectiveDefaultPermissions", func(t *testing.T) {
 		// github/github has default_permissions: true
@@ -198,4 +313,86 @@ func TestEffectiveSettings(t *testing.T) {
 			t.Errorf("GetEffectiveCopyTerminfo() with nil = %v, want true (default)", got)
 		}
 	})
+
+	// Test GetEffectiveCacheTTLSeconds
+	t.Run("GetEffectiveCacheTTLSeconds", func(t *testing.T) {
+		if got := cfg.GetEffectiveCacheTTLSeconds(); got != defaultCacheTTLSeconds {
+			t.Errorf("GetEffectiveCacheTTLSeconds() = %d, want %d (default)", got, defaultCacheTTLSeconds)
+		}
+
+		cfg.Cache.TTLSeconds = 30
+		if got := cfg.GetEffectiveCacheTTLSeconds(); got != 30 {
+			t.Errorf("GetEffectiveCacheTTLSeconds() = %d, want 30", got)
+		}
+
+		cfg.Cache.TTLSeconds = -1
+		if got := cfg.GetEffectiveCacheTTLSeconds(); got != 0 {
+			t.Errorf("GetEffectiveCacheTTLSeconds() = %d, want 0 (disabled)", got)
+		}
+	})
+
+	// Test GetEffectivePostCreateTimeout
+	t.Run("GetEffectivePostCreateTimeout", func(t *testing.T) {
+		if got := cfg.GetEffectivePostCreateTimeout(); got != defaultPostCreateTimeout {
+			t.Errorf("GetEffectivePostCreateTimeout() = %d, want %d (default)", got, defaultPostCreateTimeout)
+		}
+
+		cfg.Defaults.PostCreateTimeout = 45
+		if got := cfg.GetEffectivePostCreateTimeout(); got != 45 {
+			t.Errorf("GetEffectivePostCreateTimeout() = %d, want 45", got)
+		}
+	})
+
+	// Test GetEffectiveRefreshTitleSeconds
+	t.Run("GetEffectiveRefreshTitleSeconds", func(t *testing.T) {
+		if got := cfg.GetEffectiveRefreshTitleSeconds(); got != 0 {
+			t.Errorf("GetEffectiveRefreshTitleSeconds() = %d, want 0 (disabled)", got)
+		}
+
+		cfg.Terminal.RefreshTitleSeconds = 5
+		if got := cfg.GetEffectiveRefreshTitleSeconds(); got != minRefreshTitleSeconds {
+			t.Errorf("GetEffectiveRefreshTitleSeconds() = %d, want %d (minimum)", got, minRefreshTitleSeconds)
+		}
+
+		cfg.Terminal.RefreshTitleSeconds = 120
+		if got := cfg.GetEffectiveRefreshTitleSeconds(); got != 120 {
+			t.Errorf("GetEffectiveRefreshTitleSeconds() = %d, want 120", got)
+		}
+	})
+
+	// Test GetEffectivePromptFormat
+	t.Run("GetEffectivePromptFormat", func(t *testing.T) {
+		if got := cfg.GetEffectivePromptFormat(); got != defaultPromptFormat {
+			t.Errorf("GetEffectivePromptFormat() = %q, want %q (default)", got, defaultPromptFormat)
+		}
+
+		cfg.Prompt.Format = "{name}"
+		if got := cfg.GetEffectivePromptFormat(); got != "{name}" {
+			t.Errorf("GetEffectivePromptFormat() = %q, want {name}", got)
+		}
+	})
+
+	// Test GetEffectiveLocalMaxStdinBytes
+	t.Run("GetEffectiveLocalMaxStdinBytes", func(t *testing.T) {
+		if got := cfg.GetEffectiveLocalMaxStdinBytes(); got != defaultLocalMaxStdinBytes {
+			t.Errorf("GetEffectiveLocalMaxStdinBytes() = %d, want %d (default)", got, defaultLocalMaxStdinBytes)
+		}
+
+		cfg.Local.MaxStdinBytes = 1024
+		if got := cfg.GetEffectiveLocalMaxStdinBytes(); got != 1024 {
+			t.Errorf("GetEffectiveLocalMaxStdinBytes() = %d, want 1024", got)
+		}
+	})
+}
+
+func TestGetFallbackMachine(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Defaults.Machine = "premiumLinux"
+	if got := cfg.GetFallbackMachine(); got != "premiumLinux" {
+		t.Errorf("GetFallbackMachine() = %q, want defaults.machine", got)
+	}
+	cfg.Defaults.Machine = AutoMachine
+	if got, want := cfg.GetFallbackMachine(), DefaultConfig().Defaults.Machine; got != want {
+		t.Errorf("GetFallbackMachine() with machine: auto = %q, want %q", got, want)
+	}
 }
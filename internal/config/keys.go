@@ -0,0 +1,267 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrKeyNotSet is returned by GetValue for a map entry or section that
+// isn't in the config.
+var ErrKeyNotSet = errors.New("not set")
+
+// GetValue returns the value at a dotted key such as "defaults.machine" or
+// "repos.github/github.ports". Scalars are returned as-is; lists and
+// sections are returned as YAML. Unset optional values are "".
+func (c *Config) GetValue(key string) (string, error) {
+	var out string
+	err := walkKey(reflect.ValueOf(c).Elem(), splitKey(key), false, func(v reflect.Value) error {
+		var err error
+		out, err = formatValue(v)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	return out, nil
+}
+
+// SetValue parses value according to the type of the field at key and
+// stores it, creating map entries (e.g. a new repo) as needed. Lists take
+// comma-separated items ("80,3000") or YAML flow syntax ("[80, 3000]");
+// an empty value clears them.
+func (c *Config) SetValue(key, value string) error {
+	err := walkKey(reflect.ValueOf(c).Elem(), splitKey(key), true, func(v reflect.Value) error {
+		return parseValue(v, value)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+func splitKey(key string) []string {
+	if key == "" {
+		return nil
+	}
+	return strings.Split(key, ".")
+}
+
+// walkKey follows path from v and calls fn on the value it names. Map
+// entries aren't addressable, so they are copied out and, when create is
+// set, stored back after fn succeeds. With create unset, missing map
+// entries and nil sections are reported as ErrKeyNotSet.
+func walkKey(v reflect.Value, path []string, create bool, fn func(reflect.Value) error) error {
+	if len(path) == 0 {
+		return fn(v)
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			if !create {
+				return ErrKeyNotSet
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return walkKey(v.Elem(), path, create, fn)
+
+	case reflect.Struct:
+		field, ok := fieldByYAMLName(v, path[0])
+		if !ok {
+			return fmt.Errorf("unknown key %q", path[0])
+		}
+		return walkKey(field, path[1:], create, fn)
+
+	case reflect.Map:
+		mapKey, rest, ok := splitMapKey(v, path)
+		if !ok {
+			return fmt.Errorf("invalid key %q", strings.Join(path, "."))
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		existing := v.MapIndex(reflect.ValueOf(mapKey))
+		if existing.IsValid() {
+			elem.Set(existing)
+		} else if !create {
+			return ErrKeyNotSet
+		}
+		if err := walkKey(elem, rest, create, fn); err != nil {
+			return err
+		}
+		if create {
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			v.SetMapIndex(reflect.ValueOf(mapKey), elem)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%q is not a section", path[0])
+}
+
+// splitMapKey picks how many segments of path form the map key, since
+// keys such as repo names may themselves contain dots. An existing key
+// wins; otherwise the shortest key that leaves a valid path is used.
+func splitMapKey(m reflect.Value, path []string) (string, []string, bool) {
+	elemType := m.Type().Elem()
+	var fallback int
+	for i := 1; i <= len(path); i++ {
+		if !validPath(elemType, path[i:]) {
+			continue
+		}
+		key := strings.Join(path[:i], ".")
+		if m.MapIndex(reflect.ValueOf(key)).IsValid() {
+			return key, path[i:], true
+		}
+		if fallback == 0 {
+			fallback = i
+		}
+	}
+	if fallback == 0 {
+		return "", nil, false
+	}
+	return strings.Join(path[:fallback], "."), path[fallback:], true
+}
+
+// validPath reports whether path names a value within type t.
+func validPath(t reflect.Type, path []string) bool {
+	if len(path) == 0 {
+		return true
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if yamlName(t.Field(i)) == path[0] {
+				return validPath(t.Field(i).Type, path[1:])
+			}
+		}
+	case reflect.Map:
+		for i := 1; i <= len(path); i++ {
+			if validPath(t.Elem(), path[i:]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func fieldByYAMLName(v reflect.Value, name string) (reflect.Value, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		if yamlName(v.Type().Field(i)) == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// yamlName returns the key a struct field is stored under, following
+// yaml.v3's default of the lowercased field name.
+func yamlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" || !field.IsExported() {
+		return ""
+	}
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+func formatValue(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int64:
+		return fmt.Sprint(v.Interface()), nil
+	}
+
+	data, err := yaml.Marshal(v.Interface())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// parseValue parses s into v according to v's type.
+func parseValue(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := parseValue(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+
+	case reflect.Slice:
+		if s == "" {
+			v.Set(reflect.Zero(v.Type()))
+			return nil
+		}
+		if strings.HasPrefix(s, "[") {
+			list := reflect.New(v.Type())
+			if err := yaml.Unmarshal([]byte(s), list.Interface()); err != nil {
+				return fmt.Errorf("expected a list of %s, got %q", typeName(v.Type().Elem()), s)
+			}
+			v.Set(list.Elem())
+			return nil
+		}
+		items := strings.Split(s, ",")
+		list := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := parseValue(list.Index(i), strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
+		v.Set(list)
+		return nil
+
+	case reflect.String:
+		v.SetString(s)
+		return nil
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", s)
+		}
+		v.SetBool(b)
+		return nil
+
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", s)
+		}
+		v.SetInt(n)
+		return nil
+	}
+
+	return fmt.Errorf("is a section; set one of its fields instead")
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64:
+		return "integers"
+	case reflect.Bool:
+		return "booleans"
+	case reflect.String:
+		return "strings"
+	}
+	return t.String()
+}
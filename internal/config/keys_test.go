@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetValue(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Repos["owner/site.js"] = Repo{Alias: "site"}
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"defaults.machine", "xLargePremiumLinux"},
+		{"defaults.idle_timeout", "240"},
+		{"defaults.copy_terminfo", "true"},
+		{"terminal.set_tab_title", "true"},
+		{"repos.github/github.alias", "gh"},
+		{"repos.github/github.ports", "- 80"},
+		{"repos.github/github.ssh_retry", "true"},
+		{"repos.github/meuse.ssh_retry", ""},
+		{"repos.owner/site.js.alias", "site"},
+	}
+
+	for _, tt := range tests {
+		got, err := cfg.GetValue(tt.key)
+		if err != nil {
+			t.Errorf("GetValue(%q) failed: %v", tt.key, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("GetValue(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestGetValueErrors(t *testing.T) {
+	cfg := DefaultConfig()
+
+	if _, err := cfg.GetValue("defaults.machin"); err == nil || !strings.Contains(err.Error(), "unknown key") {
+		t.Errorf("GetValue(typo) error = %v, want unknown key", err)
+	}
+	if _, err := cfg.GetValue("repos.owner/missing.alias"); !errors.Is(err, ErrKeyNotSet) {
+		t.Errorf("GetValue(missing repo) error = %v, want ErrKeyNotSet", err)
+	}
+	if _, err := cfg.GetValue("defaults.machine.name"); err == nil {
+		t.Error("GetValue(path below a scalar) succeeded, want error")
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	cfg := DefaultConfig()
+
+	sets := map[string]string{
+		"defaults.machine":                          "largePremiumLinux",
+		"defaults.idle_timeout":                     "60",
+		"defaults.copy_terminfo":                    "false",
+		"repos.github/github.ports":                 "80,3000",
+		"repos.owner/new.alias":                     "new",
+		"repos.owner/new.ssh_retry":                 "true",
+		"hooks.post_create":                         `["echo a, b", "echo c"]`,
+		"server.token_minting.app_id":               "42",
+		"server.token_minting.permissions.contents": "read",
+	}
+	for key, value := range sets {
+		if err := cfg.SetValue(key, value); err != nil {
+			t.Fatalf("SetValue(%q, %q) failed: %v", key, value, err)
+		}
+	}
+
+	if cfg.Defaults.Machine != "largePremiumLinux" || cfg.Defaults.IdleTimeout != 60 {
+		t.Errorf("defaults = %+v", cfg.Defaults)
+	}
+	if cfg.GetEffectiveCopyTerminfo() {
+		t.Error("copy_terminfo should be false")
+	}
+	if got := cfg.Repos["github/github"].Ports; !reflect.DeepEqual(got, []int{80, 3000}) {
+		t.Errorf("ports = %v, want [80 3000]", got)
+	}
+	if cfg.Repos["github/github"].Alias != "gh" {
+		t.Error("setting ports should keep the rest of the repo config")
+	}
+	if repo := cfg.Repos["owner/new"]; repo.Alias != "new" || repo.SSHRetry == nil || !*repo.SSHRetry {
+		t.Errorf("new repo = %+v", repo)
+	}
+	if got := cfg.Hooks.PostCreate; !reflect.DeepEqual(got, []string{"echo a, b", "echo c"}) {
+		t.Errorf("post_create = %q", got)
+	}
+	if tm := cfg.Server.TokenMinting; tm == nil || tm.AppID != 42 || tm.Permissions["contents"] != "read" {
+		t.Errorf("token_minting = %+v", tm)
+	}
+
+	if err := cfg.SetValue("repos.github/github.ports", ""); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Repos["github/github"].Ports != nil {
+		t.Error("empty value should clear ports")
+	}
+}
+
+func TestSetValueErrors(t *testing.T) {
+	tests := []struct {
+		key, value, want string
+	}{
+		{"defaults.idle_timeout", "soon", "expected an integer"},
+		{"defaults.ssh_retry", "maybe", "expected true or false"},
+		{"repos.github/github.ports", "80,http", "expected an integer"},
+		{"defaults", "x", "is a section"},
+		{"defaults.nope", "x", "unknown key"},
+	}
+
+	for _, tt := range tests {
+		err := DefaultConfig().SetValue(tt.key, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("SetValue(%q, %q) error = %v, want %q", tt.key, tt.value, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LocalProjectFileName is the per-project config file for 'gh csd local',
+// committed to (or ignored in) the repository checked out in a codespace.
+const LocalProjectFileName = ".csd-local.yaml"
+
+// LocalProject holds per-project defaults for 'gh csd local'. Flags given
+// on the command line always win.
+type LocalProject struct {
+	// Repo is injected as -R instead of the repository detected from the
+	// git remote.
+	Repo string `yaml:"repo,omitempty"`
+	// Timeout is the default --timeout in seconds. 0 means no limit.
+	Timeout int `yaml:"timeout,omitempty"`
+	// ForwardEnv lists environment variables forwarded in addition to
+	// local.forward_env.
+	ForwardEnv []string `yaml:"forward_env,omitempty"`
+}
+
+// FindLocalProject looks for LocalProjectFileName in dir and its parents,
+// stopping at the root of the git repository containing dir. It returns
+// nil and an empty path if there is none.
+func FindLocalProject(dir string) (*LocalProject, string, error) {
+	for {
+		path := filepath.Join(dir, LocalProjectFileName)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			project, err := parseLocalProject(data)
+			if err != nil {
+				return nil, path, fmt.Errorf("%s: %w", path, err)
+			}
+			return project, path, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, path, err
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return nil, "", nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, "", nil
+		}
+		dir = parent
+	}
+}
+
+// parseLocalProject decodes a project file, rejecting unknown keys so
+// typos don't silently fall back to the defaults.
+func parseLocalProject(data []byte) (*LocalProject, error) {
+	var project LocalProject
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&project); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if project.Timeout < 0 {
+		return nil, fmt.Errorf("timeout must not be negative, got %d", project.Timeout)
+	}
+	return &project, nil
+}
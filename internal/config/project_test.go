@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindLocalProject(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	sub := filepath.Join(repo, "src", "pkg")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	project, path, err := FindLocalProject(sub)
+	if err != nil || project != nil || path != "" {
+		t.Fatalf("FindLocalProject() without a file = %v, %q, %v", project, path, err)
+	}
+
+	// Files above the repository root are ignored
+	if err := os.WriteFile(filepath.Join(root, LocalProjectFileName), []byte("repo: other/repo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if project, _, _ := FindLocalProject(sub); project != nil {
+		t.Fatalf("FindLocalProject() read a file outside the repository: %+v", project)
+	}
+
+	content := "repo: github/github\ntimeout: 120\nforward_env: [GH_DEBUG]\n"
+	want := filepath.Join(repo, LocalProjectFileName)
+	if err := os.WriteFile(want, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	project, path, err = FindLocalProject(sub)
+	if err != nil {
+		t.Fatalf("FindLocalProject() error = %v", err)
+	}
+	if path != want {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+	wantProject := &LocalProject{Repo: "github/github", Timeout: 120, ForwardEnv: []string{"GH_DEBUG"}}
+	if !reflect.DeepEqual(project, wantProject) {
+		t.Errorf("project = %+v, want %+v", project, wantProject)
+	}
+}
+
+func TestFindLocalProjectInvalid(t *testing.T) {
+	for _, content := range []string{"repos: github/github\n", "timeout: -5\n"} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, LocalProjectFileName), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, _, err := FindLocalProject(dir)
+		if err == nil || !strings.Contains(err.Error(), LocalProjectFileName) {
+			t.Errorf("FindLocalProject(%q) error = %v, want an error naming the file", content, err)
+		}
+	}
+}
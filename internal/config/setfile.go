@@ -0,0 +1,163 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetFileValue sets the value at a dotted key in the config file at path,
+// like SetValue, and writes the file back. Only that value changes: the
+// rest of the file keeps its comments and key order, and defaults aren't
+// written out. A missing file is created. The value is refused if it
+// makes the config invalid, so the file is left as it was.
+func SetFileValue(path, key, value string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	cfg, _, err := decodeStrict(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	before := validationMessages(cfg)
+	if err := cfg.SetValue(key, value); err != nil {
+		return err
+	}
+	// Only problems the new value causes count; ones already in the file
+	// are reported by the commands that load it
+	for msg := range validationMessages(cfg) {
+		if !before[msg] {
+			return fmt.Errorf("invalid value: %s", msg)
+		}
+	}
+
+	keys, leaf, ok := resolveKey(reflect.ValueOf(cfg).Elem(), splitKey(key))
+	if !ok {
+		return fmt.Errorf("%s: %w", key, ErrKeyNotSet)
+	}
+	var node yaml.Node
+	if err := node.Encode(leaf.Interface()); err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode}}
+	}
+	setNode(doc.Content[0], keys, &node)
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, out.Bytes(), 0644)
+}
+
+// validationMessages returns the problems Validate finds in a copy of c,
+// without the fallback each one describes.
+func validationMessages(c *Config) map[string]bool {
+	var copied Config
+	data, err := yaml.Marshal(c)
+	if err == nil {
+		err = yaml.Unmarshal(data, &copied)
+	}
+	if err != nil {
+		return nil
+	}
+
+	msgs := make(map[string]bool)
+	for _, err := range Validate(&copied) {
+		msg := err.Error()
+		if i := strings.LastIndex(msg, "; "); i >= 0 {
+			msg = msg[:i]
+		}
+		msgs[msg] = true
+	}
+	return msgs
+}
+
+// resolveKey returns the YAML keys path names in v, keeping map keys that
+// contain dots (such as repo names) whole, and the value they lead to.
+func resolveKey(v reflect.Value, path []string) ([]string, reflect.Value, bool) {
+	if len(path) == 0 {
+		return nil, v, true
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil, reflect.Value{}, false
+		}
+		return resolveKey(v.Elem(), path)
+
+	case reflect.Struct:
+		field, ok := fieldByYAMLName(v, path[0])
+		if !ok {
+			return nil, reflect.Value{}, false
+		}
+		keys, leaf, ok := resolveKey(field, path[1:])
+		return append([]string{path[0]}, keys...), leaf, ok
+
+	case reflect.Map:
+		mapKey, rest, ok := splitMapKey(v, path)
+		if !ok {
+			return nil, reflect.Value{}, false
+		}
+		elem := v.MapIndex(reflect.ValueOf(mapKey))
+		if !elem.IsValid() {
+			return nil, reflect.Value{}, false
+		}
+		keys, leaf, ok := resolveKey(elem, rest)
+		return append([]string{mapKey}, keys...), leaf, ok
+	}
+	return nil, reflect.Value{}, false
+}
+
+// setNode stores value under keys in mapping, adding the mappings on the
+// way that don't exist yet. A replaced value keeps its comments.
+func setNode(mapping *yaml.Node, keys []string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != keys[0] {
+			continue
+		}
+		existing := mapping.Content[i+1]
+		if len(keys) == 1 {
+			value.HeadComment = existing.HeadComment
+			value.LineComment = existing.LineComment
+			value.FootComment = existing.FootComment
+			mapping.Content[i+1] = value
+			return
+		}
+		if existing.Kind != yaml.MappingNode {
+			*existing = yaml.Node{Kind: yaml.MappingNode, LineComment: existing.LineComment}
+		}
+		setNode(existing, keys[1:], value)
+		return
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: keys[0]}
+	if len(keys) == 1 {
+		mapping.Content = append(mapping.Content, key, value)
+		return
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	mapping.Content = append(mapping.Content, key, child)
+	setNode(child, keys[1:], value)
+}
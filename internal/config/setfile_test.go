@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetFileValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	original := `# My codespaces
+defaults:
+  idle_timeout: 60 # an hour
+  machine: basicLinux32gb
+repos:
+  github/github:
+    alias: gh
+`
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sets := map[string]string{
+		"defaults.machine":          "largePremiumLinux",
+		"defaults.idle_timeout":     "90",
+		"repos.github/github.ports": "80,3000",
+		"repos.owner/new.alias":     "new",
+	}
+	for key, value := range sets {
+		if err := SetFileValue(path, key, value); err != nil {
+			t.Fatalf("SetFileValue(%q, %q) failed: %v", key, value, err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# My codespaces
+defaults:
+  idle_timeout: 90 # an hour
+  machine: largePremiumLinux
+repos:
+  github/github:
+    alias: gh
+    ports:
+      - 80
+      - 3000
+  owner/new:
+    alias: new
+`
+	if string(data) != want {
+		t.Errorf("config file =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestSetFileValueInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	original := "defaults:\n  machine: basicLinux32gb\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := SetFileValue(path, "defaults.idle_timeout", "0")
+	if err == nil || !strings.Contains(err.Error(), "defaults.idle_timeout must be positive") {
+		t.Fatalf("SetFileValue() error = %v, want the validation problem", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Errorf("config file changed to\n%s", data)
+	}
+}
+
+func TestSetFileValueNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gh-csd", "config.yaml")
+	if err := SetFileValue(path, "server.nice", "10"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "server:\n  nice: 10\n"; string(data) != want {
+		t.Errorf("config file = %q, want %q", data, want)
+	}
+}
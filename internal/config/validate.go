@@ -0,0 +1,247 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// memoryLimitSupported reports whether server.max_memory_mb can be applied.
+// macOS's sh rejects `ulimit -v`, so every limited command would fail there.
+var memoryLimitSupported = runtime.GOOS != "darwin"
+
+// titlePlaceholders are the placeholders terminal.title_format supports.
+var titlePlaceholders = map[string]bool{
+	"{repo}":       true,
+	"{short_repo}": true,
+	"{branch}":     true,
+	"{name}":       true,
+}
+
+var titlePlaceholderPattern = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// notificationBackends are the values notifications.backend accepts.
+var notificationBackends = []string{"osascript", "terminal-notifier", "notify-send", "none"}
+
+// Validate checks settings that parse fine but can't work, such as an
+// empty machine type or an out-of-range port. Each invalid field is reset
+// to its default (or dropped) so callers can carry on, and described in
+// the returned errors.
+func Validate(c *Config) []error {
+	defaults := DefaultConfig()
+	var errs []error
+
+	if c.Defaults.Machine == "" {
+		c.Defaults.Machine = defaults.Defaults.Machine
+		errs = append(errs, fmt.Errorf("defaults.machine is empty; using %s", c.Defaults.Machine))
+	}
+	if c.Defaults.IdleTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("defaults.idle_timeout must be positive, got %d; using %d", c.Defaults.IdleTimeout, defaults.Defaults.IdleTimeout))
+		c.Defaults.IdleTimeout = defaults.Defaults.IdleTimeout
+	}
+
+	for _, placeholder := range titlePlaceholderPattern.FindAllString(c.Terminal.TitleFormat, -1) {
+		if !titlePlaceholders[placeholder] {
+			errs = append(errs, fmt.Errorf("terminal.title_format has unknown placeholder %s; using %q", placeholder, defaults.Terminal.TitleFormat))
+			c.Terminal.TitleFormat = defaults.Terminal.TitleFormat
+			break
+		}
+	}
+
+	if backend := c.Notifications.Backend; backend != "" && !slices.Contains(notificationBackends, backend) {
+		errs = append(errs, fmt.Errorf("notifications.backend %q is not one of %s; detecting one instead", backend, strings.Join(notificationBackends, ", ")))
+		c.Notifications.Backend = ""
+	}
+
+	if c.Server.Nice < -20 || c.Server.Nice > 19 {
+		errs = append(errs, fmt.Errorf("server.nice must be between -20 and 19, got %d; leaving priorities unchanged", c.Server.Nice))
+		c.Server.Nice = 0
+	}
+	if c.Server.MaxCPUSeconds < 0 {
+		errs = append(errs, fmt.Errorf("server.max_cpu_seconds must not be negative, got %d; not limiting CPU time", c.Server.MaxCPUSeconds))
+		c.Server.MaxCPUSeconds = 0
+	}
+	if c.Server.MaxMemoryMB < 0 {
+		errs = append(errs, fmt.Errorf("server.max_memory_mb must not be negative, got %d; not limiting memory", c.Server.MaxMemoryMB))
+		c.Server.MaxMemoryMB = 0
+	}
+	if c.Server.MaxMemoryMB > 0 && !memoryLimitSupported {
+		errs = append(errs, fmt.Errorf("server.max_memory_mb is not supported on macOS; not limiting memory"))
+		c.Server.MaxMemoryMB = 0
+	}
+
+	errs = append(errs, validateTemplates(c)...)
+
+	repos := make([]string, 0, len(c.Repos))
+	for repo := range c.Repos {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+
+	aliasOwner := make(map[string]string)
+	for _, repo := range repos {
+		cfg, repoErrs := validateRepo("repos."+repo, c.Repos[repo])
+		errs = append(errs, repoErrs...)
+
+		if _, ok := c.Templates[cfg.Template]; cfg.Template != "" && !ok {
+			errs = append(errs, fmt.Errorf("repos.%s.template: no template named %q; ignoring it", repo, cfg.Template))
+			cfg.Template = ""
+		}
+
+		if cfg.Alias != "" {
+			if owner, taken := aliasOwner[cfg.Alias]; taken {
+				errs = append(errs, fmt.Errorf("repos.%s.alias %q is already used by %s; ignoring it", repo, cfg.Alias, owner))
+				cfg.Alias = ""
+			} else {
+				aliasOwner[cfg.Alias] = repo
+			}
+		}
+
+		c.Repos[repo] = cfg
+	}
+
+	return errs
+}
+
+// validateRepo checks the settings of a repo or template at path, such as
+// "repos.github/github".
+func validateRepo(path string, cfg Repo) (Repo, []error) {
+	var errs []error
+
+	if cfg.IdleTimeout < 0 {
+		errs = append(errs, fmt.Errorf("%s.idle_timeout must not be negative, got %d; using the default", path, cfg.IdleTimeout))
+		cfg.IdleTimeout = 0
+	}
+
+	var ports []int
+	for _, port := range cfg.Ports {
+		if port < 1 || port > 65535 {
+			errs = append(errs, fmt.Errorf("%s.ports: %d is not a valid port (1-65535); ignoring it", path, port))
+			continue
+		}
+		ports = append(ports, port)
+	}
+	if len(ports) != len(cfg.Ports) {
+		cfg.Ports = ports
+	}
+
+	return cfg, errs
+}
+
+// validateTemplates checks each template's settings and its reference to
+// another template, breaking any cycles so templates can be resolved.
+func validateTemplates(c *Config) []error {
+	var errs []error
+
+	names := make([]string, 0, len(c.Templates))
+	for name := range c.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := "templates." + name
+		tmpl, tmplErrs := validateRepo(path, c.Templates[name])
+		errs = append(errs, tmplErrs...)
+
+		if tmpl.Alias != "" {
+			errs = append(errs, fmt.Errorf("%s.alias: an alias names a single repo, so templates can't set one; ignoring it", path))
+			tmpl.Alias = ""
+		}
+		if _, ok := c.Templates[tmpl.Template]; tmpl.Template != "" && !ok {
+			errs = append(errs, fmt.Errorf("%s.template: no template named %q; ignoring it", path, tmpl.Template))
+			tmpl.Template = ""
+		}
+		c.Templates[name] = tmpl
+	}
+
+	// Follow each chain; the template that leads back into it loses its
+	// reference
+	for _, name := range names {
+		chain := []string{name}
+		seen := map[string]bool{name: true}
+		for next := c.Templates[name].Template; next != ""; next = c.Templates[next].Template {
+			if seen[next] {
+				last := chain[len(chain)-1]
+				errs = append(errs, fmt.Errorf("templates.%s.template: %s is a cycle; ignoring it", last, strings.Join(append(chain, next), " -> ")))
+				tmpl := c.Templates[last]
+				tmpl.Template = ""
+				c.Templates[last] = tmpl
+				break
+			}
+			seen[next] = true
+			chain = append(chain, next)
+		}
+	}
+
+	return errs
+}
+
+// LoadAndValidate is Load for commands that report configuration problems.
+// Unknown keys and values of the wrong type are returned as warnings with
+// their line numbers instead of failing, keeping the defaults for those
+// fields, followed by the problems found by Validate. The error is only
+// set when the file can't be read or isn't valid YAML.
+func LoadAndValidate() (*Config, []error, error) {
+	path, err := configPath()
+	if err != nil {
+		return DefaultConfig(), nil, nil
+	}
+
+	cfg, warnings, err := LoadFileAndValidate(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultConfig(), nil, nil
+	}
+	return cfg, warnings, err
+}
+
+// LoadFileAndValidate is LoadAndValidate for the config file at path, such
+// as a config shared in a repository. A missing file is an error.
+func LoadFileAndValidate(path string) (*Config, []error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg, warnings, err := decodeStrict(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, warning := range warnings {
+		warnings[i] = fmt.Errorf("%s: %w", path, warning)
+	}
+	return cfg, append(warnings, Validate(cfg)...), nil
+}
+
+// decodeStrict decodes data over the defaults, returning each unknown key
+// or mistyped value as a separate warning. yaml.v3 keeps decoding past
+// such errors, so the rest of the config still applies.
+func decodeStrict(data []byte) (*Config, []error, error) {
+	cfg := DefaultConfig()
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err := decoder.Decode(cfg)
+
+	var typeErr *yaml.TypeError
+	switch {
+	case err == nil || errors.Is(err, io.EOF):
+		return cfg, nil, nil
+	case errors.As(err, &typeErr):
+		warnings := make([]error, len(typeErr.Errors))
+		for i, msg := range typeErr.Errors {
+			warnings[i] = errors.New(strings.TrimSpace(msg))
+		}
+		return cfg, warnings, nil
+	}
+	return nil, nil, err
+}
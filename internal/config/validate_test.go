@@ -0,0 +1,270 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+		check  func(*testing.T, *Config)
+	}{
+		{
+			name:   "empty machine",
+			modify: func(c *Config) { c.Defaults.Machine = "" },
+			want:   "defaults.machine is empty",
+			check: func(t *testing.T, c *Config) {
+				if c.Defaults.Machine != DefaultConfig().Defaults.Machine {
+					t.Errorf("machine = %q, want the default", c.Defaults.Machine)
+				}
+			},
+		},
+		{
+			name:   "non-positive idle timeout",
+			modify: func(c *Config) { c.Defaults.IdleTimeout = 0 },
+			want:   "defaults.idle_timeout must be positive",
+			check: func(t *testing.T, c *Config) {
+				if c.Defaults.IdleTimeout != 240 {
+					t.Errorf("idle_timeout = %d, want 240", c.Defaults.IdleTimeout)
+				}
+			},
+		},
+		{
+			name:   "unknown notification backend",
+			modify: func(c *Config) { c.Notifications.Backend = "growl" },
+			want:   `notifications.backend "growl" is not one of`,
+			check: func(t *testing.T, c *Config) {
+				if c.Notifications.Backend != "" {
+					t.Errorf("backend = %q, want it reset", c.Notifications.Backend)
+				}
+			},
+		},
+		{
+			name:   "server niceness out of range",
+			modify: func(c *Config) { c.Server.Nice = 25 },
+			want:   "server.nice must be between -20 and 19",
+			check: func(t *testing.T, c *Config) {
+				if c.Server.Nice != 0 {
+					t.Errorf("nice = %d, want it reset", c.Server.Nice)
+				}
+			},
+		},
+		{
+			name: "negative repo idle timeout",
+			modify: func(c *Config) {
+				c.Repos["github/meuse"] = Repo{Alias: "meuse", IdleTimeout: -5}
+			},
+			want: "repos.github/meuse.idle_timeout must not be negative",
+			check: func(t *testing.T, c *Config) {
+				if got := c.GetEffectiveIdleTimeout("github/meuse"); got != 240 {
+					t.Errorf("effective idle timeout = %d, want 240", got)
+				}
+			},
+		},
+		{
+			name: "ports out of range",
+			modify: func(c *Config) {
+				c.Repos["github/meuse"] = Repo{Alias: "meuse", Ports: []int{0, 3000, 70000}}
+			},
+			want: "repos.github/meuse.ports: 0 is not a valid port",
+			check: func(t *testing.T, c *Config) {
+				if got := c.Repos["github/meuse"].Ports; !reflect.DeepEqual(got, []int{3000}) {
+					t.Errorf("ports = %v, want [3000]", got)
+				}
+			},
+		},
+		{
+			name:   "unknown title placeholder",
+			modify: func(c *Config) { c.Terminal.TitleFormat = "{short_repo}:{brnch}" },
+			want:   "unknown placeholder {brnch}",
+			check: func(t *testing.T, c *Config) {
+				if c.Terminal.TitleFormat != DefaultConfig().Terminal.TitleFormat {
+					t.Errorf("title_format = %q, want the default", c.Terminal.TitleFormat)
+				}
+			},
+		},
+		{
+			name:   "duplicate alias",
+			modify: func(c *Config) { c.Repos["github/zzz"] = Repo{Alias: "gh"} },
+			want:   `repos.github/zzz.alias "gh" is already used by github/github`,
+			check: func(t *testing.T, c *Config) {
+				if got := c.ResolveAlias("gh"); got != "github/github" {
+					t.Errorf("ResolveAlias(gh) = %q, want github/github", got)
+				}
+			},
+		},
+		{
+			name: "missing template",
+			modify: func(c *Config) {
+				c.Repos["github/meuse"] = Repo{Alias: "meuse", Template: "web"}
+			},
+			want: `repos.github/meuse.template: no template named "web"`,
+			check: func(t *testing.T, c *Config) {
+				if got := c.Repos["github/meuse"].Template; got != "" {
+					t.Errorf("template = %q, want it dropped", got)
+				}
+			},
+		},
+		{
+			name: "template cycle",
+			modify: func(c *Config) {
+				c.Templates = map[string]Repo{
+					"a": {Template: "b"},
+					"b": {Template: "c", Machine: "large"},
+					"c": {Template: "b"},
+				}
+				c.Repos["github/meuse"] = Repo{Alias: "meuse", Template: "a"}
+			},
+			want: "templates.c.template: a -> b -> c -> b is a cycle",
+			check: func(t *testing.T, c *Config) {
+				if got := c.Templates["c"].Template; got != "" {
+					t.Errorf("templates.c.template = %q, want it dropped", got)
+				}
+				if got := c.GetEffectiveMachine("github/meuse"); got != "large" {
+					t.Errorf("effective machine = %q, want large", got)
+				}
+			},
+		},
+		{
+			name: "template ports out of range",
+			modify: func(c *Config) {
+				c.Templates = map[string]Repo{"web": {Ports: []int{3000, 0}}}
+			},
+			want: "templates.web.ports: 0 is not a valid port",
+			check: func(t *testing.T, c *Config) {
+				if got := c.Templates["web"].Ports; !reflect.DeepEqual(got, []int{3000}) {
+					t.Errorf("ports = %v, want [3000]", got)
+				}
+			},
+		},
+	}
+
+	if errs := Validate(DefaultConfig()); len(errs) != 0 {
+		t.Fatalf("Validate(DefaultConfig()) = %v, want no errors", errs)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.modify(cfg)
+
+			errs := Validate(cfg)
+			if len(errs) == 0 || !strings.Contains(errs[0].Error(), tt.want) {
+				t.Fatalf("Validate() = %v, want an error containing %q", errs, tt.want)
+			}
+			tt.check(t, cfg)
+
+			if errs := Validate(cfg); len(errs) != 0 {
+				t.Errorf("Validate() after reset = %v, want no errors", errs)
+			}
+		})
+	}
+}
+
+func TestValidateMemoryLimitUnsupported(t *testing.T) {
+	supported := memoryLimitSupported
+	memoryLimitSupported = false
+	defer func() { memoryLimitSupported = supported }()
+
+	c := DefaultConfig()
+	c.Server.MaxMemoryMB = 4096
+	errs := Validate(c)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "server.max_memory_mb is not supported") {
+		t.Fatalf("Validate = %v, want a max_memory_mb error", errs)
+	}
+	if c.Server.MaxMemoryMB != 0 {
+		t.Errorf("max_memory_mb = %d, want it cleared", c.Server.MaxMemoryMB)
+	}
+}
+
+func TestLoadAndValidate(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+
+	content := `defaults:
+  machin: largePremiumLinux
+  idle_timeout: soon
+  ssh_retry: true
+repos:
+  owner/repo:
+    ports: [8080, 99999]
+`
+	path := filepath.Join(tmpDir, "gh-csd", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, warnings, err := LoadAndValidate()
+	if err != nil {
+		t.Fatalf("LoadAndValidate() failed: %v", err)
+	}
+
+	var messages []string
+	for _, w := range warnings {
+		messages = append(messages, w.Error())
+	}
+	joined := strings.Join(messages, "\n")
+	for _, want := range []string{"line 2: field machin not found", "line 3: cannot unmarshal", "99999 is not a valid port"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("warnings %q don't mention %q", messages, want)
+		}
+	}
+
+	// Fields around the bad ones still apply
+	if !cfg.Defaults.SSHRetry {
+		t.Error("ssh_retry should still be loaded")
+	}
+	if cfg.Defaults.IdleTimeout != 240 {
+		t.Errorf("idle_timeout = %d, want the default 240", cfg.Defaults.IdleTimeout)
+	}
+	if got := cfg.Repos["owner/repo"].Ports; !reflect.DeepEqual(got, []int{8080}) {
+		t.Errorf("ports = %v, want [8080]", got)
+	}
+}
+
+func TestLoadAndValidateSyntaxError(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmpDir)
+
+	path := filepath.Join(tmpDir, "gh-csd", "config.yaml")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte("defaults:\n  machine: [unclosed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := LoadAndValidate(); err == nil {
+		t.Fatal("LoadAndValidate() succeeded on invalid YAML")
+	}
+}
+
+func TestLoadFileAndValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared.yaml")
+	if err := os.WriteFile(path, []byte("defaults:\n  machine: \"\"\n  idel_timeout: 30\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, warnings, err := LoadFileAndValidate(path)
+	if err != nil {
+		t.Fatalf("LoadFileAndValidate() failed: %v", err)
+	}
+	if len(warnings) != 2 {
+		t.Fatalf("warnings = %v, want the unknown key and the empty machine", warnings)
+	}
+	if !strings.HasPrefix(warnings[0].Error(), path+": line 3") {
+		t.Errorf("warnings[0] = %q, want it prefixed with the path and line", warnings[0])
+	}
+
+	if _, _, err := LoadFileAndValidate(filepath.Join(t.TempDir(), "missing.yaml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadFileAndValidate() on a missing file = %v, want ErrNotExist", err)
+	}
+}
@@ -0,0 +1,123 @@
+package gh
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ListCacheTTL is how long 'gh cs list' results are reused across calls
+// (and gh-csd invocations). 0 disables the cache.
+var ListCacheTTL = 10 * time.Second
+
+// cacheMu serializes cache file access within this process.
+var cacheMu sync.Mutex
+
+// cacheEntry is one cached 'gh cs list' result.
+type cacheEntry struct {
+	FetchedAt time.Time       `json:"fetched_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// cachePath returns the path to the cache file (~/.csd/cache.json).
+func cachePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".csd", "cache.json"), nil
+}
+
+// loadCache reads all cache entries, keyed by the requested JSON field set.
+// A missing or unreadable cache is treated as empty.
+func loadCache() map[string]cacheEntry {
+	entries := map[string]cacheEntry{}
+	path, err := cachePath()
+	if err != nil {
+		return entries
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return entries
+	}
+	json.Unmarshal(data, &entries)
+	return entries
+}
+
+// readCache returns the cached output for key if it is younger than
+// ListCacheTTL.
+func readCache(key string) ([]byte, bool) {
+	if ListCacheTTL <= 0 {
+		return nil, false
+	}
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	entry, ok := loadCache()[key]
+	if !ok || time.Since(entry.FetchedAt) > ListCacheTTL {
+		return nil, false
+	}
+	return entry.Data, true
+}
+
+// writeCache stores output for key. The cache is only an optimization, so
+// failures are ignored.
+func writeCache(key string, output []byte) {
+	if ListCacheTTL <= 0 || !json.Valid(output) {
+		return
+	}
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	path, err := cachePath()
+	if err != nil {
+		return
+	}
+
+	entries := loadCache()
+	entries[key] = cacheEntry{FetchedAt: time.Now(), Data: output}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+
+	// Write to a temp file and rename so concurrent readers never see a
+	// partially written cache
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "cache-*.json")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		return
+	}
+	os.Rename(tmp.Name(), path)
+}
+
+// InvalidateCache drops all cached 'gh cs list' results. Call it after
+// anything that changes the set or state of codespaces.
+func InvalidateCache() error {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	path, err := cachePath()
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package gh
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	origTTL := ListCacheTTL
+	defer func() { ListCacheTTL = origTTL }()
+	ListCacheTTL = time.Minute
+
+	// Test read with nothing cached
+	if _, ok := readCache("name,state"); ok {
+		t.Fatal("readCache() with empty cache: got ok, want miss")
+	}
+
+	writeCache("name,state", []byte(`[{"name":"a"}]`))
+	writeCache("name", []byte(`[{"name":"b"}]`))
+
+	// Entries are keyed by field set
+	data, ok := readCache("name,state")
+	if !ok || string(data) != `[{"name":"a"}]` {
+		t.Fatalf("readCache(name,state) = %s, %v", data, ok)
+	}
+	data, ok = readCache("name")
+	if !ok || string(data) != `[{"name":"b"}]` {
+		t.Fatalf("readCache(name) = %s, %v", data, ok)
+	}
+
+	// Invalid JSON is never cached
+	writeCache("bad", []byte("not json"))
+	if _, ok := readCache("bad"); ok {
+		t.Error("readCache(bad): got ok, want miss")
+	}
+
+	// Test InvalidateCache
+	if err := InvalidateCache(); err != nil {
+		t.Fatalf("InvalidateCache() failed: %v", err)
+	}
+	if _, ok := readCache("name,state"); ok {
+		t.Error("readCache() after InvalidateCache: got ok, want miss")
+	}
+
+	// Test disabled cache
+	writeCache("name", []byte(`[]`))
+	ListCacheTTL = 0
+	if _, ok := readCache("name"); ok {
+		t.Error("readCache() with TTL 0: got ok, want miss")
+	}
+}
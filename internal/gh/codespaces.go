@@ -4,9 +4,14 @@ package gh
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 )
 
+// NoBranchPlaceholder is shown in place of a branch for codespaces whose
+// git status is not available yet (e.g. still provisioning or failed).
+const NoBranchPlaceholder = "(no branch)"
+
 // Codespace represents a GitHub Codespace.
 type Codespace struct {
 	Name        string    `json:"name"`
@@ -17,31 +22,67 @@ type Codespace struct {
 	MachineName string    `json:"machineName"`
 	CreatedAt   time.Time `json:"createdAt"`
 	LastUsedAt  time.Time `json:"lastUsedAt"`
+
+	// HasUncommittedChanges and HasUnpushedChanges come from the
+	// codespace's last reported git status.
+	HasUncommittedChanges bool `json:"gitStatus.hasUncommittedChanges"`
+	HasUnpushedChanges    bool `json:"gitStatus.hasUnpushedChanges"`
+}
+
+// HasUnsavedChanges reports whether deleting the codespace would lose
+// uncommitted or unpushed work.
+func (c *Codespace) HasUnsavedChanges() bool {
+	return c.HasUncommittedChanges || c.HasUnpushedChanges
+}
+
+// DisplayBranch returns the branch name, or NoBranchPlaceholder if unknown.
+func (c *Codespace) DisplayBranch() string {
+	if c.Branch == "" {
+		return NoBranchPlaceholder
+	}
+	return c.Branch
 }
 
 // codespaceJSON is used for parsing the gh cs list output.
+// gitStatus may be null for codespaces that are provisioning or in an error state.
 type codespaceJSON struct {
 	Name        string `json:"name"`
 	DisplayName string `json:"displayName"`
 	State       string `json:"state"`
 	Repository  string `json:"repository"`
-	GitStatus   struct {
-		Ref string `json:"ref"`
+	GitStatus   *struct {
+		Ref                   string `json:"ref"`
+		HasUncommittedChanges bool   `json:"hasUncommittedChanges"`
+		HasUnpushedChanges    bool   `json:"hasUnpushedChanges"`
 	} `json:"gitStatus"`
 	MachineName string `json:"machineName"`
 	CreatedAt   string `json:"createdAt"`
 	LastUsedAt  string `json:"lastUsedAt"`
 }
 
+// codespaceListFields are the JSON fields requested from gh cs list.
+const codespaceListFields = "name,displayName,state,repository,gitStatus,machineName,createdAt,lastUsedAt"
+
 // ListCodespaces returns all codespaces for the authenticated user.
+// Results are cached for ListCacheTTL.
 func ListCodespaces() ([]Codespace, error) {
-	result, err := Run("cs", "list", "--json", "name,displayName,state,repository,gitStatus,machineName,createdAt,lastUsedAt")
+	if data, ok := readCache(codespaceListFields); ok {
+		return parseCodespaces(data)
+	}
+
+	result, err := Run("cs", "list", "--json", codespaceListFields)
 	if err != nil {
 		return nil, err
 	}
 
+	writeCache(codespaceListFields, result.Stdout)
+	return parseCodespaces(result.Stdout)
+}
+
+// parseCodespaces converts gh cs list JSON output into Codespaces.
+func parseCodespaces(data []byte) ([]Codespace, error) {
 	var raw []codespaceJSON
-	if err := json.Unmarshal(result.Stdout, &raw); err != nil {
+	if err := json.Unmarshal(data, &raw); err != nil {
 		return nil, fmt.Errorf("failed to parse codespaces: %w", err)
 	}
 
@@ -52,11 +93,15 @@ func ListCodespaces() ([]Codespace, error) {
 			DisplayName: cs.DisplayName,
 			State:       cs.State,
 			Repository:  cs.Repository,
-			Branch:      cs.GitStatus.Ref,
 			MachineName: cs.MachineName,
 			CreatedAt:   parseTime(cs.CreatedAt),
 			LastUsedAt:  parseTime(cs.LastUsedAt),
 		}
+		if cs.GitStatus != nil {
+			codespaces[i].Branch = cs.GitStatus.Ref
+			codespaces[i].HasUncommittedChanges = cs.GitStatus.HasUncommittedChanges
+			codespaces[i].HasUnpushedChanges = cs.GitStatus.HasUnpushedChanges
+		}
 	}
 
 	return codespaces, nil
@@ -107,3 +152,60 @@ func parseTime(value string) time.Time {
 
 	return time.Time{}
 }
+
+// CurrentUser returns the login of the authenticated user.
+func CurrentUser() (string, error) {
+	result, err := Run("api", "user", "--jq", ".login")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(result.Stdout)), nil
+}
+
+// StopCodespace stops a running codespace.
+func StopCodespace(name string) error {
+	_, err := Run("cs", "stop", "-c", name)
+	InvalidateCache()
+	return err
+}
+
+// RebuildCodespace rebuilds a codespace's dev container. A full rebuild
+// skips the container cache.
+func RebuildCodespace(name string, full bool) error {
+	args := []string{"cs", "rebuild", "-c", name}
+	if full {
+		args = append(args, "--full")
+	}
+	_, err := Run(args...)
+	InvalidateCache()
+	return err
+}
+
+// StartCodespace asks GitHub to start a stopped codespace. gh cs has no
+// start subcommand, so this goes through the REST API.
+func StartCodespace(name string) error {
+	_, err := Run("api", "--method", "POST", fmt.Sprintf("user/codespaces/%s/start", name))
+	InvalidateCache()
+	return err
+}
+
+// RenameCodespace sets the display name of a codespace. Its name, which
+// commands take, stays the same.
+func RenameCodespace(name, displayName string) error {
+	_, err := Run("cs", "edit", "-c", name, "--display-name", displayName)
+	InvalidateCache()
+	return err
+}
+
+// CodespaceWebURL returns the URL of the codespace's web editor.
+func CodespaceWebURL(name string) (string, error) {
+	result, err := Run("api", "user/codespaces/"+name, "--jq", ".web_url")
+	if err != nil {
+		return "", err
+	}
+	url := strings.TrimSpace(string(result.Stdout))
+	if url == "" {
+		return "", fmt.Errorf("codespace %q has no web URL", name)
+	}
+	return url, nil
+}
@@ -0,0 +1,66 @@
+package gh
+
+import "testing"
+
+func TestParseCodespaces(t *testing.T) {
+	data := []byte(`[
+		{
+			"name": "ready-cs",
+			"displayName": "ready",
+			"state": "Available",
+			"repository": "github/github",
+			"gitStatus": {"ref": "main", "hasUncommittedChanges": true, "hasUnpushedChanges": false},
+			"machineName": "xLargePremiumLinux",
+			"createdAt": "2024-01-02T03:04:05Z",
+			"lastUsedAt": "2024-01-02T03:04:05Z"
+		},
+		{
+			"name": "provisioning-cs",
+			"state": "Provisioning",
+			"repository": "github/github",
+			"gitStatus": null,
+			"machineName": null
+		},
+		{
+			"name": "error-cs",
+			"state": "Failed",
+			"repository": "github/meuse"
+		}
+	]`)
+
+	codespaces, err := parseCodespaces(data)
+	if err != nil {
+		t.Fatalf("parseCodespaces failed: %v", err)
+	}
+	if len(codespaces) != 3 {
+		t.Fatalf("expected 3 codespaces, got %d", len(codespaces))
+	}
+
+	if codespaces[0].Branch != "main" {
+		t.Errorf("Branch = %q, want main", codespaces[0].Branch)
+	}
+	if !codespaces[0].HasUncommittedChanges || codespaces[0].HasUnpushedChanges {
+		t.Errorf("git status = uncommitted %v, unpushed %v; want true, false", codespaces[0].HasUncommittedChanges, codespaces[0].HasUnpushedChanges)
+	}
+	if !codespaces[0].HasUnsavedChanges() {
+		t.Error("HasUnsavedChanges() = false, want true")
+	}
+	if codespaces[0].CreatedAt.IsZero() {
+		t.Error("CreatedAt should be parsed")
+	}
+
+	for _, cs := range codespaces[1:] {
+		if cs.Branch != "" {
+			t.Errorf("%s: Branch = %q, want empty", cs.Name, cs.Branch)
+		}
+		if cs.MachineName != "" {
+			t.Errorf("%s: MachineName = %q, want empty", cs.Name, cs.MachineName)
+		}
+		if cs.HasUnsavedChanges() {
+			t.Errorf("%s: HasUnsavedChanges() = true without a git status", cs.Name)
+		}
+		if got := cs.DisplayBranch(); got != NoBranchPlaceholder {
+			t.Errorf("%s: DisplayBranch() = %q, want %q", cs.Name, got, NoBranchPlaceholder)
+		}
+	}
+}
@@ -2,13 +2,34 @@ package gh
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 )
 
+// ErrNotInstalled is returned when gh isn't on the PATH.
+var ErrNotInstalled = errors.New("the GitHub CLI (gh) is not installed or not on your PATH; install it from https://cli.github.com")
+
+var (
+	ensureOnce sync.Once
+	ensureErr  error
+)
+
+// EnsureGH returns ErrNotInstalled if gh can't be found. The lookup is
+// done once per process.
+func EnsureGH() error {
+	ensureOnce.Do(func() {
+		if _, err := exec.LookPath("gh"); err != nil {
+			ensureErr = ErrNotInstalled
+		}
+	})
+	return ensureErr
+}
+
 // Result holds the output from a gh command.
 type Result struct {
 	Stdout []byte
@@ -25,16 +46,31 @@ func Run(args ...string) (*Result, error) {
 // The env slice should contain strings in "KEY=VALUE" format.
 // If the command fails, the error includes the stderr content.
 func RunWithEnv(env []string, args ...string) (*Result, error) {
+	return run(env, nil, args...)
+}
+
+// RunWithStdin executes a gh command with stdin read from r. Use it to
+// pass values that shouldn't appear in the process's arguments.
+func RunWithStdin(r io.Reader, args ...string) (*Result, error) {
+	return run(nil, r, args...)
+}
+
+func run(env []string, stdin io.Reader, args ...string) (*Result, error) {
+	if err := EnsureGH(); err != nil {
+		return &Result{}, err
+	}
+
 	cmd := exec.Command("gh", args...)
 	if len(env) > 0 {
 		cmd.Env = append(os.Environ(), env...)
 	}
+	cmd.Stdin = stdin
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	err := RunTraced(cmd)
 	result := &Result{
 		Stdout: stdout.Bytes(),
 		Stderr: stderr.Bytes(),
@@ -67,7 +103,7 @@ func RunWithStderrAndEnv(env []string, args ...string) (*Result, error) {
 	// Tee stderr to both the buffer and os.Stderr
 	cmd.Stderr = io.MultiWriter(&stderr, os.Stderr)
 
-	err := cmd.Run()
+	err := RunTraced(cmd)
 	result := &Result{
 		Stdout: stdout.Bytes(),
 		Stderr: stderr.Bytes(),
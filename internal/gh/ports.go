@@ -0,0 +1,34 @@
+package gh
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Port is a port of a codespace as reported by gh cs ports.
+type Port struct {
+	SourcePort int    `json:"sourcePort"`
+	Label      string `json:"label"`
+	Visibility string `json:"visibility"`
+	BrowseURL  string `json:"browseUrl"`
+}
+
+// portFields are the JSON fields requested from gh cs ports.
+const portFields = "sourcePort,label,visibility,browseUrl"
+
+// ListPorts returns the ports of codespace name.
+func ListPorts(name string) ([]Port, error) {
+	result, err := Run("cs", "ports", "-c", name, "--json", portFields)
+	if err != nil {
+		return nil, err
+	}
+	return parsePorts(result.Stdout)
+}
+
+func parsePorts(data []byte) ([]Port, error) {
+	var ports []Port
+	if err := json.Unmarshal(data, &ports); err != nil {
+		return nil, fmt.Errorf("failed to parse ports: %w", err)
+	}
+	return ports, nil
+}
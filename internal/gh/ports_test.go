@@ -0,0 +1,28 @@
+package gh
+
+import "testing"
+
+func TestParsePorts(t *testing.T) {
+	data := []byte(`[
+		{"sourcePort": 3000, "label": "web", "visibility": "private", "browseUrl": "https://cs-3000.app.github.dev"},
+		{"sourcePort": 5432, "label": "", "visibility": "org", "browseUrl": ""}
+	]`)
+
+	ports, err := parsePorts(data)
+	if err != nil {
+		t.Fatalf("parsePorts failed: %v", err)
+	}
+	if len(ports) != 2 {
+		t.Fatalf("expected 2 ports, got %d", len(ports))
+	}
+	if ports[0].SourcePort != 3000 || ports[0].Label != "web" || ports[0].BrowseURL != "https://cs-3000.app.github.dev" {
+		t.Errorf("ports[0] = %+v", ports[0])
+	}
+	if ports[1].Visibility != "org" {
+		t.Errorf("ports[1].Visibility = %q, want org", ports[1].Visibility)
+	}
+
+	if _, err := parsePorts([]byte("not json")); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
@@ -0,0 +1,63 @@
+package gh
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// RepoExists reports whether repo ("owner/name") exists and is visible to
+// the authenticated user. Errors other than the repo not being found, such
+// as being offline, are returned.
+func RepoExists(repo string) (bool, error) {
+	result, err := Run("api", fmt.Sprintf("repos/%s", repo), "--silent")
+	if err != nil {
+		if result != nil && bytes.Contains(result.Stderr, []byte("HTTP 404")) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// ListMachineTypes returns the names of the machine types codespaces for
+// repo can be created with.
+func ListMachineTypes(repo string) ([]string, error) {
+	machines, err := ListMachines(repo)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(machines))
+	for i, machine := range machines {
+		names[i] = machine.Name
+	}
+	return names, nil
+}
+
+// Machine is a machine type codespaces can be created with.
+type Machine struct {
+	Name          string `json:"name"`
+	DisplayName   string `json:"display_name"`
+	CPUs          int    `json:"cpus"`
+	MemoryInBytes int64  `json:"memory_in_bytes"`
+}
+
+// ListMachines returns the machine types codespaces for repo can be
+// created with.
+func ListMachines(repo string) ([]Machine, error) {
+	result, err := Run("api", fmt.Sprintf("repos/%s/codespaces/machines", repo))
+	if err != nil {
+		return nil, err
+	}
+	return parseMachines(result.Stdout)
+}
+
+func parseMachines(data []byte) ([]Machine, error) {
+	var resp struct {
+		Machines []Machine `json:"machines"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse machine types: %w", err)
+	}
+	return resp.Machines, nil
+}
@@ -0,0 +1,25 @@
+package gh
+
+import "testing"
+
+func TestParseMachines(t *testing.T) {
+	data := []byte(`{"total_count":2,"machines":[
+		{"name":"basicLinux32gb","display_name":"2 cores, 8 GB RAM, 32 GB storage","operating_system":"linux","storage_in_bytes":34359738368,"memory_in_bytes":8589934592,"cpus":2,"prebuild_availability":null},
+		{"name":"premiumLinux","display_name":"8 cores, 32 GB RAM, 64 GB storage","operating_system":"linux","storage_in_bytes":68719476736,"memory_in_bytes":34359738368,"cpus":8,"prebuild_availability":"ready"}
+	]}`)
+
+	machines, err := parseMachines(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(machines) != 2 {
+		t.Fatalf("parseMachines() returned %d machines, want 2", len(machines))
+	}
+	if m := machines[1]; m.Name != "premiumLinux" || m.CPUs != 8 || m.MemoryInBytes != 34359738368 {
+		t.Errorf("machines[1] = %+v", m)
+	}
+
+	if _, err := parseMachines([]byte("not json")); err == nil {
+		t.Error("parseMachines() of invalid JSON should fail")
+	}
+}
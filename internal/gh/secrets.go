@@ -0,0 +1,44 @@
+package gh
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+)
+
+// SetCodespacesUserSecret sets a Codespaces user secret that is available
+// to codespaces for repo. The value is passed on stdin so it never shows
+// up in the process list. An existing secret with the same name gets the
+// new value and stays available to the repositories it already was, since
+// 'gh secret set --repos' replaces the whole list.
+func SetCodespacesUserSecret(name, value, repo string) error {
+	existing, err := CodespacesUserSecretRepos(name)
+	if err != nil {
+		return err
+	}
+	repos := withRepo(existing, repo)
+	_, err = RunWithStdin(strings.NewReader(value), "secret", "set", name, "--user", "--repos", strings.Join(repos, ","))
+	return err
+}
+
+// CodespacesUserSecretRepos returns the repositories ("owner/name") the
+// Codespaces user secret name is available to, or nil if there is no such
+// secret.
+func CodespacesUserSecretRepos(name string) ([]string, error) {
+	result, err := Run("api", "--paginate", "user/codespaces/secrets/"+name+"/repositories", "--jq", ".repositories[].full_name")
+	if err != nil {
+		if result != nil && bytes.Contains(result.Stderr, []byte("HTTP 404")) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return strings.Fields(string(result.Stdout)), nil
+}
+
+// withRepo returns repos with repo added, unless it is already there.
+func withRepo(repos []string, repo string) []string {
+	if slices.ContainsFunc(repos, func(r string) bool { return strings.EqualFold(r, repo) }) {
+		return repos
+	}
+	return append(repos, repo)
+}
@@ -0,0 +1,22 @@
+package gh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithRepo(t *testing.T) {
+	tests := []struct {
+		repos []string
+		want  []string
+	}{
+		{nil, []string{"github/github"}},
+		{[]string{"octo/app"}, []string{"octo/app", "github/github"}},
+		{[]string{"GitHub/GitHub", "octo/app"}, []string{"GitHub/GitHub", "octo/app"}},
+	}
+	for _, tt := range tests {
+		if got := withRepo(tt.repos, "github/github"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("withRepo(%q) = %q, want %q", tt.repos, got, tt.want)
+		}
+	}
+}
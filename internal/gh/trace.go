@@ -0,0 +1,62 @@
+package gh
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Verbose makes every gh invocation log its arguments and how long it
+// took to stderr. It is set from the global --verbose flag.
+var Verbose bool
+
+// tokenPattern matches GitHub tokens, so one passed as an argument is
+// never logged.
+var tokenPattern = regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]+|github_pat_[A-Za-z0-9_]+)`)
+
+// Trace logs the gh invocation with args when Verbose is set and returns a
+// function to call with its error once it finishes, which logs how long it
+// took. The environment and stdin are never logged.
+func Trace(args []string) func(err error) {
+	if !Verbose {
+		return func(error) {}
+	}
+
+	line := FormatCommand(args)
+	fmt.Fprintf(os.Stderr, "[verbose] %s\n", line)
+	start := time.Now()
+	return func(err error) {
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[verbose] %s failed after %s: %v\n", line, elapsed, err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "[verbose] %s took %s\n", line, elapsed)
+	}
+}
+
+// RunTraced runs cmd, a gh command built by the caller, with Trace.
+func RunTraced(cmd *exec.Cmd) error {
+	done := Trace(cmd.Args[1:])
+	err := cmd.Run()
+	done(err)
+	return err
+}
+
+// FormatCommand returns args as a gh command line, quoting arguments that
+// a shell would split and redacting tokens.
+func FormatCommand(args []string) string {
+	parts := make([]string, 0, len(args)+1)
+	parts = append(parts, "gh")
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$`|&;<>()*?[]{}~#") {
+			arg = strconv.Quote(arg)
+		}
+		parts = append(parts, tokenPattern.ReplaceAllString(arg, "[REDACTED]"))
+	}
+	return strings.Join(parts, " ")
+}
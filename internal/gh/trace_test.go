@@ -0,0 +1,25 @@
+package gh
+
+import "testing"
+
+func TestFormatCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"plain", []string{"cs", "list", "--json", "name"}, "gh cs list --json name"},
+		{"spaces", []string{"cs", "edit", "-c", "cs1", "--display-name", "billing refactor"}, `gh cs edit -c cs1 --display-name "billing refactor"`},
+		{"shell", []string{"cs", "ssh", "-c", "cs1", "--", "echo $HOME; ls"}, `gh cs ssh -c cs1 -- "echo $HOME; ls"`},
+		{"empty", []string{"api", ""}, `gh api ""`},
+		{"token", []string{"api", "-H", "Authorization: token ghp_abc123"}, `gh api -H "Authorization: token [REDACTED]"`},
+		{"fine-grained token", []string{"secret", "set", "X", "-b", "github_pat_11AB_cd"}, "gh secret set X -b [REDACTED]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatCommand(tt.args); got != tt.want {
+				t.Errorf("FormatCommand(%q) = %s, want %s", tt.args, got, tt.want)
+			}
+		})
+	}
+}
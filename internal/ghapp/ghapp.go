@@ -0,0 +1,196 @@
+// Package ghapp mints short-lived, scope-limited GitHub App installation
+// tokens.
+package ghapp
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the GitHub REST API endpoint.
+const DefaultBaseURL = "https://api.github.com"
+
+// App identifies a GitHub App that can mint tokens.
+type App struct {
+	AppID int64
+	// InstallationID, if set, is the only installation tokens are minted
+	// from; repos the app reaches through another one are refused. 0 uses
+	// whichever installation covers the repo.
+	InstallationID int64
+	PrivateKey     *rsa.PrivateKey
+	// BaseURL overrides DefaultBaseURL, e.g. for GitHub Enterprise.
+	BaseURL string
+	Client  *http.Client
+}
+
+// Token is an installation access token.
+type Token struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LoadPrivateKey reads an app's PEM-encoded private key (PKCS #1 as
+// downloaded from GitHub, or PKCS #8).
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an RSA private key", path)
+	}
+	return key, nil
+}
+
+// JWT returns the app's signed JSON Web Token, valid for a few minutes
+// around now.
+func (a *App) JWT(now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		// Backdated to allow for clock drift, as GitHub recommends
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": fmt.Sprint(a.AppID),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// InstallationToken mints a token limited to repo (owner/name) and the
+// given permissions (e.g. {"pull_requests": "write"}). Tokens expire after
+// an hour.
+//
+// An installation token can only name repositories by name, within the
+// installation's account, so the token is minted from the installation
+// that covers repo's owner, looked up first.
+func (a *App) InstallationToken(ctx context.Context, repo string, permissions map[string]string) (*Token, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repository %q (expected owner/repo)", repo)
+	}
+
+	jwt, err := a.JWT(time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign app JWT: %w", err)
+	}
+
+	data, err := a.request(ctx, jwt, http.MethodGet, fmt.Sprintf("/repos/%s/%s/installation", owner, name), nil, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("the app isn't installed for %s: %w", repo, err)
+	}
+	var installation struct {
+		ID      int64 `json:"id"`
+		Account struct {
+			Login string `json:"login"`
+		} `json:"account"`
+	}
+	if err := json.Unmarshal(data, &installation); err != nil {
+		return nil, fmt.Errorf("failed to decode installation: %w", err)
+	}
+	if !strings.EqualFold(installation.Account.Login, owner) {
+		return nil, fmt.Errorf("the app's installation for %s belongs to %q, not %s", repo, installation.Account.Login, owner)
+	}
+	if a.InstallationID != 0 && installation.ID != a.InstallationID {
+		return nil, fmt.Errorf("%s is covered by installation %d, not the configured installation %d", repo, installation.ID, a.InstallationID)
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"repositories": []string{name},
+		"permissions":  permissions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	data, err = a.request(ctx, jwt, http.MethodPost, fmt.Sprintf("/app/installations/%d/access_tokens", installation.ID), body, http.StatusCreated)
+	if err != nil {
+		return nil, fmt.Errorf("GitHub refused to mint a token: %w", err)
+	}
+
+	var token Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if token.Token == "" {
+		return nil, errors.New("GitHub returned an empty token")
+	}
+	return &token, nil
+}
+
+// request makes an API request authenticated as the app and returns the
+// response body, or GitHub's error message if the status isn't want.
+func (a *App) request(ctx context.Context, jwt, method, path string, body []byte, want int) ([]byte, error) {
+	baseURL := a.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(baseURL, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	client := a.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != want {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &apiErr)
+		if apiErr.Message == "" {
+			apiErr.Message = resp.Status
+		}
+		return nil, errors.New(apiErr.Message)
+	}
+	return data, nil
+}
@@ -0,0 +1,175 @@
+package ghapp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	key := testKey(t)
+	path := filepath.Join(t.TempDir(), "app.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadPrivateKey(path)
+	if err != nil {
+		t.Fatalf("LoadPrivateKey failed: %v", err)
+	}
+	if !loaded.Equal(key) {
+		t.Error("loaded key differs from the saved key")
+	}
+}
+
+func TestJWT(t *testing.T) {
+	key := testKey(t)
+	app := &App{AppID: 42, PrivateKey: key}
+	now := time.Unix(1_700_000_000, 0)
+
+	jwt, err := app.JWT(now)
+	if err != nil {
+		t.Fatalf("JWT failed: %v", err)
+	}
+
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("JWT has %d parts, want 3", len(parts))
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Fatalf("signature doesn't verify: %v", err)
+	}
+
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Iss != "42" || claims.Iat >= now.Unix() || claims.Exp <= now.Unix() {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+}
+
+// installationHandler answers the repo installation lookup with
+// installation 7 of the luanzeba account, then calls next.
+func installationHandler(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/installation") {
+			w.Write([]byte(`{"id":7,"account":{"login":"luanzeba"}}`))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func TestInstallationToken(t *testing.T) {
+	server := httptest.NewServer(installationHandler(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/app/installations/7/access_tokens" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			t.Errorf("missing bearer JWT")
+		}
+
+		var body struct {
+			Repositories []string          `json:"repositories"`
+			Permissions  map[string]string `json:"permissions"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Repositories) != 1 || body.Repositories[0] != "gh-csd" {
+			t.Errorf("repositories = %v, want [gh-csd]", body.Repositories)
+		}
+		if body.Permissions["pull_requests"] != "write" {
+			t.Errorf("permissions = %v", body.Permissions)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"token":"ghs_test","expires_at":"2026-10-16T13:00:00Z"}`))
+	}))
+	defer server.Close()
+
+	app := &App{AppID: 1, PrivateKey: testKey(t), BaseURL: server.URL}
+	token, err := app.InstallationToken(context.Background(), "luanzeba/gh-csd", map[string]string{"pull_requests": "write"})
+	if err != nil {
+		t.Fatalf("InstallationToken failed: %v", err)
+	}
+	if token.Token != "ghs_test" || token.ExpiresAt.IsZero() {
+		t.Errorf("token = %+v", token)
+	}
+}
+
+func TestInstallationTokenOtherInstallation(t *testing.T) {
+	server := httptest.NewServer(installationHandler(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("minted a token with %s %s", r.Method, r.URL.Path)
+	}))
+	defer server.Close()
+
+	// A repo reached through another installation than the configured one
+	app := &App{AppID: 1, InstallationID: 8, PrivateKey: testKey(t), BaseURL: server.URL}
+	if _, err := app.InstallationToken(context.Background(), "luanzeba/gh-csd", nil); err == nil || !strings.Contains(err.Error(), "installation 7") {
+		t.Fatalf("err = %v, want the installation mismatch", err)
+	}
+}
+
+func TestInstallationTokenNotInstalled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/repos/octo/foo/installation" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Not Found"}`))
+	}))
+	defer server.Close()
+
+	app := &App{AppID: 1, PrivateKey: testKey(t), BaseURL: server.URL}
+	if _, err := app.InstallationToken(context.Background(), "octo/foo", nil); err == nil || !strings.Contains(err.Error(), "isn't installed for octo/foo") {
+		t.Fatalf("err = %v, want a missing installation", err)
+	}
+}
+
+func TestInstallationTokenRefused(t *testing.T) {
+	server := httptest.NewServer(installationHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message":"The permissions requested are not granted to this installation."}`))
+	}))
+	defer server.Close()
+
+	app := &App{AppID: 1, InstallationID: 7, PrivateKey: testKey(t), BaseURL: server.URL}
+	_, err := app.InstallationToken(context.Background(), "luanzeba/gh-csd", nil)
+	if err == nil || !strings.Contains(err.Error(), "not granted") {
+		t.Fatalf("err = %v, want GitHub's message", err)
+	}
+}
@@ -0,0 +1,156 @@
+// Package notify sends desktop notifications through a configurable
+// backend.
+package notify
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Backend names, as set in notifications.backend. An empty backend picks
+// one for the OS.
+const (
+	BackendOsascript        = "osascript"
+	BackendTerminalNotifier = "terminal-notifier"
+	BackendNotifySend       = "notify-send"
+	BackendNone             = "none"
+)
+
+// DefaultSound is the sound osascript and terminal-notifier play unless
+// another is configured.
+const DefaultSound = "Glass"
+
+// Notifier sends a desktop notification.
+type Notifier interface {
+	Notify(title, message string) error
+}
+
+// Options choose and configure the Notifier returned by New.
+type Options struct {
+	// Backend is one of the Backend names, or empty to detect one.
+	Backend string
+	// Command, if set, is run with sh -c instead of a backend, after
+	// substituting {title} and {message}. They are also set in
+	// $CSD_TITLE and $CSD_MESSAGE.
+	Command string
+	// Sound is the sound name for osascript and terminal-notifier. Empty
+	// means DefaultSound and "none" plays no sound.
+	Sound string
+}
+
+// New returns the Notifier opts describe. Without a backend, it uses
+// osascript on macOS and notify-send on Linux, or sends nothing if that
+// isn't installed, so notifications never get in the way.
+func New(opts Options) (Notifier, error) {
+	if opts.Command != "" {
+		return commandNotifier{template: opts.Command}, nil
+	}
+
+	sound := opts.Sound
+	if sound == "" {
+		sound = DefaultSound
+	} else if sound == "none" {
+		sound = ""
+	}
+
+	backend := opts.Backend
+	if backend == "" {
+		backend = detectBackend()
+	}
+	switch backend {
+	case BackendOsascript:
+		return osascriptNotifier{sound: sound}, nil
+	case BackendTerminalNotifier:
+		return terminalNotifier{sound: sound}, nil
+	case BackendNotifySend:
+		return notifySendNotifier{}, nil
+	case BackendNone:
+		return noneNotifier{}, nil
+	}
+	return nil, fmt.Errorf("unknown notification backend %q (expected %s, %s, %s or %s)", backend, BackendOsascript, BackendTerminalNotifier, BackendNotifySend, BackendNone)
+}
+
+// detectBackend returns the backend for the OS, or BackendNone if its
+// tool isn't installed.
+func detectBackend() string {
+	backend := BackendNone
+	switch runtime.GOOS {
+	case "darwin":
+		backend = BackendOsascript
+	case "linux":
+		backend = BackendNotifySend
+	}
+	if backend != BackendNone {
+		if _, err := exec.LookPath(backend); err != nil {
+			return BackendNone
+		}
+	}
+	return backend
+}
+
+type osascriptNotifier struct {
+	sound string
+}
+
+func (n osascriptNotifier) Notify(title, message string) error {
+	return exec.Command("osascript", "-e", osascriptScript(title, message, n.sound)).Run()
+}
+
+// osascriptScript returns the AppleScript that shows the notification.
+func osascriptScript(title, message, sound string) string {
+	script := fmt.Sprintf("display notification %q with title %q", message, title)
+	if sound != "" {
+		script += fmt.Sprintf(" sound name %q", sound)
+	}
+	return script
+}
+
+type terminalNotifier struct {
+	sound string
+}
+
+func (n terminalNotifier) Notify(title, message string) error {
+	return exec.Command("terminal-notifier", terminalNotifierArgs(title, message, n.sound)...).Run()
+}
+
+func terminalNotifierArgs(title, message, sound string) []string {
+	args := []string{"-title", title, "-message", message, "-group", "gh-csd"}
+	if sound != "" {
+		args = append(args, "-sound", sound)
+	}
+	return args
+}
+
+type notifySendNotifier struct{}
+
+func (notifySendNotifier) Notify(title, message string) error {
+	return exec.Command("notify-send", title, message).Run()
+}
+
+type noneNotifier struct{}
+
+func (noneNotifier) Notify(title, message string) error {
+	return nil
+}
+
+// commandNotifier runs a custom command, like a hook.
+type commandNotifier struct {
+	template string
+}
+
+func (n commandNotifier) Notify(title, message string) error {
+	cmd := exec.Command("sh", "-c", expandCommand(n.template, title, message))
+	cmd.Env = append(os.Environ(), "CSD_TITLE="+title, "CSD_MESSAGE="+message)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// expandCommand substitutes {title} and {message} in template, as-is like
+// hook placeholders. $CSD_TITLE and $CSD_MESSAGE carry them safely.
+func expandCommand(template, title, message string) string {
+	return strings.NewReplacer("{title}", title, "{message}", message).Replace(template)
+}
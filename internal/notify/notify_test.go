@@ -0,0 +1,74 @@
+package notify
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		want    Notifier
+		wantErr bool
+	}{
+		{"osascript", Options{Backend: "osascript"}, osascriptNotifier{sound: DefaultSound}, false},
+		{"osascript with sound", Options{Backend: "osascript", Sound: "Ping"}, osascriptNotifier{sound: "Ping"}, false},
+		{"terminal-notifier silent", Options{Backend: "terminal-notifier", Sound: "none"}, terminalNotifier{}, false},
+		{"notify-send", Options{Backend: "notify-send"}, notifySendNotifier{}, false},
+		{"none", Options{Backend: "none"}, noneNotifier{}, false},
+		{"command wins", Options{Backend: "none", Command: "echo {title}"}, commandNotifier{template: "echo {title}"}, false},
+		{"unknown", Options{Backend: "growl"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOsascriptScript(t *testing.T) {
+	got := osascriptScript("Codespace ready", `say "hi"`, "Glass")
+	want := `display notification "say \"hi\"" with title "Codespace ready" sound name "Glass"`
+	if got != want {
+		t.Errorf("osascriptScript() = %s, want %s", got, want)
+	}
+	if got := osascriptScript("t", "m", ""); got != `display notification "m" with title "t"` {
+		t.Errorf("osascriptScript() without sound = %s", got)
+	}
+}
+
+func TestTerminalNotifierArgs(t *testing.T) {
+	got := terminalNotifierArgs("Codespace ready", "done", "Ping")
+	want := []string{"-title", "Codespace ready", "-message", "done", "-group", "gh-csd", "-sound", "Ping"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("terminalNotifierArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestExpandCommand(t *testing.T) {
+	got := expandCommand(`ntfy publish csd "{title}: {message}"`, "Codespace ready", "super-robot")
+	if want := `ntfy publish csd "Codespace ready: super-robot"`; got != want {
+		t.Errorf("expandCommand() = %s, want %s", got, want)
+	}
+}
+
+func TestCommandNotifier(t *testing.T) {
+	if err := (commandNotifier{template: "test {title} = ready"}).Notify("ready", "msg"); err != nil {
+		t.Errorf("Notify() error = %v", err)
+	}
+	// The environment carries values that aren't safe to substitute
+	message := "it's $(exit 1) ready"
+	if err := (commandNotifier{template: `test "$CSD_TITLE: $CSD_MESSAGE" = "ready: it's \$(exit 1) ready"`}).Notify("ready", message); err != nil {
+		t.Errorf("Notify() with $CSD_MESSAGE error = %v", err)
+	}
+	if err := (commandNotifier{template: "exit 3"}).Notify("t", "m"); err == nil {
+		t.Error("Notify() with a failing command succeeded")
+	}
+}
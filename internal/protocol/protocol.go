@@ -6,22 +6,135 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"slices"
+	"time"
 )
 
 // ExecRequest is sent from the Codespace to the local machine
 // to execute a command.
 type ExecRequest struct {
-	Type    string   `json:"type"`    // Always "exec" for now
+	Type    string   `json:"type"`    // "exec" (buffered), "exec-stream" or "exec-tty"
 	Command []string `json:"command"` // Command and arguments
 	Workdir string   `json:"workdir,omitempty"`
+	Stdin   string   `json:"stdin,omitempty"`   // Piped input for the command
+	Timeout int      `json:"timeout,omitempty"` // Seconds before the command is killed; 0 means no limit
+	Token   string   `json:"token,omitempty"`   // Shared secret, when the server requires one
+	Client  string   `json:"client,omitempty"`  // ID of the 'gh csd ssh' session the request came through
+
+	// Env holds environment variables from the Codespace to set for the
+	// command, on top of the server's own environment.
+	Env map[string]string `json:"env,omitempty"`
+
+	// Repository and Permissions scope a "token" request, e.g.
+	// {"pull_requests": "write"}. Empty Permissions asks for everything
+	// the server allows.
+	Repository  string            `json:"repository,omitempty"`
+	Permissions map[string]string `json:"permissions,omitempty"`
+
+	// Rows and Cols are the initial terminal size of an "exec-tty" request.
+	Rows int `json:"rows,omitempty"`
+	Cols int `json:"cols,omitempty"`
+
+	// Pipe asks for binary-safe input and output, for 'gh csd local
+	// --pipe': stdin is sent in StdinRaw instead of Stdin, and stdout comes
+	// back in Raw frames or ExecResponse.StdoutRaw. JSON carries []byte as
+	// base64, so bytes that aren't valid UTF-8 survive.
+	Pipe     bool   `json:"pipe,omitempty"`
+	StdinRaw []byte `json:"stdin_raw,omitempty"`
+}
+
+// Input returns the stdin for the command, from StdinRaw for Pipe
+// requests and Stdin otherwise.
+func (r *ExecRequest) Input() string {
+	if r.Pipe {
+		return string(r.StdinRaw)
+	}
+	return r.Stdin
 }
 
+// TTYUpgrade is the protocol an "exec-tty" request upgrades its connection
+// to. Once the server answers 101 Switching Protocols, both sides exchange
+// StreamFrames over the connection: the server sends "tty" output and a
+// final "exit" frame, and the client sends "stdin" input and "resize"
+// frames.
+const TTYUpgrade = "csd-tty"
+
 // ExecResponse is sent back from the local machine with the result.
+// When the command timed out, Timeout is set and Stdout and Stderr hold
+// the output it produced before it was killed.
 type ExecResponse struct {
 	Stdout   string `json:"stdout"`
 	Stderr   string `json:"stderr"`
 	ExitCode int    `json:"exit_code"`
 	Error    string `json:"error,omitempty"`
+	Timeout  bool   `json:"timeout,omitempty"`
+
+	// StdoutRaw replaces Stdout in answers to Pipe requests.
+	StdoutRaw []byte `json:"stdout_raw,omitempty"`
+}
+
+// TokenResponse answers a "token" request with a short-lived token.
+type TokenResponse struct {
+	Token     string    `json:"token,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// StatusResponse answers a "status" request. Clients is only filled in
+// for requests that pass the server's token check. Servers older than the
+// diagnostic fields leave them empty.
+type StatusResponse struct {
+	Status          string         `json:"status"`
+	Version         string         `json:"version,omitempty"`
+	PID             int            `json:"pid,omitempty"`
+	StartedAt       time.Time      `json:"started_at,omitzero"`
+	AllowedCommands []string       `json:"allowed_commands,omitempty"`
+	Clients         []ClientStatus `json:"clients,omitempty"`
+
+	// Capabilities lists the optional request features the server
+	// handles, so clients can refuse what an older server would ignore.
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// CapabilityPipe is the capability of servers that handle Pipe requests.
+const CapabilityPipe = "pipe"
+
+// Supports reports whether the server listed capability.
+func (s *StatusResponse) Supports(capability string) bool {
+	return slices.Contains(s.Capabilities, capability)
+}
+
+// ClientStatus is the server's view of one client: a 'gh csd ssh'
+// session, or requests that didn't identify one (empty Codespace).
+type ClientStatus struct {
+	Codespace   string    `json:"codespace,omitempty"`
+	Repository  string    `json:"repository,omitempty"`
+	Connected   bool      `json:"connected"` // the session is still running
+	Requests    int       `json:"requests"`
+	Blocked     int       `json:"blocked"`
+	LastSeen    time.Time `json:"last_seen"`
+	LastCommand []string  `json:"last_command,omitempty"`
+}
+
+// StreamFrame is one newline-delimited JSON frame of an "exec-stream"
+// response. Output frames carry Stream "stdout" or "stderr" with Data.
+// The final frame has Stream "exit" and carries the exit code and any
+// error, with Timeout set if the command was killed by its timeout.
+//
+// For Pipe requests, "stdout" frames carry Raw instead of Data.
+//
+// "exec-tty" sessions use "tty" and "stdin" frames carrying Raw, since
+// terminal I/O isn't necessarily valid UTF-8, and "resize" frames carrying
+// Rows and Cols.
+type StreamFrame struct {
+	Stream   string `json:"stream"`
+	Data     string `json:"data,omitempty"`
+	Raw      []byte `json:"raw,omitempty"`
+	Rows     int    `json:"rows,omitempty"`
+	Cols     int    `json:"cols,omitempty"`
+	ExitCode int    `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Timeout  bool   `json:"timeout,omitempty"`
 }
 
 // WriteRequest encodes and writes a request to the writer.
@@ -57,3 +170,11 @@ func ReadResponse(r io.Reader) (*ExecResponse, error) {
 	}
 	return &resp, nil
 }
+
+// WriteFrame encodes and writes a stream frame to the writer.
+func WriteFrame(w io.Writer, frame *StreamFrame) error {
+	if err := json.NewEncoder(w).Encode(frame); err != nil {
+		return fmt.Errorf("failed to encode frame: %w", err)
+	}
+	return nil
+}
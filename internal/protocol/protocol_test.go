@@ -2,6 +2,8 @@ package protocol
 
 import (
 	"bytes"
+	"encoding/json"
+	"reflect"
 	"testing"
 )
 
@@ -38,6 +40,84 @@ func TestRequestRoundTrip(t *testing.T) {
 	}
 }
 
+func TestRequestRoundTripWithStdin(t *testing.T) {
+	req := &ExecRequest{
+		Type:    "exec",
+		Command: []string{"gh", "pr", "comment", "42", "--body-file", "-"},
+		Stdin:   "multi-line\nbody\n",
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRequest(&buf, req); err != nil {
+		t.Fatalf("WriteRequest failed: %v", err)
+	}
+
+	decoded, err := ReadRequest(&buf)
+	if err != nil {
+		t.Fatalf("ReadRequest failed: %v", err)
+	}
+
+	if decoded.Stdin != req.Stdin {
+		t.Errorf("Stdin mismatch: got %q, want %q", decoded.Stdin, req.Stdin)
+	}
+}
+
+// binaryPayload holds every byte value, so it isn't valid UTF-8.
+func binaryPayload() []byte {
+	payload := make([]byte, 512)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+	return payload
+}
+
+func TestPipeRoundTripBinary(t *testing.T) {
+	payload := binaryPayload()
+
+	var buf bytes.Buffer
+	if err := WriteRequest(&buf, &ExecRequest{Type: "exec", Command: []string{"gh", "gist", "create", "-"}, Pipe: true, StdinRaw: payload}); err != nil {
+		t.Fatalf("WriteRequest failed: %v", err)
+	}
+	req, err := ReadRequest(&buf)
+	if err != nil {
+		t.Fatalf("ReadRequest failed: %v", err)
+	}
+	if got := req.Input(); got != string(payload) {
+		t.Errorf("Input() corrupted the payload: got %d bytes %q...", len(got), got[:min(len(got), 16)])
+	}
+
+	buf.Reset()
+	if err := WriteResponse(&buf, &ExecResponse{StdoutRaw: payload}); err != nil {
+		t.Fatalf("WriteResponse failed: %v", err)
+	}
+	resp, err := ReadResponse(&buf)
+	if err != nil {
+		t.Fatalf("ReadResponse failed: %v", err)
+	}
+	if !bytes.Equal(resp.StdoutRaw, payload) {
+		t.Errorf("StdoutRaw corrupted: got %d bytes", len(resp.StdoutRaw))
+	}
+
+	buf.Reset()
+	if err := WriteFrame(&buf, &StreamFrame{Stream: "stdout", Raw: payload}); err != nil {
+		t.Fatalf("WriteFrame failed: %v", err)
+	}
+	var frame StreamFrame
+	if err := json.NewDecoder(&buf).Decode(&frame); err != nil {
+		t.Fatalf("decoding frame failed: %v", err)
+	}
+	if !bytes.Equal(frame.Raw, payload) {
+		t.Errorf("frame Raw corrupted: got %d bytes", len(frame.Raw))
+	}
+}
+
+func TestInputWithoutPipe(t *testing.T) {
+	req := &ExecRequest{Stdin: "text", StdinRaw: []byte("ignored")}
+	if got := req.Input(); got != "text" {
+		t.Errorf("Input() = %q, want Stdin", got)
+	}
+}
+
 func TestResponseRoundTrip(t *testing.T) {
 	resp := &ExecResponse{
 		Stdout:   "Created PR #42",
@@ -86,3 +166,31 @@ func TestResponseWithError(t *testing.T) {
 		t.Errorf("Error mismatch: got %q, want %q", decoded.Error, resp.Error)
 	}
 }
+
+func TestStreamFrames(t *testing.T) {
+	frames := []*StreamFrame{
+		{Stream: "stdout", Data: "line 1\n"},
+		{Stream: "stderr", Data: "warning\n"},
+		{Stream: "tty", Raw: []byte("\x1b[1mbold\r\n")},
+		{Stream: "resize", Rows: 40, Cols: 120},
+		{Stream: "exit", ExitCode: 3},
+	}
+
+	var buf bytes.Buffer
+	for _, frame := range frames {
+		if err := WriteFrame(&buf, frame); err != nil {
+			t.Fatalf("WriteFrame failed: %v", err)
+		}
+	}
+
+	decoder := json.NewDecoder(&buf)
+	for i, want := range frames {
+		var got StreamFrame
+		if err := decoder.Decode(&got); err != nil {
+			t.Fatalf("decoding frame %d failed: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, *want) {
+			t.Errorf("frame %d mismatch: got %+v, want %+v", i, got, *want)
+		}
+	}
+}
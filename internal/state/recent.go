@@ -0,0 +1,93 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	recentFileName = "recent.json"
+
+	// maxRecent is how many selections are remembered.
+	maxRecent = 10
+)
+
+// RecentEntry is one past selection.
+type RecentEntry struct {
+	Name       string    `json:"name"`
+	SelectedAt time.Time `json:"selected_at"`
+}
+
+// Recent returns past selections, most recent first.
+func Recent() ([]RecentEntry, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, recentFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []RecentEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// SetRecent replaces the list of past selections, e.g. after pruning
+// deleted codespaces.
+func SetRecent(entries []RecentEntry) error {
+	dir, err := stateDir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, recentFileName), data, 0644)
+}
+
+// Previous returns the most recent selection other than current.
+func Previous(current string) (string, bool) {
+	entries, err := Recent()
+	if err != nil {
+		return "", false
+	}
+	for _, entry := range entries {
+		if entry.Name != current {
+			return entry.Name, true
+		}
+	}
+	return "", false
+}
+
+// addRecent moves name to the front of the recent list.
+func addRecent(name string, now time.Time) error {
+	entries, err := Recent()
+	if err != nil {
+		// A corrupt list isn't worth keeping
+		entries = nil
+	}
+
+	updated := []RecentEntry{{Name: name, SelectedAt: now}}
+	for _, entry := range entries {
+		if entry.Name != name && len(updated) < maxRecent {
+			updated = append(updated, entry)
+		}
+	}
+	return SetRecent(updated)
+}
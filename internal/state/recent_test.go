@@ -0,0 +1,57 @@
+package state
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRecent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if entries, err := Recent(); err != nil || len(entries) != 0 {
+		t.Fatalf("Recent() with no history = %v, %v; want empty", entries, err)
+	}
+
+	for _, name := range []string{"a", "b", "c", "b"} {
+		if err := Set(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := Recent()
+	if err != nil {
+		t.Fatalf("Recent() failed: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	if fmt.Sprint(names) != "[b c a]" {
+		t.Errorf("Recent() = %v, want [b c a]", names)
+	}
+	if entries[0].SelectedAt.IsZero() {
+		t.Error("SelectedAt should be set")
+	}
+
+	if prev, ok := Previous("b"); !ok || prev != "c" {
+		t.Errorf("Previous(b) = %q, %v; want c", prev, ok)
+	}
+}
+
+func TestRecentLimit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	for i := 0; i < maxRecent+5; i++ {
+		if err := Set(fmt.Sprintf("cs-%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, _ := Recent()
+	if len(entries) != maxRecent {
+		t.Fatalf("len(Recent()) = %d, want %d", len(entries), maxRecent)
+	}
+	if entries[0].Name != fmt.Sprintf("cs-%d", maxRecent+4) {
+		t.Errorf("newest entry = %s", entries[0].Name)
+	}
+}
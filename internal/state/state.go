@@ -1,12 +1,23 @@
 // Package state manages the current codespace selection.
 // State is stored in ~/.csd/current which contains the codespace name.
+// Details about the selection are cached in ~/.csd/current.json so they
+// can be shown without a network call. Past selections are kept, most
+// recent first, in ~/.csd/recent.json.
+//
+// Setting $CSD_PROFILE gives a shell its own selection, stored in
+// ~/.csd/current.<profile> and ~/.csd/current.<profile>.json instead.
+// Recent selections are shared between profiles.
 package state
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 )
 
 const (
@@ -14,10 +25,28 @@ const (
 	stateFileName = "current"
 )
 
+// ProfileEnvVar names the environment variable that selects a profile.
+const ProfileEnvVar = "CSD_PROFILE"
+
 var (
 	ErrNoCodespace = errors.New("no codespace selected")
 )
 
+var validProfile = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Profile returns the profile from $CSD_PROFILE, "" for the default one.
+func Profile() (string, error) {
+	profile := strings.TrimSpace(os.Getenv(ProfileEnvVar))
+	if profile != "" && !validProfile.MatchString(profile) {
+		return "", fmt.Errorf("invalid %s %q (use letters, digits, '-' and '_')", ProfileEnvVar, profile)
+	}
+	// ~/.csd/current.json is the default profile's cached info
+	if profile == "json" {
+		return "", fmt.Errorf("invalid %s %q (the name is reserved)", ProfileEnvVar, profile)
+	}
+	return profile, nil
+}
+
 // stateDir returns the path to the state directory (~/.csd)
 func stateDir() (string, error) {
 	home, err := os.UserHomeDir()
@@ -27,13 +56,31 @@ func stateDir() (string, error) {
 	return filepath.Join(home, stateDirName), nil
 }
 
-// stateFile returns the path to the state file (~/.csd/current)
+// stateFile returns the path to the state file (~/.csd/current, or
+// ~/.csd/current.<profile>)
 func stateFile() (string, error) {
+	return profileFile(stateFileName, "")
+}
+
+// infoFile returns the path to the cached info (~/.csd/current.json, or
+// ~/.csd/current.<profile>.json)
+func infoFile() (string, error) {
+	return profileFile(stateFileName, ".json")
+}
+
+func profileFile(base, ext string) (string, error) {
 	dir, err := stateDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(dir, stateFileName), nil
+	profile, err := Profile()
+	if err != nil {
+		return "", err
+	}
+	if profile != "" {
+		base += "." + profile
+	}
+	return filepath.Join(dir, base+ext), nil
 }
 
 // Get returns the currently selected codespace name.
@@ -60,7 +107,8 @@ func Get() (string, error) {
 	return name, nil
 }
 
-// Set saves the given codespace name as the current selection.
+// Set saves the given codespace name as the current selection and adds
+// it to the recent selections.
 func Set(name string) error {
 	dir, err := stateDir()
 	if err != nil {
@@ -76,10 +124,16 @@ func Set(name string) error {
 		return err
 	}
 
-	return os.WriteFile(path, []byte(name+"\n"), 0644)
+	if err := os.WriteFile(path, []byte(name+"\n"), 0644); err != nil {
+		return err
+	}
+
+	// The history is a convenience; don't fail the selection over it
+	addRecent(name, time.Now())
+	return nil
 }
 
-// Clear removes the current codespace selection.
+// Clear removes the current codespace selection and its cached info.
 func Clear() error {
 	path, err := stateFile()
 	if err != nil {
@@ -87,8 +141,66 @@ func Clear() error {
 	}
 
 	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	infoPath, err := infoFile()
+	if err != nil {
+		return err
+	}
+	err = os.Remove(infoPath)
 	if os.IsNotExist(err) {
 		return nil
 	}
 	return err
 }
+
+// Info is cached metadata about the selected codespace.
+type Info struct {
+	Name       string `json:"name"`
+	Repository string `json:"repository"`
+	Branch     string `json:"branch"`
+}
+
+// SetInfo caches metadata about the selected codespace.
+func SetInfo(info Info) error {
+	dir, err := stateDir()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	path, err := infoFile()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// GetInfo returns the cached metadata for the codespace called name.
+// It returns false if nothing is cached for that codespace.
+func GetInfo(name string) (Info, bool) {
+	path, err := infoFile()
+	if err != nil {
+		return Info{}, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Info{}, false
+	}
+
+	var info Info
+	if err := json.Unmarshal(data, &info); err != nil || info.Name != name {
+		return Info{}, false
+	}
+	return info, true
+}
@@ -51,3 +51,115 @@ func TestGetSetClear(t *testing.T) {
 		t.Errorf("Get() after Clear: got err=%v, want ErrNoCodespace", err)
 	}
 }
+
+func TestInfo(t *testing.T) {
+	tmpDir := t.TempDir()
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", origHome)
+
+	// Test GetInfo with nothing cached
+	if _, ok := GetInfo("test-codespace-123"); ok {
+		t.Error("GetInfo() with nothing cached: got ok, want not ok")
+	}
+
+	info := Info{Name: "test-codespace-123", Repository: "github/github", Branch: "main"}
+	if err := SetInfo(info); err != nil {
+		t.Fatalf("SetInfo() failed: %v", err)
+	}
+
+	got, ok := GetInfo("test-codespace-123")
+	if !ok || got != info {
+		t.Errorf("GetInfo() = %+v, %v; want %+v, true", got, ok, info)
+	}
+
+	// Info cached for another codespace is stale
+	if _, ok := GetInfo("other-codespace"); ok {
+		t.Error("GetInfo() for a different codespace: got ok, want not ok")
+	}
+
+	// Clear removes cached info too
+	if err := Clear(); err != nil {
+		t.Fatalf("Clear() failed: %v", err)
+	}
+	if _, ok := GetInfo("test-codespace-123"); ok {
+		t.Error("GetInfo() after Clear: got ok, want not ok")
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+
+	t.Setenv(ProfileEnvVar, "")
+	if err := Set("default-cs"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(ProfileEnvVar, "left")
+	if _, err := Get(); err != ErrNoCodespace {
+		t.Errorf("Get() in a new profile: got err=%v, want ErrNoCodespace", err)
+	}
+	if err := Set("left-cs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetInfo(Info{Name: "left-cs", Repository: "github/github"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(ProfileEnvVar, "right")
+	if err := Set("right-cs"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := GetInfo("left-cs"); ok {
+		t.Error("GetInfo() in profile right returned the info cached by profile left")
+	}
+
+	for profile, want := range map[string]string{"": "default-cs", "left": "left-cs", "right": "right-cs"} {
+		t.Setenv(ProfileEnvVar, profile)
+		if got, err := Get(); err != nil || got != want {
+			t.Errorf("Get() in profile %q = %q, %v; want %q", profile, got, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, ".csd", "current.left")); err != nil {
+		t.Errorf("profile left wasn't stored in current.left: %v", err)
+	}
+
+	// Clearing one profile leaves the others alone
+	t.Setenv(ProfileEnvVar, "left")
+	if err := Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Get(); err != ErrNoCodespace {
+		t.Errorf("Get() after Clear: got err=%v, want ErrNoCodespace", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, ".csd", "current.left.json")); !os.IsNotExist(err) {
+		t.Error("Clear() left the profile's cached info behind")
+	}
+	t.Setenv(ProfileEnvVar, "right")
+	if got, _ := Get(); got != "right-cs" {
+		t.Errorf("Get() in profile right after clearing left = %q, want right-cs", got)
+	}
+	t.Setenv(ProfileEnvVar, "")
+	if got, _ := Get(); got != "default-cs" {
+		t.Errorf("Get() in the default profile after clearing left = %q, want default-cs", got)
+	}
+
+	t.Setenv(ProfileEnvVar, "../escape")
+	if _, err := Get(); err == nil || err == ErrNoCodespace {
+		t.Errorf("Get() with an invalid profile = %v, want an error", err)
+	}
+
+	t.Setenv(ProfileEnvVar, "")
+	if err := SetInfo(Info{Name: "default-cs", Repository: "github/github"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(ProfileEnvVar, "json")
+	if err := Set("json-cs"); err == nil {
+		t.Error("Set() in profile json succeeded, want an error")
+	}
+	t.Setenv(ProfileEnvVar, "")
+	if _, ok := GetInfo("default-cs"); !ok {
+		t.Error("profile json clobbered the default profile's info")
+	}
+}
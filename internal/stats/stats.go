@@ -0,0 +1,177 @@
+// Package stats keeps local usage counters for codespaces.
+// Counters are stored in ~/.csd/stats.json and never leave the machine.
+package stats
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	statsDirName  = ".csd"
+	statsFileName = "stats.json"
+)
+
+// Event is a lifecycle event that gets counted.
+type Event string
+
+const (
+	Create    Event = "create"
+	Session   Event = "ssh"
+	Reconnect Event = "reconnect"
+	Delete    Event = "delete"
+)
+
+// RepoStats are the counters for one repository.
+type RepoStats struct {
+	Creates    int       `json:"creates"`
+	Sessions   int       `json:"sessions"`
+	Reconnects int       `json:"reconnects"`
+	Deletes    int       `json:"deletes"`
+	LastUsed   time.Time `json:"last_used"`
+	// ConnectedSeconds is the total time spent in SSH sessions.
+	ConnectedSeconds int64 `json:"connected_seconds"`
+}
+
+// Total is the number of events recorded for the repository.
+func (r RepoStats) Total() int {
+	return r.Creates + r.Sessions + r.Reconnects + r.Deletes
+}
+
+// Stats holds the counters for every repository seen.
+type Stats struct {
+	Repos map[string]RepoStats `json:"repos"`
+}
+
+// RepoCount pairs a repository with its counters.
+type RepoCount struct {
+	Repo string
+	RepoStats
+}
+
+// Sorted returns the repositories ordered by activity, most active first.
+func (s *Stats) Sorted() []RepoCount {
+	counts := make([]RepoCount, 0, len(s.Repos))
+	for repo, rs := range s.Repos {
+		counts = append(counts, RepoCount{Repo: repo, RepoStats: rs})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Total() != counts[j].Total() {
+			return counts[i].Total() > counts[j].Total()
+		}
+		return counts[i].Repo < counts[j].Repo
+	})
+	return counts
+}
+
+// statsFile returns the path to the stats file (~/.csd/stats.json)
+func statsFile() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, statsDirName, statsFileName), nil
+}
+
+// Load reads the recorded stats. A missing file yields empty stats.
+func Load() (*Stats, error) {
+	s := &Stats{Repos: map[string]RepoStats{}}
+
+	path, err := statsFile()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.Repos == nil {
+		s.Repos = map[string]RepoStats{}
+	}
+	return s, nil
+}
+
+// Record counts one event for repo.
+func Record(repo string, event Event) error {
+	return update(repo, func(rs *RepoStats) {
+		switch event {
+		case Create:
+			rs.Creates++
+		case Session:
+			rs.Sessions++
+		case Reconnect:
+			rs.Reconnects++
+		case Delete:
+			rs.Deletes++
+		}
+	})
+}
+
+// AddConnectedTime adds the duration of an SSH session for repo.
+func AddConnectedTime(repo string, d time.Duration) error {
+	return update(repo, func(rs *RepoStats) {
+		rs.ConnectedSeconds += int64(d / time.Second)
+	})
+}
+
+// update applies fn to repo's counters and saves them.
+func update(repo string, fn func(*RepoStats)) error {
+	if repo == "" {
+		return nil
+	}
+
+	s, err := Load()
+	if err != nil {
+		return err
+	}
+
+	rs := s.Repos[repo]
+	fn(&rs)
+	rs.LastUsed = time.Now()
+	s.Repos[repo] = rs
+
+	return save(s)
+}
+
+// save writes s via a temp file so concurrent readers never see a partial
+// file.
+func save(s *Stats) error {
+	path, err := statsFile()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), statsFileName+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
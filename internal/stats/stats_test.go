@@ -0,0 +1,73 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecord(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("Load() with no file failed: %v", err)
+	}
+	if len(s.Repos) != 0 {
+		t.Fatalf("Load() with no file = %v, want empty", s.Repos)
+	}
+
+	events := []struct {
+		repo  string
+		event Event
+	}{
+		{"github/github", Create},
+		{"github/github", Session},
+		{"github/github", Reconnect},
+		{"github/github", Reconnect},
+		{"luanzeba/gh-csd", Session},
+		{"luanzeba/gh-csd", Delete},
+		{"", Session}, // ignored
+	}
+	for _, e := range events {
+		if err := Record(e.repo, e.event); err != nil {
+			t.Fatalf("Record(%q, %q) failed: %v", e.repo, e.event, err)
+		}
+	}
+
+	s, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	gh := s.Repos["github/github"]
+	if gh.Creates != 1 || gh.Sessions != 1 || gh.Reconnects != 2 || gh.Deletes != 0 {
+		t.Errorf("github/github = %+v, want 1 create, 1 session, 2 reconnects", gh)
+	}
+	if gh.LastUsed.IsZero() {
+		t.Error("github/github LastUsed not set")
+	}
+
+	sorted := s.Sorted()
+	if len(sorted) != 2 || sorted[0].Repo != "github/github" || sorted[1].Repo != "luanzeba/gh-csd" {
+		t.Errorf("Sorted() = %+v, want github/github then luanzeba/gh-csd", sorted)
+	}
+}
+
+func TestAddConnectedTime(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if err := AddConnectedTime("github/github", 90*time.Second); err != nil {
+		t.Fatalf("AddConnectedTime failed: %v", err)
+	}
+	if err := AddConnectedTime("github/github", 30*time.Second); err != nil {
+		t.Fatalf("AddConnectedTime failed: %v", err)
+	}
+
+	s, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if got := s.Repos["github/github"].ConnectedSeconds; got != 120 {
+		t.Errorf("ConnectedSeconds = %d, want 120", got)
+	}
+}
@@ -0,0 +1,79 @@
+package terminal
+
+import (
+	"bytes"
+	"io"
+)
+
+// scrollbackClears are escape sequences that wipe the terminal's scrollback:
+// ED 3 (erase saved lines) and RIS (full reset).
+var scrollbackClears = [][]byte{
+	[]byte("\033[3J"),
+	[]byte("\033c"),
+}
+
+// ClearFilter is an io.Writer that strips scrollback-clearing escape
+// sequences before passing output through. Sequences split across writes
+// are held back until they can be matched.
+type ClearFilter struct {
+	w       io.Writer
+	pending []byte
+}
+
+// NewClearFilter returns a ClearFilter that writes to w.
+func NewClearFilter(w io.Writer) *ClearFilter {
+	return &ClearFilter{w: w}
+}
+
+// Write filters p and writes the result to the underlying writer.
+func (f *ClearFilter) Write(p []byte) (int, error) {
+	buf := append(f.pending, p...)
+	f.pending = nil
+
+	out := make([]byte, 0, len(buf))
+	for i := 0; i < len(buf); {
+		if buf[i] != '\033' {
+			out = append(out, buf[i])
+			i++
+			continue
+		}
+
+		rest := buf[i:]
+		matched, partial := false, false
+		for _, seq := range scrollbackClears {
+			if bytes.HasPrefix(rest, seq) {
+				i += len(seq)
+				matched = true
+				break
+			}
+			if len(rest) < len(seq) && bytes.HasPrefix(seq, rest) {
+				partial = true
+			}
+		}
+		if matched {
+			continue
+		}
+		if partial {
+			// Wait for the rest of the sequence on the next write
+			f.pending = append([]byte(nil), rest...)
+			break
+		}
+		out = append(out, buf[i])
+		i++
+	}
+
+	if _, err := f.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes any held-back partial sequence to the underlying writer.
+func (f *ClearFilter) Flush() error {
+	if len(f.pending) == 0 {
+		return nil
+	}
+	_, err := f.w.Write(f.pending)
+	f.pending = nil
+	return err
+}
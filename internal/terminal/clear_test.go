@@ -0,0 +1,63 @@
+package terminal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestClearFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   string
+	}{
+		{
+			name:   "plain output",
+			writes: []string{"hello\n"},
+			want:   "hello\n",
+		},
+		{
+			name:   "strips erase scrollback",
+			writes: []string{"a\033[H\033[2J\033[3Jb"},
+			want:   "a\033[H\033[2Jb",
+		},
+		{
+			name:   "strips full reset",
+			writes: []string{"before\033cafter"},
+			want:   "beforeafter",
+		},
+		{
+			name:   "sequence split across writes",
+			writes: []string{"x\033[", "3Jy"},
+			want:   "xy",
+		},
+		{
+			name:   "other escape sequences pass through",
+			writes: []string{"\033[31mred\033[0m"},
+			want:   "\033[31mred\033[0m",
+		},
+		{
+			name:   "trailing partial sequence is flushed",
+			writes: []string{"end\033["},
+			want:   "end\033[",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			f := NewClearFilter(&buf)
+			for _, w := range tt.writes {
+				if n, err := f.Write([]byte(w)); err != nil || n != len(w) {
+					t.Fatalf("Write(%q) = %d, %v", w, n, err)
+				}
+			}
+			if err := f.Flush(); err != nil {
+				t.Fatalf("Flush failed: %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package terminal
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// SetWorkingDirectory reports uri, a file:// URL, as the working directory
+// with OSC 7. Ghostty, iTerm2, WezTerm and others use it for features such
+// as opening a new tab in the same directory.
+func SetWorkingDirectory(uri string) {
+	fmt.Fprint(os.Stdout, workingDirectorySequence(uri))
+}
+
+// ResetWorkingDirectory reports the local working directory again, to undo
+// SetWorkingDirectory once a session ends.
+func ResetWorkingDirectory() {
+	dir, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	host, _ := os.Hostname()
+	SetWorkingDirectory(WorkingDirectoryURI(host, dir))
+}
+
+// WorkingDirectoryURI returns the file:// URL for path on host, escaping
+// characters that aren't allowed in a URL.
+func WorkingDirectoryURI(host, path string) string {
+	u := url.URL{Scheme: "file", Host: host, Path: path}
+	return u.String()
+}
+
+func workingDirectorySequence(uri string) string {
+	return "\033]7;" + uri + "\007"
+}
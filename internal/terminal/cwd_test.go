@@ -0,0 +1,28 @@
+package terminal
+
+import "testing"
+
+func TestWorkingDirectoryURI(t *testing.T) {
+	tests := []struct {
+		host string
+		path string
+		want string
+	}{
+		{"super-robot-abc123", "/workspaces/github", "file://super-robot-abc123/workspaces/github"},
+		{"laptop.local", "/Users/me/my project", "file://laptop.local/Users/me/my%20project"},
+		{"", "/tmp", "file:///tmp"},
+	}
+	for _, tt := range tests {
+		if got := WorkingDirectoryURI(tt.host, tt.path); got != tt.want {
+			t.Errorf("WorkingDirectoryURI(%q, %q) = %q, want %q", tt.host, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestWorkingDirectorySequence(t *testing.T) {
+	got := workingDirectorySequence("file://super-robot-abc123/workspaces/github")
+	want := "\x1b]7;file://super-robot-abc123/workspaces/github\x07"
+	if got != want {
+		t.Errorf("workingDirectorySequence() = %q, want %q", got, want)
+	}
+}
@@ -4,16 +4,94 @@ package terminal
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"strings"
 )
 
+// tmuxCommand runs tmux with args and returns its trimmed output.
+var tmuxCommand = func(args ...string) (string, error) {
+	out, err := exec.Command("tmux", args...).Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// tmuxWindow is the tmux window as it was before SetTabTitle first renamed
+// it, so ResetTabTitle can put it back.
+var tmuxWindow struct {
+	saved      bool
+	name       string
+	autoRename string // the window's own setting; "" means inherited
+}
+
 // SetTabTitle sets the terminal tab title using OSC escape sequences.
-// Works with Ghostty, iTerm2, and most modern terminal emulators.
+// Works with Ghostty, iTerm2, and most modern terminal emulators. Inside
+// tmux, which ignores escape sequences that rename windows unless
+// allow-rename is on, the window is renamed with tmux itself. Inside
+// screen, which swallows OSC 1, the window name is set instead.
 func SetTabTitle(title string) {
+	if InTmux() {
+		if !tmuxWindow.saved {
+			tmuxWindow.name, _ = tmuxWindowCommand("display-message", "-p", "#W")
+			tmuxWindow.autoRename, _ = tmuxWindowCommand("show-window-options", "-v", "automatic-rename")
+			tmuxWindow.saved = true
+		}
+		tmuxWindowCommand("rename-window", title)
+		return
+	}
+	fmt.Fprint(os.Stdout, tabTitleSequence(title))
+}
+
+// ResetTabTitle clears the tab title set by SetTabTitle. An empty OSC 1
+// makes most terminals fall back to their own title (e.g. the running
+// program or working directory). Inside tmux, the window gets back its
+// name and automatic-rename setting from before SetTabTitle.
+func ResetTabTitle() {
+	if InTmux() {
+		if !tmuxWindow.saved {
+			return
+		}
+		tmuxWindowCommand("rename-window", tmuxWindow.name)
+		// Renaming turns automatic-rename off for the window
+		if tmuxWindow.autoRename == "" {
+			tmuxWindowCommand("set-window-option", "-u", "automatic-rename")
+		} else {
+			tmuxWindowCommand("set-window-option", "automatic-rename", tmuxWindow.autoRename)
+		}
+		tmuxWindow.saved = false
+		return
+	}
+	fmt.Fprint(os.Stdout, tabTitleSequence(""))
+}
+
+// tmuxWindowCommand runs a tmux command on the window this process runs
+// in, which may not be the active one.
+func tmuxWindowCommand(command string, args ...string) (string, error) {
+	if pane := os.Getenv("TMUX_PANE"); pane != "" {
+		args = append([]string{"-t", pane}, args...)
+	}
+	return tmuxCommand(append([]string{command}, args...)...)
+}
+
+// tabTitleSequence returns the escape sequence that sets the tab title,
+// or the window name inside screen.
+func tabTitleSequence(title string) string {
+	if InMultiplexer() {
+		// screen takes the window name from ESC k ... ST
+		return "\033k" + title + "\033\\"
+	}
 	// OSC 0 sets both window and tab title
 	// OSC 1 sets tab title only (preferred for our use case)
 	// Using OSC 1 for tab title specifically
-	fmt.Fprintf(os.Stdout, "\033]1;%s\007", title)
+	return "\033]1;" + title + "\007"
+}
+
+// InTmux returns true if we're running inside tmux.
+func InTmux() bool {
+	return os.Getenv("TMUX") != ""
+}
+
+// InMultiplexer returns true if we're running inside tmux or GNU screen.
+func InMultiplexer() bool {
+	return InTmux() || os.Getenv("STY") != ""
 }
 
 // SetWindowTitle sets the terminal window title.
@@ -50,8 +128,14 @@ func IsGhostty() bool {
 		strings.HasPrefix(os.Getenv("TERM"), "xterm-ghostty")
 }
 
-// IsSupportedTerminal returns true if the terminal supports OSC escape sequences.
+// IsSupportedTerminal returns true if the terminal supports OSC escape
+// sequences, or we're inside tmux or screen, whose window names are set
+// instead.
 func IsSupportedTerminal() bool {
+	if InMultiplexer() {
+		return true
+	}
+
 	termProgram := os.Getenv("TERM_PROGRAM")
 	term := os.Getenv("TERM")
 
@@ -1,6 +1,10 @@
 package terminal
 
-import "testing"
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
 
 func TestFormatTitle(t *testing.T) {
 	tests := []struct {
@@ -54,3 +58,76 @@ func TestFormatTitle(t *testing.T) {
 		})
 	}
 }
+
+func TestTabTitleSequence(t *testing.T) {
+	title := FormatTitle("CS: {short_repo}:{branch}", "github/github", "main", "super-robot")
+
+	tests := []struct {
+		name string
+		sty  string
+		want string
+	}{
+		{name: "plain terminal", want: "\033]1;CS: github:main\007"},
+		{name: "screen", sty: "123.pts-0.host", want: "\033kCS: github:main\033\\"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TMUX", "")
+			t.Setenv("STY", tt.sty)
+			if got := tabTitleSequence(title); got != tt.want {
+				t.Errorf("tabTitleSequence() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTmuxTabTitle(t *testing.T) {
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,123,0")
+	t.Setenv("TMUX_PANE", "%3")
+
+	var calls []string
+	oldCommand := tmuxCommand
+	t.Cleanup(func() { tmuxCommand = oldCommand })
+	tmuxCommand = func(args ...string) (string, error) {
+		calls = append(calls, strings.Join(args, " "))
+		switch args[0] {
+		case "display-message":
+			return "zsh", nil
+		case "show-window-options":
+			return "", nil // not set on the window, so inherited
+		}
+		return "", nil
+	}
+
+	SetTabTitle("CS: github:main")
+	SetTabTitle("CS: github:feature")
+	ResetTabTitle()
+
+	want := []string{
+		"display-message -t %3 -p #W",
+		"show-window-options -t %3 -v automatic-rename",
+		"rename-window -t %3 CS: github:main",
+		"rename-window -t %3 CS: github:feature",
+		"rename-window -t %3 zsh",
+		"set-window-option -t %3 -u automatic-rename",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("tmux commands =\n%s\nwant\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestIsSupportedTerminalMultiplexer(t *testing.T) {
+	t.Setenv("TERM_PROGRAM", "")
+	t.Setenv("TERM", "screen-256color")
+	t.Setenv("STY", "")
+	t.Setenv("TMUX", "")
+	if IsSupportedTerminal() {
+		t.Error("IsSupportedTerminal() = true outside a multiplexer with TERM=screen-256color")
+	}
+
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,123,0")
+	if !IsSupportedTerminal() {
+		t.Error("IsSupportedTerminal() = false inside tmux")
+	}
+}
@@ -125,6 +125,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.status = "Refreshing codespaces..."
 			m.statusIsError = false
 			m.loading = true
+			// An explicit refresh should never show cached results
+			gh.InvalidateCache()
 			return m, fetchCodespacesCmd()
 		case "up", "k":
 			m.moveCursor(-1)